@@ -23,6 +23,7 @@ import (
 	craneinformers "github.com/gocrane/api/pkg/generated/informers/externalversions"
 	"github.com/gocrane/crane/cmd/crane-agent/app/options"
 	"github.com/gocrane/crane/pkg/agent"
+	"github.com/gocrane/crane/pkg/ensurance/cgroup"
 	"github.com/gocrane/crane/pkg/metrics"
 )
 
@@ -72,6 +73,11 @@ func NewAgentCommand(ctx context.Context) *cobra.Command {
 func Run(ctx context.Context, opts *options.Options) error {
 	hostname := getHostName(opts.HostnameOverride)
 
+	if err := cgroup.SetDriver(cgroup.Driver(opts.CgroupDriver)); err != nil {
+		return err
+	}
+	klog.Infof("Cgroup driver %s, cgroup v2 %t", cgroup.GetDriver(), cgroup.IsV2())
+
 	healthCheck := metrics.NewHealthCheck(opts.MaxInactivity)
 	metrics.RegisterCraneAgent()
 
@@ -94,13 +100,17 @@ func Run(ctx context.Context, opts *options.Options) error {
 	podInformer := podInformerFactory.Core().V1().Pods()
 	nodeInformer := nodeInformerFactory.Core().V1().Nodes()
 
+	// the disruption budgets are watched in all namespaces to respect them when the pods are evicted
+	pdbInformerFactory := informers.NewSharedInformerFactory(kubeClient, informerSyncPeriod)
+	pdbInformer := pdbInformerFactory.Policy().V1beta1().PodDisruptionBudgets()
+
 	craneInformerFactory := craneinformers.NewSharedInformerFactory(craneClient, informerSyncPeriod)
 	nepInformer := craneInformerFactory.Ensurance().V1alpha1().NodeQOSEnsurancePolicies()
 	actionInformer := craneInformerFactory.Ensurance().V1alpha1().AvoidanceActions()
 	tspInformer := craneInformerFactory.Prediction().V1alpha1().TimeSeriesPredictions()
 
 	newAgent, err := agent.NewAgent(ctx, hostname, opts.RuntimeEndpoint, kubeClient, craneClient,
-		podInformer, nodeInformer, nepInformer, actionInformer, tspInformer, opts.NodeResourceReserved, opts.Ifaces, healthCheck, opts.CollectInterval)
+		podInformer, nodeInformer, nepInformer, actionInformer, pdbInformer, tspInformer, opts.NodeResourceReserved, opts.Ifaces, healthCheck, opts.CollectInterval, opts.EvictionOptions())
 
 	if err != nil {
 		return err
@@ -108,13 +118,19 @@ func Run(ctx context.Context, opts *options.Options) error {
 
 	podInformerFactory.Start(ctx.Done())
 	nodeInformerFactory.Start(ctx.Done())
+	pdbInformerFactory.Start(ctx.Done())
 	craneInformerFactory.Start(ctx.Done())
 
 	podInformerFactory.WaitForCacheSync(ctx.Done())
 	nodeInformerFactory.WaitForCacheSync(ctx.Done())
+	pdbInformerFactory.WaitForCacheSync(ctx.Done())
 	craneInformerFactory.WaitForCacheSync(ctx.Done())
 
-	newAgent.Run(healthCheck, opts.EnableProfiling, opts.BindAddr)
+	var debugBindAddr string
+	if opts.EnableDebugAPI {
+		debugBindAddr = opts.DebugBindAddr
+	}
+	newAgent.Run(healthCheck, opts.EnableProfiling, opts.BindAddr, debugBindAddr)
 	return nil
 }
 
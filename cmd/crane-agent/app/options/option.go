@@ -1,10 +1,14 @@
 package options
 
 import (
+	"fmt"
 	"time"
 
 	"github.com/spf13/pflag"
 	cliflag "k8s.io/component-base/cli/flag"
+
+	"github.com/gocrane/crane/pkg/ensurance/cgroup"
+	"github.com/gocrane/crane/pkg/ensurance/executor"
 )
 
 // Options hold the command-line options about crane manager
@@ -17,6 +21,10 @@ type Options struct {
 	EnableProfiling bool
 	// BindAddr is the address the endpoint binds to.
 	BindAddr string
+	// EnableDebugAPI enables the debug endpoints to inspect the live state of the agent
+	EnableDebugAPI bool
+	// DebugBindAddr is the address the debug endpoints bind to, it is separated from BindAddr.
+	DebugBindAddr string
 	// CollectInterval is the period for state collector to collect metrics
 	CollectInterval time.Duration
 	// MaxInactivity is the maximum time from last recorded activity before automatic restart
@@ -24,6 +32,16 @@ type Options struct {
 	// Ifaces is the network devices to collect metric
 	Ifaces               []string
 	NodeResourceReserved map[string]string
+	// EvictionRateLimit is the max number of pods evicted in the EvictionRateInterval
+	EvictionRateLimit    int
+	EvictionRateInterval time.Duration
+	// EvictionNodeBudget is the max number of pods evicted on the node in the EvictionBudgetWindow
+	EvictionNodeBudget int
+	// EvictionNamespaceBudget is the max number of pods of a namespace evicted on the node in the EvictionBudgetWindow
+	EvictionNamespaceBudget int
+	EvictionBudgetWindow    time.Duration
+	// CgroupDriver is the cgroup driver of kubelet, it is detected by the kubepods cgroup if it is empty
+	CgroupDriver string
 }
 
 // NewOptions builds an empty options.
@@ -38,17 +56,50 @@ func (o *Options) Complete() error {
 
 // Validate all required options.
 func (o *Options) Validate() error {
+	if o.EvictionRateLimit < 0 || o.EvictionNodeBudget < 0 || o.EvictionNamespaceBudget < 0 {
+		return fmt.Errorf("the eviction rate limit and budgets must not be negative")
+	}
+	if o.EvictionRateLimit > 0 && o.EvictionRateInterval <= 0 {
+		return fmt.Errorf("eviction-rate-interval must be positive when eviction-rate-limit is set")
+	}
+	if (o.EvictionNodeBudget > 0 || o.EvictionNamespaceBudget > 0) && o.EvictionBudgetWindow <= 0 {
+		return fmt.Errorf("eviction-budget-window must be positive when the eviction budgets are set")
+	}
+	switch cgroup.Driver(o.CgroupDriver) {
+	case "", cgroup.DriverCgroupfs, cgroup.DriverSystemd:
+	default:
+		return fmt.Errorf("cgroup-driver must be %s or %s", cgroup.DriverCgroupfs, cgroup.DriverSystemd)
+	}
 	return nil
 }
 
+// EvictionOptions returns the options to limit the evictions.
+func (o *Options) EvictionOptions() executor.EvictionOptions {
+	return executor.EvictionOptions{
+		RateLimit:       o.EvictionRateLimit,
+		RateInterval:    o.EvictionRateInterval,
+		NodeBudget:      o.EvictionNodeBudget,
+		NamespaceBudget: o.EvictionNamespaceBudget,
+		BudgetWindow:    o.EvictionBudgetWindow,
+	}
+}
+
 // AddFlags adds flags to the specified FlagSet.
 func (o *Options) AddFlags(flags *pflag.FlagSet) {
 	flags.StringVar(&o.HostnameOverride, "hostname-override", "", "Which is the name of k8s node be used to filtered.")
 	flags.StringVar(&o.RuntimeEndpoint, "runtime-endpoint", "", "The runtime endpoint docker: unix:///var/run/dockershim.sock, containerd: unix:///run/containerd/containerd.sock, cri-o: unix:///run/crio/crio.sock, k3s: unix:///run/k3s/containerd/containerd.sock.")
 	flags.Bool("enable-profiling", false, "Is debug/pprof endpoint enabled, default: false")
 	flags.StringVar(&o.BindAddr, "bind-address", "0.0.0.0:8081", "The address the agent binds to for metrics, health-check and pprof, default: 0.0.0.0:8081.")
+	flags.BoolVar(&o.EnableDebugAPI, "enable-debug-api", false, "Are the debug endpoints of the live state enabled, default: false")
+	flags.StringVar(&o.DebugBindAddr, "debug-bind-address", "127.0.0.1:8082", "The address the debug endpoints bind to if enable-debug-api, default: 127.0.0.1:8082.")
 	flags.DurationVar(&o.CollectInterval, "collect-interval", 10*time.Second, "Period for the state collector to collect metrics, default: 10s")
 	flags.StringArrayVar(&o.Ifaces, "ifaces", []string{"eth0"}, "The network devices to collect metric, use comma to separated, default: eth0")
 	flags.Var(cliflag.NewMapStringString(&o.NodeResourceReserved), "node-resource-reserved", "A set of ResourceName=Percent (e.g. cpu=40%,memory=40%)")
+	flags.IntVar(&o.EvictionRateLimit, "eviction-rate-limit", 0, "The max number of pods evicted in the eviction-rate-interval, 0 means unlimited, default: 0")
+	flags.DurationVar(&o.EvictionRateInterval, "eviction-rate-interval", time.Minute, "The interval for the eviction-rate-limit, default: 1m")
+	flags.IntVar(&o.EvictionNodeBudget, "eviction-node-budget", 0, "The max number of pods evicted on the node in the eviction-budget-window, 0 means unlimited, default: 0")
+	flags.IntVar(&o.EvictionNamespaceBudget, "eviction-namespace-budget", 0, "The max number of pods of a namespace evicted on the node in the eviction-budget-window, 0 means unlimited, default: 0")
+	flags.DurationVar(&o.EvictionBudgetWindow, "eviction-budget-window", time.Hour, "The window for the eviction-node-budget and eviction-namespace-budget, default: 1h")
+	flags.StringVar(&o.CgroupDriver, "cgroup-driver", "", "The cgroup driver of kubelet, cgroupfs or systemd, it is detected by the kubepods cgroup if empty, default: \"\"")
 	flags.DurationVar(&o.MaxInactivity, "max-inactivity", 5*time.Minute, "Maximum time from last recorded activity before automatic restart, default: 5min")
 }
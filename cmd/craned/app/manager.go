@@ -13,6 +13,7 @@ import (
 	utilfeature "k8s.io/apiserver/pkg/util/feature"
 	"k8s.io/client-go/discovery"
 	"k8s.io/client-go/dynamic"
+	"k8s.io/client-go/kubernetes"
 	clientgoscheme "k8s.io/client-go/kubernetes/scheme"
 	"k8s.io/client-go/scale"
 	"k8s.io/klog/v2"
@@ -34,6 +35,7 @@ import (
 	"github.com/gocrane/crane/pkg/known"
 	"github.com/gocrane/crane/pkg/metrics"
 	"github.com/gocrane/crane/pkg/oom"
+	"github.com/gocrane/crane/pkg/prediction/checkpoint"
 	"github.com/gocrane/crane/pkg/predictor"
 	"github.com/gocrane/crane/pkg/providers"
 	"github.com/gocrane/crane/pkg/providers/metricserver"
@@ -106,7 +108,7 @@ func Run(ctx context.Context, opts *options.Options) error {
 	}
 	// initialize data sources and predictor
 	realtimeDataSources, histroyDataSources, _ := initializationDataSource(mgr, opts)
-	predictorMgr := initializationPredictorManager(opts, realtimeDataSources, histroyDataSources)
+	predictorMgr := initializationPredictorManager(mgr, opts, realtimeDataSources, histroyDataSources)
 
 	initializationScheme()
 	initializationWebhooks(mgr, opts)
@@ -192,8 +194,28 @@ func initializationDataSource(mgr ctrl.Manager, opts *options.Options) (map[prov
 	return realtimeDataSources, historyDataSources, hybridDataSources
 }
 
-func initializationPredictorManager(opts *options.Options, realtimeDataSources map[providers.DataSourceType]providers.RealTime, historyDataSources map[providers.DataSourceType]providers.History) predictor.Manager {
-	return predictor.NewManager(realtimeDataSources, historyDataSources, predictor.DefaultPredictorsConfig(opts.AlgorithmModelConfig))
+func initializationPredictorManager(mgr ctrl.Manager, opts *options.Options, realtimeDataSources map[providers.DataSourceType]providers.RealTime, historyDataSources map[providers.DataSourceType]providers.History) predictor.Manager {
+	return predictor.NewManager(realtimeDataSources, historyDataSources, predictor.DefaultPredictorsConfig(opts.AlgorithmModelConfig, initializationCheckpointStore(mgr, opts)))
+}
+
+// initializationCheckpointStore returns the store of the model checkpoints, nil is returned if checkpoint is disabled
+func initializationCheckpointStore(mgr ctrl.Manager, opts *options.Options) checkpoint.Store {
+	switch opts.CheckpointStore {
+	case checkpoint.StoreTypeFile:
+		store, err := checkpoint.NewFileStore(opts.CheckpointDir)
+		if err != nil {
+			klog.Exitf("unable to create checkpoint store %v, err: %v", opts.CheckpointStore, err)
+		}
+		return store
+	case checkpoint.StoreTypeConfigMap:
+		kubeClient, err := kubernetes.NewForConfig(mgr.GetConfig())
+		if err != nil {
+			klog.Exitf("unable to create checkpoint store %v, err: %v", opts.CheckpointStore, err)
+		}
+		return checkpoint.NewConfigMapStore(kubeClient, opts.CheckpointNamespace)
+	default:
+		return nil
+	}
 }
 
 // initializationControllers setup controllers with manager
@@ -275,6 +297,7 @@ func initializationControllers(ctx context.Context, mgr ctrl.Manager, opts *opti
 			opts.PredictionUpdateFrequency,
 			predictorMgr,
 			targetSelectorFetcher,
+			historyDataSource,
 		)
 		if err := tspController.SetupWithManager(mgr); err != nil {
 			klog.Exit(err, "unable to create controller", "controller", "TspController")
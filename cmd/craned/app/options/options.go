@@ -1,12 +1,15 @@
 package options
 
 import (
+	"fmt"
 	"time"
 
 	"github.com/spf13/pflag"
 	componentbaseconfig "k8s.io/component-base/config"
 
 	"github.com/gocrane/crane/pkg/controller/ehpa"
+	"github.com/gocrane/crane/pkg/known"
+	"github.com/gocrane/crane/pkg/prediction/checkpoint"
 	"github.com/gocrane/crane/pkg/prediction/config"
 	"github.com/gocrane/crane/pkg/providers"
 	serverconfig "github.com/gocrane/crane/pkg/server/config"
@@ -36,6 +39,12 @@ type Options struct {
 
 	// AlgorithmModelConfig
 	AlgorithmModelConfig config.AlgorithmModelConfig
+	// CheckpointStore is the type of the store to save the model checkpoints, file or configmap. checkpoint is disabled if empty
+	CheckpointStore string
+	// CheckpointDir is the directory of the checkpoints for the file store
+	CheckpointDir string
+	// CheckpointNamespace is the namespace of the checkpoints for the configmap store
+	CheckpointNamespace string
 
 	// WebhookConfig
 	WebhookConfig webhooks.WebhookConfig
@@ -65,7 +74,13 @@ func (o *Options) Complete() error {
 
 // Validate all required options.
 func (o *Options) Validate() []error {
-	return o.ServerOptions.Validate()
+	errs := o.ServerOptions.Validate()
+	switch o.CheckpointStore {
+	case "", checkpoint.StoreTypeFile, checkpoint.StoreTypeConfigMap:
+	default:
+		errs = append(errs, fmt.Errorf("unknown model checkpoint store %q, file and configmap are supported", o.CheckpointStore))
+	}
+	return errs
 }
 
 func (o *Options) ApplyTo(cfg *serverconfig.Config) error {
@@ -104,6 +119,11 @@ func (o *Options) AddFlags(flags *pflag.FlagSet) {
 	flags.StringVar(&o.DataSourceMockConfig.SeedFile, "seed-file", "", "mock provider seed file")
 
 	flags.DurationVar(&o.AlgorithmModelConfig.UpdateInterval, "model-update-interval", 12*time.Hour, "algorithm model update interval, now used for dsp model update interval")
+	flags.DurationVar(&o.AlgorithmModelConfig.CheckpointInterval, "model-checkpoint-interval", 10*time.Minute, "algorithm model checkpoint interval")
+	flags.DurationVar(&o.AlgorithmModelConfig.CheckpointExpiration, "model-checkpoint-expiration", 24*time.Hour, "the stale model checkpoints older than it are not restored and deleted")
+	flags.StringVar(&o.CheckpointStore, "model-checkpoint-store", "", "store of the model checkpoints, file and configmap are available, checkpoint is disabled if empty")
+	flags.StringVar(&o.CheckpointDir, "model-checkpoint-dir", "/var/lib/crane/checkpoints", "directory of the model checkpoints for the file store")
+	flags.StringVar(&o.CheckpointNamespace, "model-checkpoint-namespace", known.CraneSystemNamespace, "namespace of the model checkpoints for the configmap store")
 
 	flags.BoolVar(&o.WebhookConfig.Enabled, "webhook-enabled", true, "whether enable webhook or not, default to true")
 
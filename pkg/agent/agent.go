@@ -20,6 +20,7 @@ import (
 	"k8s.io/apiserver/pkg/server/routes"
 	utilfeature "k8s.io/apiserver/pkg/util/feature"
 	coreinformers "k8s.io/client-go/informers/core/v1"
+	policyinformers "k8s.io/client-go/informers/policy/v1beta1"
 	"k8s.io/client-go/kubernetes"
 	"k8s.io/client-go/kubernetes/scheme"
 	"k8s.io/component-base/metrics/legacyregistry"
@@ -32,6 +33,7 @@ import (
 	"github.com/gocrane/api/pkg/generated/informers/externalversions/ensurance/v1alpha1"
 	predictionv1 "github.com/gocrane/api/pkg/generated/informers/externalversions/prediction/v1alpha1"
 	v1alpha12 "github.com/gocrane/api/prediction/v1alpha1"
+	"github.com/gocrane/crane/pkg/common"
 	"github.com/gocrane/crane/pkg/ensurance/analyzer"
 	"github.com/gocrane/crane/pkg/ensurance/cm"
 	"github.com/gocrane/crane/pkg/ensurance/collector"
@@ -50,6 +52,13 @@ type Agent struct {
 	kubeClient  kubernetes.Interface
 	craneClient craneclientset.Interface
 	managers    []manager.Manager
+
+	// the managers kept for the debug endpoints, the optional ones are nil if not enabled
+	stateCollector      *collector.StateCollector
+	analyzer            *analyzer.AnormalyAnalyzer
+	executor            *executor.ActionExecutor
+	cpuManager          *cm.AdvancedCpuManager
+	nodeResourceManager *resource.NodeResourceManager
 }
 
 func NewAgent(ctx context.Context,
@@ -60,11 +69,13 @@ func NewAgent(ctx context.Context,
 	nodeInformer coreinformers.NodeInformer,
 	nepInformer v1alpha1.NodeQOSEnsurancePolicyInformer,
 	actionInformer v1alpha1.AvoidanceActionInformer,
+	pdbInformer policyinformers.PodDisruptionBudgetInformer,
 	tspInformer predictionv1.TimeSeriesPredictionInformer,
 	nodeResourceReserved map[string]string,
 	ifaces []string,
 	healthCheck *metrics.HealthCheck,
 	CollectInterval time.Duration,
+	evictionOptions executor.EvictionOptions,
 ) (*Agent, error) {
 	var managers []manager.Manager
 	var noticeCh = make(chan executor.AvoidanceExecutor)
@@ -79,17 +90,24 @@ func NewAgent(ctx context.Context,
 	utilruntime.Must(ensuranceapi.AddToScheme(scheme.Scheme))
 	cadvisorManager := cadvisor.NewCadvisorManager()
 	exclusiveCPUSet := cm.DefaultExclusiveCPUSet
+	// the cpu manager and the state collector depend on each other, the state is got after the collector is created
+	var stateCollector *collector.StateCollector
 	if craneCpuSetManager := utilfeature.DefaultFeatureGate.Enabled(features.CraneCpuSetManager); craneCpuSetManager {
-		cpuManager := cm.NewAdvancedCpuManager(podInformer, runtimeEndpoint, cadvisorManager)
+		getState := func() (map[string][]common.TimeSeries, time.Time) { return stateCollector.GetState() }
+		cpuManager := cm.NewAdvancedCpuManager(kubeClient, podInformer, runtimeEndpoint, cadvisorManager, getState)
 		exclusiveCPUSet = cpuManager.GetExclusiveCpu
 		managers = appendManagerIfNotNil(managers, cpuManager)
+		agent.cpuManager = cpuManager
 	}
-	stateCollector := collector.NewStateCollector(nodeName, nepInformer.Lister(), podInformer.Lister(), nodeInformer.Lister(), ifaces, healthCheck, CollectInterval, exclusiveCPUSet, cadvisorManager)
+	stateCollector = collector.NewStateCollector(kubeClient, nodeName, nepInformer.Lister(), podInformer.Lister(), nodeInformer.Lister(), ifaces, healthCheck, CollectInterval, exclusiveCPUSet, cadvisorManager)
 	managers = appendManagerIfNotNil(managers, stateCollector)
-	analyzerManager := analyzer.NewAnormalyAnalyzer(kubeClient, nodeName, podInformer, nodeInformer, nepInformer, actionInformer, stateCollector.AnalyzerChann, noticeCh)
+	agent.stateCollector = stateCollector
+	analyzerManager := analyzer.NewAnormalyAnalyzer(kubeClient, nodeName, podInformer, nodeInformer, nepInformer, actionInformer, pdbInformer, stateCollector.AnalyzerChann, noticeCh)
 	managers = appendManagerIfNotNil(managers, analyzerManager)
-	avoidanceManager := executor.NewActionExecutor(kubeClient, nodeName, podInformer, nodeInformer, noticeCh, runtimeEndpoint)
+	agent.analyzer = analyzerManager
+	avoidanceManager := executor.NewActionExecutor(kubeClient, nodeName, podInformer, nodeInformer, noticeCh, runtimeEndpoint, evictionOptions)
 	managers = appendManagerIfNotNil(managers, avoidanceManager)
+	agent.executor = avoidanceManager
 	if nodeResource := utilfeature.DefaultFeatureGate.Enabled(features.CraneNodeResource); nodeResource {
 		tspName, err := agent.CreateNodeResourceTsp()
 		if err != nil {
@@ -100,6 +118,7 @@ func NewAgent(ctx context.Context,
 			return agent, err
 		}
 		managers = appendManagerIfNotNil(managers, nodeResourceManager)
+		agent.nodeResourceManager = nodeResourceManager
 	}
 
 	if podResource := utilfeature.DefaultFeatureGate.Enabled(features.CranePodResource); podResource {
@@ -112,7 +131,9 @@ func NewAgent(ctx context.Context,
 	return agent, nil
 }
 
-func (a *Agent) Run(healthCheck *metrics.HealthCheck, enableProfiling bool, bindAddr string) {
+// Run runs the managers and serves the metrics on bindAddr, the debug endpoints are served on debugBindAddr if it is
+// not empty.
+func (a *Agent) Run(healthCheck *metrics.HealthCheck, enableProfiling bool, bindAddr string, debugBindAddr string) {
 	klog.Infof("Crane agent %s is starting", a.name)
 
 	for _, m := range a.managers {
@@ -136,6 +157,17 @@ func (a *Agent) Run(healthCheck *metrics.HealthCheck, enableProfiling bool, bind
 		klog.Fatalf("Failed to start metrics: %v", err)
 	}()
 
+	// the debug endpoints expose the pods and the policies of the node, they are served on a separate address which
+	// is local by default
+	if debugBindAddr != "" {
+		go func() {
+			debugMux := mux.NewPathRecorderMux("crane-agent-debug")
+			a.installDebugHandlers(debugMux)
+			err := http.ListenAndServe(debugBindAddr, debugMux)
+			klog.Fatalf("Failed to start debug endpoints: %v", err)
+		}()
+	}
+
 	<-a.ctx.Done()
 }
 
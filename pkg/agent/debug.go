@@ -0,0 +1,141 @@
+package agent
+
+import (
+	"bytes"
+	"encoding/json"
+	"math"
+	"net/http"
+	"time"
+
+	v1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/util/sets"
+	"k8s.io/apiserver/pkg/server/mux"
+	"k8s.io/klog/v2"
+
+	"github.com/gocrane/crane/pkg/common"
+	"github.com/gocrane/crane/pkg/resource"
+)
+
+// installDebugHandlers installs the read-only endpoints to inspect the live state of the agent in json.
+func (a *Agent) installDebugHandlers(m *mux.PathRecorderMux) {
+	m.HandleFunc("/debug/state", a.serveState)
+	m.HandleFunc("/debug/policies", a.servePolicies)
+	m.HandleFunc("/debug/objectives", a.serveObjectives)
+	m.HandleFunc("/debug/preview", a.servePreview)
+	m.HandleFunc("/debug/executor", a.serveExecutor)
+	m.HandleFunc("/debug/cpu", a.serveCPUAssignments)
+	m.HandleFunc("/debug/node-resource", a.serveNodeResource)
+}
+
+// serveState serves the latest metrics collected, the metrics can be filtered by the query parameter metric.
+func (a *Agent) serveState(w http.ResponseWriter, r *http.Request) {
+	if a.stateCollector == nil {
+		http.Error(w, "the state collector is not running", http.StatusNotFound)
+		return
+	}
+
+	state, collectedTime := a.stateCollector.GetState()
+	names := sets.NewString(r.URL.Query()["metric"]...)
+	metrics := make(map[string][]common.TimeSeries, len(state))
+	for name, series := range state {
+		if names.Len() == 0 || names.Has(name) {
+			metrics[name] = finiteSeries(series)
+		}
+	}
+
+	serveJSON(w, r, struct {
+		Time    time.Time                      `json:"time"`
+		Metrics map[string][]common.TimeSeries `json:"metrics"`
+	}{collectedTime, metrics})
+}
+
+// servePolicies serves the NodeQOSEnsurancePolicies matched to the node.
+func (a *Agent) servePolicies(w http.ResponseWriter, r *http.Request) {
+	neps, err := a.analyzer.GetMatchedPolicies()
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	serveJSON(w, r, neps)
+}
+
+// serveObjectives serves the status of the objective ensurances, including the counters of the analyzer.
+func (a *Agent) serveObjectives(w http.ResponseWriter, r *http.Request) {
+	serveJSON(w, r, a.analyzer.GetObjectiveStatuses())
+}
+
+// servePreview serves the plan of the actions in preview.
+func (a *Agent) servePreview(w http.ResponseWriter, r *http.Request) {
+	serveJSON(w, r, a.analyzer.GetPreviewPlan())
+}
+
+// serveExecutor serves the plan of the latest AvoidanceExecutor executed and its result.
+func (a *Agent) serveExecutor(w http.ResponseWriter, r *http.Request) {
+	if a.executor == nil {
+		http.Error(w, "the action executor is not running", http.StatusNotFound)
+		return
+	}
+	serveJSON(w, r, a.executor.GetLastExecution())
+}
+
+// serveCPUAssignments serves the cpusets assigned by the advanced cpu manager.
+func (a *Agent) serveCPUAssignments(w http.ResponseWriter, r *http.Request) {
+	if a.cpuManager == nil {
+		http.Error(w, "the advanced cpu manager is not enabled", http.StatusNotFound)
+		return
+	}
+	assignments, ok := a.cpuManager.GetCPUAssignments()
+	if !ok {
+		http.Error(w, "the advanced cpu manager is not started", http.StatusServiceUnavailable)
+		return
+	}
+	serveJSON(w, r, assignments)
+}
+
+// serveNodeResource serves the extended resources computed by the node resource manager.
+func (a *Agent) serveNodeResource(w http.ResponseWriter, r *http.Request) {
+	if a.nodeResourceManager == nil {
+		http.Error(w, "the node resource manager is not enabled", http.StatusNotFound)
+		return
+	}
+	resources, computedTime := a.nodeResourceManager.GetExtResources()
+	serveJSON(w, r, struct {
+		Time      time.Time                                `json:"time"`
+		Resources map[v1.ResourceName]resource.ExtResource `json:"resources"`
+	}{computedTime, resources})
+}
+
+// serveJSON writes the object in json, only GET is allowed since the endpoints are read-only.
+func serveJSON(w http.ResponseWriter, r *http.Request, obj interface{}) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "only GET is allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	var buf bytes.Buffer
+	encoder := json.NewEncoder(&buf)
+	encoder.SetIndent("", "  ")
+	if err := encoder.Encode(obj); err != nil {
+		klog.Errorf("Failed to encode %s: %v", r.URL.Path, err)
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	_, _ = w.Write(buf.Bytes())
+}
+
+// finiteSeries drops the samples which are NaN or infinite, since they can not be encoded in json.
+func finiteSeries(series []common.TimeSeries) []common.TimeSeries {
+	var result = make([]common.TimeSeries, 0, len(series))
+	for _, ts := range series {
+		var samples = make([]common.Sample, 0, len(ts.Samples))
+		for _, sample := range ts.Samples {
+			if !math.IsNaN(sample.Value) && !math.IsInf(sample.Value, 0) {
+				samples = append(samples, sample)
+			}
+		}
+		result = append(result, common.TimeSeries{Labels: ts.Labels, Samples: samples})
+	}
+	return result
+}
@@ -0,0 +1,89 @@
+package agent
+
+import (
+	"math"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"testing"
+
+	"github.com/gocrane/crane/pkg/common"
+)
+
+func TestDebugHandlers(t *testing.T) {
+	a := &Agent{}
+
+	cases := map[string]struct {
+		method string
+		path   string
+		code   int
+	}{
+		"state collector not running": {
+			method: http.MethodGet,
+			path:   "/debug/state",
+			code:   http.StatusNotFound,
+		},
+		"action executor not running": {
+			method: http.MethodGet,
+			path:   "/debug/executor",
+			code:   http.StatusNotFound,
+		},
+		"cpu manager not enabled": {
+			method: http.MethodGet,
+			path:   "/debug/cpu",
+			code:   http.StatusNotFound,
+		},
+		"node resource manager not enabled": {
+			method: http.MethodGet,
+			path:   "/debug/node-resource",
+			code:   http.StatusNotFound,
+		},
+	}
+
+	handlers := map[string]http.HandlerFunc{
+		"/debug/state":         a.serveState,
+		"/debug/executor":      a.serveExecutor,
+		"/debug/cpu":           a.serveCPUAssignments,
+		"/debug/node-resource": a.serveNodeResource,
+	}
+	for name, c := range cases {
+		t.Run(name, func(t *testing.T) {
+			w := httptest.NewRecorder()
+			handlers[c.path](w, httptest.NewRequest(c.method, c.path, nil))
+			if w.Code != c.code {
+				t.Errorf("Expected status code %d, got %d: %s", c.code, w.Code, w.Body.String())
+			}
+		})
+	}
+}
+
+func TestServeJSON(t *testing.T) {
+	w := httptest.NewRecorder()
+	serveJSON(w, httptest.NewRequest(http.MethodPost, "/debug/preview", nil), struct{}{})
+	if w.Code != http.StatusMethodNotAllowed {
+		t.Errorf("Expected status code %d for POST, got %d", http.StatusMethodNotAllowed, w.Code)
+	}
+
+	w = httptest.NewRecorder()
+	serveJSON(w, httptest.NewRequest(http.MethodGet, "/debug/preview", nil), map[string]int{"a": 1})
+	if w.Code != http.StatusOK || w.Header().Get("Content-Type") != "application/json" {
+		t.Errorf("Expected json with status code %d, got %d %s", http.StatusOK, w.Code, w.Header().Get("Content-Type"))
+	}
+	if w.Body.String() != "{\n  \"a\": 1\n}\n" {
+		t.Errorf("Unexpected body %q", w.Body.String())
+	}
+}
+
+func TestFiniteSeries(t *testing.T) {
+	series := []common.TimeSeries{{
+		Labels:  []common.Label{{Name: "cpu", Value: "0"}},
+		Samples: []common.Sample{{Value: 1, Timestamp: 1}, {Value: math.NaN(), Timestamp: 2}, {Value: math.Inf(1), Timestamp: 3}},
+	}}
+	expect := []common.TimeSeries{{
+		Labels:  []common.Label{{Name: "cpu", Value: "0"}},
+		Samples: []common.Sample{{Value: 1, Timestamp: 1}},
+	}}
+	if got := finiteSeries(series); !reflect.DeepEqual(got, expect) {
+		t.Errorf("Expected %v, got %v", expect, got)
+	}
+}
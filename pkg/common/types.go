@@ -12,6 +12,8 @@ const (
 	LabelNameContainerName = "ContainerName"
 	LabelNameContainerId   = "ContainerId"
 	LabelNameHasExtRes     = "HasExtRes"
+	// LabelNameNodeQOS is the name of the NodeQOSEnsurancePolicy whose probe exposes the series
+	LabelNameNodeQOS = "NodeQOSEnsurancePolicy"
 )
 
 // TimeSeries is a stream of samples that belong to a metric with a set of labels
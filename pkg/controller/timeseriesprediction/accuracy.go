@@ -0,0 +1,234 @@
+package timeseriesprediction
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"sync"
+	"time"
+
+	"k8s.io/apimachinery/pkg/api/equality"
+	"k8s.io/klog/v2"
+
+	predictionapi "github.com/gocrane/api/prediction/v1alpha1"
+
+	"github.com/gocrane/crane/pkg/common"
+	"github.com/gocrane/crane/pkg/known"
+	"github.com/gocrane/crane/pkg/metricnaming"
+	"github.com/gocrane/crane/pkg/prediction"
+	"github.com/gocrane/crane/pkg/prediction/accuracy"
+	"github.com/gocrane/crane/pkg/providers"
+)
+
+const (
+	// accuracyEvaluationDelay is the delay to evaluate a predicted window after it ends, so the actual samples arrive
+	accuracyEvaluationDelay = 2 * time.Minute
+	// maxPredictedWindows is the max number of the predicted windows kept for a metric to evaluate
+	maxPredictedWindows = 10
+)
+
+// predictedWindow is the predicted time series of a metric in a time window.
+type predictedWindow struct {
+	start      time.Time
+	end        time.Time
+	step       time.Duration
+	timeSeries []*common.TimeSeries
+}
+
+// accuracyTracker keeps the predicted windows of the metrics of the TimeSeriesPredictions until they end and are evaluated.
+type accuracyTracker struct {
+	mutex   sync.Mutex
+	windows map[string] /*tsp key*/ map[string] /*resource identifier*/ []*predictedWindow
+}
+
+func newAccuracyTracker() *accuracyTracker {
+	return &accuracyTracker{windows: map[string]map[string][]*predictedWindow{}}
+}
+
+// record keeps the predicted samples in [start, end] of the metric. The predictions are updated more often than the
+// window, so a window overlapping the last one kept is skipped, the windows evaluated are back to back.
+func (t *accuracyTracker) record(key string, resourceIdentifier string, start, end time.Time, tsList []*common.TimeSeries) {
+	if t.overlaps(key, resourceIdentifier, start) {
+		return
+	}
+
+	window := &predictedWindow{start: start, end: end, step: time.Minute}
+	for _, ts := range tsList {
+		var samples []common.Sample
+		for _, sample := range ts.Samples {
+			if sample.Timestamp >= start.Unix() && sample.Timestamp <= end.Unix() {
+				samples = append(samples, sample)
+			}
+		}
+		if len(samples) == 0 {
+			continue
+		}
+		if len(samples) > 1 {
+			window.step = time.Duration(samples[1].Timestamp-samples[0].Timestamp) * time.Second
+		}
+		window.timeSeries = append(window.timeSeries, &common.TimeSeries{Labels: ts.Labels, Samples: samples})
+	}
+	if len(window.timeSeries) == 0 {
+		return
+	}
+
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+	if _, exists := t.windows[key]; !exists {
+		t.windows[key] = map[string][]*predictedWindow{}
+	}
+	windows := append(t.windows[key][resourceIdentifier], window)
+	if len(windows) > maxPredictedWindows {
+		windows = windows[len(windows)-maxPredictedWindows:]
+	}
+	t.windows[key][resourceIdentifier] = windows
+}
+
+// overlaps returns true if the time is before the end of the last window kept for the metric.
+func (t *accuracyTracker) overlaps(key string, resourceIdentifier string, start time.Time) bool {
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+	windows := t.windows[key][resourceIdentifier]
+	return len(windows) > 0 && start.Before(windows[len(windows)-1].end)
+}
+
+// popEnded removes and returns the predicted windows which end before the time, in chronological order.
+func (t *accuracyTracker) popEnded(key string, before time.Time) map[string][]*predictedWindow {
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+
+	ended := map[string][]*predictedWindow{}
+	for resourceIdentifier, windows := range t.windows[key] {
+		var pending []*predictedWindow
+		for _, window := range windows {
+			if window.end.Before(before) {
+				ended[resourceIdentifier] = append(ended[resourceIdentifier], window)
+			} else {
+				pending = append(pending, window)
+			}
+		}
+		t.windows[key][resourceIdentifier] = pending
+	}
+	return ended
+}
+
+func (t *accuracyTracker) delete(key string) {
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+	delete(t.windows, key)
+}
+
+// evaluate returns the accuracy of the predicted window against the actual time series from the history provider.
+func (w *predictedWindow) evaluate(history providers.History, namer metricnaming.MetricNamer) (*accuracy.WindowResult, error) {
+	first, last := w.end.Unix(), w.start.Unix()
+	for _, ts := range w.timeSeries {
+		if ts.Samples[0].Timestamp < first {
+			first = ts.Samples[0].Timestamp
+		}
+		if ts.Samples[len(ts.Samples)-1].Timestamp > last {
+			last = ts.Samples[len(ts.Samples)-1].Timestamp
+		}
+	}
+
+	tsList, err := history.QueryTimeSeries(namer, time.Unix(first, 0), time.Unix(last, 0), w.step)
+	if err != nil {
+		return nil, err
+	}
+
+	actualSamples := map[string]map[int64]float64{}
+	for _, ts := range tsList {
+		samples := map[int64]float64{}
+		for _, sample := range ts.Samples {
+			samples[sample.Timestamp] = sample.Value
+		}
+		actualSamples[prediction.AggregateSignalKey(ts.Labels)] = samples
+	}
+
+	var actual, predicted []float64
+	for _, ts := range w.timeSeries {
+		samples, ok := actualSamples[prediction.AggregateSignalKey(ts.Labels)]
+		if !ok {
+			continue
+		}
+		for _, sample := range ts.Samples {
+			if value, ok := samples[sample.Timestamp]; ok {
+				actual = append(actual, value)
+				predicted = append(predicted, sample.Value)
+			}
+		}
+	}
+
+	result, err := accuracy.Evaluate(actual, predicted)
+	if err != nil {
+		return nil, fmt.Errorf("failed to evaluate the window [%v, %v]: %v", w.start, w.end, err)
+	}
+	return &accuracy.WindowResult{Start: w.start.Unix(), End: w.end.Unix(), Result: *result}, nil
+}
+
+// syncPredictionAccuracy evaluates the predicted windows ended by the actual time series, and saves the accuracy of the
+// latest window of each metric to the annotation of the TimeSeriesPrediction.
+func (tc *Controller) syncPredictionAccuracy(ctx context.Context, tsPrediction *predictionapi.TimeSeriesPrediction) error {
+	if tc.historyProvider == nil {
+		return nil
+	}
+
+	ended := tc.accuracyTracker.popEnded(GetTimeSeriesPredictionKey(tsPrediction), time.Now().Add(-accuracyEvaluationDelay))
+	if len(ended) == 0 {
+		return nil
+	}
+
+	c, err := NewMetricContext(tc.TargetFetcher, tsPrediction, tc.predictorMgr)
+	if err != nil {
+		return err
+	}
+
+	results := getPredictionAccuracy(tsPrediction)
+	newResults := map[string]accuracy.WindowResult{}
+	for _, metric := range tsPrediction.Spec.PredictionMetrics {
+		if result, ok := results[metric.ResourceIdentifier]; ok {
+			newResults[metric.ResourceIdentifier] = result
+		}
+		namer := c.GetMetricNamer(&metric)
+		if namer == nil {
+			continue
+		}
+		for _, window := range ended[metric.ResourceIdentifier] {
+			result, err := window.evaluate(tc.historyProvider, namer)
+			if err != nil {
+				klog.V(4).InfoS("Failed to evaluate the prediction accuracy.", "tsp", klog.KObj(tsPrediction), "resourceIdentifier", metric.ResourceIdentifier, "err", err)
+				continue
+			}
+			klog.V(4).InfoS("Prediction accuracy evaluated.", "tsp", klog.KObj(tsPrediction), "resourceIdentifier", metric.ResourceIdentifier,
+				"predictionError", result.PredictionError, "mae", result.MAE, "samples", result.Samples)
+			newResults[metric.ResourceIdentifier] = *result
+		}
+	}
+
+	if equality.Semantic.DeepEqual(results, newResults) {
+		return nil
+	}
+	value, err := json.Marshal(newResults)
+	if err != nil {
+		return err
+	}
+	tsPredictionCopy := tsPrediction.DeepCopy()
+	if tsPredictionCopy.Annotations == nil {
+		tsPredictionCopy.Annotations = map[string]string{}
+	}
+	tsPredictionCopy.Annotations[known.PredictionAccuracyAnnotation] = string(value)
+	return tc.Client.Update(ctx, tsPredictionCopy)
+}
+
+// getPredictionAccuracy returns the accuracy of the metrics in the annotation of the TimeSeriesPrediction.
+func getPredictionAccuracy(tsPrediction *predictionapi.TimeSeriesPrediction) map[string]accuracy.WindowResult {
+	results := map[string]accuracy.WindowResult{}
+	value, ok := tsPrediction.Annotations[known.PredictionAccuracyAnnotation]
+	if !ok {
+		return results
+	}
+	if err := json.Unmarshal([]byte(value), &results); err != nil {
+		klog.ErrorS(err, "Failed to parse the prediction accuracy annotation.", "tsp", klog.KObj(tsPrediction))
+		return map[string]accuracy.WindowResult{}
+	}
+	return results
+}
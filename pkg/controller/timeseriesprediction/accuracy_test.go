@@ -0,0 +1,63 @@
+package timeseriesprediction
+
+import (
+	"testing"
+	"time"
+
+	"github.com/gocrane/crane/pkg/common"
+	"github.com/gocrane/crane/pkg/metricnaming"
+	"github.com/stretchr/testify/assert"
+)
+
+type fakeHistory struct {
+	ts *common.TimeSeries
+}
+
+func (h *fakeHistory) QueryTimeSeries(_ metricnaming.MetricNamer, _ time.Time, _ time.Time, _ time.Duration) ([]*common.TimeSeries, error) {
+	return []*common.TimeSeries{h.ts}, nil
+}
+
+func predictedTimeSeries(start time.Time, n int, value float64) []*common.TimeSeries {
+	ts := &common.TimeSeries{}
+	for i := 0; i < n; i++ {
+		ts.Samples = append(ts.Samples, common.Sample{Value: value, Timestamp: start.Add(time.Duration(i) * time.Minute).Unix()})
+	}
+	return []*common.TimeSeries{ts}
+}
+
+func TestAccuracyTracker(t *testing.T) {
+	tracker := newAccuracyTracker()
+	start := time.Unix(3600, 0)
+
+	tracker.record("tsp", "cpu", start, start.Add(10*time.Minute), predictedTimeSeries(start, 20, 1))
+	// the window overlapping the last one is skipped
+	tracker.record("tsp", "cpu", start.Add(time.Minute), start.Add(11*time.Minute), predictedTimeSeries(start, 20, 1))
+	tracker.record("tsp", "cpu", start.Add(10*time.Minute), start.Add(20*time.Minute), predictedTimeSeries(start.Add(10*time.Minute), 20, 1))
+	assert.Len(t, tracker.windows["tsp"]["cpu"], 2)
+	// the samples out of the window are dropped
+	assert.Len(t, tracker.windows["tsp"]["cpu"][0].timeSeries[0].Samples, 11)
+
+	ended := tracker.popEnded("tsp", start.Add(15*time.Minute))
+	assert.Len(t, ended["cpu"], 1)
+	assert.Equal(t, start, ended["cpu"][0].start)
+	assert.Len(t, tracker.windows["tsp"]["cpu"], 1)
+
+	tracker.delete("tsp")
+	assert.Empty(t, tracker.popEnded("tsp", start.Add(time.Hour)))
+}
+
+func TestPredictedWindowEvaluate(t *testing.T) {
+	start := time.Unix(3600, 0)
+	actual := predictedTimeSeries(start, 10, 2)[0]
+	window := &predictedWindow{start: start, end: start.Add(time.Hour), step: time.Minute, timeSeries: predictedTimeSeries(start, 20, 1.5)}
+
+	result, err := window.evaluate(&fakeHistory{ts: actual}, nil)
+	assert.NoError(t, err)
+	assert.Equal(t, 10, result.Samples)
+	assert.InDelta(t, 0.5, result.MAE, 1e-9)
+	assert.Equal(t, start.Unix(), result.Start)
+	assert.Equal(t, start.Add(time.Hour).Unix(), result.End)
+
+	_, err = window.evaluate(&fakeHistory{ts: &common.TimeSeries{}}, nil)
+	assert.Error(t, err)
+}
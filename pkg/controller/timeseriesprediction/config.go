@@ -1,6 +1,7 @@
 package timeseriesprediction
 
 import (
+	"encoding/json"
 	"fmt"
 	"sort"
 	"strings"
@@ -10,6 +11,7 @@ import (
 	"k8s.io/apimachinery/pkg/labels"
 	"k8s.io/klog/v2"
 
+	"github.com/gocrane/crane/pkg/known"
 	"github.com/gocrane/crane/pkg/metricnaming"
 	"github.com/gocrane/crane/pkg/metricquery"
 	predconf "github.com/gocrane/crane/pkg/prediction/config"
@@ -188,8 +190,27 @@ func (c *MetricContext) ConvertApiMetrics2InternalConfigs(metrics []predictionap
 
 // ConvertApiMetric2InternalConfig
 func (c *MetricContext) ConvertApiMetric2InternalConfig(metric *predictionapi.PredictionMetric) *predconf.Config {
-	return &predconf.Config{
+	conf := &predconf.Config{
 		DSP:        metric.Algorithm.DSP,
 		Percentile: metric.Algorithm.Percentile,
 	}
+	if metric.Algorithm.AlgorithmType == predconf.AlgorithmTypeHoltWinters {
+		conf.HoltWinters = c.holtWintersConfig()
+	}
+	return conf
+}
+
+// holtWintersConfig returns the holt-winters config in the annotation of the TimeSeriesPrediction, the api does not
+// define it yet. nil is returned if the annotation is absent or invalid, so the defaults are used.
+func (c *MetricContext) holtWintersConfig() *predconf.HoltWinters {
+	value, ok := c.SeriesPrediction.Annotations[known.HoltWintersAnnotation]
+	if !ok {
+		return nil
+	}
+	var conf predconf.HoltWinters
+	if err := json.Unmarshal([]byte(value), &conf); err != nil {
+		klog.ErrorS(err, "Failed to parse the holt-winters annotation.", "tsp", klog.KObj(c.SeriesPrediction))
+		return nil
+	}
+	return &conf
 }
@@ -149,6 +149,10 @@ func (tc *Controller) doPredict(tsPrediction *predictionapi.TimeSeriesPrediction
 		if err != nil {
 			return result, err
 		}
+		if tc.historyProvider != nil {
+			windowEnd := start.Add(time.Duration(tsPrediction.Spec.PredictionWindowSeconds) * time.Second)
+			tc.accuracyTracker.record(GetTimeSeriesPredictionKey(tsPrediction), metric.ResourceIdentifier, start, windowEnd, data)
+		}
 		predictedData := CommonTimeSeries2ApiTimeSeries(data)
 		if klog.V(6).Enabled() {
 			apiDataBytes, err1 := json.Marshal(predictedData)
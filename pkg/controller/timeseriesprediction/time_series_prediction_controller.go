@@ -18,6 +18,7 @@ import (
 
 	predictionapi "github.com/gocrane/api/prediction/v1alpha1"
 	predictormgr "github.com/gocrane/crane/pkg/predictor"
+	"github.com/gocrane/crane/pkg/providers"
 	"github.com/gocrane/crane/pkg/utils/target"
 )
 
@@ -36,6 +37,11 @@ type Controller struct {
 	lock sync.Mutex
 	// predictors used to do predict and config, maybe the predictor should running as a independent system not as a built-in goroutines evaluator
 	predictorMgr predictormgr.Manager
+
+	// historyProvider provides the actual time series to evaluate the prediction accuracy, the accuracy is not
+	// evaluated if it is nil
+	historyProvider providers.History
+	accuracyTracker *accuracyTracker
 }
 
 func NewController(
@@ -44,13 +50,16 @@ func NewController(
 	updatePeriod time.Duration,
 	predictorMgr predictormgr.Manager,
 	targetFetcher target.SelectorFetcher,
+	historyProvider providers.History,
 ) *Controller {
 	return &Controller{
-		Client:        client,
-		Recorder:      recorder,
-		UpdatePeriod:  updatePeriod,
-		predictorMgr:  predictorMgr,
-		TargetFetcher: targetFetcher,
+		Client:          client,
+		Recorder:        recorder,
+		UpdatePeriod:    updatePeriod,
+		predictorMgr:    predictorMgr,
+		TargetFetcher:   targetFetcher,
+		historyProvider: historyProvider,
+		accuracyTracker: newAccuracyTracker(),
 	}
 }
 
@@ -128,8 +137,14 @@ func (tc *Controller) syncTimeSeriesPrediction(ctx context.Context, tsp *predict
 
 	tc.tsPredictionMap.Store(key, tsp)
 
-	return tc.syncPredictionStatus(ctx, tsp)
-
+	result, err := tc.syncPredictionStatus(ctx, tsp)
+	if err != nil {
+		return result, err
+	}
+	if err := tc.syncPredictionAccuracy(ctx, tsp); err != nil {
+		klog.Errorf("Failed to sync prediction accuracy for %v, err: %v", klog.KObj(tsp), err)
+	}
+	return result, nil
 }
 
 func (tc *Controller) removeTimeSeriesPrediction(tsp *predictionapi.TimeSeriesPrediction) error {
@@ -142,6 +157,7 @@ func (tc *Controller) removeTimeSeriesPrediction(tsp *predictionapi.TimeSeriesPr
 	c.DeleteApiConfigs(tsp.Spec.PredictionMetrics)
 	key := GetTimeSeriesPredictionKey(tsp)
 	tc.tsPredictionMap.Delete(key)
+	tc.accuracyTracker.delete(key)
 	return nil
 }
 
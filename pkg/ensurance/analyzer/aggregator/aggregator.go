@@ -0,0 +1,123 @@
+package aggregator
+
+import (
+	"fmt"
+	"math"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/gocrane/crane/pkg/common"
+)
+
+// Function aggregates a group of samples into one value.
+type Function string
+
+const (
+	FunctionAvg Function = "avg"
+	FunctionMax Function = "max"
+	FunctionMin Function = "min"
+	FunctionSum Function = "sum"
+	// FunctionRate is the change per second from the first sample to the last sample.
+	FunctionRate Function = "rate"
+
+	// percentilePrefix is the prefix of the percentile functions, such as p95 and p99.
+	percentilePrefix = "p"
+)
+
+// WindowFunctions are the functions to aggregate the samples of a series in a window.
+var WindowFunctions = []string{string(FunctionAvg), string(FunctionMax), string(FunctionMin), string(FunctionRate), "p<percentile>"}
+
+// SeriesFunctions are the functions to aggregate the latest samples of multiple series.
+var SeriesFunctions = []string{string(FunctionAvg), string(FunctionMax), string(FunctionMin), string(FunctionSum), "p<percentile>"}
+
+// ValidateWindowFunction returns an error if the function is not supported for the window aggregation.
+func ValidateWindowFunction(fn Function) error {
+	switch fn {
+	case FunctionAvg, FunctionMax, FunctionMin, FunctionRate:
+		return nil
+	}
+	_, err := parsePercentile(fn)
+	return err
+}
+
+// ValidateSeriesFunction returns an error if the function is not supported for the series aggregation.
+func ValidateSeriesFunction(fn Function) error {
+	switch fn {
+	case FunctionAvg, FunctionMax, FunctionMin, FunctionSum:
+		return nil
+	}
+	_, err := parsePercentile(fn)
+	return err
+}
+
+// Aggregate aggregates the samples with the function, the samples should be in chronological order.
+func Aggregate(fn Function, samples []common.Sample) (float64, error) {
+	if len(samples) == 0 {
+		return 0, fmt.Errorf("no samples to aggregate")
+	}
+
+	switch fn {
+	case FunctionAvg:
+		return sum(samples) / float64(len(samples)), nil
+	case FunctionSum:
+		return sum(samples), nil
+	case FunctionMax:
+		var max = samples[0].Value
+		for _, s := range samples[1:] {
+			max = math.Max(max, s.Value)
+		}
+		return max, nil
+	case FunctionMin:
+		var min = samples[0].Value
+		for _, s := range samples[1:] {
+			min = math.Min(min, s.Value)
+		}
+		return min, nil
+	case FunctionRate:
+		first, last := samples[0], samples[len(samples)-1]
+		if last.Timestamp <= first.Timestamp {
+			return 0, fmt.Errorf("rate needs samples with different timestamps")
+		}
+		return (last.Value - first.Value) / float64(last.Timestamp-first.Timestamp), nil
+	}
+
+	percentile, err := parsePercentile(fn)
+	if err != nil {
+		return 0, err
+	}
+
+	var values = make([]float64, 0, len(samples))
+	for _, s := range samples {
+		values = append(values, s.Value)
+	}
+	sort.Float64s(values)
+
+	// nearest rank
+	rank := int(math.Ceil(percentile / 100 * float64(len(values))))
+	if rank < 1 {
+		rank = 1
+	}
+	return values[rank-1], nil
+}
+
+func sum(samples []common.Sample) float64 {
+	var total float64
+	for _, s := range samples {
+		total += s.Value
+	}
+	return total
+}
+
+func parsePercentile(fn Function) (float64, error) {
+	if !strings.HasPrefix(string(fn), percentilePrefix) {
+		return 0, fmt.Errorf("unsupported function %s", fn)
+	}
+
+	percentile, err := strconv.ParseFloat(strings.TrimPrefix(string(fn), percentilePrefix), 64)
+	if err != nil || percentile <= 0 || percentile > 100 {
+		return 0, fmt.Errorf("unsupported percentile function %s, the percentile should be in (0, 100]", fn)
+	}
+
+	return percentile, nil
+}
@@ -0,0 +1,87 @@
+package aggregator
+
+import (
+	"testing"
+
+	"github.com/gocrane/crane/pkg/common"
+)
+
+func TestAggregate(t *testing.T) {
+	samples := []common.Sample{
+		{Value: 4, Timestamp: 10},
+		{Value: 1, Timestamp: 20},
+		{Value: 7, Timestamp: 30},
+		{Value: 8, Timestamp: 40},
+	}
+
+	cases := map[string]struct {
+		fn        Function
+		samples   []common.Sample
+		expect    float64
+		expectErr bool
+	}{
+		"avg":            {fn: FunctionAvg, samples: samples, expect: 5},
+		"sum":            {fn: FunctionSum, samples: samples, expect: 20},
+		"max":            {fn: FunctionMax, samples: samples, expect: 8},
+		"min":            {fn: FunctionMin, samples: samples, expect: 1},
+		"rate":           {fn: FunctionRate, samples: samples, expect: 4.0 / 30},
+		"p50":            {fn: "p50", samples: samples, expect: 4},
+		"p95":            {fn: "p95", samples: samples, expect: 8},
+		"p99.9":          {fn: "p99.9", samples: samples, expect: 8},
+		"p0 is invalid":  {fn: "p0", samples: samples, expectErr: true},
+		"unknown":        {fn: "median", samples: samples, expectErr: true},
+		"no samples":     {fn: FunctionAvg, expectErr: true},
+		"rate same time": {fn: FunctionRate, samples: []common.Sample{{Value: 1, Timestamp: 10}, {Value: 2, Timestamp: 10}}, expectErr: true},
+	}
+
+	for k, v := range cases {
+		t.Run(k, func(t *testing.T) {
+			value, err := Aggregate(v.fn, v.samples)
+			if v.expectErr {
+				if err == nil {
+					t.Errorf("Expected error, got value %v", value)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+			if value != v.expect {
+				t.Errorf("Expected %v, got %v", v.expect, value)
+			}
+		})
+	}
+}
+
+func TestWindowUpdate(t *testing.T) {
+	labels := []common.Label{{Name: "pod", Value: "a"}, {Name: "namespace", Value: "default"}}
+	state := func(timestamp int64, value float64) map[string][]common.TimeSeries {
+		return map[string][]common.TimeSeries{
+			"cpu_total_usage": {{Labels: labels, Samples: []common.Sample{{Value: value, Timestamp: timestamp}}}},
+		}
+	}
+
+	w := NewWindow()
+	sizes := map[string]int{"cpu_total_usage": 3}
+	w.Update(state(10, 1), sizes)
+	w.Update(state(10, 1), sizes)
+	w.Update(state(20, 2), sizes)
+	w.Update(state(30, 3), sizes)
+	w.Update(state(40, 4), sizes)
+
+	// labels in other order are the same series
+	samples := w.Samples("cpu_total_usage", []common.Label{labels[1], labels[0]})
+	if len(samples) != 3 || samples[0].Value != 2 || samples[2].Value != 4 {
+		t.Errorf("Expected the latest 3 samples, got %v", samples)
+	}
+
+	w.Update(map[string][]common.TimeSeries{}, sizes)
+	if samples := w.Samples("cpu_total_usage", labels); len(samples) != 0 {
+		t.Errorf("Expected the series removed, got %v", samples)
+	}
+
+	w.Update(state(50, 5), map[string]int{})
+	if samples := w.Samples("cpu_total_usage", labels); len(samples) != 0 {
+		t.Errorf("Expected the metric removed, got %v", samples)
+	}
+}
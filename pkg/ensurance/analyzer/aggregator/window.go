@@ -0,0 +1,85 @@
+package aggregator
+
+import (
+	"sort"
+	"strings"
+
+	"github.com/gocrane/crane/pkg/common"
+)
+
+const (
+	// MaxWindowSize is the max number of the collections kept in a window.
+	MaxWindowSize = 360
+)
+
+type windowSeries struct {
+	labels  []common.Label
+	samples []common.Sample
+}
+
+// Window keeps the samples of the latest collections for the metrics in memory.
+type Window struct {
+	metrics map[string]map[string]*windowSeries
+}
+
+func NewWindow() *Window {
+	return &Window{metrics: make(map[string]map[string]*windowSeries)}
+}
+
+// Update appends the latest samples of the state to the window, sizes is the window size for each metric.
+// The metrics not in sizes and the series not in the state are removed from the window.
+func (w *Window) Update(state map[string][]common.TimeSeries, sizes map[string]int) {
+	for metricName := range w.metrics {
+		if _, ok := sizes[metricName]; !ok {
+			delete(w.metrics, metricName)
+		}
+	}
+
+	for metricName, size := range sizes {
+		if size > MaxWindowSize {
+			size = MaxWindowSize
+		}
+
+		var current = make(map[string]*windowSeries)
+		for _, ts := range state[metricName] {
+			if len(ts.Samples) == 0 {
+				continue
+			}
+
+			key := seriesKey(ts.Labels)
+			ws, ok := w.metrics[metricName][key]
+			if !ok {
+				ws = &windowSeries{labels: ts.Labels}
+			}
+
+			latest := ts.Samples[len(ts.Samples)-1]
+			if n := len(ws.samples); n == 0 || ws.samples[n-1].Timestamp != latest.Timestamp {
+				ws.samples = append(ws.samples, latest)
+			}
+			if len(ws.samples) > size {
+				ws.samples = ws.samples[len(ws.samples)-size:]
+			}
+
+			current[key] = ws
+		}
+
+		w.metrics[metricName] = current
+	}
+}
+
+// Samples returns the samples in the window of the series in chronological order.
+func (w *Window) Samples(metricName string, labels []common.Label) []common.Sample {
+	if ws, ok := w.metrics[metricName][seriesKey(labels)]; ok {
+		return ws.samples
+	}
+	return nil
+}
+
+func seriesKey(labels []common.Label) string {
+	labelSet := make([]string, 0, len(labels))
+	for _, label := range labels {
+		labelSet = append(labelSet, label.Name+"="+label.Value)
+	}
+	sort.Strings(labelSet)
+	return strings.Join(labelSet, ",")
+}
@@ -1,20 +1,25 @@
 package analyzer
 
 import (
+	"errors"
 	"fmt"
 	"sort"
 	"strings"
+	"sync"
 	"time"
 
 	v1 "k8s.io/api/core/v1"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/labels"
 	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/apimachinery/pkg/util/sets"
 	coreinformers "k8s.io/client-go/informers/core/v1"
+	policyinformers "k8s.io/client-go/informers/policy/v1beta1"
 	"k8s.io/client-go/kubernetes"
 	"k8s.io/client-go/kubernetes/scheme"
 	typedcorev1 "k8s.io/client-go/kubernetes/typed/core/v1"
 	corelisters "k8s.io/client-go/listers/core/v1"
+	policylisters "k8s.io/client-go/listers/policy/v1beta1"
 	"k8s.io/client-go/tools/cache"
 	"k8s.io/client-go/tools/record"
 	"k8s.io/klog/v2"
@@ -23,17 +28,20 @@ import (
 	"github.com/gocrane/api/pkg/generated/informers/externalversions/ensurance/v1alpha1"
 	ensurancelisters "github.com/gocrane/api/pkg/generated/listers/ensurance/v1alpha1"
 	"github.com/gocrane/crane/pkg/common"
+	"github.com/gocrane/crane/pkg/ensurance/analyzer/aggregator"
 	"github.com/gocrane/crane/pkg/ensurance/analyzer/evaluator"
 	ecache "github.com/gocrane/crane/pkg/ensurance/cache"
 	stypes "github.com/gocrane/crane/pkg/ensurance/collector/types"
 	"github.com/gocrane/crane/pkg/ensurance/executor"
+	"github.com/gocrane/crane/pkg/ensurance/extension"
 	"github.com/gocrane/crane/pkg/known"
 	"github.com/gocrane/crane/pkg/metrics"
 	"github.com/gocrane/crane/pkg/utils"
 )
 
 type AnormalyAnalyzer struct {
-	nodeName string
+	nodeName   string
+	kubeClient kubernetes.Interface
 
 	podLister corelisters.PodLister
 	podSynced cache.InformerSynced
@@ -47,15 +55,32 @@ type AnormalyAnalyzer struct {
 	avoidanceActionLister ensurancelisters.AvoidanceActionLister
 	avoidanceActionSynced cache.InformerSynced
 
+	pdbLister policylisters.PodDisruptionBudgetLister
+	pdbSynced cache.InformerSynced
+
 	stateChann chan map[string][]common.TimeSeries
 	recorder   record.EventRecorder
 	actionCh   chan<- executor.AvoidanceExecutor
 
-	evaluator         evaluator.Evaluator
+	evaluators        map[evaluator.EvaluatorType]evaluator.Evaluator
+	window            *aggregator.Window
+	invalidRules      map[string]string
 	triggered         map[string]uint64
 	restored          map[string]uint64
 	actionEventStatus map[string]ecache.DetectionStatus
 	lastTriggeredTime time.Time
+
+	// statuses are the status of the objective ensurances kept by the annotation of the node, they are replaced but
+	// never modified in the analysis
+	statusLock sync.RWMutex
+	statuses   map[string]extension.ObjectiveStatus
+	// statusPatchedValue is the latest status patched to the node
+	statusPatchedValue string
+
+	// previewPlan is the plan of the latest analysis for the actions in preview
+	previewLock    sync.RWMutex
+	previewPlan    executor.Plan
+	previewSummary string
 }
 
 // NewAnormalyAnalyzer create an analyzer manager
@@ -65,18 +90,25 @@ func NewAnormalyAnalyzer(kubeClient *kubernetes.Clientset,
 	nodeInformer coreinformers.NodeInformer,
 	nepInformer v1alpha1.NodeQOSEnsurancePolicyInformer,
 	actionInformer v1alpha1.AvoidanceActionInformer,
+	pdbInformer policyinformers.PodDisruptionBudgetInformer,
 	stateChann chan map[string][]common.TimeSeries,
 	noticeCh chan<- executor.AvoidanceExecutor,
 ) *AnormalyAnalyzer {
 
-	expressionEvaluator := evaluator.NewExpressionEvaluator()
+	evaluators := map[evaluator.EvaluatorType]evaluator.Evaluator{
+		evaluator.ExpressionEvaluatorType: evaluator.NewExpressionEvaluator(),
+		evaluator.OpaEvaluatorType:        evaluator.NewOpaEvaluator(),
+	}
 	eventBroadcaster := record.NewBroadcaster()
 	eventBroadcaster.StartStructuredLogging(0)
 	eventBroadcaster.StartRecordingToSink(&typedcorev1.EventSinkImpl{Interface: kubeClient.CoreV1().Events("")})
 	recorder := eventBroadcaster.NewRecorder(scheme.Scheme, v1.EventSource{Component: "crane-agent"})
 	return &AnormalyAnalyzer{
 		nodeName:              nodeName,
-		evaluator:             expressionEvaluator,
+		kubeClient:            kubeClient,
+		evaluators:            evaluators,
+		window:                aggregator.NewWindow(),
+		invalidRules:          make(map[string]string),
 		actionCh:              noticeCh,
 		recorder:              recorder,
 		podLister:             podInformer.Lister(),
@@ -87,10 +119,13 @@ func NewAnormalyAnalyzer(kubeClient *kubernetes.Clientset,
 		nodeQOSSynced:         nepInformer.Informer().HasSynced,
 		avoidanceActionLister: actionInformer.Lister(),
 		avoidanceActionSynced: actionInformer.Informer().HasSynced,
+		pdbLister:             pdbInformer.Lister(),
+		pdbSynced:             pdbInformer.Informer().HasSynced,
 		stateChann:            stateChann,
 		triggered:             make(map[string]uint64),
 		restored:              make(map[string]uint64),
 		actionEventStatus:     make(map[string]ecache.DetectionStatus),
+		statuses:              make(map[string]extension.ObjectiveStatus),
 	}
 }
 
@@ -108,10 +143,14 @@ func (s *AnormalyAnalyzer) Run(stop <-chan struct{}) {
 		s.nodeSynced,
 		s.nodeQOSSynced,
 		s.avoidanceActionSynced,
+		s.pdbSynced,
 	) {
 		return
 	}
 
+	// restore the counters from the status of the node, so that they survive the restart of the agent
+	s.restoreStatuses()
+
 	go func() {
 		for {
 			select {
@@ -137,20 +176,12 @@ func (s *AnormalyAnalyzer) Analyze(state map[string][]common.TimeSeries) {
 		return
 	}
 
-	var neps []*ensuranceapi.NodeQOSEnsurancePolicy
-	allNeps, err := s.nodeQOSLister.List(labels.Everything())
+	neps, err := s.matchedPolicies(node)
 	if err != nil {
 		klog.Errorf("Failed to list NodeQOS: %v", err)
 		return
 	}
 
-	for _, nep := range allNeps {
-		if matched, err := utils.LabelSelectorMatched(node.Labels, nep.Spec.Selector); err != nil || !matched {
-			continue
-		}
-		neps = append(neps, nep.DeepCopy())
-	}
-
 	var avoidanceMaps = make(map[string]*ensuranceapi.AvoidanceAction)
 	allAvoidance, err := s.avoidanceActionLister.List(labels.Everything())
 	if err != nil {
@@ -162,12 +193,33 @@ func (s *AnormalyAnalyzer) Analyze(state map[string][]common.TimeSeries) {
 		avoidanceMaps[a.Name] = a
 	}
 
-	// step 2: do analyze for neps
+	// step 2: keep the samples in window for the objectives aggregated over windows
+	var extObjects = make(map[string]map[string]extension.ObjectiveEnsurance, len(neps))
+	var windowSizes = make(map[string]int)
+	for _, n := range neps {
+		objectives, err := extension.GetObjectiveEnsurances(n)
+		if err != nil {
+			klog.Errorf("Failed to get objective ensurances of NodeQOS %s: %v", n.Name, err)
+		}
+		extObjects[n.Name] = objectives
+
+		for _, v := range n.Spec.ObjectiveEnsurances {
+			if v.MetricRule != nil && int(objectives[v.Name].Window) > windowSizes[v.MetricRule.Name] {
+				windowSizes[v.MetricRule.Name] = int(objectives[v.Name].Window)
+			}
+		}
+	}
+	s.window.Update(state, windowSizes)
+
+	// step 3: do analyze for neps
 	var actionContexts []ecache.ActionContext
+	var input = regoInput(node, state)
+	var keys = sets.NewString()
 	for _, n := range neps {
 		for _, v := range n.Spec.ObjectiveEnsurances {
 			var key = strings.Join([]string{n.Name, v.Name}, ".")
-			ac, err := s.analyze(key, v, state)
+			keys.Insert(key)
+			ac, err := s.analyze(key, n.Name, v, extObjects[n.Name][v.Name], state, input)
 			if err != nil {
 				metrics.UpdateAnalyzerWithKeyStatus(metrics.AnalyzeTypeAnalyzeError, key, 1.0)
 				klog.Errorf("Failed to analyze, %v.", err)
@@ -180,21 +232,61 @@ func (s *AnormalyAnalyzer) Analyze(state map[string][]common.TimeSeries) {
 		}
 	}
 
+	// drop the states of the objectives removed
+	for _, e := range s.evaluators {
+		e.Retain(keys)
+	}
+	for key := range s.invalidRules {
+		if !keys.Has(key) {
+			delete(s.invalidRules, key)
+		}
+	}
+
 	klog.V(6).Infof("Analyze actionContexts: %v", actionContexts)
 
-	//step 3 : merge
-	avoidanceAction := s.merge(state, avoidanceMaps, actionContexts)
-	if err != nil {
-		klog.Errorf("Failed to merge, %v.", err)
-		return
+	//step 4 : merge, the actions in preview are merged alone, they are only recorded but not executed
+	var now = time.Now()
+	enforced, previewed := s.filterDryRun(actionContexts, now)
+	avoidanceAction, influencedPods := s.merge(state, avoidanceMaps, enforced, false)
+	previewAction, previewPods := s.merge(state, avoidanceMaps, previewed, true)
+	for key, pods := range previewPods {
+		influencedPods[key] = pods
 	}
 
-	//step 4 :notice the enforcer manager
+	s.updateStatuses(actionContexts, influencedPods, now)
+	s.preview(previewAction, previewed, now)
+
+	//step 5 :notice the enforcer manager
 	s.notify(avoidanceAction)
 
 	return
 }
 
+// GetMatchedPolicies returns the NodeQOSEnsurancePolicies matched to the node.
+func (s *AnormalyAnalyzer) GetMatchedPolicies() ([]*ensuranceapi.NodeQOSEnsurancePolicy, error) {
+	node, err := s.nodeLister.Get(s.nodeName)
+	if err != nil {
+		return nil, err
+	}
+	return s.matchedPolicies(node)
+}
+
+func (s *AnormalyAnalyzer) matchedPolicies(node *v1.Node) ([]*ensuranceapi.NodeQOSEnsurancePolicy, error) {
+	allNeps, err := s.nodeQOSLister.List(labels.Everything())
+	if err != nil {
+		return nil, err
+	}
+
+	var neps []*ensuranceapi.NodeQOSEnsurancePolicy
+	for _, nep := range allNeps {
+		if matched, err := utils.LabelSelectorMatched(node.Labels, nep.Spec.Selector); err != nil || !matched {
+			continue
+		}
+		neps = append(neps, nep.DeepCopy())
+	}
+	return neps, nil
+}
+
 func (s *AnormalyAnalyzer) getSeries(state []common.TimeSeries, selector *metav1.LabelSelector, metricName string) ([]common.TimeSeries, error) {
 	series := s.getTimeSeriesFromMap(state, selector)
 	if len(series) == 0 {
@@ -203,90 +295,239 @@ func (s *AnormalyAnalyzer) getSeries(state []common.TimeSeries, selector *metav1
 	return series, nil
 }
 
-func (s *AnormalyAnalyzer) trigger(series []common.TimeSeries, object ensuranceapi.ObjectiveEnsurance) bool {
-	var triggered, threshold bool
+func (s *AnormalyAnalyzer) trigger(series []common.TimeSeries, metricName string, threshold evaluator.Threshold) bool {
+	var triggered, reached bool
 	for _, ts := range series {
-		triggered = s.evaluator.EvalWithMetric(object.MetricRule.Name, float64(object.MetricRule.Value.Value()), ts.Samples[0].Value)
+		triggered = s.evaluators[evaluator.ExpressionEvaluatorType].EvalWithMetric(metricName, threshold, ts.Samples[0].Value)
 
-		klog.V(6).Infof("Anormaly detection result %v, Name: %s, Value: %.2f, %s/%s", triggered,
-			object.MetricRule.Name,
+		klog.V(6).Infof("Anormaly detection result %v, Name: %s, Value: %.2f, Operator: %s, Threshold: %.2f, %s/%s", triggered,
+			metricName,
 			ts.Samples[0].Value,
+			threshold.Operator,
+			threshold.Value,
 			common.GetValueByName(ts.Labels, common.LabelNamePodNamespace),
 			common.GetValueByName(ts.Labels, common.LabelNamePodName))
 
 		if triggered {
-			threshold = true
+			reached = true
+		}
+	}
+	return reached
+}
+
+// triggeredPods returns the pods of the series which reach the threshold, it is empty for the metrics of the node.
+func (s *AnormalyAnalyzer) triggeredPods(series []common.TimeSeries, metricName string, threshold evaluator.Threshold) []types.NamespacedName {
+	var pods []types.NamespacedName
+	var found = sets.NewString()
+	for _, ts := range series {
+		var pod = types.NamespacedName{Namespace: common.GetValueByName(ts.Labels, common.LabelNamePodNamespace), Name: common.GetValueByName(ts.Labels, common.LabelNamePodName)}
+		if pod.Name == "" || found.Has(pod.String()) {
+			continue
+		}
+		if s.evaluators[evaluator.ExpressionEvaluatorType].EvalWithMetric(metricName, threshold, ts.Samples[0].Value) {
+			found.Insert(pod.String())
+			pods = append(pods, pod)
 		}
 	}
-	return threshold
+	return pods
 }
 
-func (s *AnormalyAnalyzer) analyze(key string, object ensuranceapi.ObjectiveEnsurance, stateMap map[string][]common.TimeSeries) (ecache.ActionContext, error) {
+func (s *AnormalyAnalyzer) analyze(key string, nepName string, object ensuranceapi.ObjectiveEnsurance, extObject extension.ObjectiveEnsurance,
+	stateMap map[string][]common.TimeSeries, input map[string]interface{}) (ecache.ActionContext, error) {
 	var ac = ecache.ActionContext{Strategy: object.Strategy, ObjectiveEnsuranceName: object.Name, ActionName: object.AvoidanceActionName}
 
+	if extObject.Rego != "" {
+		return s.analyzeWithRego(key, object, extObject.Rego, input)
+	}
+
+	if object.MetricRule == nil {
+		return ac, fmt.Errorf("metric rule is not set")
+	}
+
 	state, ok := stateMap[object.MetricRule.Name]
 	if !ok {
 		return ac, fmt.Errorf("metric %s not found", object.MetricRule.Name)
 	}
 
-	//step1: get series from value
-	series, err := s.getSeries(state, object.MetricRule.Selector, object.MetricRule.Name)
+	//step1: get series from value, the metrics of the probes of other policies are not the objective of this one
+	series, err := s.getSeries(policySeries(state, nepName), object.MetricRule.Selector, object.MetricRule.Name)
+	if err != nil {
+		return ac, err
+	}
+
+	series, complete, err := s.aggregate(object.MetricRule.Name, series, extObject)
 	if err != nil {
 		return ac, err
 	}
 
 	//step2: check if triggered for NodeQOSEnsurance
-	threshold := s.trigger(series, object)
+	triggerThreshold, restoreThreshold := extension.GetThresholds(object.MetricRule, extObject)
+	threshold := s.trigger(series, object.MetricRule.Name, triggerThreshold)
+
+	// the objective is not restorable until the windows of all the series are full, there is no decision then, so
+	// the actions are not restored in the warm-up after the agent starts
+	if !threshold && !complete {
+		klog.V(4).Infof("for NodeQOS %s, the windows are not full, no decision is made", key)
+		return ac, nil
+	}
+
+	// the metric should leave the hysteresis band to be restorable
+	restorable := !threshold
+	if extObject.RestoreValue != nil && !threshold {
+		restorable = !s.trigger(series, object.MetricRule.Name, restoreThreshold)
+	}
 
-	klog.V(4).Infof("for NodeQOS %s, metrics reach the threshold: %v", key, threshold)
+	klog.V(4).Infof("for NodeQOS %s, metrics reach the threshold: %v, restorable: %v", key, threshold, restorable)
 
 	//step3: check is triggered action or restored, set the detection
-	s.computeActionContext(threshold, key, object, &ac)
+	s.computeActionContext(threshold, restorable, key, object, &ac)
+
+	if threshold {
+		ac.BeInfluencedPods = s.triggeredPods(series, object.MetricRule.Name, triggerThreshold)
+	}
+	ac.MetricName = object.MetricRule.Name
+	ac.MetricValue, ac.TargetValue = targetValue(series, triggerThreshold, restoreThreshold)
+	ac.Threshold = triggerThreshold.Value
 
 	return ac, nil
 }
 
-func (s *AnormalyAnalyzer) computeActionContext(threshold bool, key string, object ensuranceapi.ObjectiveEnsurance, ac *ecache.ActionContext) {
+// aggregate aggregates the samples in the window for each series, and then aggregates the series, by the functions of
+// the objective. The series whose window is not full are dropped, and false is returned if any of them is dropped.
+func (s *AnormalyAnalyzer) aggregate(metricName string, series []common.TimeSeries, extObject extension.ObjectiveEnsurance) ([]common.TimeSeries, bool, error) {
+	var complete = true
+	if extObject.Window > 1 {
+		var windowFunction = extObject.WindowFunction
+		if windowFunction == "" {
+			windowFunction = aggregator.FunctionAvg
+		}
+
+		var windowSeries []common.TimeSeries
+		for _, ts := range series {
+			samples := s.window.Samples(metricName, ts.Labels)
+			// the series is not evaluated until the window is full
+			if len(samples) < int(extObject.Window) {
+				complete = false
+				continue
+			}
+			samples = samples[len(samples)-int(extObject.Window):]
+
+			value, err := aggregator.Aggregate(windowFunction, samples)
+			if err != nil {
+				return series, false, fmt.Errorf("failed to aggregate metric %s in window: %v", metricName, err)
+			}
+			windowSeries = append(windowSeries, common.TimeSeries{Labels: ts.Labels, Samples: []common.Sample{{Value: value, Timestamp: samples[len(samples)-1].Timestamp}}})
+		}
+		series = windowSeries
+	}
+
+	// the series are not aggregated until all of them are complete, an aggregation of a part of them is misleading
+	if extObject.SeriesFunction != "" && !complete {
+		return nil, false, nil
+	}
+
+	if extObject.SeriesFunction != "" && len(series) > 0 {
+		var samples = make([]common.Sample, 0, len(series))
+		for _, ts := range series {
+			samples = append(samples, ts.Samples[0])
+		}
+
+		value, err := aggregator.Aggregate(extObject.SeriesFunction, samples)
+		if err != nil {
+			return series, false, fmt.Errorf("failed to aggregate metric %s across series: %v", metricName, err)
+		}
+		series = []common.TimeSeries{{Samples: []common.Sample{{Value: value, Timestamp: samples[0].Timestamp}}}}
+	}
+
+	return series, complete, nil
+}
+
+func (s *AnormalyAnalyzer) analyzeWithRego(key string, object ensuranceapi.ObjectiveEnsurance, rule string, input map[string]interface{}) (ecache.ActionContext, error) {
+	var ac = ecache.ActionContext{Strategy: object.Strategy, ObjectiveEnsuranceName: object.Name, ActionName: object.AvoidanceActionName}
+
+	threshold, err := s.evaluators[evaluator.OpaEvaluatorType].EvalWithRawQuery(key, input, rule)
+	if err != nil {
+		var compileErr *evaluator.CompileError
+		if errors.As(err, &compileErr) && s.invalidRules[key] != rule {
+			// record the event only once for the same invalid rule
+			s.invalidRules[key] = rule
+			s.recorder.Event(utils.GetNodeRef(s.nodeName), v1.EventTypeWarning, "InvalidObjectiveRule", fmt.Sprintf("%s: %s", key, err.Error()))
+		}
+		return ac, err
+	}
+	delete(s.invalidRules, key)
+
+	klog.V(4).Infof("for NodeQOS %s, rego rule triggered: %v", key, threshold)
+
+	s.computeActionContext(threshold, !threshold, key, object, &ac)
+
+	return ac, nil
+}
+
+// computeActionContext counts the consecutive times the objective is triggered or restorable, if the metric is
+// in the hysteresis band, it is neither triggered nor restorable, and both of the counts are reset.
+func (s *AnormalyAnalyzer) computeActionContext(threshold bool, restorable bool, key string, object ensuranceapi.ObjectiveEnsurance, ac *ecache.ActionContext) {
+	// the counters stop at the thresholds, so they are not changed by every analysis after the action is triggered or
+	// restored
 	if threshold {
 		s.restored[key] = 0
 		triggered := utils.GetUint64FromMaps(key, s.triggered)
-		triggered++
+		if triggered < uint64(object.AvoidanceThreshold) {
+			triggered++
+		}
 		s.triggered[key] = triggered
 		if triggered >= uint64(object.AvoidanceThreshold) {
 			ac.Triggered = true
 		}
-	} else {
+	} else if restorable {
 		s.triggered[key] = 0
 		restored := utils.GetUint64FromMaps(key, s.restored)
-		restored++
+		if restored < uint64(object.RestoreThreshold) {
+			restored++
+		}
 		s.restored[key] = restored
 		if restored >= uint64(object.RestoreThreshold) {
 			ac.Restored = true
 		}
+	} else {
+		s.triggered[key] = 0
+		s.restored[key] = 0
 	}
 }
 
-func (s *AnormalyAnalyzer) filterDryRun(acs []ecache.ActionContext) []ecache.ActionContext {
-	var dcsFiltered []ecache.ActionContext
-	now := time.Now()
+// filterDryRun splits the action contexts to the ones to be enforced and the ones in preview.
+func (s *AnormalyAnalyzer) filterDryRun(acs []ecache.ActionContext, now time.Time) ([]ecache.ActionContext, []ecache.ActionContext) {
+	var dcsFiltered, dcsPreview []ecache.ActionContext
 	for _, ac := range acs {
 		s.logEvent(ac, now)
-		if !(ac.Strategy == ensuranceapi.AvoidanceActionStrategyPreview) {
+		if ac.Strategy == ensuranceapi.AvoidanceActionStrategyPreview {
+			dcsPreview = append(dcsPreview, ac)
+		} else {
 			dcsFiltered = append(dcsFiltered, ac)
 		}
 	}
-	return dcsFiltered
+	return dcsFiltered, dcsPreview
 }
 
-func (s *AnormalyAnalyzer) merge(stateMap map[string][]common.TimeSeries, avoidanceMaps map[string]*ensuranceapi.AvoidanceAction, acs []ecache.ActionContext) executor.AvoidanceExecutor {
+// merge merges the actions of the triggered and restored objectives, it also returns the pods throttled or evicted
+// for each triggered objective keyed by "<policy name>.<objective ensurance name>". The state of the analyzer is not
+// changed by the actions in preview.
+func (s *AnormalyAnalyzer) merge(stateMap map[string][]common.TimeSeries, avoidanceMaps map[string]*ensuranceapi.AvoidanceAction,
+	acsFiltered []ecache.ActionContext, preview bool) (executor.AvoidanceExecutor, map[string]sets.String) {
 	var ae executor.AvoidanceExecutor
+	ae.ThrottleExecutor.Targets = sets.NewString()
+	ae.DiskIOThrottleExecutor.Targets = sets.NewString()
+	ae.NetworkThrottleExecutor.Targets = sets.NewString()
+	var influencedPods = make(map[string]sets.String)
 
-	//step1 filter dry run ActionContext
-	acsFiltered := s.filterDryRun(acs)
+	//step1 do DisableScheduled merge
+	enableSchedule := s.disableSchedulingMerge(acsFiltered, avoidanceMaps, &ae, preview)
 
-	//step2 do DisableScheduled merge
-	enableSchedule := s.disableSchedulingMerge(acsFiltered, avoidanceMaps, &ae)
+	// the disruption budgets are shared by all the actions to avoid evicting more pods than allowed
+	budgets := newDisruptionBudgets(s.pdbLister)
 
+	// the throttled containers and pods are not restored as orphans if the scope of any throttle is unknown
+	var scopeUnknown, diskIOScopeUnknown, networkScopeUnknown bool
 	for _, ac := range acsFiltered {
 		action, ok := avoidanceMaps[ac.ActionName]
 		if !ok {
@@ -294,29 +535,88 @@ func (s *AnormalyAnalyzer) merge(stateMap map[string][]common.TimeSeries, avoida
 			continue
 		}
 
-		//step3 get and deduplicate throttlePods, throttleUpPods
+		var influenced = sets.NewString()
+		if ac.Triggered {
+			influencedPods[strings.Join([]string{ac.Nep.Name, ac.ObjectiveEnsuranceName}, ".")] = influenced
+		}
+
+		//step2 get and deduplicate throttlePods, throttleUpPods
 		if action.Spec.Throttle != nil {
+			if scope, err := s.getThrottleScope(action, ""); err != nil {
+				klog.Errorf("Failed to get the throttle scope of AvoidanceAction %s: %v", action.Name, err)
+				scopeUnknown = true
+			} else {
+				ae.ThrottleExecutor.Targets.Insert(scope...)
+			}
 			throttlePods, throttleUpPods := s.getThrottlePods(enableSchedule, ac, action, stateMap)
+			for _, p := range throttlePods {
+				influenced.Insert(p.PodTypes.String())
+			}
 			// combine the replicated pod
 			combineThrottleDuplicate(&ae.ThrottleExecutor, throttlePods, throttleUpPods)
 		}
 
+		//step3 get and deduplicate disk io and network throttle pods
+		if diskIOThrottle, err := extension.GetDiskIOThrottle(action); err != nil || (diskIOThrottle != nil && diskIOThrottle.StepIORatio != 0) {
+			if scope, err := s.getThrottleScope(action, v1.PodQOSBurstable); err != nil {
+				klog.Errorf("Failed to get the disk io throttle scope of AvoidanceAction %s: %v", action.Name, err)
+				diskIOScopeUnknown = true
+			} else {
+				ae.DiskIOThrottleExecutor.Targets.Insert(scope...)
+			}
+		}
+		if networkThrottle, err := extension.GetNetworkThrottle(action); err != nil || (networkThrottle != nil && networkThrottle.StepNetworkRatio != 0) {
+			if scope, err := s.getThrottleScope(action, v1.PodQOSBurstable); err != nil {
+				klog.Errorf("Failed to get the network throttle scope of AvoidanceAction %s: %v", action.Name, err)
+				networkScopeUnknown = true
+			} else {
+				ae.NetworkThrottleExecutor.Targets.Insert(scope...)
+			}
+		}
+		diskIOThrottlePods, diskIOThrottleUpPods := s.getDiskIOThrottlePods(enableSchedule, ac, action, stateMap)
+		for _, p := range diskIOThrottlePods {
+			influenced.Insert(p.PodTypes.String())
+		}
+		combineDiskIOThrottleDuplicate(&ae.DiskIOThrottleExecutor, diskIOThrottlePods, diskIOThrottleUpPods)
+		networkThrottlePods, networkThrottleUpPods := s.getNetworkThrottlePods(enableSchedule, ac, action, stateMap)
+		for _, p := range networkThrottlePods {
+			influenced.Insert(p.PodTypes.String())
+		}
+		combineNetworkThrottleDuplicate(&ae.NetworkThrottleExecutor, networkThrottlePods, networkThrottleUpPods)
+
 		//step4 get and deduplicate evictPods
 		if action.Spec.Eviction != nil {
-			evictPods := s.getEvictPods(ac.Triggered, action)
+			evictPods := s.getEvictPods(ac, action, stateMap, budgets)
+			for _, p := range evictPods {
+				influenced.Insert(p.PodKey.String())
+			}
 			// combine the replicated pod
 			combineEvictDuplicate(&ae.EvictExecutor, evictPods)
 		}
 	}
 
+	if scopeUnknown {
+		ae.ThrottleExecutor.Targets = nil
+	}
+	if diskIOScopeUnknown {
+		ae.DiskIOThrottleExecutor.Targets = nil
+	}
+	if networkScopeUnknown {
+		ae.NetworkThrottleExecutor.Targets = nil
+	}
+
 	// sort the throttle executor by pod qos priority
 	sort.Sort(ae.ThrottleExecutor.ThrottleDownPods)
 	sort.Sort(sort.Reverse(ae.ThrottleExecutor.ThrottleUpPods))
+	sort.Sort(ae.DiskIOThrottleExecutor.ThrottleDownPods)
+	sort.Sort(sort.Reverse(ae.DiskIOThrottleExecutor.ThrottleUpPods))
+	sort.Sort(ae.NetworkThrottleExecutor.ThrottleDownPods)
+	sort.Sort(sort.Reverse(ae.NetworkThrottleExecutor.ThrottleUpPods))
 
-	// sort the evict executor by pod qos priority
-	sort.Sort(ae.EvictExecutor.EvictPods)
+	// sort the evict executor by pod qos priority, keep the order by usage in the same priority
+	sort.Stable(ae.EvictExecutor.EvictPods)
 
-	return ae
+	return ae, influencedPods
 }
 
 func (s *AnormalyAnalyzer) logEvent(ac ecache.ActionContext, now time.Time) {
@@ -366,6 +666,17 @@ func (s *AnormalyAnalyzer) getTimeSeriesFromMap(state []common.TimeSeries, selec
 	return series
 }
 
+// policySeries returns the series of the built-in metrics and the ones exposed by the probe of the policy.
+func policySeries(state []common.TimeSeries, nepName string) []common.TimeSeries {
+	var series []common.TimeSeries
+	for _, ts := range state {
+		if policy := common.GetValueByName(ts.Labels, common.LabelNameNodeQOS); policy == "" || policy == nepName {
+			series = append(series, ts)
+		}
+	}
+	return series
+}
+
 func (s *AnormalyAnalyzer) notify(as executor.AvoidanceExecutor) {
 	//step1: check need to notice enforcer manager
 
@@ -399,11 +710,15 @@ func (s *AnormalyAnalyzer) getThrottlePods(enableSchedule bool, ac ecache.Action
 		return throttlePods, throttleUpPods
 	}
 
-	for _, pod := range allPods {
-		if ac.Triggered {
+	if ac.Triggered {
+		for _, pod := range s.getThrottleTargets(ac, action, stateMap, allPods) {
 			throttlePods = append(throttlePods, throttlePodConstruct(pod, stateMap, action))
 		}
-		if enableSchedule && ac.Restored {
+	}
+
+	// all the pods are throttled up, since the pods throttled before may not be the targets any more
+	if enableSchedule && ac.Restored {
+		for _, pod := range allPods {
 			throttleUpPods = append(throttleUpPods, throttlePodConstruct(pod, stateMap, action))
 		}
 	}
@@ -411,34 +726,118 @@ func (s *AnormalyAnalyzer) getThrottlePods(enableSchedule bool, ac ecache.Action
 	return throttlePods, throttleUpPods
 }
 
-func (s *AnormalyAnalyzer) getEvictPods(triggered bool, action *ensuranceapi.AvoidanceAction) []executor.EvictPod {
-	evictPods := []executor.EvictPod{}
+// getThrottleScope returns the pods which may be throttled by the action whether it is triggered or not, the cpu
+// quotas of the containers and the limits of the pods throttled out of the scope of all the actions are restored at
+// once. The defaultMaxQOSClass is the ceiling of the QoS class if the throttle target doesn't set one.
+func (s *AnormalyAnalyzer) getThrottleScope(action *ensuranceapi.AvoidanceAction, defaultMaxQOSClass v1.PodQOSClass) ([]string, error) {
+	allPods, err := s.podLister.List(labels.Everything())
+	if err != nil {
+		return nil, err
+	}
 
-	if triggered {
-		allPods, err := s.podLister.List(labels.Everything())
-		if err != nil {
-			klog.Errorf("Failed to list all pods: %v.", err)
-			return evictPods
+	var pods []string
+	for _, c := range throttleCandidates(ecache.ActionContext{}, action, allPods, defaultMaxQOSClass) {
+		pods = append(pods, types.NamespacedName{Namespace: c.pod.Namespace, Name: c.pod.Name}.String())
+	}
+	return pods, nil
+}
+
+func (s *AnormalyAnalyzer) getDiskIOThrottlePods(enableSchedule bool, ac ecache.ActionContext,
+	action *ensuranceapi.AvoidanceAction, stateMap map[string][]common.TimeSeries) ([]executor.DiskIOThrottlePod, []executor.DiskIOThrottlePod) {
+
+	throttlePods, throttleUpPods := []executor.DiskIOThrottlePod{}, []executor.DiskIOThrottlePod{}
+
+	diskIOThrottle, err := extension.GetDiskIOThrottle(action)
+	if err != nil {
+		klog.Errorf("Failed to get disk io throttle of AvoidanceAction %s: %v", action.Name, err)
+		return throttlePods, throttleUpPods
+	}
+	if diskIOThrottle == nil || diskIOThrottle.StepIORatio == 0 {
+		return throttlePods, throttleUpPods
+	}
+
+	allPods, err := s.podLister.List(labels.Everything())
+	if err != nil {
+		klog.Errorf("Failed to list all pods: %v", err)
+		return throttlePods, throttleUpPods
+	}
+
+	if ac.Triggered {
+		for _, c := range throttleCandidates(ac, action, allPods, v1.PodQOSBurstable) {
+			throttlePods = append(throttlePods, diskIOThrottlePodConstruct(c.pod, stateMap, diskIOThrottle))
+		}
+	}
+
+	// all the pods are throttled up, since the pods throttled before may not be the targets any more
+	if enableSchedule && ac.Restored {
+		for _, pod := range allPods {
+			throttleUpPods = append(throttleUpPods, diskIOThrottlePodConstruct(pod, stateMap, diskIOThrottle))
+		}
+	}
+
+	return throttlePods, throttleUpPods
+}
+
+func (s *AnormalyAnalyzer) getNetworkThrottlePods(enableSchedule bool, ac ecache.ActionContext,
+	action *ensuranceapi.AvoidanceAction, stateMap map[string][]common.TimeSeries) ([]executor.NetworkThrottlePod, []executor.NetworkThrottlePod) {
+
+	throttlePods, throttleUpPods := []executor.NetworkThrottlePod{}, []executor.NetworkThrottlePod{}
+
+	networkThrottle, err := extension.GetNetworkThrottle(action)
+	if err != nil {
+		klog.Errorf("Failed to get network throttle of AvoidanceAction %s: %v", action.Name, err)
+		return throttlePods, throttleUpPods
+	}
+	if networkThrottle == nil || networkThrottle.StepNetworkRatio == 0 {
+		return throttlePods, throttleUpPods
+	}
+
+	allPods, err := s.podLister.List(labels.Everything())
+	if err != nil {
+		klog.Errorf("Failed to list all pods: %v", err)
+		return throttlePods, throttleUpPods
+	}
+
+	if ac.Triggered {
+		for _, c := range throttleCandidates(ac, action, allPods, v1.PodQOSBurstable) {
+			if c.pod.Spec.HostNetwork {
+				continue
+			}
+			throttlePods = append(throttlePods, networkThrottlePodConstruct(c.pod, stateMap, networkThrottle))
 		}
+	}
 
-		for _, v := range allPods {
-			var classAndPriority = executor.ClassAndPriority{PodQOSClass: v.Status.QOSClass, PriorityClassValue: utils.GetInt32withDefault(v.Spec.Priority, 0)}
-			evictPods = append(evictPods, executor.EvictPod{DeletionGracePeriodSeconds: action.Spec.Eviction.TerminationGracePeriodSeconds,
-				PodKey: types.NamespacedName{Name: v.Name, Namespace: v.Namespace}, ClassAndPriority: classAndPriority})
+	// all the pods are throttled up, since the pods throttled before may not be the targets any more
+	if enableSchedule && ac.Restored {
+		for _, pod := range allPods {
+			if pod.Spec.HostNetwork {
+				continue
+			}
+			throttleUpPods = append(throttleUpPods, networkThrottlePodConstruct(pod, stateMap, networkThrottle))
 		}
 	}
-	return evictPods
+
+	return throttlePods, throttleUpPods
 }
 
-func (s *AnormalyAnalyzer) disableSchedulingMerge(acsFiltered []ecache.ActionContext, avoidanceMaps map[string]*ensuranceapi.AvoidanceAction, ae *executor.AvoidanceExecutor) bool {
+func (s *AnormalyAnalyzer) disableSchedulingMerge(acsFiltered []ecache.ActionContext, avoidanceMaps map[string]*ensuranceapi.AvoidanceAction,
+	ae *executor.AvoidanceExecutor, preview bool) bool {
 	var now = time.Now()
 
+	// nothing is previewed without the rules in preview
+	if preview && len(acsFiltered) == 0 {
+		return false
+	}
+
 	// If any rules are triggered, the avoidance is true，otherwise the avoidance is false.
 	// If all rules are not triggered and some rules are restored, the restore is true，otherwise the restore is false.
 	// If the restore is true and the cool downtime  reached, the enableScheduling is true，otherwise the enableScheduling is false.
 	var enableScheduling, avoidance, restore bool
 
 	defer func() {
+		if preview {
+			return
+		}
 		metrics.UpdateAnalyzerStatus(metrics.AnalyzeTypeEnableScheduling, float64(utils.Bool2Int32(enableScheduling)))
 		metrics.UpdateAnalyzerStatus(metrics.AnalyzeTypeAvoidance, float64(utils.Bool2Int32(avoidance)))
 		metrics.UpdateAnalyzerStatus(metrics.AnalyzeTypeRestore, float64(utils.Bool2Int32(restore)))
@@ -471,7 +870,9 @@ func (s *AnormalyAnalyzer) disableSchedulingMerge(acsFiltered []ecache.ActionCon
 	}
 
 	if avoidance {
-		s.lastTriggeredTime = now
+		if !preview {
+			s.lastTriggeredTime = now
+		}
 		ae.ScheduleExecutor.DisableClassAndPriority = &executor.ClassAndPriority{PodQOSClass: v1.PodQOSBestEffort, PriorityClassValue: 0}
 	}
 
@@ -482,6 +883,42 @@ func (s *AnormalyAnalyzer) disableSchedulingMerge(acsFiltered []ecache.ActionCon
 	return enableScheduling
 }
 
+// regoInput builds the input of the rego rules, it contains the node and all the collected metrics, for example:
+//
+//	{"node": {"name": "node1", "labels": {}, "capacity": {"cpu": 4, "memory": 8589934592}},
+//	 "metrics": {"cpu_total_utilization": [{"labels": {}, "value": 85.2, "timestamp": 1650000000}]}}
+func regoInput(node *v1.Node, state map[string][]common.TimeSeries) map[string]interface{} {
+	var metricsInput = make(map[string]interface{}, len(state))
+	for name, series := range state {
+		var samples = make([]interface{}, 0, len(series))
+		for _, ts := range series {
+			if len(ts.Samples) == 0 {
+				continue
+			}
+			samples = append(samples, map[string]interface{}{
+				"labels":    common.Labels2Maps(ts.Labels),
+				"value":     ts.Samples[0].Value,
+				"timestamp": ts.Samples[0].Timestamp,
+			})
+		}
+		metricsInput[name] = samples
+	}
+
+	var nodeInput = map[string]interface{}{
+		"name":   node.Name,
+		"labels": node.Labels,
+		"capacity": map[string]interface{}{
+			"cpu":    float64(node.Status.Capacity.Cpu().MilliValue()) / 1000,
+			"memory": node.Status.Capacity.Memory().Value(),
+		},
+	}
+
+	return map[string]interface{}{
+		"node":    nodeInput,
+		"metrics": metricsInput,
+	}
+}
+
 func throttlePodConstruct(pod *v1.Pod, stateMap map[string][]common.TimeSeries, action *ensuranceapi.AvoidanceAction) executor.ThrottlePod {
 	var throttlePod executor.ThrottlePod
 	var qosPriority = executor.ClassAndPriority{PodQOSClass: pod.Status.QOSClass, PriorityClassValue: utils.GetInt32withDefault(pod.Spec.Priority, 0)}
@@ -490,15 +927,59 @@ func throttlePodConstruct(pod *v1.Pod, stateMap map[string][]common.TimeSeries,
 	throttlePod.CPUThrottle.MinCPURatio = uint64(action.Spec.Throttle.CPUThrottle.MinCPURatio)
 	throttlePod.CPUThrottle.StepCPURatio = uint64(action.Spec.Throttle.CPUThrottle.StepCPURatio)
 
+	memoryThrottle, err := extension.GetMemoryThrottle(action)
+	if err != nil {
+		klog.Errorf("Failed to get memory throttle of AvoidanceAction %s: %v", action.Name, err)
+	}
+	throttlePod.MemoryThrottle.ForceGC = action.Spec.Throttle.MemoryThrottle.ForceGC
+	throttlePod.MemoryThrottle.MinMemoryRatio = uint64(memoryThrottle.MinMemoryRatio)
+	throttlePod.MemoryThrottle.StepMemoryRatio = uint64(memoryThrottle.StepMemoryRatio)
+	throttlePod.MemoryThrottle.MemoryHigh = memoryThrottle.MemoryHigh
+
 	throttlePod.PodCPUUsage, throttlePod.ContainerCPUUsages = executor.GetPodUsage(string(stypes.MetricNameContainerCpuTotalUsage), stateMap, pod)
 	throttlePod.PodCPUShare, throttlePod.ContainerCPUShares = executor.GetPodUsage(string(stypes.MetricNameContainerCpuLimit), stateMap, pod)
 	throttlePod.PodCPUQuota, throttlePod.ContainerCPUQuotas = executor.GetPodUsage(string(stypes.MetricNameContainerCpuQuota), stateMap, pod)
 	throttlePod.PodCPUPeriod, throttlePod.ContainerCPUPeriods = executor.GetPodUsage(string(stypes.MetricNameContainerCpuPeriod), stateMap, pod)
+	throttlePod.PodMemUsage, throttlePod.ContainerMemUsages = executor.GetPodUsage(string(stypes.MetricNameContainerMemTotalUsage), stateMap, pod)
+	throttlePod.PodMemLimit, throttlePod.ContainerMemLimits = executor.GetPodUsage(string(stypes.MetricNameContainerMemLimit), stateMap, pod)
 	throttlePod.PodQOSPriority = qosPriority
 
 	return throttlePod
 }
 
+func diskIOThrottlePodConstruct(pod *v1.Pod, stateMap map[string][]common.TimeSeries, diskIOThrottle *extension.DiskIOThrottle) executor.DiskIOThrottlePod {
+	var throttlePod executor.DiskIOThrottlePod
+
+	throttlePod.PodTypes = types.NamespacedName{Namespace: pod.Namespace, Name: pod.Name}
+	throttlePod.PodQOSPriority = executor.ClassAndPriority{PodQOSClass: pod.Status.QOSClass, PriorityClassValue: utils.GetInt32withDefault(pod.Spec.Priority, 0)}
+	throttlePod.DiskIOThrottle.MinIORatio = uint64(diskIOThrottle.MinIORatio)
+	throttlePod.DiskIOThrottle.StepIORatio = uint64(diskIOThrottle.StepIORatio)
+	throttlePod.DiskIOThrottle.MinKiBps = uint64(diskIOThrottle.MinKiBps)
+	throttlePod.DiskIOThrottle.MinIOPS = uint64(diskIOThrottle.MinIOPS)
+
+	throttlePod.PodDiskReadKiBps, _ = executor.GetPodUsage(string(stypes.MetricPodDiskReadKiBPS), stateMap, pod)
+	throttlePod.PodDiskWriteKiBps, _ = executor.GetPodUsage(string(stypes.MetricPodDiskWriteKiBPS), stateMap, pod)
+	throttlePod.PodDiskReadIOPS, _ = executor.GetPodUsage(string(stypes.MetricPodDiskReadIOPS), stateMap, pod)
+	throttlePod.PodDiskWriteIOPS, _ = executor.GetPodUsage(string(stypes.MetricPodDiskWriteIOPS), stateMap, pod)
+
+	return throttlePod
+}
+
+func networkThrottlePodConstruct(pod *v1.Pod, stateMap map[string][]common.TimeSeries, networkThrottle *extension.NetworkThrottle) executor.NetworkThrottlePod {
+	var throttlePod executor.NetworkThrottlePod
+
+	throttlePod.PodTypes = types.NamespacedName{Namespace: pod.Namespace, Name: pod.Name}
+	throttlePod.PodQOSPriority = executor.ClassAndPriority{PodQOSClass: pod.Status.QOSClass, PriorityClassValue: utils.GetInt32withDefault(pod.Spec.Priority, 0)}
+	throttlePod.NetworkThrottle.MinNetworkRatio = uint64(networkThrottle.MinNetworkRatio)
+	throttlePod.NetworkThrottle.StepNetworkRatio = uint64(networkThrottle.StepNetworkRatio)
+	throttlePod.NetworkThrottle.MinEgressKibps = uint64(networkThrottle.MinEgressKibps)
+	throttlePod.NetworkThrottle.Interface = networkThrottle.Interface
+
+	throttlePod.PodNetSentKibps, _ = executor.GetPodUsage(string(stypes.MetricPodNetworkSentKiBPS), stateMap, pod)
+
+	return throttlePod
+}
+
 func combineThrottleDuplicate(e *executor.ThrottleExecutor, throttlePods, throttleUpPods executor.ThrottlePods) {
 	for _, t := range throttlePods {
 		if i := e.ThrottleDownPods.Find(t.PodTypes); i == -1 {
@@ -511,6 +992,8 @@ func combineThrottleDuplicate(e *executor.ThrottleExecutor, throttlePods, thrott
 			if t.CPUThrottle.StepCPURatio > e.ThrottleDownPods[i].CPUThrottle.StepCPURatio {
 				e.ThrottleDownPods[i].CPUThrottle.StepCPURatio = t.CPUThrottle.StepCPURatio
 			}
+
+			mergeMemoryThrottle(&e.ThrottleDownPods[i].MemoryThrottle, t.MemoryThrottle)
 		}
 	}
 	for _, t := range throttleUpPods {
@@ -525,8 +1008,93 @@ func combineThrottleDuplicate(e *executor.ThrottleExecutor, throttlePods, thrott
 			if t.CPUThrottle.StepCPURatio > e.ThrottleUpPods[i].CPUThrottle.StepCPURatio {
 				e.ThrottleUpPods[i].CPUThrottle.StepCPURatio = t.CPUThrottle.StepCPURatio
 			}
+
+			mergeMemoryThrottle(&e.ThrottleUpPods[i].MemoryThrottle, t.MemoryThrottle)
+		}
+	}
+}
+
+func mergeMemoryThrottle(m *executor.MemoryThrottleExecutor, t executor.MemoryThrottleExecutor) {
+	if t.MinMemoryRatio > m.MinMemoryRatio {
+		m.MinMemoryRatio = t.MinMemoryRatio
+	}
+
+	if t.StepMemoryRatio > m.StepMemoryRatio {
+		m.StepMemoryRatio = t.StepMemoryRatio
+	}
+
+	m.ForceGC = m.ForceGC || t.ForceGC
+	m.MemoryHigh = m.MemoryHigh || t.MemoryHigh
+}
+
+func combineDiskIOThrottleDuplicate(e *executor.DiskIOThrottleExecutor, throttlePods, throttleUpPods executor.DiskIOThrottlePods) {
+	for _, t := range throttlePods {
+		if i := e.ThrottleDownPods.Find(t.PodTypes); i == -1 {
+			e.ThrottleDownPods = append(e.ThrottleDownPods, t)
+		} else {
+			mergeDiskIORatio(&e.ThrottleDownPods[i].DiskIOThrottle, t.DiskIOThrottle)
+		}
+	}
+	for _, t := range throttleUpPods {
+		if i := e.ThrottleUpPods.Find(t.PodTypes); i == -1 {
+			e.ThrottleUpPods = append(e.ThrottleUpPods, t)
+		} else {
+			mergeDiskIORatio(&e.ThrottleUpPods[i].DiskIOThrottle, t.DiskIOThrottle)
+		}
+	}
+}
+
+func mergeDiskIORatio(m *executor.DiskIORatio, t executor.DiskIORatio) {
+	if t.MinIORatio > m.MinIORatio {
+		m.MinIORatio = t.MinIORatio
+	}
+
+	if t.StepIORatio > m.StepIORatio {
+		m.StepIORatio = t.StepIORatio
+	}
+
+	if t.MinKiBps > m.MinKiBps {
+		m.MinKiBps = t.MinKiBps
+	}
+
+	if t.MinIOPS > m.MinIOPS {
+		m.MinIOPS = t.MinIOPS
+	}
+}
+
+func combineNetworkThrottleDuplicate(e *executor.NetworkThrottleExecutor, throttlePods, throttleUpPods executor.NetworkThrottlePods) {
+	for _, t := range throttlePods {
+		if i := e.ThrottleDownPods.Find(t.PodTypes); i == -1 {
+			e.ThrottleDownPods = append(e.ThrottleDownPods, t)
+		} else {
+			mergeNetworkRatio(&e.ThrottleDownPods[i].NetworkThrottle, t.NetworkThrottle)
 		}
 	}
+	for _, t := range throttleUpPods {
+		if i := e.ThrottleUpPods.Find(t.PodTypes); i == -1 {
+			e.ThrottleUpPods = append(e.ThrottleUpPods, t)
+		} else {
+			mergeNetworkRatio(&e.ThrottleUpPods[i].NetworkThrottle, t.NetworkThrottle)
+		}
+	}
+}
+
+func mergeNetworkRatio(m *executor.NetworkRatio, t executor.NetworkRatio) {
+	if t.MinNetworkRatio > m.MinNetworkRatio {
+		m.MinNetworkRatio = t.MinNetworkRatio
+	}
+
+	if t.StepNetworkRatio > m.StepNetworkRatio {
+		m.StepNetworkRatio = t.StepNetworkRatio
+	}
+
+	if t.MinEgressKibps > m.MinEgressKibps {
+		m.MinEgressKibps = t.MinEgressKibps
+	}
+
+	if m.Interface == "" {
+		m.Interface = t.Interface
+	}
 }
 
 func combineEvictDuplicate(e *executor.EvictExecutor, evictPods executor.EvictPods) {
@@ -538,6 +1106,7 @@ func combineEvictDuplicate(e *executor.EvictExecutor, evictPods executor.EvictPo
 				(*(e.EvictPods[i].DeletionGracePeriodSeconds) > *(ep.DeletionGracePeriodSeconds))) {
 				e.EvictPods[i].DeletionGracePeriodSeconds = ep.DeletionGracePeriodSeconds
 			}
+			e.EvictPods[i].Reason = strings.Join([]string{e.EvictPods[i].Reason, ep.Reason}, "; ")
 		}
 	}
 }
@@ -0,0 +1,514 @@
+package analyzer
+
+import (
+	"reflect"
+	"sort"
+	"strings"
+	"testing"
+	"time"
+
+	v1 "k8s.io/api/core/v1"
+	policyv1beta1 "k8s.io/api/policy/v1beta1"
+	"k8s.io/apimachinery/pkg/api/resource"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	corelisters "k8s.io/client-go/listers/core/v1"
+	policylisters "k8s.io/client-go/listers/policy/v1beta1"
+	"k8s.io/client-go/tools/cache"
+
+	ensuranceapi "github.com/gocrane/api/ensurance/v1alpha1"
+	"github.com/gocrane/crane/pkg/common"
+	"github.com/gocrane/crane/pkg/ensurance/analyzer/aggregator"
+	"github.com/gocrane/crane/pkg/ensurance/analyzer/evaluator"
+	ecache "github.com/gocrane/crane/pkg/ensurance/cache"
+	stypes "github.com/gocrane/crane/pkg/ensurance/collector/types"
+	"github.com/gocrane/crane/pkg/ensurance/executor"
+	"github.com/gocrane/crane/pkg/ensurance/extension"
+	"github.com/gocrane/crane/pkg/known"
+)
+
+func TestComputeActionContextWithHysteresis(t *testing.T) {
+	s := &AnormalyAnalyzer{
+		evaluators: map[evaluator.EvaluatorType]evaluator.Evaluator{
+			evaluator.ExpressionEvaluatorType: evaluator.NewExpressionEvaluator(),
+		},
+		triggered: make(map[string]uint64),
+		restored:  make(map[string]uint64),
+	}
+	object := ensuranceapi.ObjectiveEnsurance{Name: "cpu", AvoidanceThreshold: 2, RestoreThreshold: 2}
+	triggerThreshold := evaluator.Threshold{Operator: evaluator.OperatorGreaterThan, Value: 80}
+	restoreThreshold := evaluator.Threshold{Operator: evaluator.OperatorGreaterThan, Value: 70}
+
+	steps := []struct {
+		value           float64
+		expectTriggered bool
+		expectRestored  bool
+	}{
+		{value: 85},
+		{value: 90, expectTriggered: true},
+		// in the band, the action is held
+		{value: 75},
+		{value: 65},
+		// back to the band, the restored count is reset
+		{value: 75},
+		{value: 65},
+		{value: 60, expectRestored: true},
+	}
+
+	for i, step := range steps {
+		var ac ecache.ActionContext
+		series := timeSeries(step.value)
+		threshold := s.trigger(series, "cpu_total_utilization", triggerThreshold)
+		restorable := !threshold && !s.trigger(series, "cpu_total_utilization", restoreThreshold)
+		s.computeActionContext(threshold, restorable, "nep.cpu", object, &ac)
+		if ac.Triggered != step.expectTriggered || ac.Restored != step.expectRestored {
+			t.Errorf("Step %d value %.0f: expected triggered %v restored %v, got triggered %v restored %v",
+				i, step.value, step.expectTriggered, step.expectRestored, ac.Triggered, ac.Restored)
+		}
+	}
+}
+
+func TestAnalyzeWindowWarmUp(t *testing.T) {
+	s := &AnormalyAnalyzer{
+		evaluators: map[evaluator.EvaluatorType]evaluator.Evaluator{
+			evaluator.ExpressionEvaluatorType: evaluator.NewExpressionEvaluator(),
+		},
+		window:    aggregator.NewWindow(),
+		triggered: make(map[string]uint64),
+		restored:  make(map[string]uint64),
+	}
+	metricName := string(stypes.MetricNameCpuTotalUtilization)
+	object := ensuranceapi.ObjectiveEnsurance{Name: "cpu", AvoidanceThreshold: 1, RestoreThreshold: 1,
+		MetricRule: &ensuranceapi.MetricRule{Name: metricName, Value: resource.MustParse("80")}}
+	extObject := extension.ObjectiveEnsurance{Window: 3}
+
+	steps := []struct {
+		value           float64
+		expectTriggered bool
+		expectRestored  bool
+	}{
+		// no decision until the window is full, the action is not restored in the warm-up
+		{value: 10},
+		{value: 10},
+		{value: 10, expectRestored: true},
+		// the average of the window is under the threshold
+		{value: 200, expectRestored: true},
+		{value: 200, expectTriggered: true},
+	}
+
+	for i, step := range steps {
+		state := map[string][]common.TimeSeries{metricName: {{Samples: []common.Sample{{Value: step.value, Timestamp: int64(i)}}}}}
+		s.window.Update(state, map[string]int{metricName: 3})
+		ac, err := s.analyze("nep.cpu", "nep", object, extObject, state, nil)
+		if err != nil {
+			t.Fatalf("Step %d: unexpected error %v", i, err)
+		}
+		if ac.Triggered != step.expectTriggered || ac.Restored != step.expectRestored {
+			t.Errorf("Step %d value %.0f: expected triggered %v restored %v, got triggered %v restored %v",
+				i, step.value, step.expectTriggered, step.expectRestored, ac.Triggered, ac.Restored)
+		}
+	}
+
+	// the series aggregated are not decided until all of them are complete
+	series := []common.TimeSeries{
+		{Labels: []common.Label{{Name: "pod", Value: "old"}}, Samples: []common.Sample{{Value: 10}}},
+		{Labels: []common.Label{{Name: "pod", Value: "new"}}, Samples: []common.Sample{{Value: 10}}},
+	}
+	s.window.Update(map[string][]common.TimeSeries{metricName: series[:1]}, map[string]int{metricName: 3})
+	s.window.Update(map[string][]common.TimeSeries{metricName: series[:1]}, map[string]int{metricName: 3})
+	s.window.Update(map[string][]common.TimeSeries{metricName: series}, map[string]int{metricName: 3})
+	aggregated, complete, err := s.aggregate(metricName, series, extension.ObjectiveEnsurance{Window: 3, SeriesFunction: aggregator.FunctionAvg})
+	if err != nil || complete || len(aggregated) != 0 {
+		t.Errorf("Expected no series aggregated, got %v, %v, %v", aggregated, complete, err)
+	}
+}
+
+func timeSeries(values ...float64) []common.TimeSeries {
+	var series []common.TimeSeries
+	for _, v := range values {
+		series = append(series, common.TimeSeries{Samples: []common.Sample{{Value: v}}})
+	}
+	return series
+}
+
+func newPod(name string, qosClass v1.PodQOSClass, priority int32, labels map[string]string) *v1.Pod {
+	return &v1.Pod{
+		ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: "default", UID: types.UID(name), Labels: labels},
+		Spec:       v1.PodSpec{Priority: &priority},
+		Status:     v1.PodStatus{QOSClass: qosClass, Phase: v1.PodRunning},
+	}
+}
+
+func TestGetEvictPods(t *testing.T) {
+	pods := []*v1.Pod{
+		newPod("be-small", v1.PodQOSBestEffort, 0, nil),
+		newPod("be-large", v1.PodQOSBestEffort, 0, map[string]string{"app": "protected"}),
+		newPod("be-high-priority", v1.PodQOSBestEffort, 100, nil),
+		newPod("burstable", v1.PodQOSBurstable, 0, nil),
+		newPod("mirror", v1.PodQOSBestEffort, 0, nil),
+		newPod("daemonset", v1.PodQOSBestEffort, 0, nil),
+		newPod("critical", v1.PodQOSBestEffort, 2000000000, nil),
+	}
+	controller := true
+	pods[4].Annotations = map[string]string{v1.MirrorPodAnnotationKey: "mirror"}
+	pods[5].OwnerReferences = []metav1.OwnerReference{{APIVersion: "apps/v1", Kind: "DaemonSet", Name: "daemonset", Controller: &controller}}
+	usages := map[string]float64{"be-small": 0.5, "be-large": 2, "be-high-priority": 1, "burstable": 3, "mirror": 5, "daemonset": 5, "critical": 5}
+
+	podIndexer := cache.NewIndexer(cache.MetaNamespaceKeyFunc, cache.Indexers{})
+	var series []common.TimeSeries
+	for _, pod := range pods {
+		podIndexer.Add(pod)
+		series = append(series, common.TimeSeries{
+			Labels:  []common.Label{{Name: common.LabelNamePodName, Value: pod.Name}, {Name: common.LabelNamePodNamespace, Value: pod.Namespace}, {Name: common.LabelNamePodUid, Value: string(pod.UID)}, {Name: common.LabelNameContainerId, Value: "c-" + pod.Name}},
+			Samples: []common.Sample{{Value: usages[pod.Name]}},
+		})
+	}
+	stateMap := map[string][]common.TimeSeries{string(stypes.MetricNameContainerCpuTotalUsage): series}
+
+	nodeIndexer := cache.NewIndexer(cache.MetaNamespaceKeyFunc, cache.Indexers{})
+	nodeIndexer.Add(&v1.Node{ObjectMeta: metav1.ObjectMeta{Name: "node1"}, Status: v1.NodeStatus{Capacity: v1.ResourceList{v1.ResourceCPU: resource.MustParse("10")}}})
+
+	pdbIndexer := cache.NewIndexer(cache.MetaNamespaceKeyFunc, cache.Indexers{})
+	pdbIndexer.Add(&policyv1beta1.PodDisruptionBudget{
+		ObjectMeta: metav1.ObjectMeta{Name: "protected", Namespace: "default"},
+		Spec:       policyv1beta1.PodDisruptionBudgetSpec{Selector: &metav1.LabelSelector{MatchLabels: map[string]string{"app": "protected"}}},
+	})
+
+	s := &AnormalyAnalyzer{
+		nodeName:   "node1",
+		podLister:  corelisters.NewPodLister(podIndexer),
+		nodeLister: corelisters.NewNodeLister(nodeIndexer),
+		pdbLister:  policylisters.NewPodDisruptionBudgetLister(pdbIndexer),
+	}
+	action := &ensuranceapi.AvoidanceAction{Spec: ensuranceapi.AvoidanceActionSpec{Eviction: &ensuranceapi.EvictionAction{}}}
+
+	cases := map[string]struct {
+		ac     ecache.ActionContext
+		expect []string
+	}{
+		"not triggered": {
+			ac: ecache.ActionContext{MetricName: string(stypes.MetricNameCpuTotalUsage), MetricValue: 9000, TargetValue: 8000},
+		},
+		"the pods blocked by the disruption budget are skipped": {
+			ac:     ecache.ActionContext{Triggered: true, MetricName: string(stypes.MetricNameCpuTotalUsage), MetricValue: 8400, TargetValue: 8000},
+			expect: []string{"be-small"},
+		},
+		"the pods are evicted by priority until the excess is released": {
+			ac:     ecache.ActionContext{Triggered: true, MetricName: string(stypes.MetricNameCpuTotalUtilization), MetricValue: 90, TargetValue: 80},
+			expect: []string{"be-small", "be-high-priority"},
+		},
+		"the mirror, daemonset and critical pods are never evicted": {
+			ac:     ecache.ActionContext{Triggered: true, MetricName: string(stypes.MetricNameCpuTotalUsage), MetricValue: 20000, TargetValue: 8000},
+			expect: []string{"be-small", "be-high-priority", "burstable"},
+		},
+		"only the lowest band is evicted if the usage is not released by eviction": {
+			ac:     ecache.ActionContext{Triggered: true, MetricName: string(stypes.MetricNameCpuLoad1Min), MetricValue: 20, TargetValue: 10},
+			expect: []string{"be-small"},
+		},
+	}
+
+	for k, v := range cases {
+		t.Run(k, func(t *testing.T) {
+			v.ac.Nep = &ensuranceapi.NodeQOSEnsurancePolicy{ObjectMeta: metav1.ObjectMeta{Name: "nep"}}
+			var names []string
+			for _, p := range s.getEvictPods(v.ac, action, stateMap, newDisruptionBudgets(s.pdbLister)) {
+				names = append(names, p.PodKey.Name)
+				if p.Reason == "" {
+					t.Errorf("Expected the reason of pod %s to be set", p.PodKey.Name)
+				}
+			}
+			if strings.Join(names, ",") != strings.Join(v.expect, ",") {
+				t.Errorf("Expected evicted pods %v, got %v", v.expect, names)
+			}
+		})
+	}
+}
+
+func TestMergePreview(t *testing.T) {
+	podIndexer := cache.NewIndexer(cache.MetaNamespaceKeyFunc, cache.Indexers{})
+	podIndexer.Add(&v1.Pod{ObjectMeta: metav1.ObjectMeta{Name: "pod1", Namespace: "default"}, Status: v1.PodStatus{QOSClass: v1.PodQOSBestEffort}})
+
+	s := &AnormalyAnalyzer{
+		nodeName:  "node1",
+		podLister: corelisters.NewPodLister(podIndexer),
+	}
+	nep := &ensuranceapi.NodeQOSEnsurancePolicy{ObjectMeta: metav1.ObjectMeta{Name: "nep"}}
+	avoidanceMaps := map[string]*ensuranceapi.AvoidanceAction{
+		"evict": {Spec: ensuranceapi.AvoidanceActionSpec{Eviction: &ensuranceapi.EvictionAction{}}},
+	}
+	acs := []ecache.ActionContext{{Nep: nep, ObjectiveEnsuranceName: "cpu", ActionName: "evict", Triggered: true,
+		Strategy: ensuranceapi.AvoidanceActionStrategyPreview}}
+
+	ae, influenced := s.merge(nil, avoidanceMaps, acs, true)
+	if ae.ScheduleExecutor.DisableClassAndPriority == nil || len(ae.EvictExecutor.EvictPods) != 1 {
+		t.Errorf("Expected the plan to disable scheduling and evict the pod, got %+v", ae)
+	}
+	if !influenced["nep.cpu"].Has("default/pod1") {
+		t.Errorf("Expected the influenced pod default/pod1, got %v", influenced)
+	}
+	if !s.lastTriggeredTime.IsZero() {
+		t.Errorf("Expected the triggered time not to be changed by the preview, got %v", s.lastTriggeredTime)
+	}
+
+	ae, _ = s.merge(nil, avoidanceMaps, nil, true)
+	if ae.ScheduleExecutor.RestoreClassAndPriority != nil {
+		t.Errorf("Expected nothing to be previewed without the rules in preview, got %+v", ae)
+	}
+
+	plan := executor.NewPlan(ae, s.podLister, time.Now())
+	if summary := previewSummary(plan); summary != "" {
+		t.Errorf("Expected the empty summary, got %s", summary)
+	}
+}
+
+func TestMergeThrottleTargets(t *testing.T) {
+	podIndexer := cache.NewIndexer(cache.MetaNamespaceKeyFunc, cache.Indexers{})
+	podIndexer.Add(newPod("best-effort", v1.PodQOSBestEffort, 0, nil))
+	podIndexer.Add(newPod("burstable", v1.PodQOSBurstable, 0, nil))
+	podIndexer.Add(newPod("guaranteed", v1.PodQOSGuaranteed, 0, nil))
+
+	s := &AnormalyAnalyzer{
+		nodeName:  "node1",
+		podLister: corelisters.NewPodLister(podIndexer),
+	}
+	nep := &ensuranceapi.NodeQOSEnsurancePolicy{ObjectMeta: metav1.ObjectMeta{Name: "nep"}}
+	avoidanceMaps := map[string]*ensuranceapi.AvoidanceAction{
+		"throttle": {
+			ObjectMeta: metav1.ObjectMeta{Annotations: map[string]string{known.ThrottleTargetAnnotation: `{"maxQOSClass": "BestEffort"}`}},
+			Spec:       ensuranceapi.AvoidanceActionSpec{Throttle: &ensuranceapi.ThrottleAction{}},
+		},
+		"diskio": {
+			ObjectMeta: metav1.ObjectMeta{Annotations: map[string]string{known.DiskIOThrottleAnnotation: `{"stepIORatio": 10}`}},
+		},
+	}
+
+	// the pods in the scope of the throttle are targets even if it is not triggered
+	ae, _ := s.merge(nil, avoidanceMaps, []ecache.ActionContext{
+		{Nep: nep, ObjectiveEnsuranceName: "cpu", ActionName: "throttle"},
+		{Nep: nep, ObjectiveEnsuranceName: "io", ActionName: "diskio"},
+	}, false)
+	if !reflect.DeepEqual(ae.ThrottleExecutor.Targets.List(), []string{"default/best-effort"}) {
+		t.Errorf("Expected the throttle targets [default/best-effort], got %v", ae.ThrottleExecutor.Targets.List())
+	}
+	if expect := []string{"default/best-effort", "default/burstable"}; !reflect.DeepEqual(ae.DiskIOThrottleExecutor.Targets.List(), expect) {
+		t.Errorf("Expected the disk io throttle targets %v, got %v", expect, ae.DiskIOThrottleExecutor.Targets.List())
+	}
+	if ae.NetworkThrottleExecutor.Targets == nil || ae.NetworkThrottleExecutor.Targets.Len() != 0 {
+		t.Errorf("Expected no network throttle target, got %v", ae.NetworkThrottleExecutor.Targets)
+	}
+
+	// all the throttled containers are orphaned if there is no throttle
+	ae, _ = s.merge(nil, avoidanceMaps, nil, false)
+	if ae.ThrottleExecutor.Targets == nil || ae.ThrottleExecutor.Targets.Len() != 0 {
+		t.Errorf("Expected no throttle target, got %v", ae.ThrottleExecutor.Targets)
+	}
+}
+
+func TestGetThrottleTargets(t *testing.T) {
+	pods := []*v1.Pod{
+		newPod("be-small", v1.PodQOSBestEffort, 0, map[string]string{"app": "batch"}),
+		newPod("be-large", v1.PodQOSBestEffort, 0, map[string]string{"app": "batch"}),
+		newPod("burstable", v1.PodQOSBurstable, 0, map[string]string{"app": "batch"}),
+		newPod("guaranteed", v1.PodQOSGuaranteed, 0, map[string]string{"app": "batch"}),
+		newPod("online", v1.PodQOSBurstable, 100, map[string]string{"tier": "online"}),
+	}
+	usages := map[string]float64{"be-small": 0.5, "be-large": 2, "burstable": 3, "guaranteed": 4, "online": 1}
+
+	podIndexer := cache.NewIndexer(cache.MetaNamespaceKeyFunc, cache.Indexers{})
+	var series []common.TimeSeries
+	for _, pod := range pods {
+		podIndexer.Add(pod)
+		series = append(series, common.TimeSeries{
+			Labels:  []common.Label{{Name: common.LabelNamePodName, Value: pod.Name}, {Name: common.LabelNamePodNamespace, Value: pod.Namespace}, {Name: common.LabelNamePodUid, Value: string(pod.UID)}, {Name: common.LabelNameContainerId, Value: "c-" + pod.Name}},
+			Samples: []common.Sample{{Value: usages[pod.Name]}},
+		})
+	}
+	stateMap := map[string][]common.TimeSeries{string(stypes.MetricNameContainerCpuTotalUsage): series}
+
+	nodeIndexer := cache.NewIndexer(cache.MetaNamespaceKeyFunc, cache.Indexers{})
+	nodeIndexer.Add(&v1.Node{ObjectMeta: metav1.ObjectMeta{Name: "node1"}, Status: v1.NodeStatus{Capacity: v1.ResourceList{v1.ResourceCPU: resource.MustParse("10")}}})
+
+	s := &AnormalyAnalyzer{
+		nodeName:   "node1",
+		podLister:  corelisters.NewPodLister(podIndexer),
+		nodeLister: corelisters.NewNodeLister(nodeIndexer),
+	}
+
+	cases := map[string]struct {
+		throttleTarget string
+		ac             ecache.ActionContext
+		expect         []string
+	}{
+		"the pods are throttled by priority until the excess is projected to be released": {
+			ac:     ecache.ActionContext{Triggered: true, MetricName: string(stypes.MetricNameCpuTotalUsage), MetricValue: 9000, TargetValue: 8000},
+			expect: []string{"be-large"},
+		},
+		"the pods are selected by labels": {
+			throttleTarget: `{"podSelector": {"matchLabels": {"app": "batch"}}}`,
+			ac:             ecache.ActionContext{Triggered: true, MetricName: string(stypes.MetricNameCpuTotalUtilization), MetricValue: 95, TargetValue: 70},
+			expect:         []string{"be-large", "be-small", "burstable"},
+		},
+		"all the pods under the ceiling are throttled if the release can not be projected": {
+			throttleTarget: `{"maxQOSClass": "Burstable", "maxPriority": 0}`,
+			ac:             ecache.ActionContext{Triggered: true, MetricName: string(stypes.MetricNameCpuLoad1Min), MetricValue: 20, TargetValue: 10},
+			expect:         []string{"be-small", "be-large", "burstable"},
+		},
+		"the ceiling of the priority applies to all the classes": {
+			throttleTarget: `{"maxPriority": 0}`,
+			ac:             ecache.ActionContext{Triggered: true, MetricName: string(stypes.MetricNameCpuLoad1Min), MetricValue: 20, TargetValue: 10},
+			expect:         []string{"be-small", "be-large", "burstable", "guaranteed"},
+		},
+		"the pods not lower than the protected pods are not throttled": {
+			throttleTarget: `{"protectedPodSelector": {"matchLabels": {"tier": "online"}}}`,
+			ac:             ecache.ActionContext{Triggered: true, MetricName: string(stypes.MetricNameCpuLoad1Min), MetricValue: 20, TargetValue: 10},
+			expect:         []string{"be-small", "be-large", "burstable"},
+		},
+		"the offender with the highest usage is throttled": {
+			ac: ecache.ActionContext{Triggered: true, MetricName: string(stypes.MetricNameCpuTotalUsage), MetricValue: 9000, TargetValue: 8000,
+				BeInfluencedPods: []types.NamespacedName{{Namespace: "default", Name: "guaranteed"}}},
+			expect: []string{"guaranteed"},
+		},
+		"only the offenders lower than the protected pods are throttled": {
+			throttleTarget: `{"protectedPodSelector": {"matchLabels": {"tier": "online"}}}`,
+			ac: ecache.ActionContext{Triggered: true, MetricName: string(stypes.MetricNameCpuLoad1Min), MetricValue: 20, TargetValue: 10,
+				BeInfluencedPods: []types.NamespacedName{{Namespace: "default", Name: "burstable"}, {Namespace: "default", Name: "guaranteed"}}},
+			expect: []string{"burstable"},
+		},
+	}
+
+	for k, v := range cases {
+		t.Run(k, func(t *testing.T) {
+			action := &ensuranceapi.AvoidanceAction{Spec: ensuranceapi.AvoidanceActionSpec{Throttle: &ensuranceapi.ThrottleAction{
+				CPUThrottle: ensuranceapi.CPUThrottle{StepCPURatio: 50}}}}
+			if v.throttleTarget != "" {
+				action.Annotations = map[string]string{known.ThrottleTargetAnnotation: v.throttleTarget}
+			}
+
+			var names []string
+			for _, pod := range s.getThrottleTargets(v.ac, action, stateMap, pods) {
+				names = append(names, pod.Name)
+			}
+			if strings.Join(names, ",") != strings.Join(v.expect, ",") {
+				t.Errorf("Expected throttled pods %v, got %v", v.expect, names)
+			}
+		})
+	}
+}
+
+func TestGetDiskIOAndNetworkThrottlePods(t *testing.T) {
+	hostNetwork := newPod("host-network", v1.PodQOSBestEffort, 0, nil)
+	hostNetwork.Spec.HostNetwork = true
+	pods := []*v1.Pod{
+		newPod("best-effort", v1.PodQOSBestEffort, 0, nil),
+		newPod("burstable", v1.PodQOSBurstable, 0, nil),
+		newPod("guaranteed", v1.PodQOSGuaranteed, 0, nil),
+		newPod("online", v1.PodQOSBurstable, 100, map[string]string{"tier": "online"}),
+		hostNetwork,
+	}
+
+	podIndexer := cache.NewIndexer(cache.MetaNamespaceKeyFunc, cache.Indexers{})
+	for _, pod := range pods {
+		podIndexer.Add(pod)
+	}
+	s := &AnormalyAnalyzer{podLister: corelisters.NewPodLister(podIndexer)}
+
+	cases := map[string]struct {
+		throttleTarget string
+		expectDiskIO   []string
+		expectNetwork  []string
+	}{
+		"the guaranteed pods are not throttled by default": {
+			expectDiskIO:  []string{"best-effort", "burstable", "host-network", "online"},
+			expectNetwork: []string{"best-effort", "burstable", "online"},
+		},
+		"the pods not lower than the protected pods are not throttled": {
+			throttleTarget: `{"protectedPodSelector": {"matchLabels": {"tier": "online"}}}`,
+			expectDiskIO:   []string{"best-effort", "burstable", "host-network"},
+			expectNetwork:  []string{"best-effort", "burstable"},
+		},
+		"the ceiling of the throttle target overrides the default": {
+			throttleTarget: `{"maxQOSClass": "Guaranteed", "maxPriority": 0}`,
+			expectDiskIO:   []string{"best-effort", "burstable", "guaranteed", "host-network", "online"},
+			expectNetwork:  []string{"best-effort", "burstable", "guaranteed", "online"},
+		},
+	}
+
+	for k, v := range cases {
+		t.Run(k, func(t *testing.T) {
+			action := &ensuranceapi.AvoidanceAction{ObjectMeta: metav1.ObjectMeta{Annotations: map[string]string{
+				known.DiskIOThrottleAnnotation:  `{"stepIORatio": 10}`,
+				known.NetworkThrottleAnnotation: `{"stepNetworkRatio": 10}`,
+			}}}
+			if v.throttleTarget != "" {
+				action.Annotations[known.ThrottleTargetAnnotation] = v.throttleTarget
+			}
+			ac := ecache.ActionContext{Triggered: true, MetricName: string(stypes.MetricNameCpuLoad1Min), MetricValue: 20, TargetValue: 10}
+
+			diskIOPods, _ := s.getDiskIOThrottlePods(false, ac, action, nil)
+			var names []string
+			for _, pod := range diskIOPods {
+				names = append(names, pod.PodTypes.Name)
+			}
+			sort.Strings(names)
+			if !reflect.DeepEqual(names, v.expectDiskIO) {
+				t.Errorf("Expected disk io throttled pods %v, got %v", v.expectDiskIO, names)
+			}
+
+			networkPods, _ := s.getNetworkThrottlePods(false, ac, action, nil)
+			names = nil
+			for _, pod := range networkPods {
+				names = append(names, pod.PodTypes.Name)
+			}
+			sort.Strings(names)
+			if !reflect.DeepEqual(names, v.expectNetwork) {
+				t.Errorf("Expected network throttled pods %v, got %v", v.expectNetwork, names)
+			}
+		})
+	}
+}
+
+func TestTriggeredPods(t *testing.T) {
+	s := &AnormalyAnalyzer{
+		evaluators: map[evaluator.EvaluatorType]evaluator.Evaluator{
+			evaluator.ExpressionEvaluatorType: evaluator.NewExpressionEvaluator(),
+		},
+	}
+	podSeries := func(name string, value float64) common.TimeSeries {
+		return common.TimeSeries{
+			Labels:  []common.Label{{Name: common.LabelNamePodName, Value: name}, {Name: common.LabelNamePodNamespace, Value: "default"}},
+			Samples: []common.Sample{{Value: value}},
+		}
+	}
+	series := []common.TimeSeries{podSeries("pod1", 90), podSeries("pod1", 95), podSeries("pod2", 50), podSeries("pod3", 85), {Samples: []common.Sample{{Value: 99}}}}
+
+	pods := s.triggeredPods(series, "latency", evaluator.Threshold{Operator: evaluator.OperatorGreaterThan, Value: 80})
+	expect := []types.NamespacedName{{Namespace: "default", Name: "pod1"}, {Namespace: "default", Name: "pod3"}}
+	if !reflect.DeepEqual(pods, expect) {
+		t.Errorf("Expected triggered pods %v, got %v", expect, pods)
+	}
+}
+
+func TestPolicySeries(t *testing.T) {
+	probeSeries := func(policy string, value float64) common.TimeSeries {
+		return common.TimeSeries{Labels: []common.Label{{Name: common.LabelNameNodeQOS, Value: policy}}, Samples: []common.Sample{{Value: value}}}
+	}
+	builtin := common.TimeSeries{Samples: []common.Sample{{Value: 1}}}
+	state := []common.TimeSeries{builtin, probeSeries("nep1", 2), probeSeries("nep2", 3)}
+
+	cases := map[string]struct {
+		nepName string
+		expect  []common.TimeSeries
+	}{
+		"own probe":   {nepName: "nep1", expect: []common.TimeSeries{builtin, probeSeries("nep1", 2)}},
+		"other probe": {nepName: "nep2", expect: []common.TimeSeries{builtin, probeSeries("nep2", 3)}},
+		"no probe":    {nepName: "nep3", expect: []common.TimeSeries{builtin}},
+	}
+
+	for k, v := range cases {
+		t.Run(k, func(t *testing.T) {
+			if series := policySeries(state, v.nepName); !reflect.DeepEqual(series, v.expect) {
+				t.Errorf("Expected %v, got %v", v.expect, series)
+			}
+		})
+	}
+}
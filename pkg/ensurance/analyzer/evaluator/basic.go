@@ -1,5 +1,13 @@
 package evaluator
 
+import (
+	"fmt"
+
+	"k8s.io/apimachinery/pkg/util/sets"
+
+	"github.com/gocrane/crane/pkg/utils"
+)
+
 type ExpressionEvaluator struct {
 }
 
@@ -7,10 +15,30 @@ func NewExpressionEvaluator() Evaluator {
 	return &ExpressionEvaluator{}
 }
 
-func (c *ExpressionEvaluator) EvalWithMetric(metricName string, targetValue float64, value float64) bool {
-	return value > targetValue
+func (c *ExpressionEvaluator) EvalWithMetric(metricName string, threshold Threshold, value float64) bool {
+	switch threshold.Operator {
+	case OperatorGreaterThan, "":
+		return value > threshold.Value
+	case OperatorGreaterThanOrEqual:
+		return value >= threshold.Value
+	case OperatorLessThan:
+		return value < threshold.Value
+	case OperatorLessThanOrEqual:
+		return value <= threshold.Value
+	case OperatorEqual:
+		return utils.AlmostEqual(value, threshold.Value)
+	case OperatorInRange:
+		return value >= threshold.Value && value <= threshold.UpperValue
+	case OperatorNotInRange:
+		return value < threshold.Value || value > threshold.UpperValue
+	default:
+		return false
+	}
+}
+
+func (c *ExpressionEvaluator) EvalWithRawQuery(key string, input interface{}, rule string) (bool, error) {
+	return false, fmt.Errorf("raw query is not supported by the expression evaluator")
 }
 
-func (c *ExpressionEvaluator) EvalWithRawQuery(input string, rule string) bool {
-	return false
+func (c *ExpressionEvaluator) Retain(keys sets.String) {
 }
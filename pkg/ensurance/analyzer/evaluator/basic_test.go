@@ -0,0 +1,84 @@
+package evaluator
+
+import (
+	"testing"
+)
+
+func TestEvalWithMetric(t *testing.T) {
+	cases := map[string]struct {
+		threshold Threshold
+		value     float64
+		expect    bool
+	}{
+		"default operator reached": {
+			threshold: Threshold{Value: 4000},
+			value:     4500,
+			expect:    true,
+		},
+		"greater than not reached": {
+			threshold: Threshold{Operator: OperatorGreaterThan, Value: 4000},
+			value:     4000,
+			expect:    false,
+		},
+		"greater than or equal reached": {
+			threshold: Threshold{Operator: OperatorGreaterThanOrEqual, Value: 4000},
+			value:     4000,
+			expect:    true,
+		},
+		"less than reached": {
+			threshold: Threshold{Operator: OperatorLessThan, Value: 10},
+			value:     5,
+			expect:    true,
+		},
+		"less than or equal not reached": {
+			threshold: Threshold{Operator: OperatorLessThanOrEqual, Value: 10},
+			value:     15,
+			expect:    false,
+		},
+		"equal reached": {
+			threshold: Threshold{Operator: OperatorEqual, Value: 1},
+			value:     1,
+			expect:    true,
+		},
+		"equal reached within the tolerance": {
+			threshold: Threshold{Operator: OperatorEqual, Value: 0.3},
+			value:     0.30000000000000004,
+			expect:    true,
+		},
+		"equal not reached": {
+			threshold: Threshold{Operator: OperatorEqual, Value: 0.3},
+			value:     0.3001,
+			expect:    false,
+		},
+		"in range reached": {
+			threshold: Threshold{Operator: OperatorInRange, Value: 10, UpperValue: 20},
+			value:     20,
+			expect:    true,
+		},
+		"not in range not reached": {
+			threshold: Threshold{Operator: OperatorNotInRange, Value: 10, UpperValue: 20},
+			value:     15,
+			expect:    false,
+		},
+		"not in range reached": {
+			threshold: Threshold{Operator: OperatorNotInRange, Value: 10, UpperValue: 20},
+			value:     25,
+			expect:    true,
+		},
+	}
+
+	evaluators := map[EvaluatorType]Evaluator{
+		ExpressionEvaluatorType: NewExpressionEvaluator(),
+		OpaEvaluatorType:        NewOpaEvaluator(),
+	}
+
+	for k, v := range cases {
+		for evaluatorType, e := range evaluators {
+			t.Run(string(evaluatorType)+"/"+k, func(t *testing.T) {
+				if triggered := e.EvalWithMetric("metric", v.threshold, v.value); triggered != v.expect {
+					t.Errorf("Expected %v, got %v", v.expect, triggered)
+				}
+			})
+		}
+	}
+}
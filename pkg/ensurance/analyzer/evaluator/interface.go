@@ -1,6 +1,57 @@
 package evaluator
 
+import (
+	"k8s.io/apimachinery/pkg/util/sets"
+)
+
+type EvaluatorType string
+
+const (
+	ExpressionEvaluatorType EvaluatorType = "expression"
+	OpaEvaluatorType        EvaluatorType = "opa"
+)
+
+// Operator is the operator to compare the metric value with the threshold.
+type Operator string
+
+const (
+	OperatorGreaterThan        Operator = ">"
+	OperatorGreaterThanOrEqual Operator = ">="
+	OperatorLessThan           Operator = "<"
+	OperatorLessThanOrEqual    Operator = "<="
+	OperatorEqual              Operator = "=="
+	// OperatorInRange is reached if the value is in [Value, UpperValue]
+	OperatorInRange Operator = "in"
+	// OperatorNotInRange is reached if the value is not in [Value, UpperValue]
+	OperatorNotInRange Operator = "notin"
+)
+
+// Operators are all the supported operators.
+var Operators = []Operator{OperatorGreaterThan, OperatorGreaterThanOrEqual, OperatorLessThan, OperatorLessThanOrEqual,
+	OperatorEqual, OperatorInRange, OperatorNotInRange}
+
+// IsRangeOperator returns true if the operator compares the value with a range.
+func IsRangeOperator(operator Operator) bool {
+	return operator == OperatorInRange || operator == OperatorNotInRange
+}
+
+// Threshold is the target of the metric value.
+type Threshold struct {
+	// Operator is the operator to compare the metric value, defaults to OperatorGreaterThan.
+	Operator Operator
+	// Value is the target value, or the lower bound for the range operators.
+	Value float64
+	// UpperValue is the upper bound for the range operators.
+	UpperValue float64
+}
+
+// Evaluator checks whether an objective ensurance is reached.
 type Evaluator interface {
-	EvalWithMetric(metricName string, targetValue float64, value float64) bool
-	EvalWithRawQuery(input string, rule string) bool
+	// EvalWithMetric returns true if the metric value reaches the threshold.
+	EvalWithMetric(metricName string, threshold Threshold, value float64) bool
+	// EvalWithRawQuery returns true if the rule of the objective identified by the key is true for the input.
+	// A *CompileError is returned if the rule can not be compiled.
+	EvalWithRawQuery(key string, input interface{}, rule string) (bool, error)
+	// Retain drops the states kept for the objectives which are not in the keys.
+	Retain(keys sets.String)
 }
@@ -1,23 +1,179 @@
 package evaluator
 
+import (
+	"context"
+	"fmt"
+	"sync"
+
+	"github.com/open-policy-agent/opa/ast"
+	"github.com/open-policy-agent/opa/rego"
+	"k8s.io/apimachinery/pkg/util/sets"
+)
+
+const (
+	// RegoPackage is the package which the objective rules are compiled in.
+	RegoPackage = "crane.ensurance"
+	// RegoTriggerQuery is the query of the rule which decides if an objective is triggered.
+	RegoTriggerQuery = "data." + RegoPackage + ".trigger"
+	// regoModuleFile is the file name of the module of the objective rules in the errors.
+	regoModuleFile = "objective.rego"
+
+	// metricRuleKey is the key of metricRule in the cache of the compiled rules.
+	metricRuleKey = "__metric__"
+	// metricRule is the rule used by EvalWithMetric, the values are equal within the tolerance of utils.AlmostEqual as
+	// the ExpressionEvaluator.
+	metricRule = `
+trigger { input.operator == "" ; input.value > input.target }
+trigger { input.operator == ">" ; input.value > input.target }
+trigger { input.operator == ">=" ; input.value >= input.target }
+trigger { input.operator == "<" ; input.value < input.target }
+trigger { input.operator == "<=" ; input.value <= input.target }
+trigger { input.operator == "==" ; abs(input.value - input.target) <= 1e-9 }
+trigger { input.operator == "in" ; input.value >= input.target ; input.value <= input.upperTarget }
+trigger { input.operator == "notin" ; input.value < input.target }
+trigger { input.operator == "notin" ; input.value > input.upperTarget }
+`
+)
+
+// CompileError is returned when a rule can not be compiled.
+type CompileError struct {
+	Err error
+}
+
+func (e *CompileError) Error() string {
+	return fmt.Sprintf("failed to compile rego rule: %v", e.Err)
+}
+
+type preparedRule struct {
+	rule  string
+	query rego.PreparedEvalQuery
+	err   error
+}
+
 type OpaEvaluator struct {
+	mu sync.Mutex
+	// rules are the compiled rules keyed by the objectives, an entry is replaced when the rule of the objective changes
+	rules map[string]preparedRule
 }
 
 func NewOpaEvaluator() Evaluator {
-	return &OpaEvaluator{}
+	return &OpaEvaluator{
+		rules: make(map[string]preparedRule),
+	}
+}
+
+func (c *OpaEvaluator) EvalWithMetric(metricName string, threshold Threshold, value float64) bool {
+	input := map[string]interface{}{
+		"name":        metricName,
+		"operator":    string(threshold.Operator),
+		"target":      threshold.Value,
+		"upperTarget": threshold.UpperValue,
+		"value":       value,
+	}
+
+	triggered, err := c.EvalWithRawQuery(metricRuleKey, input, metricRule)
+	if err != nil {
+		return false
+	}
+
+	return triggered
+}
+
+func (c *OpaEvaluator) EvalWithRawQuery(key string, input interface{}, rule string) (bool, error) {
+	query, err := c.prepare(key, rule)
+	if err != nil {
+		return false, err
+	}
+
+	rs, err := query.Eval(context.TODO(), rego.EvalInput(input))
+	if err != nil {
+		return false, fmt.Errorf("failed to evaluate rego rule: %v", err)
+	}
+
+	// the trigger rule is undefined, it is not triggered
+	if len(rs) == 0 || len(rs[0].Expressions) == 0 {
+		return false, nil
+	}
+
+	triggered, ok := rs[0].Expressions[0].Value.(bool)
+	if !ok {
+		return false, fmt.Errorf("the result of rego rule is %v, not a boolean", rs[0].Expressions[0].Value)
+	}
+
+	return triggered, nil
+}
+
+// prepare compiles the rule of the key, the result is cached to avoid compiling the same rule in every evaluation.
+func (c *OpaEvaluator) prepare(key string, rule string) (rego.PreparedEvalQuery, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if prepared, ok := c.rules[key]; ok && prepared.rule == rule {
+		return prepared.query, prepared.err
+	}
+
+	query, err := CompileRegoRule(rule)
+	c.rules[key] = preparedRule{rule: rule, query: query, err: err}
+
+	return query, err
+}
+
+// Retain drops the compiled rules of the objectives which are not in the keys.
+func (c *OpaEvaluator) Retain(keys sets.String) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	for key := range c.rules {
+		if key != metricRuleKey && !keys.Has(key) {
+			delete(c.rules, key)
+		}
+	}
 }
 
-func (c *OpaEvaluator) EvalWithMetric(metricName string, targetValue float64, value float64) bool {
+// CompileRegoRule compiles the rule in package RegoPackage and prepares the trigger query.
+func CompileRegoRule(rule string) (rego.PreparedEvalQuery, error) {
+	query, err := rego.New(
+		rego.Query(RegoTriggerQuery),
+		rego.Module(regoModuleFile, regoModule(rule)),
+	).PrepareForEval(context.TODO())
+	if err != nil {
+		return query, &CompileError{Err: err}
+	}
 
-	// step1 splice policy and input strings
-	// step2 opa.New().WithPolicyBytes
-	// step3 rego.Eval
-	// step4 transfer opa.result to bool
-	// return
+	return query, nil
+}
 
-	return false
+// regoModule returns the module of the rule in package RegoPackage.
+func regoModule(rule string) string {
+	return fmt.Sprintf("package %s\n\n%s\n", RegoPackage, rule)
 }
 
-func (c *OpaEvaluator) EvalWithRawQuery(input string, rule string) bool {
-	return false
+// regoMetricsRef is the reference to the metrics in the input of the rego rules.
+var regoMetricsRef = ast.InputRootRef.Append(ast.StringTerm("metrics"))
+
+// RegoMetricNames returns the names of the metrics referenced by the rule as input.metrics.<name>. It returns true
+// if the rule references the metrics by a variable or as a whole, such that any metric may be referenced.
+func RegoMetricNames(rule string) (sets.String, bool, error) {
+	module, err := ast.ParseModule(regoModuleFile, regoModule(rule))
+	if err != nil {
+		return nil, false, &CompileError{Err: err}
+	}
+
+	var names = sets.NewString()
+	var all bool
+	ast.WalkRefs(module, func(ref ast.Ref) bool {
+		if !ref.HasPrefix(regoMetricsRef) {
+			return false
+		}
+		if len(ref) > len(regoMetricsRef) {
+			if name, ok := ref[len(regoMetricsRef)].Value.(ast.String); ok {
+				names.Insert(string(name))
+				return false
+			}
+		}
+		all = true
+		return false
+	})
+
+	return names, all, nil
 }
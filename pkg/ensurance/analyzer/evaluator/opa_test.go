@@ -0,0 +1,145 @@
+package evaluator
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+
+	"k8s.io/apimachinery/pkg/util/sets"
+)
+
+func TestOpaEvaluatorEvalWithRawQuery(t *testing.T) {
+	const rule = `trigger {
+	input.metrics.cpu_total_utilization[0].value > 80
+	input.metrics.cpu_load_5_min[0].value > input.node.capacity.cpu * 1.5
+}`
+
+	input := func(utilization, load float64) map[string]interface{} {
+		return map[string]interface{}{
+			"node": map[string]interface{}{"capacity": map[string]interface{}{"cpu": 4}},
+			"metrics": map[string]interface{}{
+				"cpu_total_utilization": []interface{}{map[string]interface{}{"value": utilization}},
+				"cpu_load_5_min":        []interface{}{map[string]interface{}{"value": load}},
+			},
+		}
+	}
+
+	cases := map[string]struct {
+		input     map[string]interface{}
+		rule      string
+		expect    bool
+		expectErr bool
+	}{
+		"both conditions reached": {
+			input:  input(90, 7),
+			rule:   rule,
+			expect: true,
+		},
+		"only utilization reached": {
+			input:  input(90, 5),
+			rule:   rule,
+			expect: false,
+		},
+		"metric missing": {
+			input:  map[string]interface{}{},
+			rule:   rule,
+			expect: false,
+		},
+		"rule invalid": {
+			input:     input(90, 7),
+			rule:      "trigger { input.metrics.cpu_total_utilization[0].value > }",
+			expectErr: true,
+		},
+	}
+
+	e := NewOpaEvaluator()
+	for k, v := range cases {
+		t.Run(k, func(t *testing.T) {
+			triggered, err := e.EvalWithRawQuery(k, v.input, v.rule)
+			if v.expectErr {
+				var compileErr *CompileError
+				if !errors.As(err, &compileErr) {
+					t.Errorf("Expected compile error, got %v", err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+			if triggered != v.expect {
+				t.Errorf("Expected %v, got %v", v.expect, triggered)
+			}
+		})
+	}
+}
+
+func TestOpaEvaluatorRuleCache(t *testing.T) {
+	e := NewOpaEvaluator().(*OpaEvaluator)
+	input := map[string]interface{}{"value": 10}
+
+	triggered, err := e.EvalWithRawQuery("nep.objective", input, "trigger { input.value > 5 }")
+	if err != nil || !triggered {
+		t.Fatalf("Expected triggered, got %v, %v", triggered, err)
+	}
+
+	// the entry of the objective is replaced when the rule changes
+	triggered, err = e.EvalWithRawQuery("nep.objective", input, "trigger { input.value > 20 }")
+	if err != nil || triggered {
+		t.Fatalf("Expected not triggered, got %v, %v", triggered, err)
+	}
+	if len(e.rules) != 1 {
+		t.Errorf("Expected 1 compiled rule, got %d", len(e.rules))
+	}
+
+	// the rules of the objectives removed are dropped
+	e.EvalWithMetric("cpu_total_usage", Threshold{Value: 1}, 2)
+	e.Retain(sets.NewString())
+	if _, ok := e.rules["nep.objective"]; ok || len(e.rules) != 1 {
+		t.Errorf("Expected only the metric rule kept, got %d rules", len(e.rules))
+	}
+}
+
+func TestRegoMetricNames(t *testing.T) {
+	cases := map[string]struct {
+		rule      string
+		expect    []string
+		expectAll bool
+		expectErr bool
+	}{
+		"dotted and bracketed references": {
+			rule:   `trigger { input.metrics.cpu_total_utilization[0].value > 80; input.metrics["cpu_runqueue_latency_p99"][_].value > 1000 }`,
+			expect: []string{"cpu_runqueue_latency_p99", "cpu_total_utilization"},
+		},
+		"metric names in strings and comments are not references": {
+			rule:   "# cpu_runqueue_latency_p99\ntrigger { input.node.name == \"cpu_runqueue_latency_p99\"; input.metrics.cpu_load_1_min[0].value > 4 }",
+			expect: []string{"cpu_load_1_min"},
+		},
+		"metrics referenced by a variable": {
+			rule:      `trigger { some name; input.metrics[name][0].value > 80 }`,
+			expect:    []string{},
+			expectAll: true,
+		},
+		"rule invalid": {
+			rule:      "trigger { input.metrics.cpu_total_utilization[0].value > }",
+			expectErr: true,
+		},
+	}
+
+	for k, v := range cases {
+		t.Run(k, func(t *testing.T) {
+			names, all, err := RegoMetricNames(v.rule)
+			if v.expectErr {
+				if err == nil {
+					t.Errorf("Expected an error")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+			if !reflect.DeepEqual(names.List(), v.expect) || all != v.expectAll {
+				t.Errorf("Expected %v and %v, got %v and %v", v.expect, v.expectAll, names.List(), all)
+			}
+		})
+	}
+}
@@ -0,0 +1,276 @@
+package analyzer
+
+import (
+	"fmt"
+	"sort"
+
+	v1 "k8s.io/api/core/v1"
+	policyv1beta1 "k8s.io/api/policy/v1beta1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/types"
+	policylisters "k8s.io/client-go/listers/policy/v1beta1"
+	"k8s.io/klog/v2"
+	kubelettypes "k8s.io/kubernetes/pkg/kubelet/types"
+
+	ensuranceapi "github.com/gocrane/api/ensurance/v1alpha1"
+	"github.com/gocrane/crane/pkg/common"
+	"github.com/gocrane/crane/pkg/ensurance/analyzer/evaluator"
+	ecache "github.com/gocrane/crane/pkg/ensurance/cache"
+	stypes "github.com/gocrane/crane/pkg/ensurance/collector/types"
+	"github.com/gocrane/crane/pkg/ensurance/executor"
+	"github.com/gocrane/crane/pkg/utils"
+)
+
+// releasedUsage is the pod metric which is released by evicting the pod for a node metric.
+type releasedUsage struct {
+	podMetric stypes.MetricName
+	// scale converts the pod usage to the unit of the node metric, zero means it can not be converted
+	scale func(node *v1.Node) float64
+}
+
+func unitScale(*v1.Node) float64 {
+	return 1
+}
+
+var releasedUsages = map[stypes.MetricName]releasedUsage{
+	// the cpu usage of the node is in millicores and the cpu usage of the containers is in cores
+	stypes.MetricNameCpuTotalUsage: {podMetric: stypes.MetricNameContainerCpuTotalUsage, scale: func(*v1.Node) float64 { return 1000 }},
+	stypes.MetricNameCpuTotalUtilization: {podMetric: stypes.MetricNameContainerCpuTotalUsage, scale: func(node *v1.Node) float64 {
+		if capacity := node.Status.Capacity.Cpu().MilliValue(); capacity > 0 {
+			return 1000 * stypes.MaxPercentage / float64(capacity)
+		}
+		return 0
+	}},
+	stypes.MetricNameMemoryTotalUsage: {podMetric: stypes.MetricNameContainerMemTotalUsage, scale: unitScale},
+	stypes.MetricNameMemoryTotalUtilization: {podMetric: stypes.MetricNameContainerMemTotalUsage, scale: func(node *v1.Node) float64 {
+		if capacity := node.Status.Capacity.Memory().Value(); capacity > 0 {
+			return stypes.MaxPercentage / float64(capacity)
+		}
+		return 0
+	}},
+	stypes.MetricDiskReadKiBPS:       {podMetric: stypes.MetricPodDiskReadKiBPS, scale: unitScale},
+	stypes.MetricDiskWriteKiBPS:      {podMetric: stypes.MetricPodDiskWriteKiBPS, scale: unitScale},
+	stypes.MetricDiskReadIOPS:        {podMetric: stypes.MetricPodDiskReadIOPS, scale: unitScale},
+	stypes.MetricDiskWriteIOPS:       {podMetric: stypes.MetricPodDiskWriteIOPS, scale: unitScale},
+	stypes.MetricNetworkReceiveKiBPS: {podMetric: stypes.MetricPodNetworkReceiveKiBPS, scale: unitScale},
+	stypes.MetricNetworkSentKiBPS:    {podMetric: stypes.MetricPodNetworkSentKiBPS, scale: unitScale},
+	stypes.MetricNetworkReceivePckPS: {podMetric: stypes.MetricPodNetworkReceivePckPS, scale: unitScale},
+	stypes.MetricNetworkSentPckPS:    {podMetric: stypes.MetricPodNetworkSentPckPS, scale: unitScale},
+}
+
+// podCandidate is a pod which can be evicted or throttled, the usage is the usage released by the action in the unit
+// of the metric which triggered the action.
+type podCandidate struct {
+	pod              *v1.Pod
+	classAndPriority executor.ClassAndPriority
+	usage            float64
+}
+
+// disruptionBudgets tracks the disruptions allowed by the PodDisruptionBudgets while the victims are selected.
+type disruptionBudgets struct {
+	lister  policylisters.PodDisruptionBudgetLister
+	allowed map[types.NamespacedName]int32
+}
+
+func newDisruptionBudgets(lister policylisters.PodDisruptionBudgetLister) *disruptionBudgets {
+	return &disruptionBudgets{lister: lister, allowed: make(map[types.NamespacedName]int32)}
+}
+
+// take consumes a disruption of the budgets matching the pod, it returns false and consumes nothing if any of the
+// budgets does not allow more disruptions.
+func (d *disruptionBudgets) take(pod *v1.Pod) bool {
+	if d == nil || d.lister == nil {
+		return true
+	}
+
+	// an error is returned if no budget matches the pod
+	pdbs, err := d.lister.GetPodPodDisruptionBudgets(pod)
+	if err != nil {
+		return true
+	}
+
+	var keys []types.NamespacedName
+	for _, pdb := range pdbs {
+		key := types.NamespacedName{Namespace: pdb.Namespace, Name: pdb.Name}
+		if _, ok := d.allowed[key]; !ok {
+			d.allowed[key] = disruptionsAllowed(pdb)
+		}
+		if d.allowed[key] <= 0 {
+			klog.V(4).Infof("Pod %s is not evicted, the PodDisruptionBudget %s does not allow more disruptions", klog.KObj(pod), key)
+			return false
+		}
+		keys = append(keys, key)
+	}
+
+	for _, key := range keys {
+		d.allowed[key]--
+	}
+	return true
+}
+
+func disruptionsAllowed(pdb *policyv1beta1.PodDisruptionBudget) int32 {
+	// the status is not trusted until it is observed by the disruption controller
+	if pdb.Status.ObservedGeneration < pdb.Generation {
+		return 0
+	}
+	return pdb.Status.DisruptionsAllowed
+}
+
+// targetValue returns the max value of the series and the value it should be reduced to for the objective to be
+// restorable, the target is zero if the metric is not reduced by releasing the usage, such as the less than operators.
+func targetValue(series []common.TimeSeries, triggerThreshold, restoreThreshold evaluator.Threshold) (float64, float64) {
+	var value float64
+	for i, ts := range series {
+		if i == 0 || ts.Samples[0].Value > value {
+			value = ts.Samples[0].Value
+		}
+	}
+
+	switch triggerThreshold.Operator {
+	case evaluator.OperatorGreaterThan, evaluator.OperatorGreaterThanOrEqual:
+		return value, restoreThreshold.Value
+	default:
+		return value, 0
+	}
+}
+
+// podUsage returns the usage of the pod, it is the sum of the containers if the metric is not collected for the pod.
+func podUsage(metricName stypes.MetricName, stateMap map[string][]common.TimeSeries, pod *v1.Pod) float64 {
+	usage, containerUsages := executor.GetPodUsage(string(metricName), stateMap, pod)
+	if usage == 0 {
+		for _, c := range containerUsages {
+			usage += c.Value
+		}
+	}
+	return usage
+}
+
+func (s *AnormalyAnalyzer) getEvictPods(ac ecache.ActionContext, action *ensuranceapi.AvoidanceAction,
+	stateMap map[string][]common.TimeSeries, budgets *disruptionBudgets) []executor.EvictPod {
+	evictPods := []executor.EvictPod{}
+
+	if !ac.Triggered {
+		return evictPods
+	}
+
+	allPods, err := s.podLister.List(labels.Everything())
+	if err != nil {
+		klog.Errorf("Failed to list all pods: %v.", err)
+		return evictPods
+	}
+
+	var candidates []podCandidate
+	for _, pod := range allPods {
+		if !isRunning(pod) || !isEvictable(pod) {
+			continue
+		}
+		candidates = append(candidates, podCandidate{pod: pod,
+			classAndPriority: executor.ClassAndPriority{PodQOSClass: pod.Status.QOSClass, PriorityClassValue: utils.GetInt32withDefault(pod.Spec.Priority, 0)}})
+	}
+
+	excess, ranked := s.rankCandidates(ac, stateMap, candidates, 1)
+	if !ranked {
+		candidates = lowestBand(candidates)
+		klog.V(4).Infof("The usage of the pods is unknown for the metric %q, the %d pods of the lowest class and priority are evicted by the action %s",
+			ac.MetricName, len(candidates), ac.ActionName)
+	}
+
+	for _, c := range selectVictims(candidates, excess, ranked, budgets) {
+		var reason = fmt.Sprintf("Evicted by the objective %s of NodeQOSEnsurancePolicy %s with QoS class %s and priority %d",
+			ac.ObjectiveEnsuranceName, ac.Nep.Name, c.classAndPriority.PodQOSClass, c.classAndPriority.PriorityClassValue)
+		if ranked {
+			reason = fmt.Sprintf("%s, the pod uses %.2f of %s which is %.2f and should be reduced to %.2f",
+				reason, c.usage, ac.MetricName, ac.MetricValue, ac.TargetValue)
+		}
+		evictPods = append(evictPods, executor.EvictPod{DeletionGracePeriodSeconds: action.Spec.Eviction.TerminationGracePeriodSeconds,
+			PodKey: types.NamespacedName{Name: c.pod.Name, Namespace: c.pod.Namespace}, ClassAndPriority: c.classAndPriority, Reason: reason})
+	}
+
+	return evictPods
+}
+
+// isEvictable returns false for the pods which are recreated on the node or are critical to it, evicting them releases
+// nothing or breaks the node: the static and mirror pods, the pods of DaemonSets, and the system critical pods.
+func isEvictable(pod *v1.Pod) bool {
+	if kubelettypes.IsCriticalPod(pod) {
+		return false
+	}
+	if owner := metav1.GetControllerOf(pod); owner != nil && owner.Kind == "DaemonSet" {
+		return false
+	}
+	return true
+}
+
+// lowestBand returns the candidates of the lowest class and priority.
+func lowestBand(candidates []podCandidate) []podCandidate {
+	var band []podCandidate
+	for _, c := range candidates {
+		if len(band) == 0 || c.classAndPriority.Less(band[0].classAndPriority) {
+			band = []podCandidate{c}
+		} else if c.classAndPriority == band[0].classAndPriority {
+			band = append(band, c)
+		}
+	}
+	return band
+}
+
+// rankCandidates sets the usage of the candidates released for the metric of the objective, which is the usage of the
+// pods multiplied by the ratio released by the action. It returns the excess of the metric over the target, and false if
+// the usage of the pods is unknown or is not released for the metric.
+func (s *AnormalyAnalyzer) rankCandidates(ac ecache.ActionContext, stateMap map[string][]common.TimeSeries, candidates []podCandidate, ratio float64) (float64, bool) {
+	released, ok := releasedUsages[stypes.MetricName(ac.MetricName)]
+	if !ok || ac.TargetValue <= 0 || ratio <= 0 {
+		return 0, false
+	}
+
+	node, err := s.nodeLister.Get(s.nodeName)
+	if err != nil {
+		klog.Errorf("Failed to get node: %v", err)
+		return 0, false
+	}
+	scale := released.scale(node)
+	if scale <= 0 {
+		return 0, false
+	}
+
+	var ranked bool
+	for i := range candidates {
+		candidates[i].usage = podUsage(released.podMetric, stateMap, candidates[i].pod) * scale * ratio
+		ranked = ranked || candidates[i].usage > 0
+	}
+
+	return ac.MetricValue - ac.TargetValue, ranked
+}
+
+// selectVictims ranks the candidates by the class and priority, and by the usage in descending order in the same band,
+// then it picks the fewest of them whose usage covers the excess. All the candidates are picked if they are not ranked
+// by the usage. The candidates are skipped if the disruption budgets do not allow them to be evicted.
+func selectVictims(candidates []podCandidate, excess float64, ranked bool, budgets *disruptionBudgets) []podCandidate {
+	sort.SliceStable(candidates, func(i, j int) bool {
+		if candidates[i].classAndPriority != candidates[j].classAndPriority {
+			return candidates[i].classAndPriority.Less(candidates[j].classAndPriority)
+		}
+		return candidates[i].usage > candidates[j].usage
+	})
+
+	var victims []podCandidate
+	var released float64
+	for _, c := range candidates {
+		if ranked {
+			if released >= excess {
+				break
+			}
+			if c.usage <= 0 {
+				continue
+			}
+		}
+		if !budgets.take(c.pod) {
+			continue
+		}
+		victims = append(victims, c)
+		released += c.usage
+	}
+
+	return victims
+}
@@ -0,0 +1,95 @@
+package analyzer
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	v1 "k8s.io/api/core/v1"
+	"k8s.io/klog/v2"
+
+	ecache "github.com/gocrane/crane/pkg/ensurance/cache"
+	"github.com/gocrane/crane/pkg/ensurance/executor"
+	"github.com/gocrane/crane/pkg/metrics"
+	"github.com/gocrane/crane/pkg/utils"
+)
+
+// preview builds the plan of the actions in preview, which is what would be executed if they were enforced. The plan is
+// recorded by the metrics, and by an event when it is changed, and it is kept for the debug endpoint.
+func (s *AnormalyAnalyzer) preview(ae executor.AvoidanceExecutor, acs []ecache.ActionContext, now time.Time) {
+	plan := executor.NewPlan(ae, s.podLister, now)
+	for _, ac := range acs {
+		if ac.Triggered || ac.Restored {
+			plan.Objectives = append(plan.Objectives, strings.Join([]string{ac.Nep.Name, ac.ObjectiveEnsuranceName}, "."))
+		}
+	}
+
+	metrics.UpdatePreviewPlan(metrics.PreviewPlanDisableScheduling, float64(utils.Bool2Int32(plan.DisableScheduling)))
+	metrics.UpdatePreviewPlan(metrics.PreviewPlanCPUThrottleDown, float64(len(plan.CPUThrottleDown)))
+	metrics.UpdatePreviewPlan(metrics.PreviewPlanCPUThrottleUp, float64(len(plan.CPUThrottleUp)))
+	metrics.UpdatePreviewPlan(metrics.PreviewPlanMemoryThrottleDown, float64(len(plan.MemoryThrottleDown)))
+	metrics.UpdatePreviewPlan(metrics.PreviewPlanDiskIOThrottleDown, float64(len(plan.DiskIOThrottleDown)))
+	metrics.UpdatePreviewPlan(metrics.PreviewPlanNetworkThrottleDown, float64(len(plan.NetworkThrottleDown)))
+	metrics.UpdatePreviewPlan(metrics.PreviewPlanEvict, float64(len(plan.Evict)))
+
+	// the event is recorded only when the plan is changed, since the same plan is built in every analysis
+	summary := previewSummary(plan)
+	if summary != s.previewSummary {
+		s.previewSummary = summary
+		if summary != "" {
+			klog.V(4).Infof("Preview: %s", summary)
+			if s.recorder != nil {
+				s.recorder.Event(utils.GetNodeRef(s.nodeName), v1.EventTypeNormal, "AvoidancePreview", summary)
+			}
+		}
+	}
+
+	s.previewLock.Lock()
+	defer s.previewLock.Unlock()
+	s.previewPlan = plan
+}
+
+// previewSummary returns a brief of the plan, it is empty if nothing would be done.
+func previewSummary(plan executor.Plan) string {
+	if plan.Empty() {
+		return ""
+	}
+
+	var actions []string
+	if plan.DisableScheduling {
+		actions = append(actions, "disable scheduling")
+	}
+	if plan.EnableScheduling {
+		actions = append(actions, "enable scheduling")
+	}
+	for _, a := range []struct {
+		name  string
+		count int
+	}{
+		{"throttle down the cpu of %d containers", len(plan.CPUThrottleDown)},
+		{"throttle up the cpu of %d containers", len(plan.CPUThrottleUp)},
+		{"throttle down the memory of %d pods", len(plan.MemoryThrottleDown)},
+		{"throttle down the disk io of %d pods", len(plan.DiskIOThrottleDown)},
+		{"throttle down the network of %d pods", len(plan.NetworkThrottleDown)},
+	} {
+		if a.count > 0 {
+			actions = append(actions, fmt.Sprintf(a.name, a.count))
+		}
+	}
+	if len(plan.Evict) > 0 {
+		var pods []string
+		for _, e := range plan.Evict {
+			pods = append(pods, e.Pod)
+		}
+		actions = append(actions, fmt.Sprintf("evict %s", strings.Join(pods, ", ")))
+	}
+
+	return fmt.Sprintf("%s would %s", strings.Join(plan.Objectives, ", "), strings.Join(actions, ", "))
+}
+
+// GetPreviewPlan returns the plan of the latest analysis for the actions in preview.
+func (s *AnormalyAnalyzer) GetPreviewPlan() executor.Plan {
+	s.previewLock.RLock()
+	defer s.previewLock.RUnlock()
+	return s.previewPlan
+}
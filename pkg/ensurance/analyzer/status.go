@@ -0,0 +1,154 @@
+package analyzer
+
+import (
+	"encoding/json"
+	"reflect"
+	"strings"
+	"time"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/sets"
+	"k8s.io/klog/v2"
+
+	ecache "github.com/gocrane/crane/pkg/ensurance/cache"
+	"github.com/gocrane/crane/pkg/ensurance/extension"
+	"github.com/gocrane/crane/pkg/known"
+	"github.com/gocrane/crane/pkg/utils"
+)
+
+const (
+	// maxAffectedPods is the max number of the affected pods listed in the status of an objective ensurance
+	maxAffectedPods = 20
+)
+
+// restoreStatuses restores the counters and the triggered actions from the status of the node.
+func (s *AnormalyAnalyzer) restoreStatuses() {
+	node, err := s.nodeLister.Get(s.nodeName)
+	if err != nil {
+		klog.Errorf("Failed to get node: %v", err)
+		return
+	}
+
+	// the status on the node is not patched again until it is changed
+	s.statusPatchedValue = node.Annotations[known.ObjectiveStatusAnnotation]
+
+	statuses, err := extension.GetObjectiveStatuses(node)
+	if err != nil {
+		klog.Errorf("Failed to restore the status of the objective ensurances: %v", err)
+		return
+	}
+
+	for key, status := range statuses {
+		s.triggered[key] = status.TriggeredCount
+		s.restored[key] = status.RestoredCount
+
+		if status.Triggered && status.LastTriggeredTime != nil {
+			var eventKey = strings.Join([]string{status.NodeQOSEnsurancePolicy, status.ObjectiveEnsurance}, "/")
+			s.actionEventStatus[eventKey] = ecache.DetectionStatus{IsTriggered: true, LastTime: status.LastTriggeredTime.Time}
+			if status.LastTriggeredTime.After(s.lastTriggeredTime) {
+				s.lastTriggeredTime = status.LastTriggeredTime.Time
+			}
+		}
+	}
+	s.setStatuses(statuses)
+
+	klog.Infof("Restored the status of %d objective ensurances from node %s", len(statuses), s.nodeName)
+}
+
+// updateStatuses updates the status of the objective ensurances by the action contexts of the latest analysis. The
+// status is patched to the node only if it is changed apart from the fields refreshed by every analysis, that is when an
+// action is triggered or restored, the counters or the affected pods are changed, or the objectives are changed.
+func (s *AnormalyAnalyzer) updateStatuses(acs []ecache.ActionContext, influencedPods map[string]sets.String, now time.Time) {
+	var statuses = make(map[string]extension.ObjectiveStatus, len(acs))
+	var changed = len(acs) != len(s.statuses)
+
+	for _, ac := range acs {
+		var key = strings.Join([]string{ac.Nep.Name, ac.ObjectiveEnsuranceName}, ".")
+		status, ok := s.statuses[key]
+		var last = status
+
+		status.NodeQOSEnsurancePolicy = ac.Nep.Name
+		status.ObjectiveEnsurance = ac.ObjectiveEnsuranceName
+		status.MetricName = ac.MetricName
+		status.Value = ac.MetricValue
+		status.Threshold = ac.Threshold
+		status.TriggeredCount = utils.GetUint64FromMaps(key, s.triggered)
+		status.RestoredCount = utils.GetUint64FromMaps(key, s.restored)
+
+		if ac.Triggered {
+			changed = changed || !status.Triggered
+			status.Triggered = true
+			status.LastTriggeredTime = &metav1.Time{Time: now}
+
+			pods := influencedPods[key].List()
+			status.AffectedPodCount = len(pods)
+			if len(pods) > maxAffectedPods {
+				pods = pods[:maxAffectedPods]
+			}
+			status.AffectedPods = pods
+		} else if ac.Restored && status.Triggered {
+			status.Triggered = false
+			status.LastRestoredTime = &metav1.Time{Time: now}
+			status.AffectedPods, status.AffectedPodCount = nil, 0
+		}
+
+		changed = changed || !ok || !reflect.DeepEqual(stableStatus(last), stableStatus(status))
+		statuses[key] = status
+	}
+	s.setStatuses(statuses)
+
+	if changed {
+		s.patchStatuses()
+	}
+}
+
+// stableStatus returns the status without the fields refreshed by every analysis, which are the value of the metric
+// and the last time the triggered action is triggered again.
+func stableStatus(status extension.ObjectiveStatus) extension.ObjectiveStatus {
+	status.Value = 0
+	if status.Triggered {
+		status.LastTriggeredTime = nil
+	}
+	return status
+}
+
+func (s *AnormalyAnalyzer) setStatuses(statuses map[string]extension.ObjectiveStatus) {
+	s.statusLock.Lock()
+	defer s.statusLock.Unlock()
+	s.statuses = statuses
+}
+
+// GetObjectiveStatuses returns the status of the objective ensurances of the latest analysis, the statuses must not
+// be modified.
+func (s *AnormalyAnalyzer) GetObjectiveStatuses() map[string]extension.ObjectiveStatus {
+	s.statusLock.RLock()
+	defer s.statusLock.RUnlock()
+	return s.statuses
+}
+
+// patchStatuses patches the status to the annotation of the node if it is changed since the latest patch, the
+// annotation is removed if there is no status.
+func (s *AnormalyAnalyzer) patchStatuses() {
+	if s.kubeClient == nil {
+		return
+	}
+
+	var value string
+	if len(s.statuses) != 0 {
+		data, err := json.Marshal(s.statuses)
+		if err != nil {
+			klog.Errorf("Failed to marshal the status of the objective ensurances: %v", err)
+			return
+		}
+		value = string(data)
+	}
+	if value == s.statusPatchedValue {
+		return
+	}
+
+	if err := utils.PatchNodeAnnotation(s.kubeClient, s.nodeName, known.ObjectiveStatusAnnotation, value); err != nil {
+		klog.Errorf("Failed to patch the status of the objective ensurances to node %s: %v", s.nodeName, err)
+		return
+	}
+	s.statusPatchedValue = value
+}
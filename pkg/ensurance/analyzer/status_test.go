@@ -0,0 +1,144 @@
+package analyzer
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	v1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/sets"
+	"k8s.io/client-go/kubernetes/fake"
+	corelisters "k8s.io/client-go/listers/core/v1"
+	"k8s.io/client-go/tools/cache"
+
+	ensuranceapi "github.com/gocrane/api/ensurance/v1alpha1"
+	ecache "github.com/gocrane/crane/pkg/ensurance/cache"
+	"github.com/gocrane/crane/pkg/ensurance/extension"
+	"github.com/gocrane/crane/pkg/known"
+)
+
+func TestUpdateAndRestoreStatuses(t *testing.T) {
+	node := &v1.Node{ObjectMeta: metav1.ObjectMeta{Name: "node1"}}
+	client := fake.NewSimpleClientset(node)
+	nep := &ensuranceapi.NodeQOSEnsurancePolicy{ObjectMeta: metav1.ObjectMeta{Name: "nep"}}
+
+	s := &AnormalyAnalyzer{
+		nodeName:          "node1",
+		kubeClient:        client,
+		triggered:         map[string]uint64{"nep.cpu": 3},
+		restored:          make(map[string]uint64),
+		actionEventStatus: make(map[string]ecache.DetectionStatus),
+		statuses:          make(map[string]extension.ObjectiveStatus),
+	}
+
+	var now = time.Unix(1650000000, 0)
+	acs := []ecache.ActionContext{{Nep: nep, ObjectiveEnsuranceName: "cpu", Triggered: true,
+		MetricName: "cpu_total_usage", MetricValue: 7500, Threshold: 6000}}
+	s.updateStatuses(acs, map[string]sets.String{"nep.cpu": sets.NewString("default/pod2", "default/pod1")}, now)
+
+	node, err := client.CoreV1().Nodes().Get(context.TODO(), "node1", metav1.GetOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	statuses, err := extension.GetObjectiveStatuses(node)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	status, ok := statuses["nep.cpu"]
+	if !ok {
+		t.Fatalf("Expected the status of nep.cpu, got %v", statuses)
+	}
+	if !status.Triggered || status.TriggeredCount != 3 || status.Value != 7500 || status.Threshold != 6000 ||
+		status.LastTriggeredTime == nil || !status.LastTriggeredTime.Time.Equal(now) {
+		t.Errorf("Unexpected status %+v", status)
+	}
+	if status.AffectedPodCount != 2 || len(status.AffectedPods) != 2 || status.AffectedPods[0] != "default/pod1" {
+		t.Errorf("Expected the affected pods default/pod1 and default/pod2, got %v", status.AffectedPods)
+	}
+
+	// restore the counters by a new analyzer from the node
+	nodeIndexer := cache.NewIndexer(cache.MetaNamespaceKeyFunc, cache.Indexers{})
+	nodeIndexer.Add(node)
+	restored := &AnormalyAnalyzer{
+		nodeName:          "node1",
+		nodeLister:        corelisters.NewNodeLister(nodeIndexer),
+		triggered:         make(map[string]uint64),
+		restored:          make(map[string]uint64),
+		actionEventStatus: make(map[string]ecache.DetectionStatus),
+	}
+	restored.restoreStatuses()
+	if restored.triggered["nep.cpu"] != 3 {
+		t.Errorf("Expected the triggered count 3, got %d", restored.triggered["nep.cpu"])
+	}
+	if !restored.actionEventStatus["nep/cpu"].IsTriggered || !restored.lastTriggeredTime.Equal(now) {
+		t.Errorf("Expected the action triggered at %v, got %+v", now, restored.actionEventStatus["nep/cpu"])
+	}
+
+	// the action is restored
+	restored.kubeClient = client
+	restored.triggered["nep.cpu"], restored.restored["nep.cpu"] = 0, 2
+	restored.updateStatuses([]ecache.ActionContext{{Nep: nep, ObjectiveEnsuranceName: "cpu", Restored: true}}, nil, now.Add(time.Second))
+	status = restored.statuses["nep.cpu"]
+	if status.Triggered || status.RestoredCount != 2 || status.LastRestoredTime == nil || len(status.AffectedPods) != 0 {
+		t.Errorf("Unexpected status %+v after the action is restored", status)
+	}
+
+	// the annotation is removed if there is no objective
+	restored.updateStatuses(nil, nil, now.Add(2*time.Second))
+	node, err = client.CoreV1().Nodes().Get(context.TODO(), "node1", metav1.GetOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := node.Annotations[known.ObjectiveStatusAnnotation]; ok {
+		t.Errorf("Expected the annotation to be removed, got %s", node.Annotations[known.ObjectiveStatusAnnotation])
+	}
+}
+
+func TestPatchStatusesOnChange(t *testing.T) {
+	node := &v1.Node{ObjectMeta: metav1.ObjectMeta{Name: "node1"}}
+	client := fake.NewSimpleClientset(node)
+	nep := &ensuranceapi.NodeQOSEnsurancePolicy{ObjectMeta: metav1.ObjectMeta{Name: "nep"}}
+
+	s := &AnormalyAnalyzer{
+		nodeName:   "node1",
+		kubeClient: client,
+		triggered:  make(map[string]uint64),
+		restored:   make(map[string]uint64),
+		statuses:   make(map[string]extension.ObjectiveStatus),
+	}
+	ac := func(value float64, triggered bool) []ecache.ActionContext {
+		return []ecache.ActionContext{{Nep: nep, ObjectiveEnsuranceName: "cpu", Triggered: triggered, MetricName: "cpu_total_usage", MetricValue: value}}
+	}
+
+	var now = time.Unix(1650000000, 0)
+	steps := []struct {
+		acs            []ecache.ActionContext
+		triggeredCount uint64
+		expect         int
+	}{
+		{acs: ac(10, false), expect: 1},
+		// the value is refreshed by every analysis, it is not patched alone
+		{acs: ac(20, false), expect: 1},
+		{acs: ac(20, false), triggeredCount: 1, expect: 2},
+		{acs: ac(20, true), triggeredCount: 1, expect: 3},
+		// neither is the last triggered time of the triggered action
+		{acs: ac(30, true), triggeredCount: 1, expect: 3},
+		{acs: ac(30, true), triggeredCount: 1, expect: 3},
+	}
+
+	for i, step := range steps {
+		s.triggered["nep.cpu"] = step.triggeredCount
+		s.updateStatuses(step.acs, nil, now.Add(time.Duration(i)*time.Minute))
+
+		var patches int
+		for _, action := range client.Actions() {
+			if action.GetVerb() == "patch" {
+				patches++
+			}
+		}
+		if patches != step.expect {
+			t.Errorf("Expected %d patches at step %d, got %d", step.expect, i, patches)
+		}
+	}
+}
@@ -0,0 +1,161 @@
+package analyzer
+
+import (
+	"math"
+
+	v1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/apimachinery/pkg/util/sets"
+	"k8s.io/klog/v2"
+
+	ensuranceapi "github.com/gocrane/api/ensurance/v1alpha1"
+	"github.com/gocrane/crane/pkg/common"
+	ecache "github.com/gocrane/crane/pkg/ensurance/cache"
+	stypes "github.com/gocrane/crane/pkg/ensurance/collector/types"
+	"github.com/gocrane/crane/pkg/ensurance/executor"
+	"github.com/gocrane/crane/pkg/ensurance/extension"
+	"github.com/gocrane/crane/pkg/utils"
+)
+
+// getThrottleTargets returns the pods to be throttled down for the triggered objective. The candidates are chosen by
+// throttleCandidates, and only the fewest pods whose throttling brings the metric under the target are returned if the
+// release can be projected.
+func (s *AnormalyAnalyzer) getThrottleTargets(ac ecache.ActionContext, action *ensuranceapi.AvoidanceAction,
+	stateMap map[string][]common.TimeSeries, allPods []*v1.Pod) []*v1.Pod {
+	candidates := throttleCandidates(ac, action, allPods, "")
+
+	excess, ranked := s.rankCandidates(ac, stateMap, candidates, throttleStep(stypes.MetricName(ac.MetricName), action))
+	if !ranked {
+		klog.V(4).Infof("The release of throttling is unknown for the metric %q, all the target pods are throttled by the action %s", ac.MetricName, ac.ActionName)
+	}
+
+	var pods []*v1.Pod
+	for _, c := range selectVictims(candidates, excess, ranked, nil) {
+		pods = append(pods, c.pod)
+	}
+	return pods
+}
+
+// throttleCandidates returns the pods which can be throttled down for the triggered objective. The pods are selected by
+// the throttle target of the action, and the pods not lower than the protected pods of the throttle target are never
+// throttled. If the objective is evaluated per pod, only the pods influencing the objective are candidates. The
+// defaultMaxQOSClass is the ceiling of the QoS class if the throttle target doesn't set one.
+func throttleCandidates(ac ecache.ActionContext, action *ensuranceapi.AvoidanceAction, allPods []*v1.Pod, defaultMaxQOSClass v1.PodQOSClass) []podCandidate {
+	throttleTarget, err := extension.GetThrottleTarget(action)
+	if err != nil {
+		klog.Errorf("Failed to get throttle target of AvoidanceAction %s: %v", action.Name, err)
+		return nil
+	}
+
+	// the default ceiling only applies to the class, the priority of the throttle target applies to all the classes
+	var defaultCeiling *executor.ClassAndPriority
+	if defaultMaxQOSClass != "" && (throttleTarget == nil || throttleTarget.MaxQOSClass == "") {
+		defaultCeiling = &executor.ClassAndPriority{PodQOSClass: defaultMaxQOSClass, PriorityClassValue: math.MaxInt32}
+	}
+
+	var selector = labels.Everything()
+	if throttleTarget != nil && throttleTarget.PodSelector != nil {
+		if selector, err = metav1.LabelSelectorAsSelector(throttleTarget.PodSelector); err != nil {
+			klog.Errorf("Failed to parse the pod selector of AvoidanceAction %s: %v", action.Name, err)
+			return nil
+		}
+	}
+
+	// the pods hurt by the contention and the pods not lower than them are not throttled
+	var protected *executor.ClassAndPriority
+	if throttleTarget != nil && throttleTarget.ProtectedPodSelector != nil {
+		protectedSelector, err := metav1.LabelSelectorAsSelector(throttleTarget.ProtectedPodSelector)
+		if err != nil {
+			klog.Errorf("Failed to parse the protected pod selector of AvoidanceAction %s: %v", action.Name, err)
+			return nil
+		}
+		protected = lowestClassAndPriority(protectedSelector, allPods)
+	}
+
+	// the pods influencing the objective are the offenders, the others are not throttled
+	var offenders = sets.NewString()
+	for _, pod := range ac.BeInfluencedPods {
+		offenders.Insert(pod.String())
+	}
+
+	var candidates []podCandidate
+	for _, pod := range allPods {
+		if !isRunning(pod) {
+			continue
+		}
+		if !selector.Matches(labels.Set(pod.Labels)) {
+			continue
+		}
+		if offenders.Len() > 0 && !offenders.Has(types.NamespacedName{Namespace: pod.Namespace, Name: pod.Name}.String()) {
+			continue
+		}
+
+		classAndPriority := executor.ClassAndPriority{PodQOSClass: pod.Status.QOSClass, PriorityClassValue: utils.GetInt32withDefault(pod.Spec.Priority, 0)}
+		if throttleTarget != nil && exceedsThrottleTarget(throttleTarget, classAndPriority) {
+			continue
+		}
+		if defaultCeiling != nil && classAndPriority.Greater(*defaultCeiling) {
+			continue
+		}
+		if protected != nil && !classAndPriority.Less(*protected) {
+			klog.V(6).Infof("Pod %s is not throttled, it is not lower than the protected pods of the objective %s", klog.KObj(pod), ac.ObjectiveEnsuranceName)
+			continue
+		}
+		candidates = append(candidates, podCandidate{pod: pod, classAndPriority: classAndPriority})
+	}
+
+	return candidates
+}
+
+// isRunning returns false if the pod is terminating or completed, such pods release nothing.
+func isRunning(pod *v1.Pod) bool {
+	return pod.DeletionTimestamp == nil && pod.Status.Phase != v1.PodSucceeded && pod.Status.Phase != v1.PodFailed
+}
+
+// lowestClassAndPriority returns the lowest class and priority of the running pods matched by the selector, it returns
+// nil if no pod is matched.
+func lowestClassAndPriority(selector labels.Selector, allPods []*v1.Pod) *executor.ClassAndPriority {
+	var lowest *executor.ClassAndPriority
+	for _, pod := range allPods {
+		if !isRunning(pod) || !selector.Matches(labels.Set(pod.Labels)) {
+			continue
+		}
+		classAndPriority := executor.ClassAndPriority{PodQOSClass: pod.Status.QOSClass, PriorityClassValue: utils.GetInt32withDefault(pod.Spec.Priority, 0)}
+		if lowest == nil || classAndPriority.Less(*lowest) {
+			lowest = &classAndPriority
+		}
+	}
+	return lowest
+}
+
+// exceedsThrottleTarget returns true if the class and priority of the pod is higher than the ceiling of the throttle target.
+func exceedsThrottleTarget(throttleTarget *extension.ThrottleTarget, classAndPriority executor.ClassAndPriority) bool {
+	if throttleTarget.MaxQOSClass == "" {
+		return throttleTarget.MaxPriority != nil && classAndPriority.PriorityClassValue > *throttleTarget.MaxPriority
+	}
+
+	var ceiling = executor.ClassAndPriority{PodQOSClass: throttleTarget.MaxQOSClass, PriorityClassValue: math.MaxInt32}
+	if throttleTarget.MaxPriority != nil {
+		ceiling.PriorityClassValue = *throttleTarget.MaxPriority
+	}
+	return classAndPriority.Greater(ceiling)
+}
+
+// throttleStep returns the ratio of the usage released by a step of the throttle for the metric, it is zero if the
+// release can not be projected.
+func throttleStep(metricName stypes.MetricName, action *ensuranceapi.AvoidanceAction) float64 {
+	switch metricName {
+	case stypes.MetricNameCpuTotalUsage, stypes.MetricNameCpuTotalUtilization:
+		return float64(action.Spec.Throttle.CPUThrottle.StepCPURatio) / stypes.MaxPercentage
+	case stypes.MetricNameMemoryTotalUsage, stypes.MetricNameMemoryTotalUtilization:
+		memoryThrottle, err := extension.GetMemoryThrottle(action)
+		if err != nil {
+			return 0
+		}
+		return float64(memoryThrottle.StepMemoryRatio) / stypes.MaxPercentage
+	default:
+		return 0
+	}
+}
@@ -28,6 +28,13 @@ type ActionContext struct {
 	// the influenced pod list
 	// node detection the pod list is empty
 	BeInfluencedPods []types.NamespacedName
+	// the metric of the rule and its value, the metric name is empty for the rego rules
+	MetricName  string
+	MetricValue float64
+	// the value the metric should be reduced to, it is zero if the metric is not reduced by releasing usage
+	TargetValue float64
+	// the value to trigger the action
+	Threshold float64
 }
 
 type ActionContextCache struct {
@@ -0,0 +1,179 @@
+package cgroup
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	v1 "k8s.io/api/core/v1"
+)
+
+// Driver is the cgroup driver of kubelet.
+type Driver string
+
+const (
+	DriverCgroupfs Driver = "cgroupfs"
+	DriverSystemd  Driver = "systemd"
+)
+
+const (
+	ProcsFile = "cgroup.procs"
+	// ControllersFile only exists in the root of cgroup v2
+	ControllersFile = "cgroup.controllers"
+
+	kubepodsCgroupfs = "kubepods"
+	kubepodsSystemd  = "kubepods.slice"
+	podPrefix        = "pod"
+	systemdSlice     = ".slice"
+
+	// detectSubsystem is the subsystem to detect the driver in cgroup v1
+	detectSubsystem = "cpu"
+)
+
+// Root is the mount point of cgroup, it is a variable for testing.
+var Root = "/sys/fs/cgroup"
+
+// driver is the cgroup driver set by the flag, it is detected by the kubepods cgroup if it is empty.
+var driver Driver
+
+// SetDriver sets the cgroup driver, the driver is detected if it is empty.
+func SetDriver(d Driver) error {
+	switch d {
+	case "", DriverCgroupfs, DriverSystemd:
+		driver = d
+		return nil
+	default:
+		return fmt.Errorf("unknown cgroup driver %q, it should be %s or %s", d, DriverCgroupfs, DriverSystemd)
+	}
+}
+
+// IsV2 returns true if cgroup v2, the unified hierarchy, is mounted at the root.
+func IsV2() bool {
+	_, err := os.Stat(filepath.Join(Root, ControllersFile))
+	return err == nil
+}
+
+// GetDriver returns the cgroup driver, it is systemd if kubepods.slice exists when it is not set.
+func GetDriver() Driver {
+	if driver != "" {
+		return driver
+	}
+
+	var dir = Root
+	if !IsV2() {
+		dir = filepath.Join(Root, detectSubsystem)
+	}
+	if _, err := os.Stat(filepath.Join(dir, kubepodsSystemd)); err == nil {
+		return DriverSystemd
+	}
+	return DriverCgroupfs
+}
+
+// KubepodsPath returns the cgroup path of all the pods, such as /kubepods and /kubepods.slice.
+func KubepodsPath() string {
+	if GetDriver() == DriverSystemd {
+		return "/" + kubepodsSystemd
+	}
+	return "/" + kubepodsCgroupfs
+}
+
+// PodPath returns the cgroup path of the pod relative to the hierarchy, it is empty if the qos class is unknown, such as:
+//
+//	cgroupfs: /kubepods/burstable/pod<uid>
+//	systemd:  /kubepods.slice/kubepods-burstable.slice/kubepods-burstable-pod<uid>.slice
+func PodPath(pod *v1.Pod) string {
+	var qos string
+	switch pod.Status.QOSClass {
+	case v1.PodQOSGuaranteed:
+	case v1.PodQOSBurstable, v1.PodQOSBestEffort:
+		qos = strings.ToLower(string(pod.Status.QOSClass))
+	default:
+		return ""
+	}
+
+	if GetDriver() == DriverSystemd {
+		// systemd uses the dash as the separator of the slices, it is escaped to underscore in the uid
+		var parent = strings.TrimSuffix(kubepodsSystemd, systemdSlice)
+		var pathArrays = []string{"", kubepodsSystemd}
+		if qos != "" {
+			parent = parent + "-" + qos
+			pathArrays = append(pathArrays, parent+systemdSlice)
+		}
+		pathArrays = append(pathArrays, parent+"-"+podPrefix+strings.ReplaceAll(string(pod.UID), "-", "_")+systemdSlice)
+		return strings.Join(pathArrays, "/")
+	}
+
+	var pathArrays = []string{"", kubepodsCgroupfs}
+	if qos != "" {
+		pathArrays = append(pathArrays, qos)
+	}
+	pathArrays = append(pathArrays, podPrefix+string(pod.UID))
+	return strings.Join(pathArrays, "/")
+}
+
+// PodDir returns the cgroup directory of the pod, the subsystem is ignored for cgroup v2.
+func PodDir(pod *v1.Pod, subsystem string) (string, error) {
+	podPath := PodPath(pod)
+	if podPath == "" {
+		return "", fmt.Errorf("unknown cgroup path of pod %s/%s", pod.Namespace, pod.Name)
+	}
+
+	if IsV2() {
+		return filepath.Join(Root, podPath), nil
+	}
+	return filepath.Join(Root, subsystem, podPath), nil
+}
+
+// ContainerDir finds the cgroup directory of the container in the pod cgroup directory, the directory name
+// contains the container id for all the runtimes and drivers, such as <id>, docker-<id>.scope and cri-containerd-<id>.scope.
+func ContainerDir(podDir string, containerId string) (string, error) {
+	if containerId == "" {
+		return "", fmt.Errorf("container id is empty")
+	}
+
+	dirs, err := ioutil.ReadDir(podDir)
+	if err != nil {
+		return "", err
+	}
+
+	for _, dir := range dirs {
+		if dir.IsDir() && strings.Contains(dir.Name(), containerId) {
+			return filepath.Join(podDir, dir.Name()), nil
+		}
+	}
+
+	return "", fmt.Errorf("cgroup of container %s is not found in %s", containerId, podDir)
+}
+
+// FirstProcess returns a process in the cgroup directory or its children.
+func FirstProcess(dir string) (int, error) {
+	var pid int
+	err := filepath.Walk(dir, func(path string, info os.FileInfo, err error) error {
+		if err != nil || pid != 0 || info.IsDir() || info.Name() != ProcsFile {
+			return nil
+		}
+
+		data, err := ioutil.ReadFile(path)
+		if err != nil {
+			return nil
+		}
+		for _, line := range strings.Fields(string(data)) {
+			if p, err := strconv.Atoi(line); err == nil && p > 0 {
+				pid = p
+				return filepath.SkipDir
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return 0, err
+	}
+
+	if pid == 0 {
+		return 0, fmt.Errorf("no process is found in %s", dir)
+	}
+	return pid, nil
+}
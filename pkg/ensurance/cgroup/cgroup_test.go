@@ -0,0 +1,255 @@
+package cgroup
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	v1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func writeFile(t *testing.T, file string, content string) {
+	if err := os.MkdirAll(filepath.Dir(file), 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(file, []byte(content), 0644); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func readFile(t *testing.T, file string) string {
+	data, err := os.ReadFile(file)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return strings.TrimSpace(string(data))
+}
+
+// fakeRoot creates a cgroup tree with the kubepods cgroup of the driver and the hierarchy.
+func fakeRoot(t *testing.T, d Driver, v2 bool) {
+	root := t.TempDir()
+	origin := Root
+	t.Cleanup(func() { Root = origin })
+	Root = root
+
+	var kubepods = kubepodsCgroupfs
+	if d == DriverSystemd {
+		kubepods = kubepodsSystemd
+	}
+	if v2 {
+		writeFile(t, filepath.Join(root, ControllersFile), "cpu io memory\n")
+		os.MkdirAll(filepath.Join(root, kubepods), 0755)
+	} else {
+		os.MkdirAll(filepath.Join(root, detectSubsystem, kubepods), 0755)
+	}
+}
+
+func TestPodDir(t *testing.T) {
+	newPod := func(qos v1.PodQOSClass) *v1.Pod {
+		return &v1.Pod{
+			ObjectMeta: metav1.ObjectMeta{Name: "pod1", Namespace: "default", UID: "04e5e9e7-8d95-44dd"},
+			Status:     v1.PodStatus{QOSClass: qos},
+		}
+	}
+
+	cases := map[string]struct {
+		driver Driver
+		v2     bool
+		pod    *v1.Pod
+		expect string
+	}{
+		"cgroupfs v1 guaranteed": {
+			driver: DriverCgroupfs,
+			pod:    newPod(v1.PodQOSGuaranteed),
+			expect: "cpu/kubepods/pod04e5e9e7-8d95-44dd",
+		},
+		"cgroupfs v1 burstable": {
+			driver: DriverCgroupfs,
+			pod:    newPod(v1.PodQOSBurstable),
+			expect: "cpu/kubepods/burstable/pod04e5e9e7-8d95-44dd",
+		},
+		"cgroupfs v2 besteffort": {
+			driver: DriverCgroupfs,
+			v2:     true,
+			pod:    newPod(v1.PodQOSBestEffort),
+			expect: "kubepods/besteffort/pod04e5e9e7-8d95-44dd",
+		},
+		"systemd v1 guaranteed": {
+			driver: DriverSystemd,
+			pod:    newPod(v1.PodQOSGuaranteed),
+			expect: "cpu/kubepods.slice/kubepods-pod04e5e9e7_8d95_44dd.slice",
+		},
+		"systemd v2 burstable": {
+			driver: DriverSystemd,
+			v2:     true,
+			pod:    newPod(v1.PodQOSBurstable),
+			expect: "kubepods.slice/kubepods-burstable.slice/kubepods-burstable-pod04e5e9e7_8d95_44dd.slice",
+		},
+		"unknown qos class": {
+			driver: DriverCgroupfs,
+			pod:    newPod(""),
+		},
+	}
+
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			fakeRoot(t, tc.driver, tc.v2)
+
+			if IsV2() != tc.v2 {
+				t.Errorf("Expected cgroup v2 %t", tc.v2)
+			}
+			if d := GetDriver(); d != tc.driver {
+				t.Errorf("Expected driver %s, got %s", tc.driver, d)
+			}
+
+			dir, err := PodDir(tc.pod, CPUSubsystem)
+			if tc.expect == "" {
+				if err == nil {
+					t.Errorf("Expected an error, got %s", dir)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+			if expect := filepath.Join(Root, tc.expect); dir != expect {
+				t.Errorf("Expected %s, got %s", expect, dir)
+			}
+		})
+	}
+}
+
+func TestSetDriver(t *testing.T) {
+	defer SetDriver("")
+	fakeRoot(t, DriverCgroupfs, true)
+
+	if err := SetDriver(DriverSystemd); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if d := GetDriver(); d != DriverSystemd {
+		t.Errorf("Expected the driver set by the flag, got %s", d)
+	}
+	if path := KubepodsPath(); path != "/kubepods.slice" {
+		t.Errorf("Expected /kubepods.slice, got %s", path)
+	}
+	if err := SetDriver("unknown"); err == nil {
+		t.Errorf("Expected an error for the unknown driver")
+	}
+}
+
+func TestContainerDirAndFirstProcess(t *testing.T) {
+	podDir := t.TempDir()
+	writeFile(t, filepath.Join(podDir, ProcsFile), "")
+	writeFile(t, filepath.Join(podDir, "cri-containerd-abc.scope", ProcsFile), "1234\n1235\n")
+
+	dir, err := ContainerDir(podDir, "abc")
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if expect := filepath.Join(podDir, "cri-containerd-abc.scope"); dir != expect {
+		t.Errorf("Expected %s, got %s", expect, dir)
+	}
+	if _, err := ContainerDir(podDir, "def"); err == nil {
+		t.Errorf("Expected an error for the missing container")
+	}
+
+	pid, err := FirstProcess(podDir)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if pid != 1234 {
+		t.Errorf("Expected process 1234, got %d", pid)
+	}
+}
+
+func TestCPUMax(t *testing.T) {
+	cases := map[string]struct {
+		files       map[string]string
+		quota       int64
+		period      uint64
+		expectFiles map[string]string
+	}{
+		"v2 unlimited": {
+			files:       map[string]string{cpuMaxFile: "max 100000\n"},
+			quota:       -1,
+			period:      100000,
+			expectFiles: map[string]string{cpuMaxFile: "50000"},
+		},
+		"v2 limited": {
+			files:       map[string]string{cpuMaxFile: "200000 100000\n"},
+			quota:       200000,
+			period:      100000,
+			expectFiles: map[string]string{cpuMaxFile: "50000"},
+		},
+		"v1": {
+			files:       map[string]string{cpuQuotaFile: "-1\n", cpuPeriodFile: "100000\n"},
+			quota:       -1,
+			period:      100000,
+			expectFiles: map[string]string{cpuQuotaFile: "50000", cpuPeriodFile: "100000"},
+		},
+	}
+
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			dir := t.TempDir()
+			for file, content := range tc.files {
+				writeFile(t, filepath.Join(dir, file), content)
+			}
+
+			quota, period, err := ReadCPUMax(dir)
+			if err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+			if quota != tc.quota || period != tc.period {
+				t.Errorf("Expected quota %d and period %d, got %d and %d", tc.quota, tc.period, quota, period)
+			}
+
+			if err := WriteCPUMax(dir, 50000, 0); err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+			for file, content := range tc.expectFiles {
+				if data := readFile(t, filepath.Join(dir, file)); data != content {
+					t.Errorf("Expected %s %s, got %s", file, content, data)
+				}
+			}
+		})
+	}
+}
+
+func TestMemoryHigh(t *testing.T) {
+	dir := t.TempDir()
+	if _, err := ReadMemoryHigh(dir); err == nil {
+		t.Errorf("Expected an error without memory.high")
+	}
+
+	writeFile(t, filepath.Join(dir, MemoryHighFile), "max\n")
+	if value, err := ReadMemoryHigh(dir); err != nil || value != 0 {
+		t.Errorf("Expected 0 for max, got %.0f, %v", value, err)
+	}
+
+	if err := WriteMemoryHigh(dir, 1024); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if value, err := ReadMemoryHigh(dir); err != nil || value != 1024 {
+		t.Errorf("Expected 1024, got %.0f, %v", value, err)
+	}
+
+	if err := WriteMemoryHigh(dir, 0); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if data := readFile(t, filepath.Join(dir, MemoryHighFile)); data != MaxValue {
+		t.Errorf("Expected max, got %s", data)
+	}
+}
+
+func TestWriteIOMax(t *testing.T) {
+	dir := t.TempDir()
+	if err := WriteIOMax(dir, "8:0", 1024, 0, 0, 10); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if data := readFile(t, filepath.Join(dir, IOMaxFile)); data != "8:0 rbps=1024 wbps=max riops=max wiops=10" {
+		t.Errorf("Unexpected io.max %s", data)
+	}
+}
@@ -0,0 +1,132 @@
+package cgroup
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+const (
+	MaxValue = "max"
+
+	// cgroup v2
+	cpuMaxFile     = "cpu.max"
+	MemoryHighFile = "memory.high"
+	IOMaxFile      = "io.max"
+	// cgroup v1
+	cpuQuotaFile  = "cpu.cfs_quota_us"
+	cpuPeriodFile = "cpu.cfs_period_us"
+
+	CPUSubsystem = "cpu"
+)
+
+// ReadCPUMax returns the cpu quota and period of the cgroup directory, cpu.max for cgroup v2 and cpu.cfs_* for cgroup v1,
+// the quota is -1 if it is unlimited.
+func ReadCPUMax(dir string) (int64, uint64, error) {
+	if data, err := os.ReadFile(filepath.Join(dir, cpuMaxFile)); err == nil {
+		fields := strings.Fields(string(data))
+		if len(fields) != 2 {
+			return 0, 0, fmt.Errorf("invalid %s: %q", cpuMaxFile, data)
+		}
+
+		var quota int64 = -1
+		if fields[0] != MaxValue {
+			if quota, err = strconv.ParseInt(fields[0], 10, 64); err != nil {
+				return 0, 0, fmt.Errorf("invalid %s: %v", cpuMaxFile, err)
+			}
+		}
+		period, err := strconv.ParseUint(fields[1], 10, 64)
+		if err != nil {
+			return 0, 0, fmt.Errorf("invalid %s: %v", cpuMaxFile, err)
+		}
+		return quota, period, nil
+	}
+
+	quota, err := readInt(filepath.Join(dir, cpuQuotaFile))
+	if err != nil {
+		return 0, 0, err
+	}
+	period, err := readInt(filepath.Join(dir, cpuPeriodFile))
+	if err != nil {
+		return 0, 0, err
+	}
+	return quota, uint64(period), nil
+}
+
+// WriteCPUMax writes the cpu quota of the cgroup directory, a negative quota removes the limit, the period is kept if it is zero.
+func WriteCPUMax(dir string, quota int64, period uint64) error {
+	if _, err := os.Stat(filepath.Join(dir, cpuMaxFile)); err == nil {
+		var value = MaxValue
+		if quota >= 0 {
+			value = strconv.FormatInt(quota, 10)
+		}
+		if period > 0 {
+			value = fmt.Sprintf("%s %d", value, period)
+		}
+		return os.WriteFile(filepath.Join(dir, cpuMaxFile), []byte(value), 0644)
+	}
+
+	if period > 0 {
+		if err := os.WriteFile(filepath.Join(dir, cpuPeriodFile), []byte(strconv.FormatUint(period, 10)), 0644); err != nil {
+			return err
+		}
+	}
+	if quota < 0 {
+		quota = -1
+	}
+	return os.WriteFile(filepath.Join(dir, cpuQuotaFile), []byte(strconv.FormatInt(quota, 10)), 0644)
+}
+
+// ReadMemoryHigh returns the memory.high in bytes, it is zero if it is max.
+func ReadMemoryHigh(dir string) (float64, error) {
+	data, err := os.ReadFile(filepath.Join(dir, MemoryHighFile))
+	if err != nil {
+		if os.IsNotExist(err) {
+			return 0, fmt.Errorf("%s is not found, cgroup v2 is required: %v", MemoryHighFile, err)
+		}
+		return 0, err
+	}
+
+	value := strings.TrimSpace(string(data))
+	if value == MaxValue {
+		return 0, nil
+	}
+	return strconv.ParseFloat(value, 64)
+}
+
+// WriteMemoryHigh writes the memory.high in bytes, zero removes the limit.
+func WriteMemoryHigh(dir string, value float64) error {
+	var data = MaxValue
+	if value > 0 {
+		data = strconv.FormatInt(int64(value), 10)
+	}
+	return os.WriteFile(filepath.Join(dir, MemoryHighFile), []byte(data), 0644)
+}
+
+// ioMaxValue formats a limit of io.max, zero is unlimited.
+func ioMaxValue(value uint64) string {
+	if value == 0 {
+		return MaxValue
+	}
+	return strconv.FormatUint(value, 10)
+}
+
+// WriteIOMax writes the io.max of the device in the cgroup directory, zero is unlimited.
+func WriteIOMax(dir string, device string, readBps, writeBps, readIOPS, writeIOPS uint64) error {
+	value := fmt.Sprintf("%s rbps=%s wbps=%s riops=%s wiops=%s", device, ioMaxValue(readBps), ioMaxValue(writeBps),
+		ioMaxValue(readIOPS), ioMaxValue(writeIOPS))
+	if err := os.WriteFile(filepath.Join(dir, IOMaxFile), []byte(value), 0644); err != nil {
+		return fmt.Errorf("failed to write %s: %v", IOMaxFile, err)
+	}
+	return nil
+}
+
+func readInt(file string) (int64, error) {
+	data, err := os.ReadFile(file)
+	if err != nil {
+		return 0, err
+	}
+	return strconv.ParseInt(strings.TrimSpace(string(data)), 10, 64)
+}
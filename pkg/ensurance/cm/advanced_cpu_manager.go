@@ -13,6 +13,7 @@ import (
 	"k8s.io/apimachinery/pkg/labels"
 	"k8s.io/apimachinery/pkg/util/wait"
 	coreinformers "k8s.io/client-go/informers/core/v1"
+	"k8s.io/client-go/kubernetes"
 	corelisters "k8s.io/client-go/listers/core/v1"
 	"k8s.io/client-go/tools/cache"
 	pb "k8s.io/cri-api/pkg/apis/runtime/v1alpha2"
@@ -24,6 +25,8 @@ import (
 
 	"github.com/gocrane/crane/pkg/ensurance/collector/cadvisor"
 	cruntime "github.com/gocrane/crane/pkg/ensurance/runtime"
+	"github.com/gocrane/crane/pkg/metrics"
+	"github.com/gocrane/crane/pkg/utils"
 )
 
 const (
@@ -51,8 +54,9 @@ type AdvancedCpuManager struct {
 	sync.RWMutex
 	policy Policy
 
-	podLister corelisters.PodLister
-	podSynced cache.InformerSynced
+	kubeClient kubernetes.Interface
+	podLister  corelisters.PodLister
+	podSynced  cache.InformerSynced
 
 	runtimeClient pb.RuntimeServiceClient
 	runtimeConn   *grpc.ClientConn
@@ -68,11 +72,20 @@ type AdvancedCpuManager struct {
 	stateFileDirectory string
 
 	exclusiveCPUSet cpuset.CPUSet
+	// reclaimedCPUSet is the cpus of the offline pods, it is resized by the idle of the shared pool
+	reclaimedCPUSet cpuset.CPUSet
+	// getState returns the latest metrics collected to resize the reclaimed cpuset
+	getState StateFunc
+
+	topology *topology.CPUTopology
+	// numa is the NUMA nodes of the machine
+	numa numaTopology
 
 	cadvisor.Manager
 }
 
-func NewAdvancedCpuManager(podInformer coreinformers.PodInformer, runtimeEndpoint string, cadvisorManager cadvisor.Manager) *AdvancedCpuManager {
+func NewAdvancedCpuManager(kubeClient kubernetes.Interface, podInformer coreinformers.PodInformer, runtimeEndpoint string, cadvisorManager cadvisor.Manager,
+	getState StateFunc) *AdvancedCpuManager {
 	runtimeClient, runtimeConn, err := cruntime.GetRuntimeClient(runtimeEndpoint)
 	if err != nil {
 		klog.Errorf("GetRuntimeClient failed %s", err.Error())
@@ -80,6 +93,7 @@ func NewAdvancedCpuManager(podInformer coreinformers.PodInformer, runtimeEndpoin
 	}
 
 	m := &AdvancedCpuManager{
+		kubeClient:         kubeClient,
 		podLister:          podInformer.Lister(),
 		podSynced:          podInformer.Informer().HasSynced,
 		runtimeClient:      runtimeClient,
@@ -87,6 +101,8 @@ func NewAdvancedCpuManager(podInformer coreinformers.PodInformer, runtimeEndpoin
 		reconcilePeriod:    cpusetReconcilePeriod,
 		stateFileDirectory: stateFilePath,
 		Manager:            cadvisorManager,
+		getState:           getState,
+		reclaimedCPUSet:    cpuset.NewCPUSet(),
 	}
 	//pod add actions need to handle quickly, delete/update can handle in loop laterly
 	podInformer.Informer().AddEventHandler(cache.ResourceEventHandlerFuncs{
@@ -123,7 +139,10 @@ func (m *AdvancedCpuManager) Run(stop <-chan struct{}) {
 		return
 	}
 	klog.Infof("Node topology: %+v", topo)
-	m.policy, err = NewAdvancedStaticPolicy(topo)
+	m.topology = topo
+	m.numa = discoverNUMATopology(machineInfo)
+	klog.Infof("Node NUMA topology: %+v", m.numa)
+	m.policy, err = NewAdvancedStaticPolicy(topo, m.numa)
 	if err != nil {
 		klog.Errorf("New static policy error: %v", err)
 		return
@@ -182,11 +201,8 @@ func (m *AdvancedCpuManager) Allocate(p *v1.Pod, c *v1.Container) error {
 
 func (m *AdvancedCpuManager) AddContainer(p *v1.Pod, c *v1.Container) error {
 	containerID := GetContainerIdFromPod(p, c.Name)
-	cset, ok := m.state.GetCPUSet(string(p.UID), c.Name)
-	if !ok {
-		cset = m.getSharedCpu().Union(m.state.GetDefaultCPUSet())
-	}
-	err := m.updateContainerCPUSet(containerID, cset)
+	cset, mems := m.containerCPUSet(p, c, m.getSharedCpu().Union(m.state.GetDefaultCPUSet()))
+	err := m.updateContainerCPUSet(containerID, cset, mems)
 	if err != nil {
 		klog.Errorf("[Advancedcpumanager] AddContainer error: error updating CPUSet for container (pod: %s, container: %s, container id: %s, err: %v)", p.Name, c.Name, containerID, err)
 		return err
@@ -198,16 +214,25 @@ func (m *AdvancedCpuManager) AddContainer(p *v1.Pod, c *v1.Container) error {
 func (m *AdvancedCpuManager) reconcileState() {
 	m.syncState(true)
 	sharedCPUSet := m.getSharedCpu().Union(m.state.GetDefaultCPUSet())
+	m.resizeReclaimedCPUSet(sharedCPUSet, time.Now())
+	var alignedContainers, unalignedContainers int
 	for _, pod := range m.activepods() {
+		alignments := m.podNUMAAlignments(pod)
+		for _, alignment := range alignments {
+			if alignment.Aligned {
+				alignedContainers++
+			} else {
+				unalignedContainers++
+			}
+		}
+		m.updateNUMAAlignmentAnnotation(pod, alignments)
+
 		for _, container := range pod.Spec.Containers {
 			containerID := GetContainerIdFromPod(pod, container.Name)
-			cset, ok := m.state.GetCPUSet(string(pod.UID), container.Name)
-			if !ok {
-				cset = sharedCPUSet
-			}
+			cset, mems := m.containerCPUSet(pod, &container, sharedCPUSet)
 			klog.Infof("[Advancedcpumanager] reconcileState: updating container (pod: %s, container: %s, container id: %s, cpuset: \"%v\")",
 				pod.Name, container.Name, containerID, cset)
-			err := m.updateContainerCPUSet(containerID, cset)
+			err := m.updateContainerCPUSet(containerID, cset, mems)
 			if err != nil {
 				klog.Errorf("[Advancedcpumanager] reconcileState: failed to update container (pod: %s, container: %s, container id: %s, cpuset: \"%v\", error: %v)",
 					pod.Name, container.Name, containerID, cset, err)
@@ -215,6 +240,77 @@ func (m *AdvancedCpuManager) reconcileState() {
 			}
 		}
 	}
+
+	metrics.UpdateCPUManagerAllocatedContainers(true, float64(alignedContainers))
+	metrics.UpdateCPUManagerAllocatedContainers(false, float64(unalignedContainers))
+	for _, id := range m.numa.ids() {
+		metrics.UpdateCPUManagerSharePoolCPUs(id, float64(sharedCPUSet.Intersection(m.numa.cpus(id)).Size()))
+	}
+}
+
+// containerCPUSet returns the cpus and the memory nodes of the container. The container with cpus allocated is bound to
+// the memory of the NUMA nodes of the cpus, the container of the offline pod is bound to the reclaimed cpuset, and the
+// container of the pod with NUMANodeAnnotation is bound to the share pool of the NUMA nodes. The memory nodes are empty
+// if they are not changed.
+func (m *AdvancedCpuManager) containerCPUSet(pod *v1.Pod, container *v1.Container, sharedCPUSet cpuset.CPUSet) (cpuset.CPUSet, cpuset.CPUSet) {
+	if cset, ok := m.state.GetCPUSet(string(pod.UID), container.Name); ok {
+		return cset, cpuset.NewCPUSet(m.numa.nodesOf(cset)...)
+	}
+
+	if isOfflinePod(pod) {
+		if reclaimed := m.getReclaimedCPUSet(); !reclaimed.IsEmpty() {
+			return reclaimed, cpuset.NewCPUSet()
+		}
+		klog.Warningf("[Advancedcpumanager] the reclaimed cpuset is empty, the shared cpus are used (pod: %s, container: %s)", pod.Name, container.Name)
+	}
+
+	if nodes, ok := GetPodNUMANodes(pod); ok {
+		if pool := sharedCPUSet.Intersection(m.numa.cpus(nodes...)); !pool.IsEmpty() {
+			return pool, cpuset.NewCPUSet(nodes...)
+		}
+		klog.Warningf("[Advancedcpumanager] the share pool of NUMA nodes %v is empty, the shared cpus are used (pod: %s, container: %s)", nodes, pod.Name, container.Name)
+	}
+
+	return sharedCPUSet, cpuset.NewCPUSet()
+}
+
+// podNUMAAlignments returns the NUMA alignments of the containers with cpus allocated by the container names.
+func (m *AdvancedCpuManager) podNUMAAlignments(pod *v1.Pod) map[string]NUMAAlignment {
+	var alignments = make(map[string]NUMAAlignment)
+	if len(m.numa) == 0 {
+		return alignments
+	}
+
+	for _, container := range pod.Spec.Containers {
+		if cset, ok := m.state.GetCPUSet(string(pod.UID), container.Name); ok {
+			var memory uint64
+			if request, ok := container.Resources.Requests[v1.ResourceMemory]; ok && request.Value() > 0 {
+				memory = uint64(request.Value())
+			}
+			alignments[container.Name] = m.numa.alignment(cset, memory)
+		}
+	}
+	return alignments
+}
+
+// updateNUMAAlignmentAnnotation patches NUMAAlignmentAnnotation of the pod if the alignments are changed.
+func (m *AdvancedCpuManager) updateNUMAAlignmentAnnotation(pod *v1.Pod, alignments map[string]NUMAAlignment) {
+	if len(alignments) == 0 || m.kubeClient == nil {
+		return
+	}
+
+	value, err := json.Marshal(alignments)
+	if err != nil {
+		klog.Errorf("[Advancedcpumanager] failed to marshal the NUMA alignments of pod %s: %v", klog.KObj(pod), err)
+		return
+	}
+	if pod.Annotations[NUMAAlignmentAnnotation] == string(value) {
+		return
+	}
+
+	if err := utils.PatchPodAnnotation(m.kubeClient, pod.Namespace, pod.Name, NUMAAlignmentAnnotation, string(value)); err != nil {
+		klog.Errorf("[Advancedcpumanager] failed to update the NUMA alignments of pod %s: %v", klog.KObj(pod), err)
+	}
 }
 
 func (m *AdvancedCpuManager) syncState(doAllocate bool) {
@@ -266,11 +362,11 @@ func (m *AdvancedCpuManager) policyRemoveContainerByRef(podUID string, container
 	return err
 }
 
-func (m *AdvancedCpuManager) updateContainerCPUSet(containerID string, cpus cpuset.CPUSet) error {
+func (m *AdvancedCpuManager) updateContainerCPUSet(containerID string, cpus cpuset.CPUSet, mems cpuset.CPUSet) error {
 	return cruntime.UpdateContainerResources(
 		m.runtimeClient,
 		containerID,
-		cruntime.UpdateOptions{CpusetCpus: cpus.String()},
+		cruntime.UpdateOptions{CpusetCpus: cpus.String(), CpusetMems: mems.String()},
 	)
 }
 
@@ -321,6 +417,47 @@ func (m *AdvancedCpuManager) GetExclusiveCpu() cpuset.CPUSet {
 	return m.exclusiveCPUSet
 }
 
+// CPUAssignments are the cpusets assigned by the advanced cpu manager.
+type CPUAssignments struct {
+	DefaultCPUSet   string `json:"defaultCPUSet"`
+	ExclusiveCPUSet string `json:"exclusiveCPUSet"`
+	// ReclaimedCPUSet is the cpus of the offline pods
+	ReclaimedCPUSet string `json:"reclaimedCPUSet"`
+	// SharePools are the shared cpus by NUMA node id
+	SharePools map[int]string `json:"sharePools,omitempty"`
+	// Containers are the cpusets of the containers by pod uid and container name
+	Containers map[string]map[string]string `json:"containers,omitempty"`
+}
+
+// GetCPUAssignments returns the cpusets assigned by the state, false is returned if the manager is not started.
+func (m *AdvancedCpuManager) GetCPUAssignments() (CPUAssignments, bool) {
+	if !m.isStarted {
+		return CPUAssignments{}, false
+	}
+
+	m.RLock()
+	defer m.RUnlock()
+
+	assignments := CPUAssignments{
+		DefaultCPUSet:   m.state.GetDefaultCPUSet().String(),
+		ExclusiveCPUSet: m.exclusiveCPUSet.String(),
+		ReclaimedCPUSet: m.reclaimedCPUSet.String(),
+		Containers:      make(map[string]map[string]string),
+		SharePools:      make(map[int]string),
+	}
+	sharedCPUSet := m.getSharedCpu().Union(m.state.GetDefaultCPUSet())
+	for _, id := range m.numa.ids() {
+		assignments.SharePools[id] = sharedCPUSet.Intersection(m.numa.cpus(id)).String()
+	}
+	for podUID, containers := range m.state.GetCPUAssignments() {
+		assignments.Containers[podUID] = make(map[string]string, len(containers))
+		for name, cset := range containers {
+			assignments.Containers[podUID][name] = cset.String()
+		}
+	}
+	return assignments, true
+}
+
 func (m *AdvancedCpuManager) activepods() []*v1.Pod {
 	allPods, _ := m.podLister.List(labels.Everything())
 	activePods := make([]*v1.Pod, 0, len(allPods))
@@ -2,6 +2,12 @@ package cm
 
 import (
 	"testing"
+
+	v1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/kubernetes/pkg/kubelet/cm/cpumanager/state"
+	"k8s.io/kubernetes/pkg/kubelet/cm/cpuset"
 )
 
 func TestAdvancedCpuManager_loadKubeletPolicy(t *testing.T) {
@@ -30,3 +36,59 @@ func TestAdvancedCpuManager_loadKubeletPolicy(t *testing.T) {
 		})
 	}
 }
+
+func TestAdvancedCpuManager_containerCPUSet(t *testing.T) {
+	writeNUMADistances(t)
+	m := &AdvancedCpuManager{state: state.NewMemoryState(), numa: discoverNUMATopology(numaMachineInfo()), reclaimedCPUSet: cpuset.NewCPUSet(3, 11)}
+	m.state.SetCPUSet("exclusive", "app", cpuset.NewCPUSet(4, 12))
+	sharedCPUSet := cpuset.NewCPUSet(0, 1, 2, 3, 8, 9, 10, 11)
+
+	newPod := func(uid string, annotations map[string]string) *v1.Pod {
+		return &v1.Pod{ObjectMeta: metav1.ObjectMeta{Name: uid, UID: types.UID(uid), Annotations: annotations}}
+	}
+	tests := []struct {
+		name     string
+		pod      *v1.Pod
+		wantCPUs string
+		wantMems string
+	}{
+		{
+			name:     "allocated cpus are bound to the memory of their NUMA nodes",
+			pod:      newPod("exclusive", nil),
+			wantCPUs: "4,12",
+			wantMems: "2",
+		},
+		{
+			name:     "shared cpus without NUMA node annotation",
+			pod:      newPod("shared", nil),
+			wantCPUs: "0-3,8-11",
+			wantMems: "",
+		},
+		{
+			name:     "share pool of the NUMA node",
+			pod:      newPod("numa", map[string]string{NUMANodeAnnotation: "1"}),
+			wantCPUs: "2-3,10-11",
+			wantMems: "1",
+		},
+		{
+			name:     "share pool of the NUMA node is empty",
+			pod:      newPod("empty", map[string]string{NUMANodeAnnotation: "3"}),
+			wantCPUs: "0-3,8-11",
+			wantMems: "",
+		},
+		{
+			name:     "offline pod on the reclaimed cpuset",
+			pod:      newPod("offline", map[string]string{CPUSetAnnotation: string(CPUSetReclaimed), NUMANodeAnnotation: "1"}),
+			wantCPUs: "3,11",
+			wantMems: "",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cpus, mems := m.containerCPUSet(tt.pod, &v1.Container{Name: "app"}, sharedCPUSet)
+			if cpus.String() != tt.wantCPUs || mems.String() != tt.wantMems {
+				t.Errorf("AdvancedCpuManager.containerCPUSet() = %s %s, want %s %s", cpus, mems, tt.wantCPUs, tt.wantMems)
+			}
+		})
+	}
+}
@@ -8,6 +8,7 @@ import (
 	"k8s.io/kubernetes/pkg/kubelet/cm/cpumanager/state"
 	"k8s.io/kubernetes/pkg/kubelet/cm/cpumanager/topology"
 	"k8s.io/kubernetes/pkg/kubelet/cm/cpuset"
+	"k8s.io/kubernetes/pkg/kubelet/cm/topologymanager/bitmask"
 	"k8s.io/kubernetes/pkg/kubelet/util/format"
 )
 
@@ -17,13 +18,16 @@ const AdvancedPolicyStatic policyName = "advanced-static"
 type advancedStaticPolicy struct {
 	// cpu socket topology
 	topology *topology.CPUTopology
+	// numa topology with the memory and the distances of the nodes
+	numa numaTopology
 	// set of CPUs that is not available for exclusive assignment
 	reserved cpuset.CPUSet
 }
 
-func NewAdvancedStaticPolicy(topology *topology.CPUTopology) (Policy, error) {
+func NewAdvancedStaticPolicy(topology *topology.CPUTopology, numa numaTopology) (Policy, error) {
 	return &advancedStaticPolicy{
 		topology: topology,
+		numa:     numa,
 		reserved: cpuset.MustParse("0"),
 	}, nil
 }
@@ -107,7 +111,9 @@ func (p *advancedStaticPolicy) Allocate(s state.State, pod *v1.Pod, container *v
 		}
 
 		// Allocate CPUs according to the NUMA affinity contained in the hint.
-		cpuset, err := p.allocateCPUs(s, numCPUs)
+		hint := p.bestHint(p.GetTopologyHints(s, pod, container))
+		klog.Infof("[Advancedcpumanager] advanced static policy: topology hint %+v (pod: %s, container: %s)", hint, format.Pod(pod), container.Name)
+		cpuset, err := p.allocateCPUs(s, numCPUs, hint.NUMANodeAffinity)
 		if err != nil {
 			klog.Errorf("[Advancedcpumanager] unable to allocate %d CPUs (pod: %s, container: %s, error: %v)", numCPUs, format.Pod(pod), container.Name, err)
 			return err
@@ -118,13 +124,29 @@ func (p *advancedStaticPolicy) Allocate(s state.State, pod *v1.Pod, container *v
 	return nil
 }
 
-func (p *advancedStaticPolicy) allocateCPUs(s state.State, numCPUs int) (cpuset.CPUSet, error) {
-	klog.Infof("[Advancedcpumanager] allocateCpus: (numCPUs: %d)", numCPUs)
+func (p *advancedStaticPolicy) allocateCPUs(s state.State, numCPUs int, numaAffinity bitmask.BitMask) (cpuset.CPUSet, error) {
+	klog.Infof("[Advancedcpumanager] allocateCpus: (numCPUs: %d, numaAffinity: %v)", numCPUs, numaAffinity)
 
 	result := cpuset.NewCPUSet()
 	klog.Infof("[Advancedcpumanager] allocateCpu %+v", p.assignableCPUs(s))
+	// If there are aligned CPUs in numaAffinity, attempt to take those first.
+	if numaAffinity != nil {
+		alignedCPUs := p.assignableCPUs(s).Intersection(p.numa.cpus(numaAffinity.GetBits()...))
+
+		numAlignedToAlloc := alignedCPUs.Size()
+		if numCPUs < numAlignedToAlloc {
+			numAlignedToAlloc = numCPUs
+		}
+
+		alignedCPUs, err := takeByTopology(p.topology, alignedCPUs, numAlignedToAlloc)
+		if err != nil {
+			return cpuset.NewCPUSet(), err
+		}
+		result = result.Union(alignedCPUs)
+	}
+
 	// Get any remaining CPUs from what's leftover after attempting to grab aligned ones.
-	remainingCPUs, err := takeByTopology(p.topology, p.assignableCPUs(s), numCPUs)
+	remainingCPUs, err := takeByTopology(p.topology, p.assignableCPUs(s).Difference(result), numCPUs-result.Size())
 	if err != nil {
 		return cpuset.NewCPUSet(), err
 	}
@@ -164,8 +186,71 @@ func (p *advancedStaticPolicy) guaranteedCPUs(pod *v1.Pod, container *v1.Contain
 	if container.Resources.Requests[v1.ResourceCPU] != container.Resources.Limits[v1.ResourceCPU] {
 		return 0
 	}
-	if csp := GetPodCPUSetType(pod, container); csp == CPUSetNone {
+	if csp := GetPodCPUSetType(pod, container); csp == CPUSetNone || csp == CPUSetReclaimed {
 		return 0
 	}
 	return int(cpuQuantity.Value())
 }
+
+// GetTopologyHints returns a hint for each combination of the NUMA nodes which have enough cpus assignable for the
+// container, the hints of the fewest nodes which can hold the cpus and the memory of the container are preferred.
+func (p *advancedStaticPolicy) GetTopologyHints(s state.State, pod *v1.Pod, container *v1.Container) []TopologyHint {
+	numCPUs := p.guaranteedCPUs(pod, container)
+	if numCPUs == 0 || len(p.numa) == 0 {
+		return nil
+	}
+
+	// the cpus allocated before are the only hint, since they are never reallocated
+	if allocated, ok := s.GetCPUSet(string(pod.UID), container.Name); ok {
+		mask, err := bitmask.NewBitMask(p.numa.nodesOf(allocated)...)
+		if err != nil {
+			return nil
+		}
+		return []TopologyHint{{NUMANodeAffinity: mask, Preferred: true}}
+	}
+
+	var memory uint64
+	if request, ok := container.Resources.Requests[v1.ResourceMemory]; ok && request.Value() > 0 {
+		memory = uint64(request.Value())
+	}
+	minAffinitySize := p.numa.minAffinitySize(numCPUs, memory)
+
+	var hints []TopologyHint
+	assignable := p.assignableCPUs(s)
+	bitmask.IterateBitMasks(p.numa.ids(), func(mask bitmask.BitMask) {
+		if assignable.Intersection(p.numa.cpus(mask.GetBits()...)).Size() < numCPUs {
+			return
+		}
+		hints = append(hints, TopologyHint{
+			NUMANodeAffinity: mask,
+			Preferred:        mask.Count() == minAffinitySize && p.numa.fits(mask, numCPUs, memory),
+		})
+	})
+	return hints
+}
+
+// bestHint returns the preferred hint of the fewest and the nearest NUMA nodes, the NUMA nodes of the lower ids are
+// chosen if the hints are the same. An empty hint is returned if there are no hints.
+func (p *advancedStaticPolicy) bestHint(hints []TopologyHint) TopologyHint {
+	var best TopologyHint
+	for _, hint := range hints {
+		if best.NUMANodeAffinity == nil || p.betterHint(hint, best) {
+			best = hint
+		}
+	}
+	return best
+}
+
+func (p *advancedStaticPolicy) betterHint(hint, than TopologyHint) bool {
+	if hint.Preferred != than.Preferred {
+		return hint.Preferred
+	}
+	if hint.NUMANodeAffinity.Count() != than.NUMANodeAffinity.Count() {
+		return hint.NUMANodeAffinity.IsNarrowerThan(than.NUMANodeAffinity)
+	}
+	hintDistance, thanDistance := p.numa.distance(hint.NUMANodeAffinity.GetBits()...), p.numa.distance(than.NUMANodeAffinity.GetBits()...)
+	if hintDistance != thanDistance {
+		return hintDistance < thanDistance
+	}
+	return hint.NUMANodeAffinity.IsNarrowerThan(than.NUMANodeAffinity)
+}
@@ -1,11 +1,15 @@
 package cm
 
 import (
+	"reflect"
 	"testing"
 
 	v1 "k8s.io/api/core/v1"
 	"k8s.io/apimachinery/pkg/api/resource"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/kubernetes/pkg/kubelet/cm/cpumanager/state"
+	"k8s.io/kubernetes/pkg/kubelet/cm/cpumanager/topology"
+	"k8s.io/kubernetes/pkg/kubelet/cm/cpuset"
 )
 
 func Test_advancedStaticPolicy_guaranteedCPUs(t *testing.T) {
@@ -72,3 +76,121 @@ func Test_advancedStaticPolicy_guaranteedCPUs(t *testing.T) {
 		})
 	}
 }
+
+func TestAdvancedStaticPolicyAllocateNUMA(t *testing.T) {
+	writeNUMADistances(t, 0, 1, 2, 3)
+	machineInfo := numaMachineInfo()
+	topo, err := topology.Discover(machineInfo)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	newPod := func(cpu, memory string) (*v1.Pod, *v1.Container) {
+		resources := v1.ResourceList{v1.ResourceCPU: resource.MustParse(cpu), v1.ResourceMemory: resource.MustParse(memory)}
+		container := v1.Container{Name: "app", Resources: v1.ResourceRequirements{Requests: resources, Limits: resources}}
+		return &v1.Pod{
+			ObjectMeta: metav1.ObjectMeta{Name: "pod", UID: "pod", Annotations: map[string]string{CPUSetAnnotation: string(CPUSetExclusive)}},
+			Spec:       v1.PodSpec{Containers: []v1.Container{container}},
+		}, &container
+	}
+
+	cases := map[string]struct {
+		cpu           string
+		memory        string
+		allocated     cpuset.CPUSet
+		expectNodes   string
+		expectAligned bool
+	}{
+		"the cpus are allocated from a single node": {
+			cpu:           "2",
+			memory:        "1Gi",
+			expectNodes:   "0",
+			expectAligned: true,
+		},
+		"the cpus are allocated from the nearest nodes": {
+			cpu:           "6",
+			memory:        "1Gi",
+			allocated:     cpuset.NewCPUSet(2, 3, 10, 11),
+			expectNodes:   "2-3",
+			expectAligned: true,
+		},
+		"the cpus are allocated across the nearest nodes if no single node has enough cpus": {
+			cpu:    "2",
+			memory: "1Gi",
+			// only the cpus 8, 10, 12 and 14 are left on each node
+			allocated:     cpuset.NewCPUSet(1, 2, 3, 4, 5, 6, 7, 9, 11, 13, 15),
+			expectNodes:   "0-1",
+			expectAligned: false,
+		},
+	}
+
+	for name, c := range cases {
+		t.Run(name, func(t *testing.T) {
+			numa := discoverNUMATopology(machineInfo)
+			policy, _ := NewAdvancedStaticPolicy(topo, numa)
+			s := state.NewMemoryState()
+			if err := policy.Start(s); err != nil {
+				t.Fatal(err)
+			}
+			if !c.allocated.IsEmpty() {
+				s.SetCPUSet("other", "app", c.allocated)
+				s.SetDefaultCPUSet(s.GetDefaultCPUSet().Difference(c.allocated))
+			}
+
+			pod, container := newPod(c.cpu, c.memory)
+			if err := policy.Allocate(s, pod, container); err != nil {
+				t.Fatal(err)
+			}
+			cpus, ok := s.GetCPUSet(string(pod.UID), container.Name)
+			if !ok || cpus.Size() != int(container.Resources.Requests.Cpu().Value()) {
+				t.Fatalf("Expected %s cpus allocated, got %s", c.cpu, cpus)
+			}
+			if alignment := numa.alignment(cpus, uint64(container.Resources.Requests.Memory().Value())); alignment.NUMANodes != c.expectNodes || alignment.Aligned != c.expectAligned {
+				t.Errorf("Expected cpus on NUMA nodes %s aligned %v, got %s %+v", c.expectNodes, c.expectAligned, cpus, alignment)
+			}
+		})
+	}
+}
+
+func TestAdvancedStaticPolicyGetTopologyHints(t *testing.T) {
+	writeNUMADistances(t)
+	machineInfo := numaMachineInfo()
+	topo, err := topology.Discover(machineInfo)
+	if err != nil {
+		t.Fatal(err)
+	}
+	policy := &advancedStaticPolicy{topology: topo, numa: discoverNUMATopology(machineInfo), reserved: cpuset.MustParse("0")}
+	s := state.NewMemoryState()
+	if err := policy.Start(s); err != nil {
+		t.Fatal(err)
+	}
+
+	// the memory of the container does not fit in a single node
+	resources := v1.ResourceList{v1.ResourceCPU: resource.MustParse("2"), v1.ResourceMemory: resource.MustParse("12Gi")}
+	container := &v1.Container{Name: "app", Resources: v1.ResourceRequirements{Requests: resources, Limits: resources}}
+	pod := &v1.Pod{ObjectMeta: metav1.ObjectMeta{UID: "pod", Annotations: map[string]string{CPUSetAnnotation: string(CPUSetExclusive)}}}
+
+	var hints, preferred int
+	for _, hint := range policy.GetTopologyHints(s, pod, container) {
+		hints++
+		if hint.Preferred {
+			preferred++
+			if hint.NUMANodeAffinity.Count() != 2 {
+				t.Errorf("Expected the preferred hints of 2 NUMA nodes, got %v", hint.NUMANodeAffinity.GetBits())
+			}
+		}
+	}
+	// all the 15 combinations of the 4 nodes have enough cpus, 6 of them have 2 nodes
+	if hints != 15 || preferred != 6 {
+		t.Errorf("Expected 15 hints and 6 preferred, got %d hints and %d preferred", hints, preferred)
+	}
+	if best := policy.bestHint(policy.GetTopologyHints(s, pod, container)); !reflect.DeepEqual(best.NUMANodeAffinity.GetBits(), []int{0, 1}) {
+		t.Errorf("Expected the best hint of NUMA nodes [0 1], got %v", best.NUMANodeAffinity.GetBits())
+	}
+
+	// the allocated cpus are the only hint
+	s.SetCPUSet("pod", "app", cpuset.NewCPUSet(4, 12))
+	if hints := policy.GetTopologyHints(s, pod, container); len(hints) != 1 || !reflect.DeepEqual(hints[0].NUMANodeAffinity.GetBits(), []int{2}) {
+		t.Errorf("Expected the hint of the allocated cpus on NUMA node 2, got %+v", hints)
+	}
+}
@@ -0,0 +1,168 @@
+package cm
+
+import (
+	"fmt"
+	"io/ioutil"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+
+	cadvisorapi "github.com/google/cadvisor/info/v1"
+	"k8s.io/klog/v2"
+	"k8s.io/kubernetes/pkg/kubelet/cm/cpuset"
+	"k8s.io/kubernetes/pkg/kubelet/cm/topologymanager/bitmask"
+)
+
+const (
+	// localNUMADistance and remoteNUMADistance are the default distances of the ACPI SLIT, they are used if the
+	// distances can not be read from sysfs.
+	localNUMADistance  = 10
+	remoteNUMADistance = 20
+)
+
+// sysNodePath is the sysfs directory of the NUMA nodes, it is overridden in tests.
+var sysNodePath = "/sys/devices/system/node"
+
+// numaNode is a NUMA node of the machine.
+type numaNode struct {
+	cpus cpuset.CPUSet
+	// memory is the memory of the node in bytes
+	memory uint64
+	// distances are the distances to the NUMA nodes by node id, they are empty if unknown
+	distances []uint64
+}
+
+// numaTopology is the NUMA nodes of the machine by node id.
+type numaTopology map[int]numaNode
+
+// discoverNUMATopology discovers the cpus and the memory of the NUMA nodes from the machine info of cadvisor, and the
+// distances between the nodes from sysfs.
+func discoverNUMATopology(machineInfo *cadvisorapi.MachineInfo) numaTopology {
+	var topo = make(numaTopology, len(machineInfo.Topology))
+	for _, node := range machineInfo.Topology {
+		var cpus []int
+		for _, core := range node.Cores {
+			cpus = append(cpus, core.Threads...)
+		}
+
+		distances, err := readNUMADistances(node.Id)
+		if err != nil {
+			klog.V(4).Infof("[Advancedcpumanager] failed to read the distances of NUMA node %d, the default distances are used: %v", node.Id, err)
+		}
+		topo[node.Id] = numaNode{cpus: cpuset.NewCPUSet(cpus...), memory: node.Memory, distances: distances}
+	}
+	return topo
+}
+
+// readNUMADistances reads the distances from the NUMA node to the nodes, such as "10 21" for node0 of two nodes.
+func readNUMADistances(id int) ([]uint64, error) {
+	data, err := ioutil.ReadFile(filepath.Join(sysNodePath, fmt.Sprintf("node%d", id), "distance"))
+	if err != nil {
+		return nil, err
+	}
+
+	var distances []uint64
+	for _, field := range strings.Fields(string(data)) {
+		distance, err := strconv.ParseUint(field, 10, 64)
+		if err != nil {
+			return nil, fmt.Errorf("invalid distance %q: %v", field, err)
+		}
+		distances = append(distances, distance)
+	}
+	return distances, nil
+}
+
+// ids returns the ids of the NUMA nodes in ascending order.
+func (t numaTopology) ids() []int {
+	var ids = make([]int, 0, len(t))
+	for id := range t {
+		ids = append(ids, id)
+	}
+	sort.Ints(ids)
+	return ids
+}
+
+// cpus returns the cpus of the NUMA nodes.
+func (t numaTopology) cpus(ids ...int) cpuset.CPUSet {
+	var cpus = cpuset.NewCPUSet()
+	for _, id := range ids {
+		cpus = cpus.Union(t[id].cpus)
+	}
+	return cpus
+}
+
+// memory returns the memory of the NUMA nodes in bytes.
+func (t numaTopology) memory(ids ...int) uint64 {
+	var memory uint64
+	for _, id := range ids {
+		memory += t[id].memory
+	}
+	return memory
+}
+
+// distance returns the sum of the distances between each two of the NUMA nodes, the nodes far from each other have a
+// larger distance.
+func (t numaTopology) distance(ids ...int) uint64 {
+	var distance uint64
+	for i, from := range ids {
+		for _, to := range ids[i+1:] {
+			distances := t[from].distances
+			if to < len(distances) {
+				distance += distances[to]
+			} else {
+				distance += remoteNUMADistance
+			}
+		}
+	}
+	return distance
+}
+
+// nodesOf returns the ids of the NUMA nodes which the cpus belong to.
+func (t numaTopology) nodesOf(cpus cpuset.CPUSet) []int {
+	var ids []int
+	for _, id := range t.ids() {
+		if !t[id].cpus.Intersection(cpus).IsEmpty() {
+			ids = append(ids, id)
+		}
+	}
+	return ids
+}
+
+// minAffinitySize returns the fewest NUMA nodes which can hold the cpus and the memory, the memory is not considered
+// if it is zero or the memory of the nodes is unknown.
+func (t numaTopology) minAffinitySize(numCPUs int, memory uint64) int {
+	var ids = t.ids()
+	var minSize = len(ids)
+	bitmask.IterateBitMasks(ids, func(mask bitmask.BitMask) {
+		if mask.Count() < minSize && t.fits(mask, numCPUs, memory) {
+			minSize = mask.Count()
+		}
+	})
+	return minSize
+}
+
+// fits returns true if the NUMA nodes of the mask have enough cpus and memory.
+func (t numaTopology) fits(mask bitmask.BitMask, numCPUs int, memory uint64) bool {
+	if t.cpus(mask.GetBits()...).Size() < numCPUs {
+		return false
+	}
+	nodesMemory := t.memory(mask.GetBits()...)
+	return memory == 0 || nodesMemory == 0 || nodesMemory >= memory
+}
+
+// NUMAAlignment is the NUMA nodes of the cpus allocated to a container, it is aligned if the cpus are on the fewest
+// NUMA nodes which can hold the container.
+type NUMAAlignment struct {
+	NUMANodes string `json:"numaNodes"`
+	Aligned   bool   `json:"aligned"`
+}
+
+// alignment returns the NUMA alignment of the cpus allocated for the container with the memory request.
+func (t numaTopology) alignment(cpus cpuset.CPUSet, memory uint64) NUMAAlignment {
+	nodes := t.nodesOf(cpus)
+	return NUMAAlignment{
+		NUMANodes: cpuset.NewCPUSet(nodes...).String(),
+		Aligned:   len(nodes) <= t.minAffinitySize(cpus.Size(), memory),
+	}
+}
@@ -0,0 +1,126 @@
+package cm
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	cadvisorapi "github.com/google/cadvisor/info/v1"
+	"k8s.io/kubernetes/pkg/kubelet/cm/cpuset"
+)
+
+const gib = 1024 * 1024 * 1024
+
+// numaMachineInfo is a machine of 2 sockets and 4 NUMA nodes with 8Gi memory, each NUMA node has 2 cores of 2 threads.
+// The cpus of the NUMA node n are 2n, 2n+1, 2n+8 and 2n+9.
+func numaMachineInfo() *cadvisorapi.MachineInfo {
+	machineInfo := &cadvisorapi.MachineInfo{NumCores: 16, NumSockets: 2}
+	for n := 0; n < 4; n++ {
+		node := cadvisorapi.Node{Id: n, Memory: 8 * gib}
+		for _, c := range []int{2 * n, 2*n + 1} {
+			node.Cores = append(node.Cores, cadvisorapi.Core{Id: c, Threads: []int{c, c + 8}, SocketID: n / 2})
+		}
+		machineInfo.Topology = append(machineInfo.Topology, node)
+	}
+	return machineInfo
+}
+
+// writeNUMADistances writes the distances of the NUMA nodes to a fake sysfs, the NUMA nodes on the same socket are near.
+func writeNUMADistances(t *testing.T, nodes ...int) {
+	dir, err := ioutil.TempDir("", "numa")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { os.RemoveAll(dir) })
+
+	distances := []string{"10 12 32 32", "12 10 32 32", "32 32 10 12", "32 32 12 10"}
+	for _, n := range nodes {
+		nodeDir := filepath.Join(dir, fmt.Sprintf("node%d", n))
+		if err := os.MkdirAll(nodeDir, 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := ioutil.WriteFile(filepath.Join(nodeDir, "distance"), []byte(distances[n]+"\n"), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	origin := sysNodePath
+	sysNodePath = dir
+	t.Cleanup(func() { sysNodePath = origin })
+}
+
+func TestDiscoverNUMATopology(t *testing.T) {
+	// the distances of node3 are unknown
+	writeNUMADistances(t, 0, 1, 2)
+	numa := discoverNUMATopology(numaMachineInfo())
+
+	if ids := numa.ids(); !reflect.DeepEqual(ids, []int{0, 1, 2, 3}) {
+		t.Fatalf("Expected NUMA nodes [0 1 2 3], got %v", ids)
+	}
+	if cpus := numa.cpus(1); !cpus.Equals(cpuset.NewCPUSet(2, 3, 10, 11)) {
+		t.Errorf("Expected cpus 2-3,10-11 of NUMA node 1, got %s", cpus)
+	}
+	if memory := numa.memory(0, 1); memory != 16*gib {
+		t.Errorf("Expected memory 16Gi of NUMA nodes 0-1, got %d", memory)
+	}
+	if nodes := numa.nodesOf(cpuset.NewCPUSet(1, 4, 12)); !reflect.DeepEqual(nodes, []int{0, 2}) {
+		t.Errorf("Expected NUMA nodes [0 2], got %v", nodes)
+	}
+
+	cases := map[string]struct {
+		nodes  []int
+		expect uint64
+	}{
+		"single node":               {nodes: []int{0}, expect: 0},
+		"nodes on the same socket":  {nodes: []int{0, 1}, expect: 12},
+		"nodes on different socket": {nodes: []int{1, 2}, expect: 32},
+		"distances are unknown":     {nodes: []int{3, 2}, expect: remoteNUMADistance},
+		"three nodes":               {nodes: []int{0, 1, 2}, expect: 12 + 32 + 32},
+	}
+	for name, c := range cases {
+		t.Run(name, func(t *testing.T) {
+			if distance := numa.distance(c.nodes...); distance != c.expect {
+				t.Errorf("Expected distance %d, got %d", c.expect, distance)
+			}
+		})
+	}
+}
+
+func TestNUMAAlignment(t *testing.T) {
+	writeNUMADistances(t)
+	numa := discoverNUMATopology(numaMachineInfo())
+
+	cases := map[string]struct {
+		cpus   cpuset.CPUSet
+		memory uint64
+		expect NUMAAlignment
+	}{
+		"cpus on a single node": {
+			cpus:   cpuset.NewCPUSet(0, 8),
+			expect: NUMAAlignment{NUMANodes: "0", Aligned: true},
+		},
+		"cpus across nodes which fit in a single node": {
+			cpus:   cpuset.NewCPUSet(1, 2),
+			expect: NUMAAlignment{NUMANodes: "0-1", Aligned: false},
+		},
+		"memory does not fit in a single node": {
+			cpus:   cpuset.NewCPUSet(1, 2),
+			memory: 12 * gib,
+			expect: NUMAAlignment{NUMANodes: "0-1", Aligned: true},
+		},
+		"cpus more than a single node": {
+			cpus:   cpuset.NewCPUSet(4, 5, 6, 12, 13, 14),
+			expect: NUMAAlignment{NUMANodes: "2-3", Aligned: true},
+		},
+	}
+	for name, c := range cases {
+		t.Run(name, func(t *testing.T) {
+			if alignment := numa.alignment(c.cpus, c.memory); alignment != c.expect {
+				t.Errorf("Expected alignment %+v, got %+v", c.expect, alignment)
+			}
+		})
+	}
+}
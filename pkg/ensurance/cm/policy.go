@@ -3,6 +3,7 @@ package cm
 import (
 	v1 "k8s.io/api/core/v1"
 	"k8s.io/kubernetes/pkg/kubelet/cm/cpumanager/state"
+	"k8s.io/kubernetes/pkg/kubelet/cm/topologymanager/bitmask"
 )
 
 type policyName string
@@ -18,4 +19,13 @@ type Policy interface {
 	RemoveContainer(s state.State, podUID string, containerName string) error
 	// NeedAllocated is called to judge if container needs to allocate cpu
 	NeedAllocated(pod *v1.Pod, container *v1.Container) bool
+	// GetTopologyHints returns the NUMA affinities the cpus of the container can be allocated from
+	GetTopologyHints(s state.State, pod *v1.Pod, container *v1.Container) []TopologyHint
+}
+
+// TopologyHint is a NUMA affinity which an allocation can be satisfied from, in the same way as the hints of the kubelet
+// topology manager. The hint is preferred if the NUMA nodes are the fewest which can hold the allocation.
+type TopologyHint struct {
+	NUMANodeAffinity bitmask.BitMask
+	Preferred        bool
 }
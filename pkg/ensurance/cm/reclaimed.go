@@ -0,0 +1,129 @@
+package cm
+
+import (
+	"math"
+	"time"
+
+	v1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/util/sets"
+	"k8s.io/klog/v2"
+	"k8s.io/kubernetes/pkg/kubelet/cm/cpuset"
+
+	"github.com/gocrane/crane/pkg/common"
+	"github.com/gocrane/crane/pkg/ensurance/collector/types"
+	"github.com/gocrane/crane/pkg/metrics"
+)
+
+const (
+	// minReclaimedCPUs is the min size of the reclaimed cpuset, so that the offline pods always have cpus to run on
+	minReclaimedCPUs = 1
+	// reclaimedStateExpiration is the max age of the state to resize the reclaimed cpuset, the reclaimed cpuset is
+	// shrunk to the min if the state is expired
+	reclaimedStateExpiration = time.Minute
+)
+
+// StateFunc returns the latest metrics collected and the time.
+type StateFunc func() (map[string][]common.TimeSeries, time.Time)
+
+// resizeReclaimedCPUSet resizes the reclaimed cpuset of the offline pods by the idle cpus of the shared pool. The cpus
+// are taken from the shared pool except the cores of the exclusive cpus, and the cpus reclaimed before are kept as far
+// as possible so that the offline pods are not moved around.
+func (m *AdvancedCpuManager) resizeReclaimedCPUSet(sharedCPUSet cpuset.CPUSet, now time.Time) {
+	details := m.topology.CPUDetails
+	exclusiveCores := details.KeepOnly(m.GetExclusiveCpu()).Cores().ToSlice()
+	candidates := sharedCPUSet.Difference(details.CPUsInCores(exclusiveCores...))
+
+	size := minReclaimedCPUs
+	if m.getState != nil {
+		if state, stateTime := m.getState(); now.Sub(stateTime) <= reclaimedStateExpiration {
+			if reclaimable, ok := reclaimableCPUs(state, m.topology.NumCPUs, m.offlinePodUIDs()); ok && reclaimable > size {
+				size = reclaimable
+			}
+		}
+	}
+	if size > candidates.Size() {
+		size = candidates.Size()
+	}
+
+	current := m.getReclaimedCPUSet().Intersection(candidates)
+	reclaimed := current
+	var err error
+	switch {
+	case size > current.Size():
+		var more cpuset.CPUSet
+		if more, err = takeByTopology(m.topology, candidates.Difference(current), size-current.Size()); err == nil {
+			reclaimed = current.Union(more)
+		}
+	case size < current.Size():
+		reclaimed, err = takeByTopology(m.topology, current, size)
+	}
+	if err != nil {
+		klog.Errorf("[Advancedcpumanager] failed to resize the reclaimed cpuset to %d cpus from %s: %v", size, candidates, err)
+		return
+	}
+
+	if !reclaimed.Equals(m.getReclaimedCPUSet()) {
+		klog.V(4).Infof("[Advancedcpumanager] resize the reclaimed cpuset to %q", reclaimed)
+	}
+	m.Lock()
+	m.reclaimedCPUSet = reclaimed
+	m.Unlock()
+	metrics.UpdateCPUManagerReclaimedCPUs(float64(reclaimed.Size()))
+}
+
+func (m *AdvancedCpuManager) getReclaimedCPUSet() cpuset.CPUSet {
+	m.RLock()
+	defer m.RUnlock()
+	return m.reclaimedCPUSet
+}
+
+// offlinePodUIDs returns the uids of the active pods which run on the reclaimed cpuset.
+func (m *AdvancedCpuManager) offlinePodUIDs() sets.String {
+	var uids = sets.NewString()
+	for _, pod := range m.activepods() {
+		if isOfflinePod(pod) {
+			uids.Insert(string(pod.UID))
+		}
+	}
+	return uids
+}
+
+// reclaimableCPUs returns the number of cpus which can be reclaimed for the offline pods, it is the idle cpus of the
+// node except the idle of the exclusive cpus, plus the cpus used by the offline pods themselves. False is returned if the
+// metrics are not collected.
+func reclaimableCPUs(state map[string][]common.TimeSeries, numCPUs int, offlinePodUIDs sets.String) (int, bool) {
+	usage, ok := latestValue(state[string(types.MetricNameCpuTotalUsage)])
+	if !ok {
+		return 0, false
+	}
+	exclusiveIdle, ok := latestValue(state[string(types.MetricNameExclusiveCPUIdle)])
+	if !ok {
+		return 0, false
+	}
+
+	// the usage of the containers is in cores, and the usage of the node is in millicores
+	var offlineUsage float64
+	for _, ts := range state[string(types.MetricNameContainerCpuTotalUsage)] {
+		if offlinePodUIDs.Has(common.GetValueByName(ts.Labels, common.LabelNamePodUid)) && len(ts.Samples) > 0 {
+			offlineUsage += ts.Samples[0].Value * 1000
+		}
+	}
+
+	reclaimable := float64(numCPUs)*1000 - usage - exclusiveIdle + offlineUsage
+	if reclaimable <= 0 {
+		return 0, true
+	}
+	return int(math.Floor(reclaimable / 1000)), true
+}
+
+func latestValue(series []common.TimeSeries) (float64, bool) {
+	if len(series) == 0 || len(series[0].Samples) == 0 {
+		return 0, false
+	}
+	return series[0].Samples[len(series[0].Samples)-1].Value, true
+}
+
+// isOfflinePod returns true if the pod runs on the reclaimed cpuset.
+func isOfflinePod(pod *v1.Pod) bool {
+	return GetPodCPUSetType(pod, nil) == CPUSetReclaimed
+}
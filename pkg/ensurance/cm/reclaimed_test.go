@@ -0,0 +1,163 @@
+package cm
+
+import (
+	"testing"
+	"time"
+
+	"k8s.io/apimachinery/pkg/util/sets"
+	corelisters "k8s.io/client-go/listers/core/v1"
+	"k8s.io/client-go/tools/cache"
+	"k8s.io/kubernetes/pkg/kubelet/cm/cpumanager/topology"
+	"k8s.io/kubernetes/pkg/kubelet/cm/cpuset"
+
+	"github.com/gocrane/crane/pkg/common"
+	"github.com/gocrane/crane/pkg/ensurance/collector/types"
+)
+
+func newReclaimedState(usage, exclusiveIdle float64) map[string][]common.TimeSeries {
+	return map[string][]common.TimeSeries{
+		string(types.MetricNameCpuTotalUsage):    {{Samples: []common.Sample{{Value: usage}}}},
+		string(types.MetricNameExclusiveCPUIdle): {{Samples: []common.Sample{{Value: exclusiveIdle}}}},
+	}
+}
+
+func TestReclaimableCPUs(t *testing.T) {
+	withOffline := newReclaimedState(10000, 1000)
+	withOffline[string(types.MetricNameContainerCpuTotalUsage)] = []common.TimeSeries{
+		{Labels: []common.Label{{Name: common.LabelNamePodUid, Value: "offline"}}, Samples: []common.Sample{{Value: 1.5}}},
+		{Labels: []common.Label{{Name: common.LabelNamePodUid, Value: "online"}}, Samples: []common.Sample{{Value: 2}}},
+	}
+
+	tests := []struct {
+		name   string
+		state  map[string][]common.TimeSeries
+		want   int
+		wantOk bool
+	}{
+		{
+			name:   "idle cpus except the exclusive idle",
+			state:  newReclaimedState(10000, 1000),
+			want:   5,
+			wantOk: true,
+		},
+		{
+			name:   "usage of the offline pods is reclaimable",
+			state:  withOffline,
+			want:   6,
+			wantOk: true,
+		},
+		{
+			name:   "no idle cpus",
+			state:  newReclaimedState(15000, 2000),
+			want:   0,
+			wantOk: true,
+		},
+		{
+			name:   "metrics are not collected",
+			state:  map[string][]common.TimeSeries{string(types.MetricNameCpuTotalUsage): {{Samples: []common.Sample{{Value: 1000}}}}},
+			want:   0,
+			wantOk: false,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, ok := reclaimableCPUs(tt.state, 16, sets.NewString("offline"))
+			if got != tt.want || ok != tt.wantOk {
+				t.Errorf("reclaimableCPUs() = %d %v, want %d %v", got, ok, tt.want, tt.wantOk)
+			}
+		})
+	}
+}
+
+func TestResizeReclaimedCPUSet(t *testing.T) {
+	topo, err := topology.Discover(numaMachineInfo())
+	if err != nil {
+		t.Fatal(err)
+	}
+	// the cpu 4 is exclusive, so the core of the cpus 4 and 12 is not reclaimed
+	sharedCPUSet := topo.CPUDetails.CPUs().Difference(cpuset.NewCPUSet(4))
+	exclusiveCores := cpuset.NewCPUSet(4, 12)
+	now := time.Now()
+
+	tests := []struct {
+		name      string
+		current   cpuset.CPUSet
+		state     map[string][]common.TimeSeries
+		stateTime time.Time
+		wantSize  int
+		wantKept  cpuset.CPUSet
+		// shrink is true if the reclaimed cpus are expected within the current cpus
+		shrink bool
+	}{
+		{
+			name:      "shrink to the min if the state is expired",
+			current:   cpuset.NewCPUSet(0, 1, 8, 9),
+			state:     newReclaimedState(10000, 2000),
+			stateTime: now.Add(-2 * reclaimedStateExpiration),
+			wantSize:  minReclaimedCPUs,
+			wantKept:  cpuset.NewCPUSet(),
+			shrink:    true,
+		},
+		{
+			name:      "grow with the idle cpus and keep the current cpus",
+			current:   cpuset.NewCPUSet(0, 8),
+			state:     newReclaimedState(10000, 2000),
+			stateTime: now,
+			wantSize:  4,
+			wantKept:  cpuset.NewCPUSet(0, 8),
+		},
+		{
+			name:      "shrink within the current cpus",
+			current:   cpuset.NewCPUSet(0, 1, 8, 9),
+			state:     newReclaimedState(13000, 1000),
+			stateTime: now,
+			wantSize:  2,
+			wantKept:  cpuset.NewCPUSet(),
+			shrink:    true,
+		},
+		{
+			name:      "drop the cpus on the exclusive cores",
+			current:   cpuset.NewCPUSet(0, 12),
+			state:     newReclaimedState(14000, 1000),
+			stateTime: now,
+			wantSize:  1,
+			wantKept:  cpuset.NewCPUSet(0),
+		},
+		{
+			name:      "at most the candidates are reclaimed",
+			current:   cpuset.NewCPUSet(),
+			state:     newReclaimedState(0, 0),
+			stateTime: now,
+			wantSize:  14,
+			wantKept:  cpuset.NewCPUSet(),
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			m := &AdvancedCpuManager{
+				podLister:       corelisters.NewPodLister(cache.NewIndexer(cache.MetaNamespaceKeyFunc, cache.Indexers{})),
+				topology:        topo,
+				exclusiveCPUSet: cpuset.NewCPUSet(4),
+				reclaimedCPUSet: tt.current,
+				getState: func() (map[string][]common.TimeSeries, time.Time) {
+					return tt.state, tt.stateTime
+				},
+			}
+			m.resizeReclaimedCPUSet(sharedCPUSet, now)
+
+			got := m.getReclaimedCPUSet()
+			if got.Size() != tt.wantSize {
+				t.Errorf("Expected %d reclaimed cpus, got %s", tt.wantSize, got)
+			}
+			if !tt.wantKept.IsSubsetOf(got) {
+				t.Errorf("Expected the cpus %s kept, got %s", tt.wantKept, got)
+			}
+			if !got.IsSubsetOf(sharedCPUSet) || !got.Intersection(exclusiveCores).IsEmpty() {
+				t.Errorf("Expected the reclaimed cpus on the shared cores, got %s", got)
+			}
+			if tt.shrink && !got.IsSubsetOf(tt.current) {
+				t.Errorf("Expected the reclaimed cpus within %s, got %s", tt.current, got)
+			}
+		})
+	}
+}
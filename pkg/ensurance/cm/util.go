@@ -1,9 +1,19 @@
 package cm
 
-import v1 "k8s.io/api/core/v1"
+import (
+	v1 "k8s.io/api/core/v1"
+	"k8s.io/kubernetes/pkg/kubelet/cm/cpuset"
+)
 
 const CPUSetAnnotation string = "qos.gocrane.io/cpu-manager"
 
+// NUMANodeAnnotation binds the containers without cpus allocated to the share pool of the NUMA nodes, such as "0" or "0-1".
+const NUMANodeAnnotation string = "qos.gocrane.io/numa-node"
+
+// NUMAAlignmentAnnotation reports the NUMA alignment of the containers with cpus allocated by the container names,
+// such as {"app": {"numaNodes": "0", "aligned": true}}.
+const NUMAAlignmentAnnotation string = "qos.gocrane.io/numa-alignment"
+
 // CPUSetPolicy the type for cpuset
 type CPUSetPolicy string
 
@@ -11,6 +21,8 @@ const (
 	CPUSetNone      CPUSetPolicy = "none"
 	CPUSetExclusive CPUSetPolicy = "exclusive"
 	CPUSetShare     CPUSetPolicy = "share"
+	// CPUSetReclaimed is for the offline pods, which run on the cpus reclaimed from the idle of the shared pool
+	CPUSetReclaimed CPUSetPolicy = "reclaimed"
 )
 
 func GetPodCPUSetType(pod *v1.Pod, _ *v1.Container) CPUSetPolicy {
@@ -21,6 +33,19 @@ func GetPodCPUSetType(pod *v1.Pod, _ *v1.Container) CPUSetPolicy {
 	return csp
 }
 
+// GetPodNUMANodes returns the NUMA nodes of the pod set by NUMANodeAnnotation, false is returned if it is not set or invalid.
+func GetPodNUMANodes(pod *v1.Pod) ([]int, bool) {
+	value, ok := pod.GetAnnotations()[NUMANodeAnnotation]
+	if !ok || value == "" {
+		return nil, false
+	}
+	nodes, err := cpuset.Parse(value)
+	if err != nil || nodes.IsEmpty() {
+		return nil, false
+	}
+	return nodes.ToSlice(), true
+}
+
 func IsPodNotRunning(statuses []v1.ContainerStatus) bool {
 	for _, status := range statuses {
 		if status.State.Terminated == nil && status.State.Waiting == nil {
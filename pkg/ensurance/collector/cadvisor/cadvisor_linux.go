@@ -5,6 +5,7 @@ package cadvisor
 
 import (
 	"net/http"
+	"path/filepath"
 	"strconv"
 	"time"
 
@@ -20,6 +21,8 @@ import (
 	"k8s.io/klog/v2"
 
 	"github.com/gocrane/crane/pkg/common"
+	"github.com/gocrane/crane/pkg/ensurance/cgroup"
+	"github.com/gocrane/crane/pkg/ensurance/collector/psi"
 	"github.com/gocrane/crane/pkg/ensurance/collector/types"
 	"github.com/gocrane/crane/pkg/utils"
 )
@@ -30,6 +33,16 @@ var cadvisorMetrics = []types.MetricName{
 	types.MetricNameContainerCpuLimit,
 	types.MetricNameContainerCpuQuota,
 	types.MetricNameContainerCpuPeriod,
+	types.MetricNameContainerMemTotalUsage,
+	types.MetricNameContainerMemLimit,
+}
+
+const pressureFileSuffix = ".pressure"
+
+func init() {
+	for _, resource := range psi.Resources {
+		cadvisorMetrics = append(cadvisorMetrics, types.ContainerPressureMetricNames[resource].Names()...)
+	}
 }
 
 type ContainerState struct {
@@ -63,6 +76,7 @@ func NewCadvisorManager() Manager {
 	var includedMetrics = cadvisorcontainer.MetricSet{
 		cadvisorcontainer.CpuUsageMetrics:         struct{}{},
 		cadvisorcontainer.ProcessSchedulerMetrics: struct{}{},
+		cadvisorcontainer.MemoryUsageMetrics:      struct{}{},
 	}
 
 	allowDynamic := true
@@ -71,7 +85,7 @@ func NewCadvisorManager() Manager {
 	sysfs := csysfs.NewRealSysFs()
 	maxHousekeepingConfig := cmanager.HouskeepingConfig{Interval: &maxHousekeepingInterval, AllowDynamic: &allowDynamic}
 
-	m, err := cmanager.New(memCache, sysfs, maxHousekeepingConfig, includedMetrics, http.DefaultClient, []string{utils.CgroupKubePods}, "")
+	m, err := cmanager.New(memCache, sysfs, maxHousekeepingConfig, includedMetrics, http.DefaultClient, []string{cgroup.KubepodsPath()}, "")
 	if err != nil {
 		klog.Errorf("Failed to create cadvisor manager start: %v", err)
 		return nil
@@ -105,11 +119,13 @@ func (c *CadvisorCollector) Collect() (map[string][]common.TimeSeries, error) {
 		return nil, err
 	}
 	var extResCpuUse float64 = 0
+	// the pressure stall information of the cgroups is only available on cgroup v2
+	var cgroupV2 = cgroup.IsV2()
 
 	var stateMap = make(map[string][]common.TimeSeries)
 	for _, pod := range allPods {
 		var now = time.Now()
-		containers, err := c.Manager.GetContainerInfoV2(types.GetCgroupPath(pod), cadvisorapiv2.RequestOptions{
+		containers, err := c.Manager.GetContainerInfoV2(cgroup.PodPath(pod), cadvisorapiv2.RequestOptions{
 			IdType:    cadvisorapiv2.TypeName,
 			Count:     1,
 			Recursive: true,
@@ -128,6 +144,10 @@ func (c *CadvisorCollector) Collect() (map[string][]common.TimeSeries, error) {
 
 			_, hasExtRes := utils.GetContainerExtCpuResFromPod(pod, containerName)
 
+			if cgroupV2 {
+				collectPressure(key, GetContainerLabels(pod, containerId, containerName, hasExtRes), now, stateMap)
+			}
+
 			// In the GetContainerInfoV2 not collect the cpu quota and period
 			// We used GetContainerInfo instead
 			// issue https://github.com/google/cadvisor/issues/3040
@@ -153,6 +173,10 @@ func (c *CadvisorCollector) Collect() (map[string][]common.TimeSeries, error) {
 				addSampleToStateMap(types.MetricNameContainerCpuLimit, composeSample(containerLabels, float64(state.stat.Spec.Cpu.Limit), now), stateMap)
 				addSampleToStateMap(types.MetricNameContainerCpuQuota, composeSample(containerLabels, float64(containerInfoV1.Spec.Cpu.Quota), now), stateMap)
 				addSampleToStateMap(types.MetricNameContainerCpuPeriod, composeSample(containerLabels, float64(containerInfoV1.Spec.Cpu.Period), now), stateMap)
+				if len(v.Stats) != 0 && v.Stats[0].Memory != nil {
+					addSampleToStateMap(types.MetricNameContainerMemTotalUsage, composeSample(containerLabels, float64(v.Stats[0].Memory.WorkingSet), now), stateMap)
+				}
+				addSampleToStateMap(types.MetricNameContainerMemLimit, composeSample(containerLabels, float64(containerInfoV1.Spec.Memory.Limit), now), stateMap)
 
 				klog.V(10).Infof("Pod: %s, containerName: %s, key %s, scheduler run queue time %.2f", klog.KObj(pod), containerName, key, schedRunqueueTime)
 			}
@@ -166,6 +190,18 @@ func (c *CadvisorCollector) Collect() (map[string][]common.TimeSeries, error) {
 	return stateMap, nil
 }
 
+// collectPressure reads the pressure stall information in the cgroup directory of the container on cgroup v2.
+func collectPressure(cgroupName string, labels []common.Label, now time.Time, stateMap map[string][]common.TimeSeries) {
+	for _, resource := range psi.Resources {
+		stats, err := psi.ReadFile(filepath.Join(cgroup.Root, cgroupName, resource+pressureFileSuffix))
+		if err != nil {
+			klog.V(6).Infof("Failed to read %s pressure of cgroup %s: %v", resource, cgroupName, err)
+			continue
+		}
+		psi.AddSeries(stateMap, types.ContainerPressureMetricNames[resource], stats, labels, now.Unix())
+	}
+}
+
 func composeSample(labels []common.Label, cpuUsageSample float64, sampleTime time.Time) common.TimeSeries {
 	return common.TimeSeries{
 		Labels: labels,
@@ -0,0 +1,113 @@
+//go:build linux
+// +build linux
+
+package cadvisor
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	info "github.com/google/cadvisor/info/v1"
+	cadvisorapiv2 "github.com/google/cadvisor/info/v2"
+	v1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	corelisters "k8s.io/client-go/listers/core/v1"
+	"k8s.io/client-go/tools/cache"
+
+	"github.com/gocrane/crane/pkg/common"
+	"github.com/gocrane/crane/pkg/ensurance/cgroup"
+	"github.com/gocrane/crane/pkg/ensurance/collector/types"
+)
+
+type fakeManager struct {
+	containers map[string]cadvisorapiv2.ContainerInfo
+}
+
+func (m *fakeManager) GetContainerInfoV2(_ string, _ cadvisorapiv2.RequestOptions) (map[string]cadvisorapiv2.ContainerInfo, error) {
+	return m.containers, nil
+}
+
+func (m *fakeManager) GetContainerInfo(_ string, _ *info.ContainerInfoRequest) (*info.ContainerInfo, error) {
+	return &info.ContainerInfo{}, nil
+}
+
+func (m *fakeManager) GetMachineInfo() (*info.MachineInfo, error) {
+	return &info.MachineInfo{}, nil
+}
+
+func TestCollectPressure(t *testing.T) {
+	root := t.TempDir()
+	defer func(origin string) { cgroup.Root = origin }(cgroup.Root)
+	cgroup.Root = root
+
+	writeFile(t, filepath.Join(root, cgroup.ControllersFile), "cpu io memory\n")
+	podKey := "/kubepods/besteffort/poduid1"
+	containerKey := podKey + "/abc"
+	writeFile(t, filepath.Join(root, podKey, "cpu.pressure"), "some avg10=5.00 avg60=3.00 avg300=1.00 total=500\nfull avg10=2.00 avg60=1.00 avg300=0.50 total=200\n")
+	writeFile(t, filepath.Join(root, containerKey, "memory.pressure"), "some avg10=7.00 avg60=4.00 avg300=2.00 total=700\nfull avg10=6.00 avg60=3.00 avg300=1.00 total=600\n")
+
+	pod := &v1.Pod{
+		ObjectMeta: metav1.ObjectMeta{Name: "pod1", Namespace: "default", UID: "uid1"},
+		Status: v1.PodStatus{
+			QOSClass:          v1.PodQOSBestEffort,
+			ContainerStatuses: []v1.ContainerStatus{{Name: "app", ContainerID: "containerd://abc"}},
+		},
+	}
+	indexer := cache.NewIndexer(cache.MetaNamespaceKeyFunc, cache.Indexers{})
+	indexer.Add(pod)
+
+	c := NewCadvisorCollector(corelisters.NewPodLister(indexer), &fakeManager{containers: map[string]cadvisorapiv2.ContainerInfo{
+		podKey:       {},
+		containerKey: {},
+	}})
+
+	stateMap, err := c.Collect()
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	cases := map[string]struct {
+		metricName  types.MetricName
+		containerId string
+		expect      float64
+	}{
+		"cpu pressure of the pod": {
+			metricName: types.MetricNameContainerCpuPressureSomeAvg10,
+			expect:     5,
+		},
+		"full cpu pressure total of the pod": {
+			metricName: types.MetricNameContainerCpuPressureFullTotal,
+			expect:     200,
+		},
+		"memory pressure of the container": {
+			metricName:  types.MetricNameContainerMemoryPressureFullAvg60,
+			containerId: "abc",
+			expect:      3,
+		},
+	}
+
+	for k, v := range cases {
+		t.Run(k, func(t *testing.T) {
+			series := stateMap[string(v.metricName)]
+			if len(series) != 1 {
+				t.Fatalf("Expected 1 series, got %v", series)
+			}
+			if id := common.GetValueByName(series[0].Labels, common.LabelNameContainerId); id != v.containerId {
+				t.Errorf("Expected container id %q, got %q", v.containerId, id)
+			}
+			if series[0].Samples[0].Value != v.expect {
+				t.Errorf("Expected %v, got %v", v.expect, series[0].Samples[0].Value)
+			}
+		})
+	}
+}
+
+func writeFile(t *testing.T, file string, content string) {
+	if err := os.MkdirAll(filepath.Dir(file), 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(file, []byte(content), 0644); err != nil {
+		t.Fatal(err)
+	}
+}
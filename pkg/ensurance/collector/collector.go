@@ -6,15 +6,22 @@ import (
 
 	"k8s.io/apimachinery/pkg/labels"
 	utilfeature "k8s.io/apiserver/pkg/util/feature"
+	"k8s.io/client-go/kubernetes"
 	corelisters "k8s.io/client-go/listers/core/v1"
 	"k8s.io/klog/v2"
 	"k8s.io/kubernetes/pkg/kubelet/cm/cpuset"
 
+	ensuranceapi "github.com/gocrane/api/ensurance/v1alpha1"
 	ensuranceListers "github.com/gocrane/api/pkg/generated/listers/ensurance/v1alpha1"
 	"github.com/gocrane/crane/pkg/common"
+	"github.com/gocrane/crane/pkg/ensurance/analyzer/evaluator"
 	"github.com/gocrane/crane/pkg/ensurance/collector/cadvisor"
+	"github.com/gocrane/crane/pkg/ensurance/collector/ebpf"
+	"github.com/gocrane/crane/pkg/ensurance/collector/httpget"
+	"github.com/gocrane/crane/pkg/ensurance/collector/metricsserver"
 	"github.com/gocrane/crane/pkg/ensurance/collector/nodelocal"
 	"github.com/gocrane/crane/pkg/ensurance/collector/types"
+	"github.com/gocrane/crane/pkg/ensurance/extension"
 	"github.com/gocrane/crane/pkg/features"
 	"github.com/gocrane/crane/pkg/known"
 	"github.com/gocrane/crane/pkg/metrics"
@@ -23,6 +30,7 @@ import (
 
 type StateCollector struct {
 	nodeName          string
+	kubeClient        kubernetes.Interface
 	nepLister         ensuranceListers.NodeQOSEnsurancePolicyLister
 	podLister         corelisters.PodLister
 	nodeLister        corelisters.NodeLister
@@ -35,15 +43,21 @@ type StateCollector struct {
 	AnalyzerChann     chan map[string][]common.TimeSeries
 	NodeResourceChann chan map[string][]common.TimeSeries
 	PodResourceChann  chan map[string][]common.TimeSeries
+
+	// state is the latest collected metrics
+	stateLock sync.RWMutex
+	state     map[string][]common.TimeSeries
+	stateTime time.Time
 }
 
-func NewStateCollector(nodeName string, nepLister ensuranceListers.NodeQOSEnsurancePolicyLister, podLister corelisters.PodLister,
+func NewStateCollector(kubeClient kubernetes.Interface, nodeName string, nepLister ensuranceListers.NodeQOSEnsurancePolicyLister, podLister corelisters.PodLister,
 	nodeLister corelisters.NodeLister, ifaces []string, healthCheck *metrics.HealthCheck, collectInterval time.Duration, exclusiveCPUSet func() cpuset.CPUSet, manager cadvisor.Manager) *StateCollector {
 	analyzerChann := make(chan map[string][]common.TimeSeries)
 	nodeResourceChann := make(chan map[string][]common.TimeSeries)
 	podResourceChann := make(chan map[string][]common.TimeSeries)
 	return &StateCollector{
 		nodeName:          nodeName,
+		kubeClient:        kubeClient,
 		nepLister:         nepLister,
 		podLister:         podLister,
 		nodeLister:        nodeLister,
@@ -110,7 +124,7 @@ func (s *StateCollector) Collect() {
 	wg := sync.WaitGroup{}
 	start := time.Now()
 
-	var data = make(map[string][]common.TimeSeries)
+	var results = make(map[types.CollectType]map[string][]common.TimeSeries)
 	var mux sync.Mutex
 
 	s.collectors.Range(func(key, value interface{}) bool {
@@ -119,24 +133,28 @@ func (s *StateCollector) Collect() {
 		wg.Add(1)
 		metrics.UpdateLastTimeWithSubComponent(string(known.ModuleStateCollector), string(c.GetType()), metrics.StepCollect, start)
 
-		go func(c Collector, data map[string][]common.TimeSeries) {
+		go func(c Collector) {
 			defer wg.Done()
 			defer metrics.UpdateDurationFromStartWithSubComponent(string(known.ModuleStateCollector), string(c.GetType()), metrics.StepCollect, start)
 
 			if cdata, err := c.Collect(); err == nil {
 				mux.Lock()
-				for key, series := range cdata {
-					data[key] = series
-				}
+				results[c.GetType()] = cdata
 				mux.Unlock()
 			}
-		}(c, data)
+		}(c)
 
 		return true
 	})
 
 	wg.Wait()
 
+	data := mergeStates(results)
+
+	s.stateLock.Lock()
+	s.state, s.stateTime = data, start
+	s.stateLock.Unlock()
+
 	s.AnalyzerChann <- data
 
 	if nodeResource := utilfeature.DefaultFeatureGate.Enabled(features.CraneNodeResource); nodeResource {
@@ -148,6 +166,33 @@ func (s *StateCollector) Collect() {
 	}
 }
 
+// collectPrecedence is the order to merge the metrics of the collectors, the metrics of a collector replace the ones
+// of the same names collected by the collectors before it. The metrics server replaces the node local and cadvisor
+// collectors for the node and container usages when it is selected.
+var collectPrecedence = []types.CollectType{
+	types.NodeLocalCollectorType,
+	types.CadvisorCollectorType,
+	types.EbpfCollectorType,
+	types.HTTPGetCollectorType,
+	types.MetricsServerCollectorType,
+}
+
+// mergeStates merges the metrics of the collectors by collectPrecedence, so that the same collector always wins if
+// more than one of them collect a metric. The metrics of the probes never replace the built-in metrics.
+func mergeStates(results map[types.CollectType]map[string][]common.TimeSeries) map[string][]common.TimeSeries {
+	var data = make(map[string][]common.TimeSeries)
+	for _, collectType := range collectPrecedence {
+		for key, series := range results[collectType] {
+			if collectType == types.HTTPGetCollectorType && CheckMetricNameExist(key) {
+				klog.V(4).Infof("The probe metric %s is dropped, it is the name of a built-in metric", key)
+				continue
+			}
+			data[key] = series
+		}
+	}
+	return data
+}
+
 func (s *StateCollector) UpdateCollectors() {
 	allNeps, err := s.nepLister.List(labels.Everything())
 	if err != nil {
@@ -158,47 +203,110 @@ func (s *StateCollector) UpdateCollectors() {
 		klog.Errorf("Failed to get node: %v", err)
 		return
 	}
-	var nodeLocal bool
+	var nodeLocal, ebpfEnabled bool
+	var metricsServerGet *extension.MetricsServerGet
+	var httpProbes = make(map[string]httpget.Probe)
 	for _, n := range allNeps {
 		if matched, err := utils.LabelSelectorMatched(node.Labels, n.Spec.Selector); err != nil || !matched {
 			continue
 		}
 
+		if n.Spec.NodeQualityProbe.HTTPGet != nil {
+			httpProbes[n.Name] = httpget.Probe{
+				HTTPGet: n.Spec.NodeQualityProbe.HTTPGet,
+				Timeout: time.Duration(n.Spec.NodeQualityProbe.TimeoutSeconds) * time.Second,
+			}
+		}
+
+		if !ebpfEnabled {
+			ebpfEnabled = ebpfMetricsReferenced(n)
+		}
+
+		if metricsServerGet == nil {
+			probe, err := extension.GetMetricsServerGet(n)
+			if err != nil {
+				klog.Errorf("Failed to get metrics server probe of NEP %s: %v", n.Name, err)
+			}
+			metricsServerGet = probe
+		}
+
 		if n.Spec.NodeQualityProbe.NodeLocalGet == nil {
 			klog.V(4).Infof("Probe type of NEP %s/%s is not node local, continue", n.Namespace, n.Name)
 			continue
 		}
 
 		nodeLocal = true
+	}
 
+	if nodeLocal {
 		if _, exists := s.collectors.Load(types.NodeLocalCollectorType); !exists {
-			nc := nodelocal.NewNodeLocal(s.ifaces, s.exclusiveCPUSet)
+			nc := nodelocal.NewNodeLocal(s.ifaces, s.exclusiveCPUSet, s.podLister)
 			s.collectors.Store(types.NodeLocalCollectorType, nc)
 		}
 
 		if _, exists := s.collectors.Load(types.CadvisorCollectorType); !exists {
 			s.collectors.Store(types.CadvisorCollectorType, cadvisor.NewCadvisorCollector(s.podLister, s.GetCadvisorManager()))
 		}
-		break
+	} else {
+		s.stopCollectors(types.NodeLocalCollectorType, types.CadvisorCollectorType)
+	}
+
+	// the ebpf programs are only attached when the run queue latency is evaluated, since they trace every context switch
+	if ebpfEnabled {
+		if _, exists := s.collectors.Load(types.EbpfCollectorType); !exists {
+			s.collectors.Store(types.EbpfCollectorType, ebpf.NewEBPF(s.podLister))
+		}
+	} else {
+		s.stopCollectors(types.EbpfCollectorType)
 	}
 
-	if !nodeLocal {
-		stopCollectors := []types.CollectType{types.NodeLocalCollectorType, types.CadvisorCollectorType}
+	if metricsServerGet != nil {
+		// recreate the collector if the source is changed
+		if value, exists := s.collectors.Load(types.MetricsServerCollectorType); exists && value.(*metricsserver.MetricsServer).GetSource() != metricsServerGet.Source {
+			s.stopCollectors(types.MetricsServerCollectorType)
+		}
 
-		for _, collector := range stopCollectors {
-			if value, exists := s.collectors.Load(collector); exists {
-				s.collectors.Delete(collector)
-				c := value.(Collector)
-				if err = c.Stop(); err != nil {
-					klog.Errorf("Failed to stop the %s manager.", collector)
-				}
-			}
+		if _, exists := s.collectors.Load(types.MetricsServerCollectorType); !exists {
+			s.collectors.Store(types.MetricsServerCollectorType, metricsserver.NewMetricsServer(s.nodeName, metricsServerGet.Source,
+				s.kubeClient.CoreV1().RESTClient(), s.nodeLister, s.podLister))
+		}
+	} else {
+		s.stopCollectors(types.MetricsServerCollectorType)
+	}
+
+	if len(httpProbes) != 0 {
+		value, exists := s.collectors.Load(types.HTTPGetCollectorType)
+		if !exists {
+			value = httpget.NewHTTPGet(s.collectInterval)
+			s.collectors.Store(types.HTTPGetCollectorType, value)
 		}
+		value.(*httpget.HTTPGet).SetProbes(httpProbes)
+	} else {
+		s.stopCollectors(types.HTTPGetCollectorType)
 	}
 
 	return
 }
 
+func (s *StateCollector) stopCollectors(collectors ...types.CollectType) {
+	for _, collector := range collectors {
+		if value, exists := s.collectors.Load(collector); exists {
+			s.collectors.Delete(collector)
+			c := value.(Collector)
+			if err := c.Stop(); err != nil {
+				klog.Errorf("Failed to stop the %s manager.", collector)
+			}
+		}
+	}
+}
+
+// GetState returns the latest collected metrics and the time when they are collected, the metrics must not be modified.
+func (s *StateCollector) GetState() (map[string][]common.TimeSeries, time.Time) {
+	s.stateLock.RLock()
+	defer s.stateLock.RUnlock()
+	return s.state, s.stateTime
+}
+
 func (s *StateCollector) GetCollectors() *sync.Map {
 	return s.collectors
 }
@@ -233,5 +341,42 @@ func CheckMetricNameExist(name string) bool {
 		return true
 	}
 
+	if metricsserver.CheckMetricNameExist(name) {
+		return true
+	}
+
+	if ebpf.CheckMetricNameExist(name) {
+		return true
+	}
+
+	return false
+}
+
+// ebpfMetricsReferenced returns true if the metric rules or the rego rules of the policy reference the metrics
+// collected by the ebpf collector.
+func ebpfMetricsReferenced(nep *ensuranceapi.NodeQOSEnsurancePolicy) bool {
+	for _, objective := range nep.Spec.ObjectiveEnsurances {
+		if objective.MetricRule != nil && ebpf.CheckMetricNameExist(objective.MetricRule.Name) {
+			return true
+		}
+	}
+
+	objectives, err := extension.GetObjectiveEnsurances(nep)
+	if err != nil {
+		return false
+	}
+	for _, objective := range objectives {
+		if objective.Rego == "" {
+			continue
+		}
+		// the rule which can not be parsed is not evaluated
+		names, all, err := evaluator.RegoMetricNames(objective.Rego)
+		if err != nil {
+			continue
+		}
+		if all || names.HasAny(ebpf.MetricNames()...) {
+			return true
+		}
+	}
 	return false
 }
@@ -0,0 +1,34 @@
+package collector
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/gocrane/crane/pkg/common"
+	"github.com/gocrane/crane/pkg/ensurance/collector/types"
+)
+
+func TestMergeStates(t *testing.T) {
+	series := func(value float64) []common.TimeSeries {
+		return []common.TimeSeries{{Samples: []common.Sample{{Value: value}}}}
+	}
+	results := map[types.CollectType]map[string][]common.TimeSeries{
+		types.MetricsServerCollectorType: {"cpu_total_usage": series(3)},
+		types.NodeLocalCollectorType:     {"cpu_total_usage": series(1), "cpu_load_1_min": series(2)},
+		types.CadvisorCollectorType:      {"container_cpu_total_usage": series(4)},
+		types.HTTPGetCollectorType:       {"cpu_load_1_min": series(5), "queue_depth": series(6)},
+	}
+	expect := map[string][]common.TimeSeries{
+		"cpu_total_usage":           series(3),
+		"cpu_load_1_min":            series(2),
+		"container_cpu_total_usage": series(4),
+		"queue_depth":               series(6),
+	}
+
+	// the metrics server wins whatever the order of the results is, and the probes never replace the built-in metrics
+	for i := 0; i < 10; i++ {
+		if data := mergeStates(results); !reflect.DeepEqual(data, expect) {
+			t.Fatalf("Expected %v, got %v", expect, data)
+		}
+	}
+}
@@ -1,34 +1,161 @@
 package ebpf
 
 import (
-	"sync"
+	"math"
+	"strconv"
 
+	"github.com/gocrane/crane/pkg/common"
 	"github.com/gocrane/crane/pkg/ensurance/collector/types"
+)
 
-	"github.com/gocrane/crane/pkg/common"
+const (
+	// histogramSlots is the number of the log2 slots of the run queue latency in microseconds,
+	// the slot i counts the latency in [2^i, 2^(i+1)) and the slot 0 counts [0, 2)
+	histogramSlots = 32
+
+	// labelNameBucket is the label of the upper bound of the buckets of the histograms
+	labelNameBucket = "le"
 )
 
-type EBPF struct {
-	name        types.CollectType
-	StatusCache sync.Map
+// bucketBounds are the upper bounds of the exported buckets in microseconds, the last bucket is +Inf.
+var bucketBounds = []uint64{4, 16, 64, 256, 1024, 4096, 16384, 65536, 262144, 1048576}
+
+var ebpfMetrics = []types.MetricName{
+	types.MetricNameRunQueueLatencyAvg,
+	types.MetricNameRunQueueLatencyP99,
+	types.MetricNameRunQueueLatencyBucket,
+	types.MetricNameInvoluntaryContextSwitches,
+	types.MetricNameContainerRunQueueLatencyAvg,
+	types.MetricNameContainerRunQueueLatencyP99,
+	types.MetricNameContainerRunQueueLatencyBucket,
+	types.MetricNameContainerInvoluntaryContextSwitches,
 }
 
-func NewEBPF() *EBPF {
-	e := EBPF{
-		name:        types.EbpfCollectorType,
-		StatusCache: sync.Map{},
+// metricNames are the metric names of the node or the containers.
+type metricNames struct {
+	avg         types.MetricName
+	p99         types.MetricName
+	bucket      types.MetricName
+	involuntary types.MetricName
+}
+
+var (
+	nodeMetricNames = metricNames{types.MetricNameRunQueueLatencyAvg, types.MetricNameRunQueueLatencyP99,
+		types.MetricNameRunQueueLatencyBucket, types.MetricNameInvoluntaryContextSwitches}
+	containerMetricNames = metricNames{types.MetricNameContainerRunQueueLatencyAvg, types.MetricNameContainerRunQueueLatencyP99,
+		types.MetricNameContainerRunQueueLatencyBucket, types.MetricNameContainerInvoluntaryContextSwitches}
+)
+
+// cgroupStats is the value of the stats map of the ebpf programs keyed by the cgroup id, the layout must be
+// kept with the offsets used by the programs.
+type cgroupStats struct {
+	// Slots is the log2 histogram of the run queue latency in microseconds
+	Slots [histogramSlots]uint64
+	// LatencyNs is the sum of the run queue latency in nanoseconds
+	LatencyNs uint64
+	// Count is the number of the tasks scheduled after waiting in the run queue
+	Count uint64
+	// Involuntary is the number of the context switches of the tasks which are still runnable
+	Involuntary uint64
+}
+
+// sub returns the increase of the stats since the latest, the stats are counted from zero again
+// if the entry is removed from the map.
+func (s cgroupStats) sub(latest cgroupStats) cgroupStats {
+	if s.Count < latest.Count || s.Involuntary < latest.Involuntary {
+		return s
+	}
+
+	var delta = cgroupStats{
+		LatencyNs:   s.LatencyNs - latest.LatencyNs,
+		Count:       s.Count - latest.Count,
+		Involuntary: s.Involuntary - latest.Involuntary,
 	}
-	return &e
+	for i := range s.Slots {
+		if s.Slots[i] > latest.Slots[i] {
+			delta.Slots[i] = s.Slots[i] - latest.Slots[i]
+		}
+	}
+	return delta
 }
 
-func (e *EBPF) GetType() types.CollectType {
-	return e.name
+func (s *cgroupStats) add(other cgroupStats) {
+	for i := range s.Slots {
+		s.Slots[i] += other.Slots[i]
+	}
+	s.LatencyNs += other.LatencyNs
+	s.Count += other.Count
+	s.Involuntary += other.Involuntary
 }
 
-func (e *EBPF) Collect() (map[string][]common.TimeSeries, error) {
-	return nil, nil
+// quantile returns the upper bound of the slot at the quantile in microseconds.
+func (s cgroupStats) quantile(q float64) float64 {
+	if s.Count == 0 {
+		return 0
+	}
+
+	var total uint64
+	for _, count := range s.Slots {
+		total += count
+	}
+
+	var rank = uint64(math.Ceil(q * float64(total)))
+	var cumulative uint64
+	for i, count := range s.Slots {
+		cumulative += count
+		if cumulative >= rank {
+			return float64(uint64(1) << (i + 1))
+		}
+	}
+	return float64(uint64(1) << histogramSlots)
+}
+
+// addSeries adds the series of the stats increased in the seconds to the data by the metric names.
+func addSeries(data map[string][]common.TimeSeries, names metricNames, stats cgroupStats, seconds float64, labels []common.Label, timestamp int64) {
+	add := func(name types.MetricName, labels []common.Label, value float64) {
+		data[string(name)] = append(data[string(name)], common.TimeSeries{Labels: labels, Samples: []common.Sample{{Value: value, Timestamp: timestamp}}})
+	}
+
+	var avg float64
+	if stats.Count > 0 {
+		avg = float64(stats.LatencyNs) / float64(stats.Count) / 1000
+	}
+	add(names.avg, labels, avg)
+	add(names.p99, labels, stats.quantile(0.99))
+	if seconds > 0 {
+		add(names.involuntary, labels, float64(stats.Involuntary)/seconds)
+	}
+
+	// the buckets are cumulative as the prometheus histograms
+	var slot int
+	var cumulative uint64
+	for _, bound := range bucketBounds {
+		for ; slot < histogramSlots && uint64(1)<<(slot+1) <= bound; slot++ {
+			cumulative += stats.Slots[slot]
+		}
+		add(names.bucket, append(labels[:len(labels):len(labels)], common.Label{Name: labelNameBucket, Value: strconv.FormatUint(bound, 10)}), float64(cumulative))
+	}
+	for ; slot < histogramSlots; slot++ {
+		cumulative += stats.Slots[slot]
+	}
+	add(names.bucket, append(labels[:len(labels):len(labels)], common.Label{Name: labelNameBucket, Value: "+Inf"}), float64(cumulative))
 }
 
-func (e *EBPF) Stop() error {
-	return nil
+// MetricNames returns the names of the metrics collected by the ebpf collector.
+func MetricNames() []string {
+	var names = make([]string, 0, len(ebpfMetrics))
+	for _, n := range ebpfMetrics {
+		names = append(names, string(n))
+	}
+	return names
+}
+
+// CheckMetricNameExist returns true if the metric is collected by the ebpf collector.
+func CheckMetricNameExist(name string) bool {
+	for _, n := range ebpfMetrics {
+		if string(n) == name {
+			return true
+		}
+	}
+	return false
 }
@@ -0,0 +1,299 @@
+//go:build linux
+// +build linux
+
+package ebpf
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"syscall"
+	"time"
+
+	"github.com/cilium/ebpf"
+	"github.com/cilium/ebpf/asm"
+	"github.com/cilium/ebpf/link"
+	"golang.org/x/sys/unix"
+	v1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/labels"
+	corelisters "k8s.io/client-go/listers/core/v1"
+	"k8s.io/klog/v2"
+
+	"github.com/gocrane/crane/pkg/common"
+	"github.com/gocrane/crane/pkg/ensurance/cgroup"
+	"github.com/gocrane/crane/pkg/ensurance/collector/types"
+	"github.com/gocrane/crane/pkg/utils"
+)
+
+const (
+	maxTasks   = 65536
+	maxCgroups = 16384
+
+	// unifiedSubsystem is the mount point of the unified hierarchy in the hybrid mode of cgroup
+	unifiedSubsystem = "unified"
+)
+
+// EBPF traces the run queue latency and the involuntary context switches of the cgroups by the sched_wakeup,
+// sched_wakeup_new and sched_switch tracepoints. The programs are built at runtime with the offsets of the
+// tracepoint fields read from tracefs, so the kernel btf is not required, but the cgroup ids are only available
+// on the unified hierarchy of cgroup v2 and the hybrid mode.
+type EBPF struct {
+	name      types.CollectType
+	podLister corelisters.PodLister
+	// err is the reason why the programs are not loaded, the collector collects nothing if it is set
+	err     error
+	maps    maps
+	links   []link.Link
+	latest  map[uint64]cgroupStats
+	updated time.Time
+}
+
+func NewEBPF(podLister corelisters.PodLister) *EBPF {
+	e := EBPF{
+		name:      types.EbpfCollectorType,
+		podLister: podLister,
+	}
+
+	if e.err = e.load(); e.err != nil {
+		klog.Warningf("The ebpf collector is disabled, the run queue latency is not collected: %v", e.err)
+		e.close()
+	}
+	return &e
+}
+
+func (e *EBPF) GetType() types.CollectType {
+	return e.name
+}
+
+func (e *EBPF) Collect() (map[string][]common.TimeSeries, error) {
+	if e.err != nil {
+		return nil, e.err
+	}
+
+	var now = time.Now()
+	current, err := e.readStats()
+	if err != nil {
+		return nil, err
+	}
+
+	latest, updated := e.latest, e.updated
+	e.latest, e.updated = current, now
+	if latest == nil {
+		return nil, nil
+	}
+
+	var node cgroupStats
+	var deltas = make(map[uint64]cgroupStats, len(current))
+	for id, stats := range current {
+		delta := stats.sub(latest[id])
+		deltas[id] = delta
+		node.add(delta)
+	}
+
+	var data = make(map[string][]common.TimeSeries)
+	var seconds = now.Sub(updated).Seconds()
+	addSeries(data, nodeMetricNames, node, seconds, nil, now.Unix())
+
+	pods, err := e.podLister.List(labels.Everything())
+	if err != nil {
+		return nil, err
+	}
+	for _, pod := range pods {
+		podDir, err := podUnifiedDir(pod)
+		if err != nil {
+			continue
+		}
+
+		addSeries(data, containerMetricNames, sumStats(podDir, deltas), seconds, getContainerLabels(pod, "", ""), now.Unix())
+		for _, container := range pod.Spec.Containers {
+			containerId := utils.GetContainerIdFromPod(pod, container.Name)
+			containerDir, err := cgroup.ContainerDir(podDir, containerId)
+			if err != nil {
+				continue
+			}
+			addSeries(data, containerMetricNames, sumStats(containerDir, deltas), seconds, getContainerLabels(pod, container.Name, containerId), now.Unix())
+		}
+	}
+
+	return data, nil
+}
+
+func (e *EBPF) Stop() error {
+	e.close()
+	return nil
+}
+
+// load creates the maps, loads the programs and attaches them to the tracepoints.
+func (e *EBPF) load() error {
+	if _, err := unifiedRoot(); err != nil {
+		return err
+	}
+
+	wakeupFormat, err := readFormat("sched", "sched_wakeup")
+	if err != nil {
+		return fmt.Errorf("failed to read the format of the tracepoints, tracefs is required at %s: %v", tracefsRoot, err)
+	}
+	wakeupNewFormat, err := readFormat("sched", "sched_wakeup_new")
+	if err != nil {
+		return fmt.Errorf("failed to read the format of the tracepoints, tracefs is required at %s: %v", tracefsRoot, err)
+	}
+	switchFormat, err := readFormat("sched", "sched_switch")
+	if err != nil {
+		return fmt.Errorf("failed to read the format of the tracepoints, tracefs is required at %s: %v", tracefsRoot, err)
+	}
+
+	wakeupFields, err := getFields(wakeupFormat, map[string]int{"pid": 4})
+	if err != nil {
+		return fmt.Errorf("unexpected format of sched_wakeup: %v", err)
+	}
+	wakeupNewFields, err := getFields(wakeupNewFormat, map[string]int{"pid": 4})
+	if err != nil {
+		return fmt.Errorf("unexpected format of sched_wakeup_new: %v", err)
+	}
+	switchFields, err := getFields(switchFormat, map[string]int{"prev_pid": 4, "prev_state": 0, "next_pid": 4})
+	if err != nil {
+		return fmt.Errorf("unexpected format of sched_switch: %v", err)
+	}
+
+	// the memory of the maps is limited by RLIMIT_MEMLOCK before linux 5.11, it is charged to the cgroup since then
+	if err := unix.Setrlimit(unix.RLIMIT_MEMLOCK, &unix.Rlimit{Cur: unix.RLIM_INFINITY, Max: unix.RLIM_INFINITY}); err != nil {
+		klog.V(2).Infof("Failed to remove the memlock limit, CAP_SYS_RESOURCE is required before linux 5.11: %v", err)
+	}
+
+	if e.maps, err = newMaps(); err != nil {
+		return err
+	}
+
+	for _, tp := range []struct {
+		name string
+		spec *ebpf.ProgramSpec
+	}{
+		{"sched_wakeup", programSpec("crane_wakeup", wakeupProgram(e.maps, wakeupFields["pid"]))},
+		{"sched_wakeup_new", programSpec("crane_wakeup_new", wakeupProgram(e.maps, wakeupNewFields["pid"]))},
+		{"sched_switch", programSpec("crane_switch", switchProgram(e.maps, switchFields["prev_pid"], switchFields["prev_state"], switchFields["next_pid"]))},
+	} {
+		prog, err := ebpf.NewProgram(tp.spec)
+		if err != nil {
+			return permissionError(fmt.Sprintf("failed to load the program of %s", tp.name), err)
+		}
+
+		l, err := link.Tracepoint("sched", tp.name, prog)
+		// the program is held by the link
+		prog.Close()
+		if err != nil {
+			return permissionError(fmt.Sprintf("failed to attach the program to %s", tp.name), err)
+		}
+		e.links = append(e.links, l)
+	}
+
+	klog.Infof("The ebpf collector is attached to the sched tracepoints")
+	return nil
+}
+
+func (e *EBPF) close() {
+	for _, l := range e.links {
+		if err := l.Close(); err != nil {
+			klog.Errorf("Failed to detach the ebpf program: %v", err)
+		}
+	}
+	e.links = nil
+
+	e.maps.close()
+	e.maps = maps{}
+}
+
+// readStats reads the stats of the cgroups, the entries not changed since the latest collection are removed, so that
+// the entries of the removed cgroups don't fill the map.
+func (e *EBPF) readStats() (map[uint64]cgroupStats, error) {
+	var current = make(map[uint64]cgroupStats)
+	var id uint64
+	var stats cgroupStats
+	iter := e.maps.stats.Iterate()
+	for iter.Next(&id, &stats) {
+		current[id] = stats
+	}
+	if err := iter.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read the stats map: %v", err)
+	}
+
+	for id, stats := range current {
+		if latest, ok := e.latest[id]; ok && latest == stats {
+			if err := e.maps.stats.Delete(id); err != nil && !errors.Is(err, ebpf.ErrKeyNotExist) {
+				klog.V(4).Infof("Failed to remove the stats of cgroup %d: %v", id, err)
+			}
+			delete(current, id)
+		}
+	}
+	return current, nil
+}
+
+func programSpec(name string, insns asm.Instructions) *ebpf.ProgramSpec {
+	return &ebpf.ProgramSpec{
+		Name:         name,
+		Type:         ebpf.TracePoint,
+		Instructions: insns,
+		// bpf_get_current_cgroup_id is only available to the gpl programs
+		License: "GPL",
+	}
+}
+
+// permissionError explains the error if the privileges are missing.
+func permissionError(message string, err error) error {
+	if errors.Is(err, unix.EPERM) || errors.Is(err, unix.EACCES) {
+		return fmt.Errorf("%s, CAP_SYS_ADMIN, or CAP_BPF and CAP_PERFMON since linux 5.8, is required: %v", message, err)
+	}
+	return fmt.Errorf("%s: %v", message, err)
+}
+
+// unifiedRoot returns the mount point of the unified hierarchy, the cgroup ids of the ebpf programs are the inodes of
+// the cgroup directories in the unified hierarchy.
+func unifiedRoot() (string, error) {
+	if cgroup.IsV2() {
+		return cgroup.Root, nil
+	}
+
+	var root = filepath.Join(cgroup.Root, unifiedSubsystem)
+	if _, err := os.Stat(filepath.Join(root, cgroup.ControllersFile)); err != nil {
+		return "", fmt.Errorf("the unified hierarchy of cgroup v2 is required to trace the cgroups: %v", err)
+	}
+	return root, nil
+}
+
+func podUnifiedDir(pod *v1.Pod) (string, error) {
+	root, err := unifiedRoot()
+	if err != nil {
+		return "", err
+	}
+
+	podPath := cgroup.PodPath(pod)
+	if podPath == "" {
+		return "", fmt.Errorf("unknown cgroup path of pod %s/%s", pod.Namespace, pod.Name)
+	}
+	return filepath.Join(root, podPath), nil
+}
+
+// sumStats sums the stats of the cgroup directory and its children.
+func sumStats(dir string, deltas map[uint64]cgroupStats) cgroupStats {
+	var sum cgroupStats
+	filepath.Walk(dir, func(path string, info os.FileInfo, err error) error {
+		if err != nil || !info.IsDir() {
+			return nil
+		}
+		if stat, ok := info.Sys().(*syscall.Stat_t); ok {
+			sum.add(deltas[stat.Ino])
+		}
+		return nil
+	})
+	return sum
+}
+
+func getContainerLabels(pod *v1.Pod, containerName, containerId string) []common.Label {
+	return []common.Label{
+		{Name: common.LabelNamePodName, Value: pod.Name},
+		{Name: common.LabelNamePodNamespace, Value: pod.Namespace},
+		{Name: common.LabelNamePodUid, Value: string(pod.UID)},
+		{Name: common.LabelNameContainerName, Value: containerName},
+		{Name: common.LabelNameContainerId, Value: containerId},
+	}
+}
@@ -0,0 +1,124 @@
+package ebpf
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/gocrane/crane/pkg/common"
+	"github.com/gocrane/crane/pkg/ensurance/collector/types"
+)
+
+func TestCgroupStatsSub(t *testing.T) {
+	latest := cgroupStats{LatencyNs: 1000, Count: 2, Involuntary: 1}
+	latest.Slots[1] = 2
+
+	cases := map[string]struct {
+		current cgroupStats
+		expect  cgroupStats
+	}{
+		"increased": {
+			current: cgroupStats{Slots: [histogramSlots]uint64{0, 3, 1}, LatencyNs: 5000, Count: 4, Involuntary: 3},
+			expect:  cgroupStats{Slots: [histogramSlots]uint64{0, 1, 1}, LatencyNs: 4000, Count: 2, Involuntary: 2},
+		},
+		"counted again after the entry is removed": {
+			current: cgroupStats{Slots: [histogramSlots]uint64{1}, LatencyNs: 100, Count: 1},
+			expect:  cgroupStats{Slots: [histogramSlots]uint64{1}, LatencyNs: 100, Count: 1},
+		},
+	}
+
+	for k, v := range cases {
+		t.Run(k, func(t *testing.T) {
+			if delta := v.current.sub(latest); delta != v.expect {
+				t.Errorf("Expected %+v, got %+v", v.expect, delta)
+			}
+		})
+	}
+}
+
+func TestCgroupStatsQuantile(t *testing.T) {
+	cases := map[string]struct {
+		slots  map[int]uint64
+		q      float64
+		expect float64
+	}{
+		"empty": {
+			q:      0.99,
+			expect: 0,
+		},
+		"single slot": {
+			slots:  map[int]uint64{3: 10},
+			q:      0.99,
+			expect: 16,
+		},
+		"tail": {
+			slots:  map[int]uint64{2: 98, 10: 2},
+			q:      0.99,
+			expect: 2048,
+		},
+		"median": {
+			slots:  map[int]uint64{2: 98, 10: 2},
+			q:      0.5,
+			expect: 8,
+		},
+	}
+
+	for k, v := range cases {
+		t.Run(k, func(t *testing.T) {
+			var stats cgroupStats
+			for slot, count := range v.slots {
+				stats.Slots[slot] = count
+				stats.Count += count
+			}
+			if value := stats.quantile(v.q); value != v.expect {
+				t.Errorf("Expected %v, got %v", v.expect, value)
+			}
+		})
+	}
+}
+
+func TestAddSeries(t *testing.T) {
+	var stats = cgroupStats{LatencyNs: 30000, Count: 3, Involuntary: 20}
+	// [2, 4), [16, 32) and [2^20, 2^21) microseconds
+	stats.Slots[1], stats.Slots[4], stats.Slots[20] = 1, 1, 1
+
+	var labels = []common.Label{{Name: common.LabelNamePodName, Value: "pod1"}}
+	var data = make(map[string][]common.TimeSeries)
+	addSeries(data, containerMetricNames, stats, 10, labels, 100)
+
+	value := func(name types.MetricName) float64 {
+		series := data[string(name)]
+		if len(series) != 1 {
+			t.Fatalf("Expected one series of %s, got %v", name, series)
+		}
+		if !reflect.DeepEqual(series[0].Labels, labels) {
+			t.Errorf("Expected labels %v of %s, got %v", labels, name, series[0].Labels)
+		}
+		return series[0].Samples[0].Value
+	}
+	if avg := value(types.MetricNameContainerRunQueueLatencyAvg); avg != 10 {
+		t.Errorf("Expected the average latency 10us, got %v", avg)
+	}
+	if p99 := value(types.MetricNameContainerRunQueueLatencyP99); p99 != 1<<21 {
+		t.Errorf("Expected the p99 latency %d us, got %v", 1<<21, p99)
+	}
+	if rate := value(types.MetricNameContainerInvoluntaryContextSwitches); rate != 2 {
+		t.Errorf("Expected 2 involuntary context switches per second, got %v", rate)
+	}
+
+	var expect = map[string]float64{"4": 1, "16": 1, "64": 2, "256": 2, "1024": 2, "4096": 2, "16384": 2,
+		"65536": 2, "262144": 2, "1048576": 2, "+Inf": 3}
+	var buckets = data[string(types.MetricNameContainerRunQueueLatencyBucket)]
+	if len(buckets) != len(expect) {
+		t.Fatalf("Expected %d buckets, got %d", len(expect), len(buckets))
+	}
+	for _, bucket := range buckets {
+		le := bucket.Labels[len(bucket.Labels)-1]
+		if le.Name != labelNameBucket || len(bucket.Labels) != len(labels)+1 {
+			t.Errorf("Unexpected labels of bucket %v", bucket.Labels)
+			continue
+		}
+		if bucket.Samples[0].Value != expect[le.Value] {
+			t.Errorf("Expected %v in bucket %s, got %v", expect[le.Value], le.Value, bucket.Samples[0].Value)
+		}
+	}
+}
@@ -0,0 +1,35 @@
+//go:build !linux
+// +build !linux
+
+package ebpf
+
+import (
+	"errors"
+
+	corelisters "k8s.io/client-go/listers/core/v1"
+
+	"github.com/gocrane/crane/pkg/common"
+	"github.com/gocrane/crane/pkg/ensurance/collector/types"
+)
+
+var errUnsupported = errors.New("ebpf is unsupported in this build")
+
+type EBPF struct {
+	name types.CollectType
+}
+
+func NewEBPF(_ corelisters.PodLister) *EBPF {
+	return &EBPF{name: types.EbpfCollectorType}
+}
+
+func (e *EBPF) GetType() types.CollectType {
+	return e.name
+}
+
+func (e *EBPF) Collect() (map[string][]common.TimeSeries, error) {
+	return nil, errUnsupported
+}
+
+func (e *EBPF) Stop() error {
+	return nil
+}
@@ -0,0 +1,102 @@
+package ebpf
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+// tracefsRoot is the mount point of tracefs used to attach the tracepoints, it is a variable for testing.
+var tracefsRoot = "/sys/kernel/debug/tracing"
+
+// field is a field of the tracepoint context.
+type field struct {
+	Offset int
+	Size   int
+}
+
+// readFormat returns the fields of the tracepoint keyed by the field name.
+func readFormat(group, name string) (map[string]field, error) {
+	f, err := os.Open(filepath.Join(tracefsRoot, "events", group, name, "format"))
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	return parseFormat(f)
+}
+
+// parseFormat parses the format of a tracepoint, such as:
+//
+//	field:pid_t prev_pid;	offset:24;	size:4;	signed:1;
+func parseFormat(r io.Reader) (map[string]field, error) {
+	var fields = make(map[string]field)
+
+	scanner := bufio.NewScanner(r)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if !strings.HasPrefix(line, "field:") {
+			continue
+		}
+
+		var name string
+		var f = field{Offset: -1, Size: -1}
+		for _, part := range strings.Split(line, ";") {
+			kv := strings.SplitN(strings.TrimSpace(part), ":", 2)
+			if len(kv) != 2 {
+				continue
+			}
+
+			var err error
+			switch kv[0] {
+			case "field":
+				// the name is the last word of the declaration without the array size, such as char prev_comm[16]
+				words := strings.Fields(kv[1])
+				if len(words) == 0 {
+					return nil, fmt.Errorf("invalid field %q", line)
+				}
+				name = words[len(words)-1]
+				if i := strings.Index(name, "["); i >= 0 {
+					name = name[:i]
+				}
+			case "offset":
+				f.Offset, err = strconv.Atoi(kv[1])
+			case "size":
+				f.Size, err = strconv.Atoi(kv[1])
+			}
+			if err != nil {
+				return nil, fmt.Errorf("invalid field %q: %v", line, err)
+			}
+		}
+
+		if name == "" || f.Offset < 0 || f.Size <= 0 {
+			return nil, fmt.Errorf("invalid field %q", line)
+		}
+		fields[name] = f
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+
+	return fields, nil
+}
+
+// getFields returns the fields of the names in the format, the size of the fields is checked if it is not zero.
+func getFields(format map[string]field, names map[string]int) (map[string]field, error) {
+	var fields = make(map[string]field)
+	for name, size := range names {
+		f, ok := format[name]
+		if !ok {
+			return nil, fmt.Errorf("field %s is not found", name)
+		}
+		if size != 0 && f.Size != size {
+			return nil, fmt.Errorf("unexpected size %d of field %s", f.Size, name)
+		}
+		fields[name] = f
+	}
+	return fields, nil
+}
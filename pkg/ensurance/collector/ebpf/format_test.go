@@ -0,0 +1,99 @@
+package ebpf
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestReadFormat(t *testing.T) {
+	defer func(origin string) { tracefsRoot = origin }(tracefsRoot)
+	tracefsRoot = "testdata"
+
+	format, err := readFormat("sched", "sched_switch")
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	fields, err := getFields(format, map[string]int{"prev_pid": 4, "prev_state": 0, "next_pid": 4})
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	expect := map[string]field{
+		"prev_pid":   {Offset: 24, Size: 4},
+		"prev_state": {Offset: 32, Size: 8},
+		"next_pid":   {Offset: 56, Size: 4},
+	}
+	if !reflect.DeepEqual(fields, expect) {
+		t.Errorf("Expected %v, got %v", expect, fields)
+	}
+	if f := format["prev_comm"]; f.Offset != 8 || f.Size != 16 {
+		t.Errorf("Expected the array field prev_comm at offset 8, got %v", f)
+	}
+
+	if _, err := readFormat("sched", "sched_unknown"); err == nil {
+		t.Errorf("Expected an error of the unknown tracepoint")
+	}
+}
+
+func TestParseFormat(t *testing.T) {
+	cases := map[string]struct {
+		content   string
+		names     map[string]int
+		expect    map[string]field
+		expectErr bool
+	}{
+		"pid": {
+			content: "format:\n\tfield:pid_t pid;\toffset:24;\tsize:4;\tsigned:1;\n",
+			names:   map[string]int{"pid": 4},
+			expect:  map[string]field{"pid": {Offset: 24, Size: 4}},
+		},
+		"unchecked size": {
+			content: "\tfield:unsigned int prev_state;\toffset:32;\tsize:4;\tsigned:0;\n",
+			names:   map[string]int{"prev_state": 0},
+			expect:  map[string]field{"prev_state": {Offset: 32, Size: 4}},
+		},
+		"unexpected size": {
+			content:   "\tfield:pid_t pid;\toffset:24;\tsize:8;\tsigned:1;\n",
+			names:     map[string]int{"pid": 4},
+			expectErr: true,
+		},
+		"missing field": {
+			content:   "\tfield:pid_t pid;\toffset:24;\tsize:4;\tsigned:1;\n",
+			names:     map[string]int{"next_pid": 4},
+			expectErr: true,
+		},
+		"invalid offset": {
+			content:   "\tfield:pid_t pid;\toffset:abc;\tsize:4;\tsigned:1;\n",
+			names:     map[string]int{"pid": 4},
+			expectErr: true,
+		},
+		"missing offset": {
+			content:   "\tfield:pid_t pid;\tsize:4;\tsigned:1;\n",
+			names:     map[string]int{"pid": 4},
+			expectErr: true,
+		},
+	}
+
+	for k, v := range cases {
+		t.Run(k, func(t *testing.T) {
+			format, err := parseFormat(strings.NewReader(v.content))
+			var fields map[string]field
+			if err == nil {
+				fields, err = getFields(format, v.names)
+			}
+			if v.expectErr {
+				if err == nil {
+					t.Errorf("Expected an error, got %v", fields)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+			if !reflect.DeepEqual(fields, v.expect) {
+				t.Errorf("Expected %v, got %v", v.expect, fields)
+			}
+		})
+	}
+}
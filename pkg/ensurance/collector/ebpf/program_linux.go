@@ -0,0 +1,235 @@
+//go:build linux
+// +build linux
+
+package ebpf
+
+import (
+	"fmt"
+
+	"github.com/cilium/ebpf"
+	"github.com/cilium/ebpf/asm"
+)
+
+const (
+	// the offsets of the value of the stats map, see cgroupStats
+	latencyNsOffset   = histogramSlots * 8
+	countOffset       = latencyNsOffset + 8
+	involuntaryOffset = countOffset + 8
+	statsValueSize    = involuntaryOffset + 8
+
+	// taskStateMask is the mask of the sleeping states of prev_state, the task is preempted and still runnable
+	// if none of them is set
+	taskStateMask = 0xff
+	// maxLatencyUs is the max latency to compute the slot
+	maxLatencyUs = 0x7fffffff
+
+	// the stack of the sched_switch program
+	nowStackOffset     = -8
+	prevPidStackOffset = -12
+	nextPidStackOffset = -16
+	cgroupStackOffset  = -24
+	latencyStackOffset = -32
+	valueStackOffset   = latencyStackOffset - statsValueSize
+
+	bpfAny     = 0
+	bpfNoExist = 1
+)
+
+// maps are the maps shared by the programs:
+//
+//	wakeup:  pid -> the time in nanoseconds when the task is enqueued
+//	pending: pid -> the run queue latency of the running task, it is accounted to the cgroup of the task when the task
+//	         is switched out, since the cgroup of the next task is unknown in sched_switch without btf
+//	stats:   cgroup id -> cgroupStats
+type maps struct {
+	wakeup  *ebpf.Map
+	pending *ebpf.Map
+	stats   *ebpf.Map
+}
+
+// newMaps creates the maps shared by the programs.
+func newMaps() (maps, error) {
+	var m maps
+	for _, spec := range []struct {
+		m    **ebpf.Map
+		spec *ebpf.MapSpec
+	}{
+		{&m.wakeup, &ebpf.MapSpec{Name: "crane_wakeup", Type: ebpf.LRUHash, KeySize: 4, ValueSize: 8, MaxEntries: maxTasks}},
+		{&m.pending, &ebpf.MapSpec{Name: "crane_pending", Type: ebpf.LRUHash, KeySize: 4, ValueSize: 8, MaxEntries: maxTasks}},
+		{&m.stats, &ebpf.MapSpec{Name: "crane_stats", Type: ebpf.Hash, KeySize: 8, ValueSize: statsValueSize, MaxEntries: maxCgroups}},
+	} {
+		var err error
+		if *spec.m, err = ebpf.NewMap(spec.spec); err != nil {
+			m.close()
+			return maps{}, permissionError(fmt.Sprintf("failed to create map %s", spec.spec.Name), err)
+		}
+	}
+	return m, nil
+}
+
+func (m maps) close() {
+	for _, mm := range []*ebpf.Map{m.wakeup, m.pending, m.stats} {
+		if mm != nil {
+			mm.Close()
+		}
+	}
+}
+
+func mapCall(fn asm.BuiltinFunc, m *ebpf.Map, keyOffset, valueOffset int16, flags int32) asm.Instructions {
+	var insns = asm.Instructions{
+		asm.LoadMapPtr(asm.R1, m.FD()),
+		asm.Mov.Reg(asm.R2, asm.RFP),
+		asm.Add.Imm(asm.R2, int32(keyOffset)),
+	}
+	if fn == asm.FnMapUpdateElem {
+		insns = append(insns,
+			asm.Mov.Reg(asm.R3, asm.RFP),
+			asm.Add.Imm(asm.R3, int32(valueOffset)),
+			asm.Mov.Imm(asm.R4, flags),
+		)
+	}
+	return append(insns, fn.Call())
+}
+
+// xadd adds src to the dword at dst+offset atomically.
+func xadd(dst, src asm.Register, offset int16) asm.Instruction {
+	ins := asm.StoreXAdd(dst, src, asm.DWord)
+	ins.Offset = offset
+	return ins
+}
+
+func sizeOf(f field) asm.Size {
+	switch f.Size {
+	case 1:
+		return asm.Byte
+	case 2:
+		return asm.Half
+	case 4:
+		return asm.Word
+	default:
+		return asm.DWord
+	}
+}
+
+// wakeupProgram records the time when the task is woken up to the run queue, the pid is stored at the stack offset
+// of the previous task of the sched_switch program.
+func wakeupProgram(m maps, pid field) asm.Instructions {
+	var insns = asm.Instructions{
+		asm.Mov.Reg(asm.R6, asm.R1),
+		asm.LoadMem(asm.R7, asm.R6, int16(pid.Offset), asm.Word),
+		asm.StoreMem(asm.RFP, prevPidStackOffset, asm.R7, asm.Word),
+		asm.FnKtimeGetNs.Call(),
+		asm.StoreMem(asm.RFP, nowStackOffset, asm.R0, asm.DWord),
+	}
+	insns = append(insns, mapCall(asm.FnMapUpdateElem, m.wakeup, prevPidStackOffset, nowStackOffset, bpfAny)...)
+	return append(insns,
+		asm.Mov.Imm(asm.R0, 0),
+		asm.Return(),
+	)
+}
+
+// switchProgram accounts the run queue latency and the involuntary context switches to the cgroups:
+//
+//  1. the pending latency of the previous task is added to the stats of its cgroup, which is the current cgroup.
+//  2. the previous task is enqueued again if it is still runnable, which is an involuntary context switch.
+//  3. the latency of the next task is the time since it is enqueued, it is pending until the task is switched out.
+func switchProgram(m maps, prevPid, prevState, nextPid field) asm.Instructions {
+	var insns = asm.Instructions{
+		asm.Mov.Reg(asm.R6, asm.R1),
+		asm.FnKtimeGetNs.Call(),
+		asm.StoreMem(asm.RFP, nowStackOffset, asm.R0, asm.DWord),
+		asm.LoadMem(asm.R7, asm.R6, int16(nextPid.Offset), asm.Word),
+		asm.StoreMem(asm.RFP, nextPidStackOffset, asm.R7, asm.Word),
+		asm.LoadMem(asm.R7, asm.R6, int16(prevPid.Offset), asm.Word),
+		asm.StoreMem(asm.RFP, prevPidStackOffset, asm.R7, asm.Word),
+		// the idle task is not accounted
+		asm.JEq.Imm(asm.R7, 0, "next"),
+
+		asm.FnGetCurrentCgroupId.Call(),
+		asm.StoreMem(asm.RFP, cgroupStackOffset, asm.R0, asm.DWord),
+	}
+	insns = append(insns, mapCall(asm.FnMapLookupElem, m.stats, cgroupStackOffset, 0, 0)...)
+	insns = append(insns, asm.JNE.Imm(asm.R0, 0, "stats"))
+	for offset := int16(0); offset < statsValueSize; offset += 8 {
+		insns = append(insns, asm.StoreImm(asm.RFP, valueStackOffset+offset, 0, asm.DWord))
+	}
+	insns = append(insns, mapCall(asm.FnMapUpdateElem, m.stats, cgroupStackOffset, valueStackOffset, bpfNoExist)...)
+	insns = append(insns, mapCall(asm.FnMapLookupElem, m.stats, cgroupStackOffset, 0, 0)...)
+	insns = append(insns,
+		asm.JEq.Imm(asm.R0, 0, "next"),
+		asm.Mov.Reg(asm.R8, asm.R0).Sym("stats"),
+	)
+
+	// 1. the pending latency of the previous task
+	insns = append(insns, mapCall(asm.FnMapLookupElem, m.pending, prevPidStackOffset, 0, 0)...)
+	insns = append(insns,
+		asm.JEq.Imm(asm.R0, 0, "state"),
+		asm.LoadMem(asm.R7, asm.R0, 0, asm.DWord),
+	)
+	insns = append(insns, mapCall(asm.FnMapDeleteElem, m.pending, prevPidStackOffset, 0, 0)...)
+	insns = append(insns,
+		xadd(asm.R8, asm.R7, latencyNsOffset),
+		asm.Mov.Imm(asm.R1, 1),
+		xadd(asm.R8, asm.R1, countOffset),
+
+		// the slot is log2 of the latency in microseconds
+		asm.Div.Imm(asm.R7, 1000),
+		asm.JLE.Imm(asm.R7, maxLatencyUs, "log2"),
+		asm.Mov.Imm(asm.R7, maxLatencyUs),
+		asm.Mov.Imm(asm.R9, 0).Sym("log2"),
+	)
+	var shifts = []int32{16, 8, 4, 2, 1}
+	for i, shift := range shifts {
+		var skip = "slot"
+		if i+1 < len(shifts) {
+			skip = fmt.Sprintf("log2_%d", shifts[i+1])
+		}
+		jump := asm.JLE.Imm(asm.R7, int32(1)<<shift-1, skip)
+		if i > 0 {
+			jump = jump.Sym(fmt.Sprintf("log2_%d", shift))
+		}
+		insns = append(insns,
+			jump,
+			asm.RSh.Imm(asm.R7, shift),
+			asm.Add.Imm(asm.R9, shift),
+		)
+	}
+	insns = append(insns,
+		asm.And.Imm(asm.R9, histogramSlots-1).Sym("slot"),
+		asm.LSh.Imm(asm.R9, 3),
+		asm.Mov.Reg(asm.R1, asm.R8),
+		asm.Add.Reg(asm.R1, asm.R9),
+		asm.Mov.Imm(asm.R2, 1),
+		xadd(asm.R1, asm.R2, 0),
+
+		// 2. the involuntary context switch of the previous task
+		asm.LoadMem(asm.R7, asm.R6, int16(prevState.Offset), sizeOf(prevState)).Sym("state"),
+		asm.And.Imm(asm.R7, taskStateMask),
+		asm.JNE.Imm(asm.R7, 0, "next"),
+		asm.Mov.Imm(asm.R1, 1),
+		xadd(asm.R8, asm.R1, involuntaryOffset),
+	)
+	insns = append(insns, mapCall(asm.FnMapUpdateElem, m.wakeup, prevPidStackOffset, nowStackOffset, bpfAny)...)
+
+	// 3. the latency of the next task
+	insns = append(insns,
+		asm.LoadMem(asm.R7, asm.RFP, nextPidStackOffset, asm.Word).Sym("next"),
+		asm.JEq.Imm(asm.R7, 0, "exit"),
+	)
+	insns = append(insns, mapCall(asm.FnMapLookupElem, m.wakeup, nextPidStackOffset, 0, 0)...)
+	insns = append(insns,
+		asm.JEq.Imm(asm.R0, 0, "exit"),
+		asm.LoadMem(asm.R7, asm.R0, 0, asm.DWord),
+	)
+	insns = append(insns, mapCall(asm.FnMapDeleteElem, m.wakeup, nextPidStackOffset, 0, 0)...)
+	insns = append(insns,
+		asm.LoadMem(asm.R1, asm.RFP, nowStackOffset, asm.DWord),
+		asm.Sub.Reg(asm.R1, asm.R7),
+		asm.StoreMem(asm.RFP, latencyStackOffset, asm.R1, asm.DWord),
+	)
+	insns = append(insns, mapCall(asm.FnMapUpdateElem, m.pending, nextPidStackOffset, latencyStackOffset, bpfAny)...)
+	return append(insns,
+		asm.Mov.Imm(asm.R0, 0).Sym("exit"),
+		asm.Return(),
+	)
+}
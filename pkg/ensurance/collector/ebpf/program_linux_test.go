@@ -0,0 +1,57 @@
+//go:build linux
+// +build linux
+
+package ebpf
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/cilium/ebpf"
+	"github.com/cilium/ebpf/asm"
+	"golang.org/x/sys/unix"
+)
+
+// TestProgramsVerified loads the programs into the kernel, so that they are checked by the verifier. It is skipped
+// if the test is not privileged to create the maps and the programs.
+func TestProgramsVerified(t *testing.T) {
+	defer func(origin string) { tracefsRoot = origin }(tracefsRoot)
+	tracefsRoot = "testdata"
+
+	format, err := readFormat("sched", "sched_switch")
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	switchFields, err := getFields(format, map[string]int{"prev_pid": 4, "prev_state": 0, "next_pid": 4})
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	_ = unix.Setrlimit(unix.RLIMIT_MEMLOCK, &unix.Rlimit{Cur: unix.RLIM_INFINITY, Max: unix.RLIM_INFINITY})
+	m, err := newMaps()
+	if errors.Is(err, unix.EPERM) || errors.Is(err, unix.EACCES) || errors.Is(err, ebpf.ErrNotSupported) {
+		t.Skipf("CAP_BPF is required to load the programs: %v", err)
+	}
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	defer m.close()
+
+	cases := map[string]asm.Instructions{
+		"sched_wakeup": wakeupProgram(m, field{Offset: 24, Size: 4}),
+		"sched_switch": switchProgram(m, switchFields["prev_pid"], switchFields["prev_state"], switchFields["next_pid"]),
+	}
+
+	for k, v := range cases {
+		t.Run(k, func(t *testing.T) {
+			prog, err := ebpf.NewProgram(programSpec("crane_test", v))
+			if errors.Is(err, unix.EPERM) || errors.Is(err, unix.EACCES) {
+				t.Skipf("CAP_BPF and CAP_PERFMON are required to load the programs: %v", err)
+			}
+			if err != nil {
+				t.Fatalf("Expected the program to be verified, got %v", err)
+			}
+			prog.Close()
+		})
+	}
+}
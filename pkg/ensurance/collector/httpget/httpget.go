@@ -0,0 +1,349 @@
+package httpget
+
+import (
+	"context"
+	"crypto/tls"
+	"encoding/json"
+	"fmt"
+	"io"
+	"math"
+	"net"
+	"net/http"
+	"net/url"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	dto "github.com/prometheus/client_model/go"
+	"github.com/prometheus/common/expfmt"
+	v1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/util/intstr"
+	"k8s.io/klog/v2"
+
+	"github.com/gocrane/crane/pkg/common"
+	"github.com/gocrane/crane/pkg/ensurance/collector/types"
+)
+
+const (
+	defaultHost = "127.0.0.1"
+	// defaultTimeout is used when the timeout of the probe is not set, so that a hanging endpoint does not block the collection
+	defaultTimeout = 10 * time.Second
+	// maxResponseSize limits the size of the response body read from the endpoint
+	maxResponseSize = 10 << 20
+	// idleConnTimeout closes the connections to the endpoints which are not probed any more
+	idleConnTimeout = 90 * time.Second
+)
+
+// Probe is a node quality probe by http get, which is specified in the NodeQualityProbe of a NodeQOSEnsurancePolicy.
+type Probe struct {
+	HTTPGet *v1.HTTPGetAction
+	Timeout time.Duration
+}
+
+// HTTPGet collects the metrics exposed by http endpoints on the node, such as the business metrics of a sidecar.
+// The response is parsed as json if the content type is application/json, otherwise as the prometheus text format.
+//
+// The json response is either an object of the metric names and the values:
+//
+//	{"request_latency_ms": 12.5, "queue_depth": 30}
+//
+// or an array of the samples with labels:
+//
+//	[{"name": "request_latency_ms", "labels": {"path": "/api"}, "value": 12.5, "timestamp": 1650000000}]
+type HTTPGet struct {
+	name types.CollectType
+	// interval is the collect interval, the probes time out in it so that they do not delay the next collection
+	interval time.Duration
+	// transport is shared by all the probes, so the connections are reused and closed when idle
+	transport *http.Transport
+	client    *http.Client
+
+	mu     sync.Mutex
+	probes map[string]Probe
+}
+
+func NewHTTPGet(interval time.Duration) *HTTPGet {
+	transport := &http.Transport{
+		Proxy: http.ProxyFromEnvironment,
+		// like the http probes of kubelet, the certificate is not verified
+		TLSClientConfig:     &tls.Config{InsecureSkipVerify: true},
+		MaxIdleConnsPerHost: 1,
+		IdleConnTimeout:     idleConnTimeout,
+	}
+	return &HTTPGet{
+		name:      types.HTTPGetCollectorType,
+		interval:  interval,
+		transport: transport,
+		client:    &http.Client{Transport: transport},
+		probes:    make(map[string]Probe),
+	}
+}
+
+func (h *HTTPGet) GetType() types.CollectType {
+	return h.name
+}
+
+// SetProbes replaces the probes keyed by the name of the NodeQOSEnsurancePolicy.
+func (h *HTTPGet) SetProbes(probes map[string]Probe) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	h.probes = probes
+}
+
+func (h *HTTPGet) Collect() (map[string][]common.TimeSeries, error) {
+	h.mu.Lock()
+	var probes = make(map[string]Probe, len(h.probes))
+	for name, probe := range h.probes {
+		probes[name] = probe
+	}
+	h.mu.Unlock()
+
+	// the probes run concurrently, so a slow endpoint does not delay the others
+	var data = make(map[string][]common.TimeSeries)
+	var errs []string
+	var lock sync.Mutex
+	var wg sync.WaitGroup
+	for name, probe := range probes {
+		wg.Add(1)
+		go func(name string, probe Probe) {
+			defer wg.Done()
+
+			probeData, err := h.collectProbe(probe, time.Now())
+
+			lock.Lock()
+			defer lock.Unlock()
+			if err != nil {
+				klog.Errorf("Failed to probe %s of NodeQOS %s: %v", probe.HTTPGet.Path, name, err)
+				errs = append(errs, err.Error())
+				return
+			}
+			// the series are labeled by the policy, so the same metric exposed by the probes of different policies
+			// is told apart
+			for metricName, series := range probeData {
+				for i := range series {
+					series[i].Labels = withPolicyLabel(series[i].Labels, name)
+				}
+				data[metricName] = append(data[metricName], series...)
+			}
+		}(name, probe)
+	}
+	wg.Wait()
+
+	if len(data) == 0 && len(errs) != 0 {
+		return nil, fmt.Errorf("all the probes are failed: %s", strings.Join(errs, "; "))
+	}
+
+	return data, nil
+}
+
+func (h *HTTPGet) Stop() error {
+	h.transport.CloseIdleConnections()
+	return nil
+}
+
+// timeout returns the timeout of the probe, it is not longer than the collect interval.
+func (h *HTTPGet) timeout(probe Probe) time.Duration {
+	var timeout = probe.Timeout
+	if timeout <= 0 {
+		timeout = defaultTimeout
+	}
+	if h.interval > 0 && timeout > h.interval {
+		timeout = h.interval
+	}
+	return timeout
+}
+
+func (h *HTTPGet) collectProbe(probe Probe, now time.Time) (map[string][]common.TimeSeries, error) {
+	u, err := probeURL(probe.HTTPGet)
+	if err != nil {
+		return nil, err
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), h.timeout(probe))
+	defer cancel()
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, u.String(), nil)
+	if err != nil {
+		return nil, err
+	}
+	for _, header := range probe.HTTPGet.HTTPHeaders {
+		if header.Name == "Host" {
+			req.Host = header.Value
+		} else {
+			req.Header.Add(header.Name, header.Value)
+		}
+	}
+	if req.Header.Get("Accept") == "" {
+		req.Header.Set("Accept", "application/json, text/plain;q=0.9, */*;q=0.1")
+	}
+
+	resp, err := h.client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode < http.StatusOK || resp.StatusCode >= http.StatusBadRequest {
+		return nil, fmt.Errorf("unexpected status code %d", resp.StatusCode)
+	}
+
+	body, err := io.ReadAll(io.LimitReader(resp.Body, maxResponseSize))
+	if err != nil {
+		return nil, err
+	}
+
+	if strings.HasPrefix(resp.Header.Get("Content-Type"), "application/json") {
+		return ParseJSON(body, now)
+	}
+	return ParsePrometheusText(body, now)
+}
+
+func probeURL(httpGet *v1.HTTPGetAction) (*url.URL, error) {
+	if httpGet.Port.Type != intstr.Int || httpGet.Port.IntValue() <= 0 {
+		return nil, fmt.Errorf("port %s is not a port number", httpGet.Port.String())
+	}
+
+	var host = httpGet.Host
+	if host == "" {
+		host = defaultHost
+	}
+
+	var scheme = strings.ToLower(string(httpGet.Scheme))
+	if scheme == "" {
+		scheme = "http"
+	}
+
+	u, err := url.Parse(httpGet.Path)
+	if err != nil {
+		return nil, err
+	}
+	u.Scheme = scheme
+	u.Host = net.JoinHostPort(host, strconv.Itoa(httpGet.Port.IntValue()))
+	return u, nil
+}
+
+type jsonSample struct {
+	Name      string            `json:"name"`
+	Labels    map[string]string `json:"labels,omitempty"`
+	Value     float64           `json:"value"`
+	Timestamp int64             `json:"timestamp,omitempty"`
+}
+
+// ParseJSON parses the json response into the time series keyed by the metric name.
+func ParseJSON(body []byte, now time.Time) (map[string][]common.TimeSeries, error) {
+	var data = make(map[string][]common.TimeSeries)
+
+	var samples []jsonSample
+	if err := json.Unmarshal(body, &samples); err == nil {
+		for _, s := range samples {
+			if s.Name == "" {
+				return nil, fmt.Errorf("the name of the sample is empty")
+			}
+			var timestamp = s.Timestamp
+			if timestamp == 0 {
+				timestamp = now.Unix()
+			}
+			addSample(data, s.Name, labelsFromMap(s.Labels), s.Value, timestamp)
+		}
+		return data, nil
+	}
+
+	var values map[string]float64
+	if err := json.Unmarshal(body, &values); err != nil {
+		return nil, fmt.Errorf("failed to decode json metrics: %v", err)
+	}
+	for name, value := range values {
+		addSample(data, name, nil, value, now.Unix())
+	}
+
+	return data, nil
+}
+
+// ParsePrometheusText parses the prometheus text format into the time series keyed by the metric name.
+// The summaries and histograms are flattened as they are exposed, such as name{quantile="0.99"}, name_bucket{le="0.5"},
+// name_sum and name_count.
+func ParsePrometheusText(body []byte, now time.Time) (map[string][]common.TimeSeries, error) {
+	var parser expfmt.TextParser
+	families, err := parser.TextToMetricFamilies(strings.NewReader(string(body)))
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse prometheus metrics: %v", err)
+	}
+
+	var data = make(map[string][]common.TimeSeries)
+	for name, family := range families {
+		for _, m := range family.Metric {
+			var labels = make([]common.Label, 0, len(m.Label))
+			for _, l := range m.Label {
+				labels = append(labels, common.Label{Name: l.GetName(), Value: l.GetValue()})
+			}
+
+			var timestamp = now.Unix()
+			if m.TimestampMs != nil {
+				timestamp = m.GetTimestampMs() / 1000
+			}
+
+			switch family.GetType() {
+			case dto.MetricType_COUNTER:
+				addSample(data, name, labels, m.GetCounter().GetValue(), timestamp)
+			case dto.MetricType_GAUGE:
+				addSample(data, name, labels, m.GetGauge().GetValue(), timestamp)
+			case dto.MetricType_UNTYPED:
+				addSample(data, name, labels, m.GetUntyped().GetValue(), timestamp)
+			case dto.MetricType_SUMMARY:
+				for _, q := range m.GetSummary().Quantile {
+					addSample(data, name, withLabel(labels, "quantile", formatFloat(q.GetQuantile())), q.GetValue(), timestamp)
+				}
+				addSample(data, name+"_sum", labels, m.GetSummary().GetSampleSum(), timestamp)
+				addSample(data, name+"_count", labels, float64(m.GetSummary().GetSampleCount()), timestamp)
+			case dto.MetricType_HISTOGRAM:
+				for _, b := range m.GetHistogram().Bucket {
+					addSample(data, name+"_bucket", withLabel(labels, "le", formatFloat(b.GetUpperBound())), float64(b.GetCumulativeCount()), timestamp)
+				}
+				addSample(data, name+"_sum", labels, m.GetHistogram().GetSampleSum(), timestamp)
+				addSample(data, name+"_count", labels, float64(m.GetHistogram().GetSampleCount()), timestamp)
+			}
+		}
+	}
+
+	return data, nil
+}
+
+func addSample(data map[string][]common.TimeSeries, name string, labels []common.Label, value float64, timestamp int64) {
+	data[name] = append(data[name], common.TimeSeries{Labels: labels, Samples: []common.Sample{{Value: value, Timestamp: timestamp}}})
+}
+
+func labelsFromMap(m map[string]string) []common.Label {
+	var labels = make([]common.Label, 0, len(m))
+	for k, v := range m {
+		labels = append(labels, common.Label{Name: k, Value: v})
+	}
+	sort.Slice(labels, func(i, j int) bool { return labels[i].Name < labels[j].Name })
+	return labels
+}
+
+func withLabel(labels []common.Label, name, value string) []common.Label {
+	var result = make([]common.Label, 0, len(labels)+1)
+	result = append(result, labels...)
+	return append(result, common.Label{Name: name, Value: value})
+}
+
+// withPolicyLabel sets the label of the policy, it replaces the label of the same name exposed by the endpoint.
+func withPolicyLabel(labels []common.Label, policy string) []common.Label {
+	var result = make([]common.Label, 0, len(labels)+1)
+	for _, l := range labels {
+		if l.Name != common.LabelNameNodeQOS {
+			result = append(result, l)
+		}
+	}
+	return append(result, common.Label{Name: common.LabelNameNodeQOS, Value: policy})
+}
+
+func formatFloat(f float64) string {
+	if math.IsInf(f, 1) {
+		return "+Inf"
+	}
+	return strconv.FormatFloat(f, 'g', -1, 64)
+}
@@ -0,0 +1,194 @@
+package httpget
+
+import (
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"sync"
+	"testing"
+	"time"
+
+	v1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/util/intstr"
+
+	"github.com/gocrane/crane/pkg/common"
+)
+
+const prometheusText = `# HELP request_latency_ms The latency of requests.
+# TYPE request_latency_ms summary
+request_latency_ms{path="/api",quantile="0.99"} 120
+request_latency_ms_sum{path="/api"} 3000
+request_latency_ms_count{path="/api"} 100
+# TYPE queue_depth gauge
+queue_depth 30
+# TYPE requests_total counter
+requests_total{code="200"} 1000 1650000000000
+`
+
+func TestCollect(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("X-Token") != "token" {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		switch r.URL.Path {
+		case "/metrics":
+			w.Header().Set("Content-Type", "text/plain; version=0.0.4")
+			w.Write([]byte(prometheusText))
+		case "/json":
+			w.Header().Set("Content-Type", "application/json")
+			w.Write([]byte(`[{"name": "inflight_requests", "labels": {"path": "/api"}, "value": 8}]`))
+		case "/json-values":
+			w.Header().Set("Content-Type", "application/json; charset=utf-8")
+			w.Write([]byte(`{"cache_hit_ratio": 0.9}`))
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	defer server.Close()
+
+	host, portStr, _ := net.SplitHostPort(server.Listener.Addr().String())
+	port, _ := strconv.Atoi(portStr)
+	probe := func(path string) Probe {
+		return Probe{
+			HTTPGet: &v1.HTTPGetAction{
+				Host:        host,
+				Port:        intstr.FromInt(port),
+				Path:        path,
+				HTTPHeaders: []v1.HTTPHeader{{Name: "X-Token", Value: "token"}},
+			},
+			Timeout: time.Second,
+		}
+	}
+
+	h := NewHTTPGet(time.Second)
+	h.SetProbes(map[string]Probe{
+		"prometheus":  probe("/metrics"),
+		"json":        probe("/json"),
+		"json-values": probe("/json-values"),
+		"json-copy":   probe("/json"),
+		"not-found":   probe("/not-found"),
+	})
+
+	data, err := h.Collect()
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	cases := map[string]struct {
+		metricName  string
+		labels      map[string]string
+		expectValue float64
+	}{
+		"summary quantile":    {metricName: "request_latency_ms", labels: map[string]string{"path": "/api", "quantile": "0.99", common.LabelNameNodeQOS: "prometheus"}, expectValue: 120},
+		"summary count":       {metricName: "request_latency_ms_count", labels: map[string]string{"path": "/api", common.LabelNameNodeQOS: "prometheus"}, expectValue: 100},
+		"gauge":               {metricName: "queue_depth", labels: map[string]string{common.LabelNameNodeQOS: "prometheus"}, expectValue: 30},
+		"counter":             {metricName: "requests_total", labels: map[string]string{"code": "200", common.LabelNameNodeQOS: "prometheus"}, expectValue: 1000},
+		"json samples":        {metricName: "inflight_requests", labels: map[string]string{"path": "/api", common.LabelNameNodeQOS: "json"}, expectValue: 8},
+		"json samples copy":   {metricName: "inflight_requests", labels: map[string]string{"path": "/api", common.LabelNameNodeQOS: "json-copy"}, expectValue: 8},
+		"json metric values":  {metricName: "cache_hit_ratio", labels: map[string]string{common.LabelNameNodeQOS: "json-values"}, expectValue: 0.9},
+		"not found is missed": {metricName: "not_found"},
+	}
+
+	for k, v := range cases {
+		t.Run(k, func(t *testing.T) {
+			var found bool
+			for _, ts := range data[v.metricName] {
+				if !labelsEqual(ts.Labels, v.labels) {
+					continue
+				}
+				found = true
+				if ts.Samples[0].Value != v.expectValue {
+					t.Errorf("Expected value %v, got %v", v.expectValue, ts.Samples[0].Value)
+				}
+			}
+			if found != (v.expectValue != 0) {
+				t.Errorf("Expected series found %v, got %v", v.expectValue != 0, data[v.metricName])
+			}
+		})
+	}
+
+	if ts := data["requests_total"]; len(ts) != 1 || ts[0].Samples[0].Timestamp != 1650000000 {
+		t.Errorf("Expected the timestamp of the exposition, got %v", ts)
+	}
+}
+
+func TestCollectConcurrently(t *testing.T) {
+	var lock sync.Mutex
+	var conns int
+	server := httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/slow" {
+			time.Sleep(300 * time.Millisecond)
+		}
+		w.Header().Set("Content-Type", "application/json")
+		w.Write([]byte(`{"value": 1}`))
+	}))
+	server.Config.ConnState = func(_ net.Conn, state http.ConnState) {
+		if state == http.StateNew {
+			lock.Lock()
+			conns++
+			lock.Unlock()
+		}
+	}
+	server.Start()
+	defer server.Close()
+
+	host, portStr, _ := net.SplitHostPort(server.Listener.Addr().String())
+	port, _ := strconv.Atoi(portStr)
+	probe := func(path string) Probe {
+		return Probe{HTTPGet: &v1.HTTPGetAction{Host: host, Port: intstr.FromInt(port), Path: path}, Timeout: time.Second}
+	}
+
+	h := NewHTTPGet(time.Second)
+	defer h.Stop()
+	h.SetProbes(map[string]Probe{"slow1": probe("/slow"), "slow2": probe("/slow"), "slow3": probe("/slow")})
+
+	// the slow probes run concurrently
+	start := time.Now()
+	if _, err := h.Collect(); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if elapsed := time.Since(start); elapsed > 800*time.Millisecond {
+		t.Errorf("Expected the probes to run concurrently, but took %v", elapsed)
+	}
+
+	// the connections are reused by the next collections
+	h.SetProbes(map[string]Probe{"fast": probe("/fast")})
+	lock.Lock()
+	before := conns
+	lock.Unlock()
+	for i := 0; i < 3; i++ {
+		if _, err := h.Collect(); err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+	}
+	lock.Lock()
+	defer lock.Unlock()
+	if conns != before {
+		t.Errorf("Expected the idle connection to be reused, got %d new connections", conns-before)
+	}
+}
+
+func TestCollectAllFailed(t *testing.T) {
+	h := NewHTTPGet(time.Second)
+	h.SetProbes(map[string]Probe{
+		"named-port": {HTTPGet: &v1.HTTPGetAction{Port: intstr.FromString("http"), Path: "/metrics"}},
+	})
+
+	if _, err := h.Collect(); err == nil {
+		t.Errorf("Expected error when all the probes are failed")
+	}
+}
+
+func labelsEqual(labels []common.Label, expect map[string]string) bool {
+	if len(labels) != len(expect) {
+		return false
+	}
+	for _, l := range labels {
+		if expect[l.Name] != l.Value {
+			return false
+		}
+	}
+	return true
+}
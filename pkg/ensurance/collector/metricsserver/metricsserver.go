@@ -1,22 +1,61 @@
 package metricsserver
 
 import (
-	"sync"
+	"context"
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"time"
 
-	"github.com/gocrane/crane/pkg/common"
+	v1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	corelisters "k8s.io/client-go/listers/core/v1"
+	"k8s.io/client-go/rest"
+	"k8s.io/klog/v2"
+	metricsapi "k8s.io/metrics/pkg/apis/metrics/v1beta1"
 
+	"github.com/gocrane/crane/pkg/common"
 	"github.com/gocrane/crane/pkg/ensurance/collector/types"
+	"github.com/gocrane/crane/pkg/ensurance/extension"
+	"github.com/gocrane/crane/pkg/utils"
 )
 
+const (
+	defaultRequestTimeout = 10 * time.Second
+)
+
+var metricsServerMetrics = []types.MetricName{
+	types.MetricNameCpuTotalUsage,
+	types.MetricNameCpuTotalUtilization,
+	types.MetricNameMemoryTotalUsage,
+	types.MetricNameMemoryTotalUtilization,
+	types.MetricNameContainerCpuTotalUsage,
+	types.MetricNameExtResContainerCpuTotalUsage,
+}
+
+// MetricsServer collects the node and container metrics from the kubelet summary api or the resource
+// metrics api, for the nodes where cadvisor can not be accessed. It emits the same metrics as the
+// node local and cadvisor collectors: the cpu usage of the node is in milli cores, the memory usage is
+// the working set in bytes, and the cpu usage of the containers is in cores.
 type MetricsServer struct {
-	name        types.CollectType
-	statusCache sync.Map
+	name       types.CollectType
+	nodeName   string
+	source     extension.MetricsSource
+	client     rest.Interface
+	nodeLister corelisters.NodeLister
+	podLister  corelisters.PodLister
 }
 
-func NewMetricsServer() *MetricsServer {
+// NewMetricsServer returns the metrics server collector, the client is the rest client of the core api group,
+// which is used for the node proxy and the resource metrics api.
+func NewMetricsServer(nodeName string, source extension.MetricsSource, client rest.Interface, nodeLister corelisters.NodeLister, podLister corelisters.PodLister) *MetricsServer {
 	m := MetricsServer{
-		name:        types.MetricsServerCollectorType,
-		statusCache: sync.Map{},
+		name:       types.MetricsServerCollectorType,
+		nodeName:   nodeName,
+		source:     source,
+		client:     client,
+		nodeLister: nodeLister,
+		podLister:  podLister,
 	}
 	return &m
 }
@@ -25,10 +64,235 @@ func (m *MetricsServer) GetType() types.CollectType {
 	return m.name
 }
 
+func (m *MetricsServer) GetSource() extension.MetricsSource {
+	return m.source
+}
+
 func (m *MetricsServer) Collect() (map[string][]common.TimeSeries, error) {
-	return nil, nil
+	ctx, cancel := context.WithTimeout(context.Background(), defaultRequestTimeout)
+	defer cancel()
+
+	var usage *nodeUsage
+	var err error
+	switch m.source {
+	case extension.MetricsSourceMetricsAPI:
+		usage, err = m.getMetricsAPIUsage(ctx)
+	default:
+		usage, err = m.getKubeletSummaryUsage(ctx)
+	}
+	if err != nil {
+		klog.Errorf("Failed to get metrics from %s: %v", m.source, err)
+		return nil, err
+	}
+
+	return m.toStateMap(usage)
 }
 
 func (m *MetricsServer) Stop() error {
 	return nil
 }
+
+func CheckMetricNameExist(name string) bool {
+	for _, n := range metricsServerMetrics {
+		if string(n) == name {
+			return true
+		}
+	}
+
+	return false
+}
+
+// nodeUsage is the usage of the node and its containers read from the source.
+type nodeUsage struct {
+	// cpu usage in milli cores
+	cpu float64
+	// memory working set in bytes
+	memory    float64
+	timestamp time.Time
+
+	containers []containerUsage
+}
+
+type containerUsage struct {
+	podName      string
+	podNamespace string
+	name         string
+	// cpu usage in cores
+	cpu       float64
+	timestamp time.Time
+}
+
+// summary is the part of the kubelet summary api used by the collector.
+type summary struct {
+	Node struct {
+		CPU    *cpuStats    `json:"cpu,omitempty"`
+		Memory *memoryStats `json:"memory,omitempty"`
+	} `json:"node"`
+	Pods []struct {
+		PodRef struct {
+			Name      string `json:"name"`
+			Namespace string `json:"namespace"`
+		} `json:"podRef"`
+		Containers []struct {
+			Name string    `json:"name"`
+			CPU  *cpuStats `json:"cpu,omitempty"`
+		} `json:"containers"`
+	} `json:"pods"`
+}
+
+type cpuStats struct {
+	Time           metav1.Time `json:"time"`
+	UsageNanoCores *uint64     `json:"usageNanoCores,omitempty"`
+}
+
+type memoryStats struct {
+	Time            metav1.Time `json:"time"`
+	WorkingSetBytes *uint64     `json:"workingSetBytes,omitempty"`
+}
+
+func (m *MetricsServer) getKubeletSummaryUsage(ctx context.Context) (*nodeUsage, error) {
+	data, err := m.client.Get().AbsPath("/api/v1/nodes", m.nodeName, "proxy", "stats", "summary").DoRaw(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	var s summary
+	if err = json.Unmarshal(data, &s); err != nil {
+		return nil, fmt.Errorf("failed to decode kubelet summary: %v", err)
+	}
+
+	if s.Node.CPU == nil || s.Node.CPU.UsageNanoCores == nil || s.Node.Memory == nil || s.Node.Memory.WorkingSetBytes == nil {
+		return nil, fmt.Errorf("node usage is missing in kubelet summary")
+	}
+
+	var usage = &nodeUsage{
+		cpu:       float64(*s.Node.CPU.UsageNanoCores) / 1e6,
+		memory:    float64(*s.Node.Memory.WorkingSetBytes),
+		timestamp: s.Node.CPU.Time.Time,
+	}
+
+	for _, pod := range s.Pods {
+		for _, c := range pod.Containers {
+			if c.CPU == nil || c.CPU.UsageNanoCores == nil {
+				continue
+			}
+			usage.containers = append(usage.containers, containerUsage{
+				podName:      pod.PodRef.Name,
+				podNamespace: pod.PodRef.Namespace,
+				name:         c.Name,
+				cpu:          float64(*c.CPU.UsageNanoCores) / 1e9,
+				timestamp:    c.CPU.Time.Time,
+			})
+		}
+	}
+
+	return usage, nil
+}
+
+func (m *MetricsServer) getMetricsAPIUsage(ctx context.Context) (*nodeUsage, error) {
+	data, err := m.client.Get().AbsPath("/apis/metrics.k8s.io/v1beta1/nodes", m.nodeName).DoRaw(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	var nodeMetrics metricsapi.NodeMetrics
+	if err = json.Unmarshal(data, &nodeMetrics); err != nil {
+		return nil, fmt.Errorf("failed to decode node metrics: %v", err)
+	}
+
+	var usage = &nodeUsage{
+		cpu:       float64(nodeMetrics.Usage.Cpu().MilliValue()),
+		memory:    float64(nodeMetrics.Usage.Memory().Value()),
+		timestamp: nodeMetrics.Timestamp.Time,
+	}
+
+	data, err = m.client.Get().AbsPath("/apis/metrics.k8s.io/v1beta1/pods").
+		Param("fieldSelector", "spec.nodeName="+m.nodeName).DoRaw(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	var podMetricsList metricsapi.PodMetricsList
+	if err = json.Unmarshal(data, &podMetricsList); err != nil {
+		return nil, fmt.Errorf("failed to decode pod metrics: %v", err)
+	}
+
+	for _, pod := range podMetricsList.Items {
+		for _, c := range pod.Containers {
+			usage.containers = append(usage.containers, containerUsage{
+				podName:      pod.Name,
+				podNamespace: pod.Namespace,
+				name:         c.Name,
+				cpu:          float64(c.Usage.Cpu().MilliValue()) / 1000,
+				timestamp:    pod.Timestamp.Time,
+			})
+		}
+	}
+
+	return usage, nil
+}
+
+func (m *MetricsServer) toStateMap(usage *nodeUsage) (map[string][]common.TimeSeries, error) {
+	node, err := m.nodeLister.Get(m.nodeName)
+	if err != nil {
+		return nil, err
+	}
+
+	var stateMap = make(map[string][]common.TimeSeries)
+	var timestamp = sampleTime(usage.timestamp)
+
+	addSample(stateMap, types.MetricNameCpuTotalUsage, nil, usage.cpu, timestamp)
+	addSample(stateMap, types.MetricNameMemoryTotalUsage, nil, usage.memory, timestamp)
+	if capacity := node.Status.Capacity.Cpu(); !capacity.IsZero() {
+		addSample(stateMap, types.MetricNameCpuTotalUtilization, nil, usage.cpu/float64(capacity.MilliValue())*types.MaxPercentage, timestamp)
+	}
+	if capacity := node.Status.Capacity.Memory(); !capacity.IsZero() {
+		addSample(stateMap, types.MetricNameMemoryTotalUtilization, nil, usage.memory/float64(capacity.Value())*types.MaxPercentage, timestamp)
+	}
+
+	var extResCpuUse float64
+	for _, c := range usage.containers {
+		pod, err := m.podLister.Pods(c.podNamespace).Get(c.podName)
+		if err != nil {
+			// the pod is not on the node or has been deleted
+			klog.V(6).Infof("Skip the container %s of pod %s/%s: %v", c.name, c.podNamespace, c.podName, err)
+			continue
+		}
+
+		_, hasExtRes := utils.GetContainerExtCpuResFromPod(pod, c.name)
+		if hasExtRes {
+			extResCpuUse += c.cpu
+		}
+
+		addSample(stateMap, types.MetricNameContainerCpuTotalUsage, getContainerLabels(pod, c.name, hasExtRes), c.cpu, sampleTime(c.timestamp))
+	}
+	addSample(stateMap, types.MetricNameExtResContainerCpuTotalUsage, []common.Label{}, extResCpuUse, timestamp)
+
+	return stateMap, nil
+}
+
+func addSample(stateMap map[string][]common.TimeSeries, metricName types.MetricName, labels []common.Label, value float64, timestamp int64) {
+	stateMap[string(metricName)] = append(stateMap[string(metricName)], common.TimeSeries{
+		Labels:  labels,
+		Samples: []common.Sample{{Value: value, Timestamp: timestamp}},
+	})
+}
+
+func getContainerLabels(pod *v1.Pod, containerName string, hasExtRes bool) []common.Label {
+	return []common.Label{
+		{Name: common.LabelNamePodName, Value: pod.Name},
+		{Name: common.LabelNamePodNamespace, Value: pod.Namespace},
+		{Name: common.LabelNamePodUid, Value: string(pod.UID)},
+		{Name: common.LabelNameContainerName, Value: containerName},
+		{Name: common.LabelNameContainerId, Value: utils.GetContainerIdFromPod(pod, containerName)},
+		{Name: common.LabelNameHasExtRes, Value: strconv.FormatBool(hasExtRes)},
+	}
+}
+
+// sampleTime returns the unix time of the sample, the current time is used if it is not reported.
+func sampleTime(t time.Time) int64 {
+	if t.IsZero() {
+		return time.Now().Unix()
+	}
+	return t.Unix()
+}
@@ -0,0 +1,163 @@
+package metricsserver
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	v1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes"
+	corelisters "k8s.io/client-go/listers/core/v1"
+	"k8s.io/client-go/rest"
+	"k8s.io/client-go/tools/cache"
+
+	"github.com/gocrane/crane/pkg/common"
+	"github.com/gocrane/crane/pkg/ensurance/collector/types"
+	"github.com/gocrane/crane/pkg/ensurance/extension"
+)
+
+const (
+	summaryResponse = `{
+  "node": {
+    "nodeName": "node1",
+    "cpu": {"time": "2022-04-01T00:00:10Z", "usageNanoCores": 2000000000, "usageCoreNanoSeconds": 1000000000000},
+    "memory": {"time": "2022-04-01T00:00:10Z", "workingSetBytes": 4294967296}
+  },
+  "pods": [
+    {
+      "podRef": {"name": "pod1", "namespace": "default", "uid": "uid1"},
+      "containers": [{"name": "app", "cpu": {"time": "2022-04-01T00:00:08Z", "usageNanoCores": 500000000}}]
+    },
+    {
+      "podRef": {"name": "unknown", "namespace": "default", "uid": "uid2"},
+      "containers": [{"name": "app", "cpu": {"time": "2022-04-01T00:00:08Z", "usageNanoCores": 500000000}}]
+    }
+  ]
+}`
+
+	nodeMetricsResponse = `{
+  "kind": "NodeMetrics",
+  "apiVersion": "metrics.k8s.io/v1beta1",
+  "metadata": {"name": "node1"},
+  "timestamp": "2022-04-01T00:00:10Z",
+  "window": "30s",
+  "usage": {"cpu": "2", "memory": "4Gi"}
+}`
+
+	podMetricsResponse = `{
+  "kind": "PodMetricsList",
+  "apiVersion": "metrics.k8s.io/v1beta1",
+  "metadata": {},
+  "items": [
+    {
+      "metadata": {"name": "pod1", "namespace": "default"},
+      "timestamp": "2022-04-01T00:00:08Z",
+      "window": "30s",
+      "containers": [{"name": "app", "usage": {"cpu": "500m", "memory": "100Mi"}}]
+    }
+  ]
+}`
+)
+
+func TestCollect(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/api/v1/nodes/node1/proxy/stats/summary":
+			w.Write([]byte(summaryResponse))
+		case "/apis/metrics.k8s.io/v1beta1/nodes/node1":
+			w.Write([]byte(nodeMetricsResponse))
+		case "/apis/metrics.k8s.io/v1beta1/pods":
+			if r.URL.Query().Get("fieldSelector") != "spec.nodeName=node1" {
+				w.WriteHeader(http.StatusBadRequest)
+				return
+			}
+			w.Write([]byte(podMetricsResponse))
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	defer server.Close()
+
+	client, err := kubernetes.NewForConfig(&rest.Config{Host: server.URL})
+	if err != nil {
+		t.Fatalf("Failed to create client: %v", err)
+	}
+
+	nodeIndexer := cache.NewIndexer(cache.MetaNamespaceKeyFunc, cache.Indexers{})
+	nodeIndexer.Add(&v1.Node{
+		ObjectMeta: metav1.ObjectMeta{Name: "node1"},
+		Status: v1.NodeStatus{Capacity: v1.ResourceList{
+			v1.ResourceCPU:    resource.MustParse("4"),
+			v1.ResourceMemory: resource.MustParse("8Gi"),
+		}},
+	})
+	podIndexer := cache.NewIndexer(cache.MetaNamespaceKeyFunc, cache.Indexers{cache.NamespaceIndex: cache.MetaNamespaceIndexFunc})
+	podIndexer.Add(&v1.Pod{
+		ObjectMeta: metav1.ObjectMeta{Name: "pod1", Namespace: "default", UID: "uid1"},
+		Status: v1.PodStatus{ContainerStatuses: []v1.ContainerStatus{
+			{Name: "app", ContainerID: "docker://abc"},
+		}},
+	})
+
+	for _, source := range []extension.MetricsSource{extension.MetricsSourceKubeletSummary, extension.MetricsSourceMetricsAPI} {
+		t.Run(string(source), func(t *testing.T) {
+			m := NewMetricsServer("node1", source, client.CoreV1().RESTClient(), corelisters.NewNodeLister(nodeIndexer), corelisters.NewPodLister(podIndexer))
+			data, err := m.Collect()
+			if err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+
+			expects := map[types.MetricName]float64{
+				types.MetricNameCpuTotalUsage:          2000,
+				types.MetricNameCpuTotalUtilization:    50,
+				types.MetricNameMemoryTotalUsage:       4294967296,
+				types.MetricNameMemoryTotalUtilization: 50,
+			}
+			for name, expect := range expects {
+				series := data[string(name)]
+				if len(series) != 1 || series[0].Samples[0].Value != expect || series[0].Samples[0].Timestamp != 1648771210 {
+					t.Errorf("Expected %s %v at 1648771210, got %v", name, expect, series)
+				}
+			}
+
+			series := data[string(types.MetricNameContainerCpuTotalUsage)]
+			if len(series) != 1 {
+				t.Fatalf("Expected the container of the pod on the node only, got %v", series)
+			}
+			if series[0].Samples[0].Value != 0.5 || series[0].Samples[0].Timestamp != 1648771208 {
+				t.Errorf("Expected container cpu usage 0.5 at 1648771208, got %v", series[0].Samples)
+			}
+			if labelValue(series[0].Labels, common.LabelNameContainerId) != "abc" || labelValue(series[0].Labels, common.LabelNamePodUid) != "uid1" {
+				t.Errorf("Unexpected container labels %v", series[0].Labels)
+			}
+		})
+	}
+}
+
+func TestCollectError(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusForbidden)
+	}))
+	defer server.Close()
+
+	client, err := kubernetes.NewForConfig(&rest.Config{Host: server.URL})
+	if err != nil {
+		t.Fatalf("Failed to create client: %v", err)
+	}
+
+	m := NewMetricsServer("node1", extension.MetricsSourceKubeletSummary, client.CoreV1().RESTClient(), nil, nil)
+	if _, err := m.Collect(); err == nil {
+		t.Errorf("Expected error for forbidden response")
+	}
+}
+
+func labelValue(labels []common.Label, name string) string {
+	for _, l := range labels {
+		if l.Name == name {
+			return l.Value
+		}
+	}
+	return ""
+}
@@ -0,0 +1,25 @@
+package nodelocal
+
+import (
+	v1 "k8s.io/api/core/v1"
+
+	"github.com/gocrane/crane/pkg/common"
+)
+
+// procRoot is the mount point of proc, it is a variable for testing.
+var procRoot = "/proc"
+
+func getPodLabels(pod *v1.Pod) []common.Label {
+	return []common.Label{
+		{Name: common.LabelNamePodName, Value: pod.Name},
+		{Name: common.LabelNamePodNamespace, Value: pod.Namespace},
+		{Name: common.LabelNamePodUid, Value: string(pod.UID)},
+	}
+}
+
+func getContainerLabels(pod *v1.Pod, containerName, containerId string) []common.Label {
+	return append(getPodLabels(pod),
+		common.Label{Name: common.LabelNameContainerName, Value: containerName},
+		common.Label{Name: common.LabelNameContainerId, Value: containerId},
+	)
+}
@@ -4,6 +4,7 @@ import (
 	"strings"
 
 	"k8s.io/apimachinery/pkg/util/sets"
+	corelisters "k8s.io/client-go/listers/core/v1"
 	"k8s.io/klog/v2"
 	"k8s.io/kubernetes/pkg/kubelet/cm/cpuset"
 
@@ -14,6 +15,7 @@ import (
 type nodeLocalContext struct {
 	nodeState       *nodeState
 	exclusiveCPUSet func() cpuset.CPUSet
+	podLister       corelisters.PodLister
 }
 
 type collectFunc func(nodeLocalContext *nodeLocalContext) (map[string][]common.TimeSeries, error)
@@ -37,21 +39,26 @@ type nodeState struct {
 	latestDiskStates map[string]DiskState
 	ifaces           sets.String
 	latestNetStates  map[string]NetTimeStampState
+
+	latestPodDiskStates map[string]CgroupIOState
+	latestPodNetStates  map[string]PodNetState
 }
 
 type NodeLocal struct {
 	name            types.CollectType
 	nodeState       *nodeState
 	exclusiveCPUSet func() cpuset.CPUSet
+	podLister       corelisters.PodLister
 }
 
-func NewNodeLocal(ifaces []string, exclusiveCPUSet func() cpuset.CPUSet) *NodeLocal {
+func NewNodeLocal(ifaces []string, exclusiveCPUSet func() cpuset.CPUSet, podLister corelisters.PodLister) *NodeLocal {
 	klog.V(2).Infof("New NodeLocal collector on interfaces %v", ifaces)
 
 	n := NodeLocal{
 		name:            types.NodeLocalCollectorType,
 		nodeState:       &nodeState{ifaces: sets.NewString(ifaces...)},
 		exclusiveCPUSet: exclusiveCPUSet,
+		podLister:       podLister,
 	}
 
 	return &n
@@ -68,6 +75,7 @@ func (n *NodeLocal) Collect() (map[string][]common.TimeSeries, error) {
 	nodeLocalContext := &nodeLocalContext{
 		nodeState:       n.nodeState,
 		exclusiveCPUSet: n.exclusiveCPUSet,
+		podLister:       n.podLister,
 	}
 	for name, collect := range collectFuncMap {
 		if data, err := collect(nodeLocalContext); err == nil {
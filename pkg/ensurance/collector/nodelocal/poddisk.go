@@ -0,0 +1,230 @@
+package nodelocal
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/klog/v2"
+
+	"github.com/gocrane/crane/pkg/common"
+	"github.com/gocrane/crane/pkg/ensurance/cgroup"
+	"github.com/gocrane/crane/pkg/ensurance/collector/types"
+	"github.com/gocrane/crane/pkg/utils"
+)
+
+const (
+	podDiskioCollectorName = "poddiskio"
+
+	// cgroup v2
+	ioStatFile = "io.stat"
+	// cgroup v1
+	blkioSubsystem        = "blkio"
+	blkioServiceBytesFile = "blkio.throttle.io_service_bytes"
+	blkioServicedFile     = "blkio.throttle.io_serviced"
+	blkioRecursiveSuffix  = "_recursive"
+)
+
+func init() {
+	registerCollector(podDiskioCollectorName, []types.MetricName{types.MetricPodDiskReadKiBPS, types.MetricPodDiskWriteKiBPS, types.MetricPodDiskReadIOPS, types.MetricPodDiskWriteIOPS,
+		types.MetricContainerDiskReadKiBPS, types.MetricContainerDiskWriteKiBPS, types.MetricContainerDiskReadIOPS, types.MetricContainerDiskWriteIOPS}, collectPodDiskIO)
+}
+
+// CgroupIOStat is the io counters of a cgroup summed over all the devices.
+type CgroupIOStat struct {
+	ReadBytes  uint64
+	WriteBytes uint64
+	ReadCount  uint64
+	WriteCount uint64
+}
+
+type CgroupIOState struct {
+	stat      CgroupIOStat
+	timestamp time.Time
+}
+
+func collectPodDiskIO(nodeLocalContext *nodeLocalContext) (map[string][]common.TimeSeries, error) {
+	if nodeLocalContext.podLister == nil {
+		return nil, fmt.Errorf("pod lister is not set")
+	}
+
+	var now = time.Now()
+	nodeState := nodeLocalContext.nodeState
+
+	pods, err := nodeLocalContext.podLister.List(labels.Everything())
+	if err != nil {
+		return nil, err
+	}
+
+	var data = make(map[string][]common.TimeSeries, 8)
+	var currentStates = make(map[string]CgroupIOState)
+	addSeries := func(key string, stat CgroupIOStat, seriesLabels []common.Label, metricNames [4]types.MetricName) {
+		currentStates[key] = CgroupIOState{stat: stat, timestamp: now}
+		latest, ok := nodeState.latestPodDiskStates[key]
+		if !ok {
+			return
+		}
+
+		usage := calculateCgroupIO(latest, currentStates[key])
+		for i, value := range []float64{usage.DiskReadKiBps, usage.DiskWriteKiBps, usage.DiskReadIOps, usage.DiskWriteIOps} {
+			data[string(metricNames[i])] = append(data[string(metricNames[i])], common.TimeSeries{Labels: seriesLabels, Samples: []common.Sample{{Value: value, Timestamp: now.Unix()}}})
+		}
+	}
+
+	for _, pod := range pods {
+		podDir, err := cgroup.PodDir(pod, blkioSubsystem)
+		if err != nil {
+			continue
+		}
+
+		stat, err := readCgroupIOStat(podDir)
+		if err != nil {
+			klog.V(6).Infof("Failed to read io stat of pod %s: %v", klog.KObj(pod), err)
+			continue
+		}
+		addSeries(string(pod.UID), stat, getPodLabels(pod),
+			[4]types.MetricName{types.MetricPodDiskReadKiBPS, types.MetricPodDiskWriteKiBPS, types.MetricPodDiskReadIOPS, types.MetricPodDiskWriteIOPS})
+
+		for _, container := range pod.Spec.Containers {
+			containerId := utils.GetContainerIdFromPod(pod, container.Name)
+			containerDir, err := cgroup.ContainerDir(podDir, containerId)
+			if err != nil {
+				continue
+			}
+
+			stat, err := readCgroupIOStat(containerDir)
+			if err != nil {
+				continue
+			}
+			addSeries(containerId, stat, getContainerLabels(pod, container.Name, containerId),
+				[4]types.MetricName{types.MetricContainerDiskReadKiBPS, types.MetricContainerDiskWriteKiBPS, types.MetricContainerDiskReadIOPS, types.MetricContainerDiskWriteIOPS})
+		}
+	}
+
+	nodeState.latestPodDiskStates = currentStates
+
+	return data, nil
+}
+
+// calculateCgroupIO calculate the disk io usage of a cgroup, the counters are reset if the cgroup is recreated
+func calculateCgroupIO(stat1 CgroupIOState, stat2 CgroupIOState) DiskIOUsage {
+	duration := stat2.timestamp.Sub(stat1.timestamp).Seconds()
+	if duration <= 0 {
+		return DiskIOUsage{}
+	}
+
+	return DiskIOUsage{
+		DiskReadKiBps:  float64(counterDelta(stat1.stat.ReadBytes, stat2.stat.ReadBytes)) / types.UintConversionStep1024 / duration,
+		DiskWriteKiBps: float64(counterDelta(stat1.stat.WriteBytes, stat2.stat.WriteBytes)) / types.UintConversionStep1024 / duration,
+		DiskReadIOps:   float64(counterDelta(stat1.stat.ReadCount, stat2.stat.ReadCount)) / duration,
+		DiskWriteIOps:  float64(counterDelta(stat1.stat.WriteCount, stat2.stat.WriteCount)) / duration,
+	}
+}
+
+func counterDelta(old, new uint64) uint64 {
+	if new < old {
+		return 0
+	}
+	return new - old
+}
+
+// readCgroupIOStat reads the io stat of the cgroup directory, io.stat for cgroup v2 and blkio.throttle.* for cgroup v1.
+func readCgroupIOStat(dir string) (CgroupIOStat, error) {
+	if _, err := os.Stat(filepath.Join(dir, ioStatFile)); err == nil {
+		return readIOStat(filepath.Join(dir, ioStatFile))
+	}
+
+	var stat CgroupIOStat
+	var err error
+	if stat.ReadBytes, stat.WriteBytes, err = readBlkioFile(dir, blkioServiceBytesFile); err != nil {
+		return stat, err
+	}
+	if stat.ReadCount, stat.WriteCount, err = readBlkioFile(dir, blkioServicedFile); err != nil {
+		return stat, err
+	}
+	return stat, nil
+}
+
+// readIOStat parses io.stat of cgroup v2, such as:
+//
+//	8:0 rbytes=90112 wbytes=0 rios=3 wios=0 dbytes=0 dios=0
+func readIOStat(file string) (CgroupIOStat, error) {
+	var stat CgroupIOStat
+
+	f, err := os.Open(file)
+	if err != nil {
+		return stat, err
+	}
+	defer f.Close()
+
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		fields := strings.Fields(scanner.Text())
+		if len(fields) < 2 {
+			continue
+		}
+		for _, field := range fields[1:] {
+			kv := strings.SplitN(field, "=", 2)
+			if len(kv) != 2 {
+				continue
+			}
+			value, err := strconv.ParseUint(kv[1], 10, 64)
+			if err != nil {
+				continue
+			}
+			switch kv[0] {
+			case "rbytes":
+				stat.ReadBytes += value
+			case "wbytes":
+				stat.WriteBytes += value
+			case "rios":
+				stat.ReadCount += value
+			case "wios":
+				stat.WriteCount += value
+			}
+		}
+	}
+
+	return stat, scanner.Err()
+}
+
+// readBlkioFile parses the blkio.throttle.* files of cgroup v1, the recursive file is preferred, such as:
+//
+//	8:0 Read 90112
+//	8:0 Write 0
+//	Total 90112
+func readBlkioFile(dir string, name string) (uint64, uint64, error) {
+	f, err := os.Open(filepath.Join(dir, name+blkioRecursiveSuffix))
+	if err != nil {
+		if f, err = os.Open(filepath.Join(dir, name)); err != nil {
+			return 0, 0, err
+		}
+	}
+	defer f.Close()
+
+	var read, write uint64
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		fields := strings.Fields(scanner.Text())
+		if len(fields) != 3 {
+			continue
+		}
+		value, err := strconv.ParseUint(fields[2], 10, 64)
+		if err != nil {
+			continue
+		}
+		switch fields[1] {
+		case "Read":
+			read += value
+		case "Write":
+			write += value
+		}
+	}
+
+	return read, write, scanner.Err()
+}
@@ -0,0 +1,156 @@
+package nodelocal
+
+import (
+	"math"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	v1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	corelisters "k8s.io/client-go/listers/core/v1"
+	"k8s.io/client-go/tools/cache"
+
+	"github.com/gocrane/crane/pkg/ensurance/cgroup"
+	"github.com/gocrane/crane/pkg/ensurance/collector/types"
+)
+
+func writeFile(t *testing.T, file string, content string) {
+	if err := os.MkdirAll(filepath.Dir(file), 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(file, []byte(content), 0644); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestReadCgroupIOStat(t *testing.T) {
+	cases := map[string]struct {
+		files  map[string]string
+		expect CgroupIOStat
+	}{
+		"cgroup v2": {
+			files: map[string]string{
+				ioStatFile: "8:0 rbytes=1024 wbytes=2048 rios=1 wios=2 dbytes=0 dios=0\n8:16 rbytes=1024 wbytes=0 rios=3 wios=0 dbytes=0 dios=0\n",
+			},
+			expect: CgroupIOStat{ReadBytes: 2048, WriteBytes: 2048, ReadCount: 4, WriteCount: 2},
+		},
+		"cgroup v1": {
+			files: map[string]string{
+				blkioServiceBytesFile: "8:0 Read 1024\n8:0 Write 2048\n8:0 Total 3072\nTotal 3072\n",
+				blkioServicedFile:     "8:0 Read 1\n8:0 Write 2\n8:0 Total 3\nTotal 3\n",
+			},
+			expect: CgroupIOStat{ReadBytes: 1024, WriteBytes: 2048, ReadCount: 1, WriteCount: 2},
+		},
+		"cgroup v1 recursive is preferred": {
+			files: map[string]string{
+				blkioServiceBytesFile:                        "8:0 Read 1\n8:0 Write 1\n",
+				blkioServiceBytesFile + blkioRecursiveSuffix: "8:0 Read 4096\n8:0 Write 8192\n",
+				blkioServicedFile + blkioRecursiveSuffix:     "8:0 Read 4\n8:0 Write 8\n",
+			},
+			expect: CgroupIOStat{ReadBytes: 4096, WriteBytes: 8192, ReadCount: 4, WriteCount: 8},
+		},
+	}
+
+	for k, v := range cases {
+		t.Run(k, func(t *testing.T) {
+			dir := t.TempDir()
+			for name, content := range v.files {
+				writeFile(t, filepath.Join(dir, name), content)
+			}
+
+			stat, err := readCgroupIOStat(dir)
+			if err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+			if stat != v.expect {
+				t.Errorf("Expected %+v, got %+v", v.expect, stat)
+			}
+		})
+	}
+}
+
+func TestReadNetDev(t *testing.T) {
+	file := filepath.Join(t.TempDir(), "dev")
+	writeFile(t, file, `Inter-|   Receive                                                |  Transmit
+ face |bytes    packets errs drop fifo frame compressed multicast|bytes    packets errs drop fifo colls carrier compressed
+    lo:  9999      99    0    0    0     0          0         0     9999      99    0    0    0     0       0          0
+  eth0:  1296      16    0    1    0     0          0         0     1180      14    0    2    0     0       0          0
+  eth1:   704       4    0    0    0     0          0         0      820       6    0    0    0     0       0          0
+`)
+
+	stat, err := readNetDev(file)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	expect := PodNetStat{BytesRecv: 2000, PacketsRecv: 20, DropIn: 1, BytesSent: 2000, PacketsSent: 20, DropOut: 2}
+	if stat != expect {
+		t.Errorf("Expected %+v, got %+v", expect, stat)
+	}
+}
+
+func TestCollectPodDiskIO(t *testing.T) {
+	root := t.TempDir()
+	defer func(origin string) { cgroup.Root = origin }(cgroup.Root)
+	cgroup.Root = root
+
+	pod := &v1.Pod{
+		ObjectMeta: metav1.ObjectMeta{Name: "pod1", Namespace: "default", UID: "uid1"},
+		Spec:       v1.PodSpec{Containers: []v1.Container{{Name: "app"}}},
+		Status: v1.PodStatus{
+			QOSClass:          v1.PodQOSBestEffort,
+			ContainerStatuses: []v1.ContainerStatus{{Name: "app", ContainerID: "containerd://abc"}},
+		},
+	}
+	indexer := cache.NewIndexer(cache.MetaNamespaceKeyFunc, cache.Indexers{})
+	indexer.Add(pod)
+
+	writeFile(t, filepath.Join(root, cgroup.ControllersFile), "cpu io memory\n")
+	podDir := filepath.Join(root, "kubepods", "besteffort", "poduid1")
+	containerDir := filepath.Join(podDir, "cri-containerd-abc.scope")
+	writeFile(t, filepath.Join(podDir, ioStatFile), "8:0 rbytes=0 wbytes=0 rios=0 wios=0\n")
+	writeFile(t, filepath.Join(containerDir, ioStatFile), "8:0 rbytes=0 wbytes=0 rios=0 wios=0\n")
+
+	ctx := &nodeLocalContext{nodeState: &nodeState{}, podLister: corelisters.NewPodLister(indexer)}
+	data, err := collectPodDiskIO(ctx)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if len(data) != 0 {
+		t.Errorf("Expected no data for the first collection, got %v", data)
+	}
+
+	// move the latest states back for one second
+	for k, state := range ctx.nodeState.latestPodDiskStates {
+		state.timestamp = state.timestamp.Add(-time.Second)
+		ctx.nodeState.latestPodDiskStates[k] = state
+	}
+	writeFile(t, filepath.Join(podDir, ioStatFile), "8:0 rbytes=4096 wbytes=2048 rios=4 wios=2\n")
+	writeFile(t, filepath.Join(containerDir, ioStatFile), "8:0 rbytes=1024 wbytes=0 rios=1 wios=0\n")
+
+	data, err = collectPodDiskIO(ctx)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	cases := map[types.MetricName]float64{
+		types.MetricPodDiskReadKiBPS:        4,
+		types.MetricPodDiskWriteKiBPS:       2,
+		types.MetricPodDiskReadIOPS:         4,
+		types.MetricPodDiskWriteIOPS:        2,
+		types.MetricContainerDiskReadKiBPS:  1,
+		types.MetricContainerDiskWriteKiBPS: 0,
+	}
+	for metricName, expect := range cases {
+		series := data[string(metricName)]
+		if len(series) != 1 || len(series[0].Samples) != 1 {
+			t.Fatalf("Expected one series for %s, got %v", metricName, series)
+		}
+		// the real duration is a little more than one second
+		if value := series[0].Samples[0].Value; math.Abs(value-expect) > 0.01 {
+			t.Errorf("Expected %s %v, got %v", metricName, expect, value)
+		}
+	}
+}
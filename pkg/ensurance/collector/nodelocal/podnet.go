@@ -0,0 +1,183 @@
+package nodelocal
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/klog/v2"
+
+	"github.com/gocrane/crane/pkg/common"
+	"github.com/gocrane/crane/pkg/ensurance/cgroup"
+	"github.com/gocrane/crane/pkg/ensurance/collector/types"
+)
+
+const (
+	podNetioCollectorName = "podnetio"
+	// the processes of the pod are found in the memory subsystem for cgroup v1
+	memorySubsystem = "memory"
+	loopbackIface   = "lo"
+)
+
+func init() {
+	registerCollector(podNetioCollectorName, []types.MetricName{types.MetricPodNetworkReceiveKiBPS, types.MetricPodNetworkSentKiBPS, types.MetricPodNetworkReceivePckPS,
+		types.MetricPodNetworkSentPckPS, types.MetricPodNetworkDropIn, types.MetricPodNetworkDropOut}, collectPodNetIO)
+}
+
+// PodNetStat is the network counters of the pod network namespace summed over all the interfaces except loopback.
+type PodNetStat struct {
+	BytesRecv   uint64
+	PacketsRecv uint64
+	DropIn      uint64
+	BytesSent   uint64
+	PacketsSent uint64
+	DropOut     uint64
+}
+
+type PodNetState struct {
+	stat      PodNetStat
+	timestamp time.Time
+}
+
+// collectPodNetIO collects the network usage of the pods by /proc/<pid>/net/dev of a process in the pod,
+// the pods in the host network are skipped.
+func collectPodNetIO(nodeLocalContext *nodeLocalContext) (map[string][]common.TimeSeries, error) {
+	if nodeLocalContext.podLister == nil {
+		return nil, fmt.Errorf("pod lister is not set")
+	}
+
+	var now = time.Now()
+	nodeState := nodeLocalContext.nodeState
+
+	pods, err := nodeLocalContext.podLister.List(labels.Everything())
+	if err != nil {
+		return nil, err
+	}
+
+	var netReceiveKiBpsTimeSeries []common.TimeSeries
+	var netSentKiBpsTimeSeries []common.TimeSeries
+	var netReceivePckpsTimeSeries []common.TimeSeries
+	var netSentPckpsTimeSeries []common.TimeSeries
+	var netDropInTimeSeries []common.TimeSeries
+	var netDropOutTimeSeries []common.TimeSeries
+
+	var currentStates = make(map[string]PodNetState)
+	for _, pod := range pods {
+		if pod.Spec.HostNetwork {
+			continue
+		}
+
+		podDir, err := cgroup.PodDir(pod, memorySubsystem)
+		if err != nil {
+			continue
+		}
+
+		pid, err := cgroup.FirstProcess(podDir)
+		if err != nil {
+			klog.V(6).Infof("Failed to find the process of pod %s: %v", klog.KObj(pod), err)
+			continue
+		}
+
+		stat, err := readNetDev(filepath.Join(procRoot, strconv.Itoa(pid), "net", "dev"))
+		if err != nil {
+			klog.V(6).Infof("Failed to read net dev of pod %s: %v", klog.KObj(pod), err)
+			continue
+		}
+
+		key := string(pod.UID)
+		currentStates[key] = PodNetState{stat: stat, timestamp: now}
+		if latest, ok := nodeState.latestPodNetStates[key]; ok {
+			usage := calculatePodNetIO(latest, currentStates[key])
+			podLabels := getPodLabels(pod)
+			netReceiveKiBpsTimeSeries = append(netReceiveKiBpsTimeSeries, common.TimeSeries{Labels: podLabels, Samples: []common.Sample{{Value: usage.ReceiveKibps, Timestamp: now.Unix()}}})
+			netSentKiBpsTimeSeries = append(netSentKiBpsTimeSeries, common.TimeSeries{Labels: podLabels, Samples: []common.Sample{{Value: usage.SentKibps, Timestamp: now.Unix()}}})
+			netReceivePckpsTimeSeries = append(netReceivePckpsTimeSeries, common.TimeSeries{Labels: podLabels, Samples: []common.Sample{{Value: usage.ReceivePckps, Timestamp: now.Unix()}}})
+			netSentPckpsTimeSeries = append(netSentPckpsTimeSeries, common.TimeSeries{Labels: podLabels, Samples: []common.Sample{{Value: usage.SentPckps, Timestamp: now.Unix()}}})
+			netDropInTimeSeries = append(netDropInTimeSeries, common.TimeSeries{Labels: podLabels, Samples: []common.Sample{{Value: usage.DropIn, Timestamp: now.Unix()}}})
+			netDropOutTimeSeries = append(netDropOutTimeSeries, common.TimeSeries{Labels: podLabels, Samples: []common.Sample{{Value: usage.DropOut, Timestamp: now.Unix()}}})
+		}
+	}
+
+	nodeState.latestPodNetStates = currentStates
+
+	var data = make(map[string][]common.TimeSeries, 6)
+	data[string(types.MetricPodNetworkReceiveKiBPS)] = netReceiveKiBpsTimeSeries
+	data[string(types.MetricPodNetworkSentKiBPS)] = netSentKiBpsTimeSeries
+	data[string(types.MetricPodNetworkReceivePckPS)] = netReceivePckpsTimeSeries
+	data[string(types.MetricPodNetworkSentPckPS)] = netSentPckpsTimeSeries
+	data[string(types.MetricPodNetworkDropIn)] = netDropInTimeSeries
+	data[string(types.MetricPodNetworkDropOut)] = netDropOutTimeSeries
+
+	return data, nil
+}
+
+// calculatePodNetIO calculate the network usage of a pod, in the same units as the node network usage
+func calculatePodNetIO(stat1 PodNetState, stat2 PodNetState) NetInterfaceUsage {
+	duration := stat2.timestamp.Sub(stat1.timestamp).Seconds()
+	if duration <= 0 {
+		return NetInterfaceUsage{}
+	}
+
+	return NetInterfaceUsage{
+		ReceiveKibps: float64(counterDelta(stat1.stat.BytesRecv, stat2.stat.BytesRecv)) * 8 / 1000 / duration,
+		SentKibps:    float64(counterDelta(stat1.stat.BytesSent, stat2.stat.BytesSent)) * 8 / 1000 / duration,
+		ReceivePckps: float64(counterDelta(stat1.stat.PacketsRecv, stat2.stat.PacketsRecv)) / duration,
+		SentPckps:    float64(counterDelta(stat1.stat.PacketsSent, stat2.stat.PacketsSent)) / duration,
+		DropIn:       float64(counterDelta(stat1.stat.DropIn, stat2.stat.DropIn)) / duration,
+		DropOut:      float64(counterDelta(stat1.stat.DropOut, stat2.stat.DropOut)) / duration,
+	}
+}
+
+// readNetDev parses /proc/<pid>/net/dev, such as:
+//
+//	Inter-|   Receive                                                |  Transmit
+//	 face |bytes    packets errs drop fifo frame compressed multicast|bytes    packets errs drop fifo colls carrier compressed
+//	  eth0:  1296      16    0    0    0     0          0         0     1180      14    0    0    0     0       0          0
+func readNetDev(file string) (PodNetStat, error) {
+	var stat PodNetStat
+
+	f, err := os.Open(file)
+	if err != nil {
+		return stat, err
+	}
+	defer f.Close()
+
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		parts := strings.SplitN(scanner.Text(), ":", 2)
+		if len(parts) != 2 {
+			continue
+		}
+
+		iface := strings.TrimSpace(parts[0])
+		if iface == loopbackIface {
+			continue
+		}
+
+		fields := strings.Fields(parts[1])
+		if len(fields) < 12 {
+			continue
+		}
+
+		var values [12]uint64
+		for i := range values {
+			if values[i], err = strconv.ParseUint(fields[i], 10, 64); err != nil {
+				return stat, fmt.Errorf("failed to parse %s of interface %s: %v", file, iface, err)
+			}
+		}
+
+		stat.BytesRecv += values[0]
+		stat.PacketsRecv += values[1]
+		stat.DropIn += values[3]
+		stat.BytesSent += values[8]
+		stat.PacketsSent += values[9]
+		stat.DropOut += values[11]
+	}
+
+	return stat, scanner.Err()
+}
@@ -0,0 +1,49 @@
+package nodelocal
+
+import (
+	"os"
+	"path/filepath"
+	"time"
+
+	"k8s.io/klog/v2"
+
+	"github.com/gocrane/crane/pkg/common"
+	"github.com/gocrane/crane/pkg/ensurance/collector/psi"
+	"github.com/gocrane/crane/pkg/ensurance/collector/types"
+)
+
+const (
+	psiCollectorName = "psi"
+
+	pressureDir = "pressure"
+)
+
+func init() {
+	var metricNames []types.MetricName
+	for _, resource := range psi.Resources {
+		metricNames = append(metricNames, types.NodePressureMetricNames[resource].Names()...)
+	}
+	registerCollector(psiCollectorName, metricNames, collectPSI)
+}
+
+// collectPSI reads the pressure stall information of the node in /proc/pressure, the resources are skipped if the
+// kernel does not support it.
+func collectPSI(_ *nodeLocalContext) (map[string][]common.TimeSeries, error) {
+	var now = time.Now()
+	var data = make(map[string][]common.TimeSeries)
+
+	for _, resource := range psi.Resources {
+		stats, err := psi.ReadFile(filepath.Join(procRoot, pressureDir, resource))
+		if err != nil {
+			if os.IsNotExist(err) {
+				klog.V(6).Infof("Pressure stall information of %s is not supported: %v", resource, err)
+				continue
+			}
+			return nil, err
+		}
+
+		psi.AddSeries(data, types.NodePressureMetricNames[resource], stats, nil, now.Unix())
+	}
+
+	return data, nil
+}
@@ -0,0 +1,44 @@
+package nodelocal
+
+import (
+	"path/filepath"
+	"testing"
+
+	"github.com/gocrane/crane/pkg/ensurance/collector/types"
+)
+
+func TestCollectPSI(t *testing.T) {
+	root := t.TempDir()
+	defer func(origin string) { procRoot = origin }(procRoot)
+	procRoot = root
+
+	writeFile(t, filepath.Join(root, pressureDir, "cpu"), "some avg10=12.50 avg60=8.00 avg300=2.00 total=4000\n")
+	writeFile(t, filepath.Join(root, pressureDir, "io"), "some avg10=1.00 avg60=0.50 avg300=0.10 total=300\nfull avg10=0.80 avg60=0.40 avg300=0.05 total=200\n")
+
+	data, err := collectPSI(&nodeLocalContext{})
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	expect := map[types.MetricName]float64{
+		types.MetricNameCpuPressureSomeAvg10: 12.5,
+		types.MetricNameCpuPressureSomeAvg60: 8,
+		types.MetricNameCpuPressureSomeTotal: 4000,
+		types.MetricNameIOPressureSomeAvg10:  1,
+		types.MetricNameIOPressureSomeAvg60:  0.5,
+		types.MetricNameIOPressureSomeTotal:  300,
+		types.MetricNameIOPressureFullAvg10:  0.8,
+		types.MetricNameIOPressureFullAvg60:  0.4,
+		types.MetricNameIOPressureFullTotal:  200,
+	}
+	// the memory pressure is not supported
+	if len(data) != len(expect) {
+		t.Errorf("Expected %d metrics, got %d: %v", len(expect), len(data), data)
+	}
+	for name, value := range expect {
+		series := data[string(name)]
+		if len(series) != 1 || series[0].Samples[0].Value != value {
+			t.Errorf("Expected %s to be %v, got %v", name, value, series)
+		}
+	}
+}
@@ -0,0 +1,118 @@
+package psi
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+
+	"github.com/gocrane/crane/pkg/common"
+	"github.com/gocrane/crane/pkg/ensurance/collector/types"
+)
+
+// Resources are the resources of the pressure stall information, the files are /proc/pressure/<resource> for the node
+// and <resource>.pressure in the cgroup directories on cgroup v2.
+var Resources = []string{"cpu", "memory", "io"}
+
+// Pressure is a line of the pressure file.
+type Pressure struct {
+	Avg10  float64
+	Avg60  float64
+	Avg300 float64
+	// Total is the accumulated stall time in microseconds
+	Total uint64
+}
+
+// Stats is the pressure stall information of a resource, the full is nil if it is not reported, such as the cpu of
+// the node before linux 5.13.
+type Stats struct {
+	Some Pressure
+	Full *Pressure
+}
+
+// ReadFile parses the pressure file, such as:
+//
+//	some avg10=0.00 avg60=0.00 avg300=0.00 total=0
+//	full avg10=0.00 avg60=0.00 avg300=0.00 total=0
+func ReadFile(file string) (Stats, error) {
+	var stats Stats
+
+	f, err := os.Open(file)
+	if err != nil {
+		return stats, err
+	}
+	defer f.Close()
+
+	var hasSome bool
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		fields := strings.Fields(scanner.Text())
+		if len(fields) == 0 {
+			continue
+		}
+
+		pressure, err := parsePressure(fields[1:])
+		if err != nil {
+			return stats, fmt.Errorf("failed to parse %s: %v", file, err)
+		}
+
+		switch fields[0] {
+		case "some":
+			stats.Some = pressure
+			hasSome = true
+		case "full":
+			stats.Full = &pressure
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return stats, err
+	}
+
+	if !hasSome {
+		return stats, fmt.Errorf("no some pressure in %s", file)
+	}
+	return stats, nil
+}
+
+func parsePressure(fields []string) (Pressure, error) {
+	var pressure Pressure
+	for _, field := range fields {
+		kv := strings.SplitN(field, "=", 2)
+		if len(kv) != 2 {
+			return pressure, fmt.Errorf("invalid field %q", field)
+		}
+
+		var err error
+		switch kv[0] {
+		case "avg10":
+			pressure.Avg10, err = strconv.ParseFloat(kv[1], 64)
+		case "avg60":
+			pressure.Avg60, err = strconv.ParseFloat(kv[1], 64)
+		case "avg300":
+			pressure.Avg300, err = strconv.ParseFloat(kv[1], 64)
+		case "total":
+			pressure.Total, err = strconv.ParseUint(kv[1], 10, 64)
+		}
+		if err != nil {
+			return pressure, fmt.Errorf("invalid field %q: %v", field, err)
+		}
+	}
+	return pressure, nil
+}
+
+// AddSeries adds the series of the stats to the data by the metric names.
+func AddSeries(data map[string][]common.TimeSeries, names types.PressureMetricNames, stats Stats, labels []common.Label, timestamp int64) {
+	add := func(name types.MetricName, value float64) {
+		data[string(name)] = append(data[string(name)], common.TimeSeries{Labels: labels, Samples: []common.Sample{{Value: value, Timestamp: timestamp}}})
+	}
+
+	add(names.SomeAvg10, stats.Some.Avg10)
+	add(names.SomeAvg60, stats.Some.Avg60)
+	add(names.SomeTotal, float64(stats.Some.Total))
+	if stats.Full != nil {
+		add(names.FullAvg10, stats.Full.Avg10)
+		add(names.FullAvg60, stats.Full.Avg60)
+		add(names.FullTotal, float64(stats.Full.Total))
+	}
+}
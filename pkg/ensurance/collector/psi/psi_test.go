@@ -0,0 +1,82 @@
+package psi
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"github.com/gocrane/crane/pkg/common"
+	"github.com/gocrane/crane/pkg/ensurance/collector/types"
+)
+
+func TestReadFile(t *testing.T) {
+	cases := map[string]struct {
+		file      string
+		content   string
+		expect    Stats
+		expectErr bool
+	}{
+		"some and full": {
+			file:   "testdata/memory",
+			expect: Stats{Some: Pressure{Avg10: 1.5, Avg60: 2.25, Avg300: 0.8, Total: 123456}, Full: &Pressure{Avg10: 0.5, Avg60: 0.75, Avg300: 0.1, Total: 65432}},
+		},
+		"some only": {
+			file:   "testdata/cpu",
+			expect: Stats{Some: Pressure{Avg10: 3, Avg60: 1, Avg300: 0.5, Total: 1000}},
+		},
+		"invalid value": {
+			content:   "some avg10=abc avg60=0.00 avg300=0.00 total=0\n",
+			expectErr: true,
+		},
+		"no some": {
+			content:   "full avg10=0.00 avg60=0.00 avg300=0.00 total=0\n",
+			expectErr: true,
+		},
+	}
+
+	for k, v := range cases {
+		t.Run(k, func(t *testing.T) {
+			file := v.file
+			if file == "" {
+				file = filepath.Join(t.TempDir(), "pressure")
+				if err := os.WriteFile(file, []byte(v.content), 0644); err != nil {
+					t.Fatal(err)
+				}
+			}
+
+			stats, err := ReadFile(file)
+			if v.expectErr {
+				if err == nil {
+					t.Errorf("Expected an error, got %+v", stats)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+			if !reflect.DeepEqual(stats, v.expect) {
+				t.Errorf("Expected %+v, got %+v", v.expect, stats)
+			}
+		})
+	}
+}
+
+func TestAddSeries(t *testing.T) {
+	var data = make(map[string][]common.TimeSeries)
+	AddSeries(data, types.NodePressureMetricNames["cpu"], Stats{Some: Pressure{Avg10: 3, Avg60: 1, Total: 1000}}, nil, 1)
+
+	expect := map[string]float64{
+		string(types.MetricNameCpuPressureSomeAvg10): 3,
+		string(types.MetricNameCpuPressureSomeAvg60): 1,
+		string(types.MetricNameCpuPressureSomeTotal): 1000,
+	}
+	if len(data) != len(expect) {
+		t.Errorf("Expected metrics %v, got %v", expect, data)
+	}
+	for name, value := range expect {
+		if len(data[name]) != 1 || data[name][0].Samples[0].Value != value {
+			t.Errorf("Expected %s to be %v, got %v", name, value, data[name])
+		}
+	}
+}
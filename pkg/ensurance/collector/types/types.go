@@ -1,13 +1,5 @@
 package types
 
-import (
-	"strings"
-
-	v1 "k8s.io/api/core/v1"
-
-	"github.com/gocrane/crane/pkg/utils"
-)
-
 type CollectType string
 
 const (
@@ -15,6 +7,7 @@ const (
 	CadvisorCollectorType      CollectType = "cadvisor"
 	EbpfCollectorType          CollectType = "ebpf"
 	MetricsServerCollectorType CollectType = "metrics-server"
+	HTTPGetCollectorType       CollectType = "http-get"
 )
 
 type MetricName string
@@ -34,8 +27,17 @@ const (
 	MetricDiskReadKiBPS   MetricName = "disk_read_kibps"
 	MetricDiskWriteKiBPS  MetricName = "disk_write_kibps"
 	MetricDiskReadIOPS    MetricName = "disk_read_iops"
-	MetricDiskWriteIOPS   MetricName = "disk_read_iops"
-	MetricDiskUtilization MetricName = "disk_read_utilization"
+	MetricDiskWriteIOPS   MetricName = "disk_write_iops"
+	MetricDiskUtilization MetricName = "disk_utilization"
+
+	MetricPodDiskReadKiBPS        MetricName = "pod_disk_read_kibps"
+	MetricPodDiskWriteKiBPS       MetricName = "pod_disk_write_kibps"
+	MetricPodDiskReadIOPS         MetricName = "pod_disk_read_iops"
+	MetricPodDiskWriteIOPS        MetricName = "pod_disk_write_iops"
+	MetricContainerDiskReadKiBPS  MetricName = "container_disk_read_kibps"
+	MetricContainerDiskWriteKiBPS MetricName = "container_disk_write_kibps"
+	MetricContainerDiskReadIOPS   MetricName = "container_disk_read_iops"
+	MetricContainerDiskWriteIOPS  MetricName = "container_disk_write_iops"
 
 	MetricNetworkReceiveKiBPS MetricName = "network_receive_kibps"
 	MetricNetworkSentKiBPS    MetricName = "network_sent_kibps"
@@ -44,27 +46,100 @@ const (
 	MetricNetworkDropIn       MetricName = "network_drop_in"
 	MetricNetworkDropOut      MetricName = "network_drop_out"
 
+	MetricPodNetworkReceiveKiBPS MetricName = "pod_network_receive_kibps"
+	MetricPodNetworkSentKiBPS    MetricName = "pod_network_sent_kibps"
+	MetricPodNetworkReceivePckPS MetricName = "pod_network_receive_pckps"
+	MetricPodNetworkSentPckPS    MetricName = "pod_network_sent_pckps"
+	MetricPodNetworkDropIn       MetricName = "pod_network_drop_in"
+	MetricPodNetworkDropOut      MetricName = "pod_network_drop_out"
+
 	MetricNameContainerCpuTotalUsage     MetricName = "container_cpu_total_usage"
 	MetricNameContainerCpuLimit          MetricName = "container_cpu_limit"
 	MetricNameContainerCpuQuota          MetricName = "container_cpu_quota"
 	MetricNameContainerCpuPeriod         MetricName = "container_cpu_period"
 	MetricNameContainerSchedRunQueueTime MetricName = "container_sched_run_queue_time"
+	MetricNameContainerMemTotalUsage     MetricName = "container_mem_total_usage"
+	MetricNameContainerMemLimit          MetricName = "container_mem_limit"
 
 	MetricNameExtResContainerCpuTotalUsage MetricName = "ext_res_container_cpu_total_usage"
+
+	// the pressure stall information of the node, the avg10 and avg60 are the percentage of the time stalled
+	// in the latest 10 and 60 seconds, the total is the accumulated stall time in microseconds
+	MetricNameCpuPressureSomeAvg10    MetricName = "cpu_pressure_some_avg10"
+	MetricNameCpuPressureSomeAvg60    MetricName = "cpu_pressure_some_avg60"
+	MetricNameCpuPressureSomeTotal    MetricName = "cpu_pressure_some_total"
+	MetricNameCpuPressureFullAvg10    MetricName = "cpu_pressure_full_avg10"
+	MetricNameCpuPressureFullAvg60    MetricName = "cpu_pressure_full_avg60"
+	MetricNameCpuPressureFullTotal    MetricName = "cpu_pressure_full_total"
+	MetricNameMemoryPressureSomeAvg10 MetricName = "memory_pressure_some_avg10"
+	MetricNameMemoryPressureSomeAvg60 MetricName = "memory_pressure_some_avg60"
+	MetricNameMemoryPressureSomeTotal MetricName = "memory_pressure_some_total"
+	MetricNameMemoryPressureFullAvg10 MetricName = "memory_pressure_full_avg10"
+	MetricNameMemoryPressureFullAvg60 MetricName = "memory_pressure_full_avg60"
+	MetricNameMemoryPressureFullTotal MetricName = "memory_pressure_full_total"
+	MetricNameIOPressureSomeAvg10     MetricName = "io_pressure_some_avg10"
+	MetricNameIOPressureSomeAvg60     MetricName = "io_pressure_some_avg60"
+	MetricNameIOPressureSomeTotal     MetricName = "io_pressure_some_total"
+	MetricNameIOPressureFullAvg10     MetricName = "io_pressure_full_avg10"
+	MetricNameIOPressureFullAvg60     MetricName = "io_pressure_full_avg60"
+	MetricNameIOPressureFullTotal     MetricName = "io_pressure_full_total"
+
+	// the pressure stall information of the pod and container cgroups on cgroup v2
+	MetricNameContainerCpuPressureSomeAvg10    MetricName = "container_cpu_pressure_some_avg10"
+	MetricNameContainerCpuPressureSomeAvg60    MetricName = "container_cpu_pressure_some_avg60"
+	MetricNameContainerCpuPressureSomeTotal    MetricName = "container_cpu_pressure_some_total"
+	MetricNameContainerCpuPressureFullAvg10    MetricName = "container_cpu_pressure_full_avg10"
+	MetricNameContainerCpuPressureFullAvg60    MetricName = "container_cpu_pressure_full_avg60"
+	MetricNameContainerCpuPressureFullTotal    MetricName = "container_cpu_pressure_full_total"
+	MetricNameContainerMemoryPressureSomeAvg10 MetricName = "container_memory_pressure_some_avg10"
+	MetricNameContainerMemoryPressureSomeAvg60 MetricName = "container_memory_pressure_some_avg60"
+	MetricNameContainerMemoryPressureSomeTotal MetricName = "container_memory_pressure_some_total"
+	MetricNameContainerMemoryPressureFullAvg10 MetricName = "container_memory_pressure_full_avg10"
+	MetricNameContainerMemoryPressureFullAvg60 MetricName = "container_memory_pressure_full_avg60"
+	MetricNameContainerMemoryPressureFullTotal MetricName = "container_memory_pressure_full_total"
+	MetricNameContainerIOPressureSomeAvg10     MetricName = "container_io_pressure_some_avg10"
+	MetricNameContainerIOPressureSomeAvg60     MetricName = "container_io_pressure_some_avg60"
+	MetricNameContainerIOPressureSomeTotal     MetricName = "container_io_pressure_some_total"
+	MetricNameContainerIOPressureFullAvg10     MetricName = "container_io_pressure_full_avg10"
+	MetricNameContainerIOPressureFullAvg60     MetricName = "container_io_pressure_full_avg60"
+	MetricNameContainerIOPressureFullTotal     MetricName = "container_io_pressure_full_total"
+
+	// the run queue latency and the involuntary context switches traced by the ebpf collector, the latency is in microseconds
+	MetricNameRunQueueLatencyAvg                  MetricName = "runqueue_latency_avg"
+	MetricNameRunQueueLatencyP99                  MetricName = "runqueue_latency_p99"
+	MetricNameRunQueueLatencyBucket               MetricName = "runqueue_latency_bucket"
+	MetricNameInvoluntaryContextSwitches          MetricName = "involuntary_context_switches"
+	MetricNameContainerRunQueueLatencyAvg         MetricName = "container_runqueue_latency_avg"
+	MetricNameContainerRunQueueLatencyP99         MetricName = "container_runqueue_latency_p99"
+	MetricNameContainerRunQueueLatencyBucket      MetricName = "container_runqueue_latency_bucket"
+	MetricNameContainerInvoluntaryContextSwitches MetricName = "container_involuntary_context_switches"
 )
 
-func GetCgroupPath(p *v1.Pod) string {
-	var pathArrays = []string{utils.CgroupKubePods}
-
-	switch p.Status.QOSClass {
-	case v1.PodQOSGuaranteed:
-		pathArrays = append(pathArrays, utils.CgroupPodPrefix+string(p.UID))
-	case v1.PodQOSBurstable:
-		pathArrays = append(pathArrays, strings.ToLower(string(v1.PodQOSBurstable)), utils.CgroupPodPrefix+string(p.UID))
-	case v1.PodQOSBestEffort:
-		pathArrays = append(pathArrays, strings.ToLower(string(v1.PodQOSBestEffort)), utils.CgroupPodPrefix+string(p.UID))
-	default:
-		return ""
-	}
-	return strings.Join(pathArrays, "/")
+// PressureMetricNames are the metric names of the pressure stall information of a resource.
+type PressureMetricNames struct {
+	SomeAvg10 MetricName
+	SomeAvg60 MetricName
+	SomeTotal MetricName
+	FullAvg10 MetricName
+	FullAvg60 MetricName
+	FullTotal MetricName
+}
+
+// Names returns all the metric names.
+func (p PressureMetricNames) Names() []MetricName {
+	return []MetricName{p.SomeAvg10, p.SomeAvg60, p.SomeTotal, p.FullAvg10, p.FullAvg60, p.FullTotal}
+}
+
+// NodePressureMetricNames are the metric names of the pressure of the node keyed by the resource: cpu, memory and io.
+var NodePressureMetricNames = map[string]PressureMetricNames{
+	"cpu":    {MetricNameCpuPressureSomeAvg10, MetricNameCpuPressureSomeAvg60, MetricNameCpuPressureSomeTotal, MetricNameCpuPressureFullAvg10, MetricNameCpuPressureFullAvg60, MetricNameCpuPressureFullTotal},
+	"memory": {MetricNameMemoryPressureSomeAvg10, MetricNameMemoryPressureSomeAvg60, MetricNameMemoryPressureSomeTotal, MetricNameMemoryPressureFullAvg10, MetricNameMemoryPressureFullAvg60, MetricNameMemoryPressureFullTotal},
+	"io":     {MetricNameIOPressureSomeAvg10, MetricNameIOPressureSomeAvg60, MetricNameIOPressureSomeTotal, MetricNameIOPressureFullAvg10, MetricNameIOPressureFullAvg60, MetricNameIOPressureFullTotal},
+}
+
+// ContainerPressureMetricNames are the metric names of the pressure of the cgroups keyed by the resource: cpu, memory and io.
+var ContainerPressureMetricNames = map[string]PressureMetricNames{
+	"cpu":    {MetricNameContainerCpuPressureSomeAvg10, MetricNameContainerCpuPressureSomeAvg60, MetricNameContainerCpuPressureSomeTotal, MetricNameContainerCpuPressureFullAvg10, MetricNameContainerCpuPressureFullAvg60, MetricNameContainerCpuPressureFullTotal},
+	"memory": {MetricNameContainerMemoryPressureSomeAvg10, MetricNameContainerMemoryPressureSomeAvg60, MetricNameContainerMemoryPressureSomeTotal, MetricNameContainerMemoryPressureFullAvg10, MetricNameContainerMemoryPressureFullAvg60, MetricNameContainerMemoryPressureFullTotal},
+	"io":     {MetricNameContainerIOPressureSomeAvg10, MetricNameContainerIOPressureSomeAvg60, MetricNameContainerIOPressureSomeTotal, MetricNameContainerIOPressureFullAvg10, MetricNameContainerIOPressureFullAvg60, MetricNameContainerIOPressureFullTotal},
 }
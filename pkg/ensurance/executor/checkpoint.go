@@ -0,0 +1,275 @@
+package executor
+
+import (
+	"encoding/json"
+	"sync"
+
+	"k8s.io/klog/v2"
+	"k8s.io/kubernetes/pkg/kubelet/checkpointmanager"
+	"k8s.io/kubernetes/pkg/kubelet/checkpointmanager/checksum"
+	"k8s.io/kubernetes/pkg/kubelet/checkpointmanager/errors"
+)
+
+const (
+	// throttleStateFileName is the file name where the action executor stores its throttle state
+	throttleStateFileName = "throttle_state"
+	// stateFileDirectory holds the directory where the state file for checkpoints is held.
+	stateFileDirectory = "/rootvar/run/crane"
+)
+
+// IOLimit is the io limit of a cgroup in bytes and operations per second, zero means unlimited.
+type IOLimit struct {
+	ReadBps   uint64 `json:"readBps,omitempty"`
+	WriteBps  uint64 `json:"writeBps,omitempty"`
+	ReadIOPS  uint64 `json:"readIOPS,omitempty"`
+	WriteIOPS uint64 `json:"writeIOPS,omitempty"`
+}
+
+func (l IOLimit) IsZero() bool {
+	return l == IOLimit{}
+}
+
+// DiskIOLimit is the disk io throttle state of a pod, the origin is the usage when the throttle began.
+type DiskIOLimit struct {
+	Namespace string   `json:"namespace"`
+	Name      string   `json:"name"`
+	Devices   []string `json:"devices"`
+	Origin    IOLimit  `json:"origin"`
+	Current   IOLimit  `json:"current"`
+}
+
+// NetworkLimit is the egress bandwidth throttle state of a pod, the origin is the usage when the throttle began.
+type NetworkLimit struct {
+	Namespace   string `json:"namespace"`
+	Name        string `json:"name"`
+	Interface   string `json:"interface"`
+	OriginKbit  uint64 `json:"originKbit"`
+	CurrentKbit uint64 `json:"currentKbit"`
+}
+
+// CPUQuota is the cpu quota throttle state of a container, the quotas are in us per period and -1 means unlimited,
+// the origin is the quota when the throttle began.
+type CPUQuota struct {
+	Namespace string `json:"namespace"`
+	Name      string `json:"name"`
+	PodUID    string `json:"podUID"`
+	Container string `json:"container"`
+	Origin    int64  `json:"origin"`
+	Current   int64  `json:"current"`
+}
+
+// Restored returns true if the quota is not less than the origin, so the container is not throttled any more.
+func (q CPUQuota) Restored() bool {
+	return q.Current < 0 || (q.Origin >= 0 && q.Current >= q.Origin)
+}
+
+// ThrottleCheckpoint is the checkpoint of the throttle state, the disk io and network limits are keyed by the pod
+// uid and the cpu quotas are keyed by the container id.
+type ThrottleCheckpoint struct {
+	DiskIO   map[string]DiskIOLimit  `json:"diskIO"`
+	Network  map[string]NetworkLimit `json:"network"`
+	CPUQuota map[string]CPUQuota     `json:"cpuQuota"`
+	Checksum checksum.Checksum       `json:"checksum"`
+}
+
+var _ checkpointmanager.Checkpoint = &ThrottleCheckpoint{}
+
+// MarshalCheckpoint returns marshalled checkpoint
+func (cp *ThrottleCheckpoint) MarshalCheckpoint() ([]byte, error) {
+	// make sure checksum wasn't set before so it doesn't affect output checksum
+	cp.Checksum = 0
+	cp.Checksum = checksum.New(cp)
+	return json.Marshal(*cp)
+}
+
+// UnmarshalCheckpoint tries to unmarshal passed bytes to checkpoint
+func (cp *ThrottleCheckpoint) UnmarshalCheckpoint(blob []byte) error {
+	return json.Unmarshal(blob, cp)
+}
+
+// VerifyChecksum verifies that current checksum of checkpoint is valid
+func (cp *ThrottleCheckpoint) VerifyChecksum() error {
+	ck := cp.Checksum
+	cp.Checksum = 0
+	err := ck.Verify(cp)
+	cp.Checksum = ck
+	return err
+}
+
+// ThrottleState keeps the limits set by the throttle executors, it is checkpointed so that
+// the limits can be restored step by step after the agent restarts.
+type ThrottleState struct {
+	sync.RWMutex
+	checkpointManager checkpointmanager.CheckpointManager
+	checkpointName    string
+
+	diskIO   map[string]DiskIOLimit
+	network  map[string]NetworkLimit
+	cpuQuota map[string]CPUQuota
+}
+
+// NewThrottleState creates the throttle state and restores it from the checkpoint in the directory.
+func NewThrottleState(stateDir string, checkpointName string) (*ThrottleState, error) {
+	checkpointManager, err := checkpointmanager.NewCheckpointManager(stateDir)
+	if err != nil {
+		return nil, err
+	}
+
+	s := &ThrottleState{
+		checkpointManager: checkpointManager,
+		checkpointName:    checkpointName,
+		diskIO:            make(map[string]DiskIOLimit),
+		network:           make(map[string]NetworkLimit),
+		cpuQuota:          make(map[string]CPUQuota),
+	}
+
+	if err := s.restoreState(); err != nil {
+		return nil, err
+	}
+
+	return s, nil
+}
+
+func (s *ThrottleState) restoreState() error {
+	s.Lock()
+	defer s.Unlock()
+
+	checkpoint := &ThrottleCheckpoint{}
+	if err := s.checkpointManager.GetCheckpoint(s.checkpointName, checkpoint); err != nil {
+		if err == errors.ErrCheckpointNotFound {
+			return nil
+		}
+		return err
+	}
+
+	for k, v := range checkpoint.DiskIO {
+		s.diskIO[k] = v
+	}
+	for k, v := range checkpoint.Network {
+		s.network[k] = v
+	}
+	for k, v := range checkpoint.CPUQuota {
+		s.cpuQuota[k] = v
+	}
+
+	klog.V(2).Infof("Restored throttle state: %d disk io limits, %d network limits, %d cpu quotas", len(s.diskIO), len(s.network), len(s.cpuQuota))
+	return nil
+}
+
+// storeState saves the state to the checkpoint, the caller must hold the lock.
+func (s *ThrottleState) storeState() error {
+	checkpoint := &ThrottleCheckpoint{
+		DiskIO:   s.diskIO,
+		Network:  s.network,
+		CPUQuota: s.cpuQuota,
+	}
+	return s.checkpointManager.CreateCheckpoint(s.checkpointName, checkpoint)
+}
+
+func (s *ThrottleState) GetDiskIOLimit(podUID string) (DiskIOLimit, bool) {
+	s.RLock()
+	defer s.RUnlock()
+
+	limit, ok := s.diskIO[podUID]
+	return limit, ok
+}
+
+func (s *ThrottleState) GetDiskIOLimits() map[string]DiskIOLimit {
+	s.RLock()
+	defer s.RUnlock()
+
+	limits := make(map[string]DiskIOLimit, len(s.diskIO))
+	for k, v := range s.diskIO {
+		limits[k] = v
+	}
+	return limits
+}
+
+// SetDiskIOLimit saves the disk io limit of the pod, it is removed if the current limit is unlimited.
+func (s *ThrottleState) SetDiskIOLimit(podUID string, limit DiskIOLimit) error {
+	s.Lock()
+	defer s.Unlock()
+
+	if limit.Current.IsZero() {
+		delete(s.diskIO, podUID)
+	} else {
+		s.diskIO[podUID] = limit
+	}
+	return s.storeState()
+}
+
+func (s *ThrottleState) DeleteDiskIOLimit(podUID string) error {
+	return s.SetDiskIOLimit(podUID, DiskIOLimit{})
+}
+
+func (s *ThrottleState) GetNetworkLimit(podUID string) (NetworkLimit, bool) {
+	s.RLock()
+	defer s.RUnlock()
+
+	limit, ok := s.network[podUID]
+	return limit, ok
+}
+
+func (s *ThrottleState) GetNetworkLimits() map[string]NetworkLimit {
+	s.RLock()
+	defer s.RUnlock()
+
+	limits := make(map[string]NetworkLimit, len(s.network))
+	for k, v := range s.network {
+		limits[k] = v
+	}
+	return limits
+}
+
+// SetNetworkLimit saves the network limit of the pod, it is removed if the current limit is unlimited.
+func (s *ThrottleState) SetNetworkLimit(podUID string, limit NetworkLimit) error {
+	s.Lock()
+	defer s.Unlock()
+
+	if limit.CurrentKbit == 0 {
+		delete(s.network, podUID)
+	} else {
+		s.network[podUID] = limit
+	}
+	return s.storeState()
+}
+
+func (s *ThrottleState) DeleteNetworkLimit(podUID string) error {
+	return s.SetNetworkLimit(podUID, NetworkLimit{})
+}
+
+func (s *ThrottleState) GetCPUQuota(containerId string) (CPUQuota, bool) {
+	s.RLock()
+	defer s.RUnlock()
+
+	quota, ok := s.cpuQuota[containerId]
+	return quota, ok
+}
+
+func (s *ThrottleState) GetCPUQuotas() map[string]CPUQuota {
+	s.RLock()
+	defer s.RUnlock()
+
+	quotas := make(map[string]CPUQuota, len(s.cpuQuota))
+	for k, v := range s.cpuQuota {
+		quotas[k] = v
+	}
+	return quotas
+}
+
+// SetCPUQuota saves the cpu quota of the container, it is removed if the quota is restored.
+func (s *ThrottleState) SetCPUQuota(containerId string, quota CPUQuota) error {
+	s.Lock()
+	defer s.Unlock()
+
+	if quota.Restored() {
+		delete(s.cpuQuota, containerId)
+	} else {
+		s.cpuQuota[containerId] = quota
+	}
+	return s.storeState()
+}
+
+func (s *ThrottleState) DeleteCPUQuota(containerId string) error {
+	return s.SetCPUQuota(containerId, CPUQuota{Current: -1})
+}
@@ -0,0 +1,45 @@
+package executor
+
+import (
+	"testing"
+)
+
+func TestThrottleStateCheckpoint(t *testing.T) {
+	stateDir := t.TempDir()
+	state, err := NewThrottleState(stateDir, throttleStateFileName)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	limit := DiskIOLimit{Namespace: "default", Name: "pod1", Current: IOLimit{ReadBps: 1024}}
+	if err := state.SetDiskIOLimit("uid1", limit); err != nil {
+		t.Fatal(err)
+	}
+	quota := CPUQuota{Namespace: "default", Name: "pod1", PodUID: "uid1", Container: "c1", Origin: 200000, Current: 100000}
+	if err := state.SetCPUQuota("abc", quota); err != nil {
+		t.Fatal(err)
+	}
+
+	// the limits and the cpu quotas are kept after restart
+	if state, err = NewThrottleState(stateDir, throttleStateFileName); err != nil {
+		t.Fatalf("Failed to restore the checkpoint: %v", err)
+	}
+	if restored, ok := state.GetDiskIOLimit("uid1"); !ok || restored.Current.ReadBps != 1024 {
+		t.Errorf("Expected the disk io limit restored, got %+v", restored)
+	}
+	if restored, ok := state.GetCPUQuota("abc"); !ok || restored != quota {
+		t.Errorf("Expected the cpu quota %+v restored, got %+v", quota, restored)
+	}
+
+	// the quota restored to the origin is removed
+	quota.Current = 200000
+	if err := state.SetCPUQuota("abc", quota); err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := state.GetCPUQuota("abc"); ok {
+		t.Errorf("Expected the cpu quota restored to the origin to be removed")
+	}
+	if restored, ok := state.GetDiskIOLimit("uid1"); !ok || restored.Current.ReadBps != 1024 {
+		t.Errorf("Expected the disk io limit kept, got %+v", restored)
+	}
+}
@@ -0,0 +1,117 @@
+package executor
+
+import (
+	"fmt"
+
+	v1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/apimachinery/pkg/util/sets"
+	pb "k8s.io/cri-api/pkg/apis/runtime/v1alpha2"
+	"k8s.io/klog/v2"
+
+	"github.com/gocrane/crane/pkg/ensurance/cgroup"
+	cruntime "github.com/gocrane/crane/pkg/ensurance/runtime"
+	"github.com/gocrane/crane/pkg/utils"
+)
+
+// UpdateContainerCPUQuota updates the cpu quota of the container by CRI, -1 removes the limit. The cpu.max of the
+// container cgroup is written directly on cgroup v2 if the runtime fails to update it, such as the runtimes which
+// only support the cfs quota of cgroup v1.
+func UpdateContainerCPUQuota(client pb.RuntimeServiceClient, pod *v1.Pod, containerId string, quota int64) error {
+	err := cruntime.UpdateContainerResources(client, containerId, cruntime.UpdateOptions{CPUQuota: quota})
+	if err == nil || containerId == "" || !cgroup.IsV2() {
+		return err
+	}
+
+	podDir, dirErr := cgroup.PodDir(pod, cgroup.CPUSubsystem)
+	if dirErr != nil {
+		return fmt.Errorf("%v, and %v", err, dirErr)
+	}
+	containerDir, dirErr := cgroup.ContainerDir(podDir, containerId)
+	if dirErr != nil {
+		return fmt.Errorf("%v, and %v", err, dirErr)
+	}
+	if writeErr := cgroup.WriteCPUMax(containerDir, quota, 0); writeErr != nil {
+		return fmt.Errorf("%v, and %v", err, writeErr)
+	}
+
+	klog.V(4).Infof("Failed to update cpu quota of container %s by CRI, cpu.max is updated: %v", containerId, err)
+	return nil
+}
+
+// checkpointCPUQuota saves the cpu quota of the container to the throttle state, the origin is the quota before the
+// first throttle. The quota is in us per period, and the quota collected which is -1 or 0 means unlimited. A container
+// which is not throttled is only saved when it is throttled down.
+func checkpointCPUQuota(ctx *ExecuteContext, pod *v1.Pod, container ContainerUsage, quota float64, quotaNew int64, down bool) {
+	cpuQuota, ok := ctx.ThrottleState.GetCPUQuota(container.ContainerId)
+	if !ok {
+		if !down {
+			return
+		}
+		cpuQuota = CPUQuota{Namespace: pod.Namespace, Name: pod.Name, PodUID: string(pod.UID), Container: container.ContainerName, Origin: -1}
+		if quota > 0 {
+			cpuQuota.Origin = int64(quota)
+		}
+	}
+
+	cpuQuota.Current = quotaNew
+	if err := ctx.ThrottleState.SetCPUQuota(container.ContainerId, cpuQuota); err != nil {
+		klog.Errorf("Failed to save cpu quota of container %s/%s: %v", klog.KObj(pod), container.ContainerName, err)
+	}
+}
+
+// reconcileCPUQuota checks the cpu quotas in the throttle state. The quotas of the containers which are gone, such as
+// the pod is recreated or the container is restarted, are removed. The quotas changed by others are not tracked any
+// more. The throttled containers whose pods are not in the targets of the throttles are orphaned, their quotas are
+// restored to the origin, none of them is orphaned if the targets are nil.
+func reconcileCPUQuota(ctx *ExecuteContext, targets sets.String) {
+	for containerId, quota := range ctx.ThrottleState.GetCPUQuotas() {
+		pod, err := ctx.PodLister.Pods(quota.Namespace).Get(quota.Name)
+		if err != nil || string(pod.UID) != quota.PodUID || utils.GetContainerIdFromPod(pod, quota.Container) != containerId {
+			klog.V(4).Infof("Container %s/%s/%s is gone, remove its cpu quota", quota.Namespace, quota.Name, quota.Container)
+			deleteCPUQuota(ctx, containerId, quota)
+			continue
+		}
+
+		current, err := readContainerCPUQuota(pod, containerId)
+		if err != nil {
+			klog.V(4).Infof("Failed to read cpu quota of container %s/%s: %v", klog.KObj(pod), quota.Container, err)
+		} else if current != quota.Current {
+			klog.Warningf("The cpu quota of container %s/%s is changed from %d to %d by others, it is not tracked any more",
+				klog.KObj(pod), quota.Container, quota.Current, current)
+			ctx.Recorder.Eventf(pod, v1.EventTypeWarning, "CPUQuotaChanged",
+				"The cpu quota of container %s throttled to %d is changed to %d by others", quota.Container, quota.Current, current)
+			deleteCPUQuota(ctx, containerId, quota)
+			continue
+		}
+
+		if targets != nil && !targets.Has(types.NamespacedName{Namespace: pod.Namespace, Name: pod.Name}.String()) {
+			if err := UpdateContainerCPUQuota(ctx.RuntimeClient, pod, containerId, quota.Origin); err != nil {
+				klog.Errorf("Failed to restore cpu quota of container %s/%s: %v", klog.KObj(pod), quota.Container, err)
+				continue
+			}
+			klog.V(2).Infof("Restored cpu quota %d of orphaned container %s/%s", quota.Origin, klog.KObj(pod), quota.Container)
+			deleteCPUQuota(ctx, containerId, quota)
+		}
+	}
+}
+
+func deleteCPUQuota(ctx *ExecuteContext, containerId string, quota CPUQuota) {
+	if err := ctx.ThrottleState.DeleteCPUQuota(containerId); err != nil {
+		klog.Errorf("Failed to remove cpu quota of container %s/%s/%s: %v", quota.Namespace, quota.Name, quota.Container, err)
+	}
+}
+
+// readContainerCPUQuota returns the cpu quota of the container cgroup, -1 means unlimited.
+func readContainerCPUQuota(pod *v1.Pod, containerId string) (int64, error) {
+	podDir, err := cgroup.PodDir(pod, cgroup.CPUSubsystem)
+	if err != nil {
+		return 0, err
+	}
+	containerDir, err := cgroup.ContainerDir(podDir, containerId)
+	if err != nil {
+		return 0, err
+	}
+	quota, _, err := cgroup.ReadCPUMax(containerDir)
+	return quota, err
+}
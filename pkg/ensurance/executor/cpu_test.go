@@ -0,0 +1,198 @@
+package executor
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"google.golang.org/grpc"
+	v1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/sets"
+	corelisters "k8s.io/client-go/listers/core/v1"
+	"k8s.io/client-go/tools/cache"
+	"k8s.io/client-go/tools/record"
+	pb "k8s.io/cri-api/pkg/apis/runtime/v1alpha2"
+
+	"github.com/gocrane/crane/pkg/ensurance/cgroup"
+)
+
+// fakeRuntimeClient records the updates of the container resources and returns the err.
+type fakeRuntimeClient struct {
+	pb.RuntimeServiceClient
+	updated []*pb.UpdateContainerResourcesRequest
+	err     error
+}
+
+func (c *fakeRuntimeClient) UpdateContainerResources(_ context.Context, in *pb.UpdateContainerResourcesRequest, _ ...grpc.CallOption) (*pb.UpdateContainerResourcesResponse, error) {
+	c.updated = append(c.updated, in)
+	return &pb.UpdateContainerResourcesResponse{}, c.err
+}
+
+func TestUpdateContainerCPUQuota(t *testing.T) {
+	pod := &v1.Pod{
+		ObjectMeta: metav1.ObjectMeta{Name: "pod1", Namespace: "default", UID: "uid1"},
+		Status:     v1.PodStatus{QOSClass: v1.PodQOSBurstable},
+	}
+
+	cases := map[string]struct {
+		v2         bool
+		runtimeErr error
+		quota      int64
+		expectErr  bool
+		expectMax  string
+	}{
+		"updated by CRI": {
+			v2:        true,
+			quota:     50000,
+			expectMax: "max 100000",
+		},
+		"fallback to cpu.max on cgroup v2": {
+			v2:         true,
+			runtimeErr: fmt.Errorf("not supported"),
+			quota:      50000,
+			expectMax:  "50000",
+		},
+		"remove the limit on cgroup v2": {
+			v2:         true,
+			runtimeErr: fmt.Errorf("not supported"),
+			quota:      -1,
+			expectMax:  "max",
+		},
+		"no fallback on cgroup v1": {
+			runtimeErr: fmt.Errorf("not supported"),
+			quota:      50000,
+			expectErr:  true,
+		},
+	}
+
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			root := t.TempDir()
+			defer func(origin string) { cgroup.Root = origin }(cgroup.Root)
+			cgroup.Root = root
+
+			var containerDir = filepath.Join(root, "kubepods", "burstable", "poduid1", "cri-containerd-abc.scope")
+			if tc.v2 {
+				os.WriteFile(filepath.Join(root, cgroup.ControllersFile), []byte("cpu io memory\n"), 0644)
+			} else {
+				containerDir = filepath.Join(root, cgroup.CPUSubsystem, "kubepods", "burstable", "poduid1", "cri-containerd-abc.scope")
+			}
+			if err := os.MkdirAll(containerDir, 0755); err != nil {
+				t.Fatal(err)
+			}
+			os.WriteFile(filepath.Join(containerDir, "cpu.max"), []byte("max 100000\n"), 0644)
+
+			client := &fakeRuntimeClient{err: tc.runtimeErr}
+			err := UpdateContainerCPUQuota(client, pod, "abc", tc.quota)
+			if tc.expectErr != (err != nil) {
+				t.Fatalf("Expected error %t, got %v", tc.expectErr, err)
+			}
+			if len(client.updated) != 1 || client.updated[0].Linux.CpuQuota != tc.quota {
+				t.Errorf("Expected to update cpu quota %d by CRI, got %v", tc.quota, client.updated)
+			}
+			if tc.expectMax != "" {
+				data, _ := os.ReadFile(filepath.Join(containerDir, "cpu.max"))
+				if strings.TrimSpace(string(data)) != tc.expectMax {
+					t.Errorf("Expected cpu.max %s, got %s", tc.expectMax, data)
+				}
+			}
+		})
+	}
+}
+
+func TestReconcileCPUQuota(t *testing.T) {
+	pod := &v1.Pod{
+		ObjectMeta: metav1.ObjectMeta{Name: "pod1", Namespace: "default", UID: "uid1"},
+		Status: v1.PodStatus{QOSClass: v1.PodQOSBurstable,
+			ContainerStatuses: []v1.ContainerStatus{{Name: "c1", ContainerID: "containerd://abc"}}},
+	}
+	throttled := CPUQuota{Namespace: "default", Name: "pod1", PodUID: "uid1", Container: "c1", Origin: 200000, Current: 50000}
+
+	cases := map[string]struct {
+		quota       CPUQuota
+		cpuMax      string
+		targets     sets.String
+		expectKept  bool
+		expectQuota int64
+		expectEvent bool
+	}{
+		"kept if the targets are unknown": {
+			quota:      throttled,
+			cpuMax:     "50000 100000",
+			expectKept: true,
+		},
+		"kept if the pod is a target of the throttles": {
+			quota:      throttled,
+			cpuMax:     "50000 100000",
+			targets:    sets.NewString("default/pod1"),
+			expectKept: true,
+		},
+		"removed if the pod is recreated": {
+			quota:  CPUQuota{Namespace: "default", Name: "pod1", PodUID: "uid0", Container: "c1", Origin: 200000, Current: 50000},
+			cpuMax: "50000 100000",
+		},
+		"removed if the container is restarted": {
+			quota:  CPUQuota{Namespace: "default", Name: "pod1", PodUID: "uid1", Container: "c0", Origin: 200000, Current: 50000},
+			cpuMax: "50000 100000",
+		},
+		"removed if changed by others": {
+			quota:       throttled,
+			cpuMax:      "80000 100000",
+			expectEvent: true,
+		},
+		"restored to the origin if the pod is not a target of the throttles": {
+			quota:       throttled,
+			cpuMax:      "50000 100000",
+			targets:     sets.NewString("default/pod2"),
+			expectQuota: 200000,
+		},
+	}
+
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			root := t.TempDir()
+			defer func(origin string) { cgroup.Root = origin }(cgroup.Root)
+			cgroup.Root = root
+
+			os.WriteFile(filepath.Join(root, cgroup.ControllersFile), []byte("cpu io memory\n"), 0644)
+			containerDir := filepath.Join(root, "kubepods", "burstable", "poduid1", "cri-containerd-abc.scope")
+			if err := os.MkdirAll(containerDir, 0755); err != nil {
+				t.Fatal(err)
+			}
+			os.WriteFile(filepath.Join(containerDir, "cpu.max"), []byte(tc.cpuMax), 0644)
+
+			indexer := cache.NewIndexer(cache.MetaNamespaceKeyFunc, cache.Indexers{})
+			indexer.Add(pod)
+			state, err := NewThrottleState(t.TempDir(), throttleStateFileName)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if err := state.SetCPUQuota("abc", tc.quota); err != nil {
+				t.Fatal(err)
+			}
+			client := &fakeRuntimeClient{}
+			recorder := record.NewFakeRecorder(1)
+			ctx := &ExecuteContext{PodLister: corelisters.NewPodLister(indexer), ThrottleState: state, RuntimeClient: client, Recorder: recorder}
+
+			reconcileCPUQuota(ctx, tc.targets)
+
+			if _, ok := state.GetCPUQuota("abc"); ok != tc.expectKept {
+				t.Errorf("Expected the cpu quota kept %t, got %t", tc.expectKept, ok)
+			}
+			if tc.expectQuota != 0 {
+				if len(client.updated) != 1 || client.updated[0].Linux.CpuQuota != tc.expectQuota {
+					t.Errorf("Expected to update cpu quota %d, got %v", tc.expectQuota, client.updated)
+				}
+			} else if len(client.updated) != 0 {
+				t.Errorf("Expected no update, got %v", client.updated)
+			}
+			if events := len(recorder.Events); (events != 0) != tc.expectEvent {
+				t.Errorf("Expected event %t, got %d events", tc.expectEvent, events)
+			}
+		})
+	}
+}
@@ -0,0 +1,340 @@
+package executor
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	v1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/apimachinery/pkg/util/sets"
+	"k8s.io/klog/v2"
+
+	"github.com/gocrane/crane/pkg/ensurance/cgroup"
+	"github.com/gocrane/crane/pkg/known"
+	"github.com/gocrane/crane/pkg/metrics"
+)
+
+const (
+	// cgroup v2
+	ioStatFile = "io.stat"
+
+	// cgroup v1
+	blkioSubsystem           = "blkio"
+	blkioServiceBytesFile    = "blkio.throttle.io_service_bytes_recursive"
+	blkioReadBpsDeviceFile   = "blkio.throttle.read_bps_device"
+	blkioWriteBpsDeviceFile  = "blkio.throttle.write_bps_device"
+	blkioReadIOPSDeviceFile  = "blkio.throttle.read_iops_device"
+	blkioWriteIOPSDeviceFile = "blkio.throttle.write_iops_device"
+	bytesPerKiB              = 1024
+)
+
+type DiskIOThrottleExecutor struct {
+	ThrottleDownPods DiskIOThrottlePods
+	ThrottleUpPods   DiskIOThrottlePods
+	// Targets are the pods which may be throttled by the disk io throttles of the node, keyed by namespace/name. The
+	// limits of the pods out of them are removed, all the limits are kept if it is nil.
+	Targets sets.String
+}
+
+type DiskIORatio struct {
+	//the min of io ratio of the usage when the throttle begins
+	MinIORatio uint64 `json:"minIORatio,omitempty"`
+
+	//the step of io limit for once down-size (1-100)
+	StepIORatio uint64 `json:"stepIORatio,omitempty"`
+
+	//the min of read and write KiB per second
+	MinKiBps uint64 `json:"minKiBps,omitempty"`
+
+	//the min of read and write operations per second
+	MinIOPS uint64 `json:"minIOPS,omitempty"`
+}
+
+type DiskIOThrottlePod struct {
+	DiskIOThrottle    DiskIORatio
+	PodTypes          types.NamespacedName
+	PodDiskReadKiBps  float64
+	PodDiskWriteKiBps float64
+	PodDiskReadIOPS   float64
+	PodDiskWriteIOPS  float64
+	PodQOSPriority    ClassAndPriority
+}
+
+type DiskIOThrottlePods []DiskIOThrottlePod
+
+func (t DiskIOThrottlePods) Len() int      { return len(t) }
+func (t DiskIOThrottlePods) Swap(i, j int) { t[i], t[j] = t[j], t[i] }
+func (t DiskIOThrottlePods) Less(i, j int) bool {
+	return t[i].PodQOSPriority.Less(t[j].PodQOSPriority)
+}
+
+func (t DiskIOThrottlePods) Find(podTypes types.NamespacedName) int {
+	for i, v := range t {
+		if v.PodTypes == podTypes {
+			return i
+		}
+	}
+
+	return -1
+}
+
+func (t *DiskIOThrottleExecutor) Avoid(ctx *ExecuteContext) error {
+	var start = time.Now()
+	metrics.UpdateLastTimeWithSubComponent(string(known.ModuleActionExecutor), string(metrics.SubComponentDiskIOThrottle), metrics.StepAvoid, start)
+	defer metrics.UpdateDurationFromStartWithSubComponent(string(known.ModuleActionExecutor), string(metrics.SubComponentDiskIOThrottle), metrics.StepAvoid, start)
+
+	klog.V(6).Infof("DiskIOThrottleExecutor avoid, %v", *t)
+
+	if len(t.ThrottleDownPods) == 0 {
+		metrics.UpdateExecutorStatus(metrics.SubComponentDiskIOThrottle, metrics.StepAvoid, 0)
+		return nil
+	}
+
+	metrics.UpdateExecutorStatus(metrics.SubComponentDiskIOThrottle, metrics.StepAvoid, 1.0)
+	metrics.ExecutorStatusCounterInc(metrics.SubComponentDiskIOThrottle, metrics.StepAvoid)
+
+	return t.throttle(ctx, t.ThrottleDownPods, true)
+}
+
+func (t *DiskIOThrottleExecutor) Restore(ctx *ExecuteContext) error {
+	var start = time.Now()
+	metrics.UpdateLastTimeWithSubComponent(string(known.ModuleActionExecutor), string(metrics.SubComponentDiskIOThrottle), metrics.StepRestore, start)
+	defer metrics.UpdateDurationFromStartWithSubComponent(string(known.ModuleActionExecutor), string(metrics.SubComponentDiskIOThrottle), metrics.StepRestore, start)
+
+	klog.V(6).Infof("DiskIOThrottleExecutor restore, %v", *t)
+
+	if len(t.ThrottleUpPods) == 0 {
+		metrics.UpdateExecutorStatus(metrics.SubComponentDiskIOThrottle, metrics.StepRestore, 0)
+		return nil
+	}
+
+	metrics.UpdateExecutorStatus(metrics.SubComponentDiskIOThrottle, metrics.StepRestore, 1.0)
+	metrics.ExecutorStatusCounterInc(metrics.SubComponentDiskIOThrottle, metrics.StepRestore)
+
+	return t.throttle(ctx, t.ThrottleUpPods, false)
+}
+
+func (t *DiskIOThrottleExecutor) throttle(ctx *ExecuteContext, throttlePods DiskIOThrottlePods, down bool) error {
+	if ctx.ThrottleState == nil {
+		return fmt.Errorf("throttle state is not initialized")
+	}
+
+	var errPodKeys []string
+	for _, throttlePod := range throttlePods {
+		pod, err := ctx.PodLister.Pods(throttlePod.PodTypes.Namespace).Get(throttlePod.PodTypes.Name)
+		if err != nil {
+			errPodKeys = append(errPodKeys, fmt.Sprintf("pod %s not found", throttlePod.PodTypes.String()))
+			continue
+		}
+
+		if err := throttleDiskIO(ctx, pod, throttlePod, down); err != nil {
+			errPodKeys = append(errPodKeys, fmt.Sprintf("failed to throttle disk io for %s, error: %v", throttlePod.PodTypes.String(), err))
+		}
+	}
+
+	if len(errPodKeys) != 0 {
+		return fmt.Errorf("some pod disk io throttle failed,err: %s", strings.Join(errPodKeys, ";"))
+	}
+
+	return nil
+}
+
+// throttleDiskIO steps the io limits of the pod cgroup for all the devices used by the pod. The origin of the limits
+// is the usage when the throttle begins, it is kept in the throttle state until the limits are removed.
+func throttleDiskIO(ctx *ExecuteContext, pod *v1.Pod, throttlePod DiskIOThrottlePod, down bool) error {
+	limit, ok := ctx.ThrottleState.GetDiskIOLimit(string(pod.UID))
+	if !ok {
+		// the pod is not throttled
+		if !down {
+			return nil
+		}
+
+		devices, err := readIODevices(pod)
+		if err != nil {
+			return err
+		}
+		if len(devices) == 0 {
+			return nil
+		}
+
+		limit = DiskIOLimit{
+			Namespace: pod.Namespace,
+			Name:      pod.Name,
+			Devices:   devices,
+			Origin: IOLimit{
+				ReadBps:   uint64(throttlePod.PodDiskReadKiBps * bytesPerKiB),
+				WriteBps:  uint64(throttlePod.PodDiskWriteKiBps * bytesPerKiB),
+				ReadIOPS:  uint64(throttlePod.PodDiskReadIOPS),
+				WriteIOPS: uint64(throttlePod.PodDiskWriteIOPS),
+			},
+		}
+	}
+
+	var ratio = throttlePod.DiskIOThrottle
+	var next = IOLimit{
+		ReadBps:   nextLimit(limit.Current.ReadBps, limit.Origin.ReadBps, ratio.MinKiBps*bytesPerKiB, ratio.MinIORatio, ratio.StepIORatio, down),
+		WriteBps:  nextLimit(limit.Current.WriteBps, limit.Origin.WriteBps, ratio.MinKiBps*bytesPerKiB, ratio.MinIORatio, ratio.StepIORatio, down),
+		ReadIOPS:  nextLimit(limit.Current.ReadIOPS, limit.Origin.ReadIOPS, ratio.MinIOPS, ratio.MinIORatio, ratio.StepIORatio, down),
+		WriteIOPS: nextLimit(limit.Current.WriteIOPS, limit.Origin.WriteIOPS, ratio.MinIOPS, ratio.MinIORatio, ratio.StepIORatio, down),
+	}
+	if next == limit.Current {
+		return nil
+	}
+
+	if err := writeIOLimit(pod, limit.Devices, next); err != nil {
+		return err
+	}
+
+	klog.V(4).Infof("DiskIOThrottleExecutor pod %s, set io limit %+v.", klog.KObj(pod), next)
+
+	limit.Current = next
+	return ctx.ThrottleState.SetDiskIOLimit(string(pod.UID), limit)
+}
+
+// nextLimit returns the limit for the next step, it is zero if it should be unlimited. When throttling down, the limit
+// is stepped down from the origin usage, but not less than the min ratio of the origin and the min value. When
+// restoring, the limit is stepped up and removed once it reaches the origin usage.
+func nextLimit(current, origin, minValue, minRatio, step uint64, down bool) uint64 {
+	if down {
+		// the resource is not used when the throttle begins
+		if origin == 0 {
+			return current
+		}
+
+		var base = current
+		if base == 0 {
+			base = origin
+		}
+
+		var next = uint64(float64(base) * (1.0 - float64(step)/MaxRatio))
+		if floor := uint64(float64(origin) * float64(minRatio) / MaxRatio); next < floor {
+			next = floor
+		}
+		if next < minValue {
+			next = minValue
+		}
+		// never throttle up when throttling down
+		if next >= base {
+			return current
+		}
+		return next
+	}
+
+	if current == 0 {
+		return 0
+	}
+
+	var next = uint64(float64(current) * (1.0 + float64(step)/MaxRatio))
+	if next >= origin || next <= current {
+		return 0
+	}
+	return next
+}
+
+// reconcileDiskIO applies the disk io limits in the throttle state again, so that the limits are kept after the agent
+// restarts. The limits of the pods which are not found or not in the targets any more are removed.
+func reconcileDiskIO(ctx *ExecuteContext, targets sets.String) {
+	for uid, limit := range ctx.ThrottleState.GetDiskIOLimits() {
+		pod, err := ctx.PodLister.Pods(limit.Namespace).Get(limit.Name)
+		if err != nil || string(pod.UID) != uid {
+			klog.V(4).Infof("Pod %s/%s is gone, remove its disk io limit", limit.Namespace, limit.Name)
+			deleteDiskIOLimit(ctx, uid, limit)
+			continue
+		}
+
+		if targets != nil && !targets.Has(types.NamespacedName{Namespace: pod.Namespace, Name: pod.Name}.String()) {
+			if err := writeIOLimit(pod, limit.Devices, IOLimit{}); err != nil {
+				klog.Errorf("Failed to remove disk io limit of pod %s: %v", klog.KObj(pod), err)
+				continue
+			}
+			klog.V(2).Infof("Removed disk io limit of orphaned pod %s", klog.KObj(pod))
+			deleteDiskIOLimit(ctx, uid, limit)
+			continue
+		}
+
+		if err := writeIOLimit(pod, limit.Devices, limit.Current); err != nil {
+			klog.Errorf("Failed to reconcile disk io limit of pod %s: %v", klog.KObj(pod), err)
+		}
+	}
+}
+
+func deleteDiskIOLimit(ctx *ExecuteContext, uid string, limit DiskIOLimit) {
+	if err := ctx.ThrottleState.DeleteDiskIOLimit(uid); err != nil {
+		klog.Errorf("Failed to remove disk io limit of pod %s/%s: %v", limit.Namespace, limit.Name, err)
+	}
+}
+
+// readIODevices returns the devices used by the pod, such as 8:0.
+func readIODevices(pod *v1.Pod) ([]string, error) {
+	dir, err := cgroup.PodDir(pod, blkioSubsystem)
+	if err != nil {
+		return nil, err
+	}
+
+	var file = filepath.Join(dir, blkioServiceBytesFile)
+	if cgroup.IsV2() {
+		file = filepath.Join(dir, ioStatFile)
+	}
+
+	f, err := os.Open(file)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	var devices = make(map[string]struct{})
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		fields := strings.Fields(scanner.Text())
+		if len(fields) > 1 && strings.Contains(fields[0], ":") {
+			devices[fields[0]] = struct{}{}
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+
+	var result []string
+	for device := range devices {
+		result = append(result, device)
+	}
+	sort.Strings(result)
+	return result, nil
+}
+
+// writeIOLimit writes the io limit of the pod cgroup for the devices, zero removes the limit.
+func writeIOLimit(pod *v1.Pod, devices []string, limit IOLimit) error {
+	dir, err := cgroup.PodDir(pod, blkioSubsystem)
+	if err != nil {
+		return err
+	}
+
+	if cgroup.IsV2() {
+		for _, device := range devices {
+			if err := cgroup.WriteIOMax(dir, device, limit.ReadBps, limit.WriteBps, limit.ReadIOPS, limit.WriteIOPS); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+
+	for _, device := range devices {
+		for file, value := range map[string]uint64{
+			blkioReadBpsDeviceFile:   limit.ReadBps,
+			blkioWriteBpsDeviceFile:  limit.WriteBps,
+			blkioReadIOPSDeviceFile:  limit.ReadIOPS,
+			blkioWriteIOPSDeviceFile: limit.WriteIOPS,
+		} {
+			// zero removes the limit of the device in cgroup v1
+			if err := os.WriteFile(filepath.Join(dir, file), []byte(fmt.Sprintf("%s %d", device, value)), 0644); err != nil {
+				return fmt.Errorf("failed to write %s: %v", file, err)
+			}
+		}
+	}
+	return nil
+}
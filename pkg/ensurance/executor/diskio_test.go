@@ -0,0 +1,182 @@
+package executor
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	v1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/apimachinery/pkg/util/sets"
+	corelisters "k8s.io/client-go/listers/core/v1"
+	"k8s.io/client-go/tools/cache"
+
+	"github.com/gocrane/crane/pkg/ensurance/cgroup"
+)
+
+func TestNextLimit(t *testing.T) {
+	cases := map[string]struct {
+		current  uint64
+		origin   uint64
+		minValue uint64
+		minRatio uint64
+		down     bool
+		expect   uint64
+	}{
+		"step down from the origin": {
+			origin: 1000,
+			down:   true,
+			expect: 800,
+		},
+		"step down the current limit": {
+			current: 800,
+			origin:  1000,
+			down:    true,
+			expect:  640,
+		},
+		"not less than the min ratio": {
+			current:  600,
+			origin:   1000,
+			minRatio: 50,
+			down:     true,
+			expect:   500,
+		},
+		"not less than the min value": {
+			current:  600,
+			origin:   1000,
+			minValue: 550,
+			down:     true,
+			expect:   550,
+		},
+		"keep the limit at the floor": {
+			current:  500,
+			origin:   1000,
+			minRatio: 50,
+			down:     true,
+			expect:   500,
+		},
+		"not throttled if the min value is above the usage": {
+			origin:   1000,
+			minValue: 2000,
+			down:     true,
+			expect:   0,
+		},
+		"not throttled if it is not used": {
+			down:   true,
+			expect: 0,
+		},
+		"step up the current limit": {
+			current: 500,
+			origin:  1000,
+			expect:  600,
+		},
+		"unlimited when it reaches the origin": {
+			current: 900,
+			origin:  1000,
+			expect:  0,
+		},
+		"keep unlimited": {
+			origin: 1000,
+			expect: 0,
+		},
+	}
+
+	for k, v := range cases {
+		t.Run(k, func(t *testing.T) {
+			if next := nextLimit(v.current, v.origin, v.minValue, v.minRatio, 20, v.down); next != v.expect {
+				t.Errorf("Expected %d, got %d", v.expect, next)
+			}
+		})
+	}
+}
+
+func TestDiskIOThrottleExecutor(t *testing.T) {
+	root := t.TempDir()
+	defer func(origin string) { cgroup.Root = origin }(cgroup.Root)
+	cgroup.Root = root
+
+	pod := &v1.Pod{
+		ObjectMeta: metav1.ObjectMeta{Name: "pod1", Namespace: "default", UID: "uid1"},
+		Status:     v1.PodStatus{QOSClass: v1.PodQOSBestEffort},
+	}
+	indexer := cache.NewIndexer(cache.MetaNamespaceKeyFunc, cache.Indexers{})
+	indexer.Add(pod)
+
+	os.WriteFile(filepath.Join(root, cgroup.ControllersFile), []byte("cpu io memory\n"), 0644)
+	dir := filepath.Join(root, "kubepods", "besteffort", "poduid1")
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		t.Fatal(err)
+	}
+	os.WriteFile(filepath.Join(dir, ioStatFile), []byte("8:0 rbytes=90112 wbytes=0 rios=3 wios=0\n"), 0644)
+
+	stateDir := t.TempDir()
+	state, err := NewThrottleState(stateDir, throttleStateFileName)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ctx := &ExecuteContext{PodLister: corelisters.NewPodLister(indexer), ThrottleState: state}
+
+	throttlePod := DiskIOThrottlePod{
+		DiskIOThrottle:   DiskIORatio{StepIORatio: 50, MinIORatio: 10},
+		PodTypes:         types.NamespacedName{Namespace: "default", Name: "pod1"},
+		PodDiskReadKiBps: 1000,
+		PodDiskReadIOPS:  100,
+	}
+	executor := DiskIOThrottleExecutor{ThrottleDownPods: DiskIOThrottlePods{throttlePod}, ThrottleUpPods: DiskIOThrottlePods{throttlePod}}
+
+	if err := executor.Avoid(ctx); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	expectIOMax(t, dir, "8:0 rbps=512000 wbps=max riops=50 wiops=max")
+
+	// the throttle state is restored after restart
+	if ctx.ThrottleState, err = NewThrottleState(stateDir, throttleStateFileName); err != nil {
+		t.Fatal(err)
+	}
+	limit, ok := ctx.ThrottleState.GetDiskIOLimit("uid1")
+	if !ok || limit.Current.ReadBps != 512000 || limit.Origin.ReadBps != 1024000 {
+		t.Fatalf("Unexpected disk io limit after restart: %+v", limit)
+	}
+
+	if err := executor.Avoid(ctx); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	expectIOMax(t, dir, "8:0 rbps=256000 wbps=max riops=25 wiops=max")
+
+	for _, expect := range []string{
+		"8:0 rbps=384000 wbps=max riops=37 wiops=max",
+		"8:0 rbps=576000 wbps=max riops=55 wiops=max",
+		"8:0 rbps=864000 wbps=max riops=82 wiops=max",
+		"8:0 rbps=max wbps=max riops=max wiops=max",
+	} {
+		if err := executor.Restore(ctx); err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+		expectIOMax(t, dir, expect)
+	}
+
+	if _, ok := ctx.ThrottleState.GetDiskIOLimit("uid1"); ok {
+		t.Errorf("Expected the disk io limit to be removed after restored")
+	}
+
+	// the limit is kept for the targets, and removed once the pod is not a target any more
+	if err := executor.Avoid(ctx); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	reconcileDiskIO(ctx, sets.NewString("default/pod1"))
+	expectIOMax(t, dir, "8:0 rbps=512000 wbps=max riops=50 wiops=max")
+	reconcileDiskIO(ctx, sets.NewString())
+	expectIOMax(t, dir, "8:0 rbps=max wbps=max riops=max wiops=max")
+	if _, ok := ctx.ThrottleState.GetDiskIOLimit("uid1"); ok {
+		t.Errorf("Expected the disk io limit of the orphaned pod to be removed")
+	}
+}
+
+func expectIOMax(t *testing.T, dir string, expect string) {
+	data, _ := os.ReadFile(filepath.Join(dir, cgroup.IOMaxFile))
+	if strings.TrimSpace(string(data)) != expect {
+		t.Errorf("Expected io.max %q, got %q", expect, data)
+	}
+}
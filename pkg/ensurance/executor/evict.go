@@ -1,11 +1,12 @@
 package executor
 
 import (
-	"fmt"
 	"strings"
 	"sync"
 	"time"
 
+	v1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
 	"k8s.io/apimachinery/pkg/types"
 	"k8s.io/klog/v2"
 
@@ -22,6 +23,8 @@ type EvictPod struct {
 	DeletionGracePeriodSeconds *int32
 	PodKey                     types.NamespacedName
 	ClassAndPriority           ClassAndPriority
+	// Reason explains why the pod is chosen to be evicted
+	Reason string
 }
 
 type EvictPods []EvictPod
@@ -57,42 +60,57 @@ func (e *EvictExecutor) Avoid(ctx *ExecuteContext) error {
 	metrics.UpdateExecutorStatus(metrics.SubComponentEvict, metrics.StepAvoid, 1.0)
 	metrics.ExecutorStatusCounterInc(metrics.SubComponentEvict, metrics.StepAvoid)
 
-	var bSucceed = true
 	var errPodKeys []string
 
-	wg := sync.WaitGroup{}
-
-	for i := range e.EvictPods {
-		wg.Add(1)
+	// the pods are evicted one by one in the order of priority, so that the limits are consumed by the pods of low priority
+	for _, evictPod := range e.EvictPods {
+		pod, err := ctx.PodLister.Pods(evictPod.PodKey.Namespace).Get(evictPod.PodKey.Name)
+		if err != nil {
+			metrics.ExecutorEvictionCounterInc(metrics.EvictionResultSkipped)
+			errPodKeys = append(errPodKeys, "not found "+evictPod.PodKey.String())
+			continue
+		}
 
-		go func(evictPod EvictPod) {
-			defer wg.Done()
+		if ctx.EvictionLimiter.InBackoff(pod.UID, time.Now()) {
+			metrics.ExecutorEvictionCounterInc(metrics.EvictionResultBlocked)
+			klog.V(4).Infof("Pod %s is not evicted, it is backing off after blocked by the PodDisruptionBudgets", klog.KObj(pod))
+			continue
+		}
 
-			pod, err := ctx.PodLister.Pods(evictPod.PodKey.Namespace).Get(evictPod.PodKey.Name)
-			if err != nil {
-				bSucceed = false
-				errPodKeys = append(errPodKeys, "not found ", evictPod.PodKey.String())
-				return
-			}
+		if !ctx.EvictionLimiter.Allow(pod.Namespace, time.Now()) {
+			metrics.ExecutorEvictionCounterInc(metrics.EvictionResultSkipped)
+			klog.V(4).Infof("Pod %s is not evicted, the eviction rate limit or budget is reached", klog.KObj(pod))
+			continue
+		}
 
-			err = utils.EvictPodWithGracePeriod(ctx.Client, pod, evictPod.DeletionGracePeriodSeconds)
-			if err != nil {
-				bSucceed = false
-				errPodKeys = append(errPodKeys, "evict failed ", evictPod.PodKey.String())
-				klog.Warningf("Failed to evict pod %s: %v", evictPod.PodKey.String(), err)
-				return
-			}
+		err = utils.EvictPodWithGracePeriod(ctx.Client, pod, evictPod.DeletionGracePeriodSeconds)
+		// the eviction is rejected with 429 if it violates the PodDisruptionBudgets, it is retried with backoff in the
+		// next rounds instead of waiting here, so that the other actions are not delayed
+		if errors.IsTooManyRequests(err) {
+			metrics.ExecutorEvictionCounterInc(metrics.EvictionResultBlocked)
+			delay := ctx.EvictionLimiter.Backoff(pod.UID, time.Now())
+			klog.V(4).Infof("Pod %s is not evicted, it is blocked by the PodDisruptionBudgets, retry after %v: %v", klog.KObj(pod), delay, err)
+			continue
+		}
+		if err != nil {
+			metrics.ExecutorEvictionCounterInc(metrics.EvictionResultFailed)
+			errPodKeys = append(errPodKeys, "evict failed "+evictPod.PodKey.String())
+			klog.Warningf("Failed to evict pod %s: %v", evictPod.PodKey.String(), err)
+			continue
+		}
 
-			metrics.ExecutorEvictCountsInc()
+		ctx.EvictionLimiter.Record(pod.Namespace, time.Now())
+		ctx.EvictionLimiter.Reset(pod.UID)
+		metrics.ExecutorEvictCountsInc()
+		metrics.ExecutorEvictionCounterInc(metrics.EvictionResultDone)
+		ctx.Recorder.Event(pod, v1.EventTypeWarning, "Evicted", evictPod.Reason)
 
-			klog.V(4).Infof("Pod %s is evicted", klog.KObj(pod))
-		}(e.EvictPods[i])
+		klog.V(4).Infof("Pod %s is evicted", klog.KObj(pod))
 	}
 
-	wg.Wait()
-
-	if !bSucceed {
-		return fmt.Errorf("some pod evict failed,err: %s", strings.Join(errPodKeys, ";"))
+	// the pods failed to evict are counted and picked again in the next round, they do not stop the other actions
+	if len(errPodKeys) != 0 {
+		klog.Warningf("Some pods are not evicted: %s", strings.Join(errPodKeys, ";"))
 	}
 
 	return nil
@@ -101,3 +119,139 @@ func (e *EvictExecutor) Avoid(ctx *ExecuteContext) error {
 func (e *EvictExecutor) Restore(ctx *ExecuteContext) error {
 	return nil
 }
+
+// EvictionOptions limits the evictions on the node, zero means unlimited.
+type EvictionOptions struct {
+	// RateLimit is the max number of evictions in the RateInterval
+	RateLimit    int
+	RateInterval time.Duration
+	// NodeBudget is the max number of evictions on the node in the BudgetWindow
+	NodeBudget int
+	// NamespaceBudget is the max number of evictions of a namespace on the node in the BudgetWindow
+	NamespaceBudget int
+	BudgetWindow    time.Duration
+}
+
+const (
+	// evictionBackoffInitial is the delay to evict a pod again after the eviction is blocked by the PodDisruptionBudgets,
+	// it is doubled on each blocked eviction up to evictionBackoffMax
+	evictionBackoffInitial = 10 * time.Second
+	evictionBackoffMax     = 5 * time.Minute
+)
+
+type evictionRecord struct {
+	namespace string
+	time      time.Time
+}
+
+type evictionBackoff struct {
+	next  time.Time
+	delay time.Duration
+}
+
+// EvictionLimiter keeps the recent evictions to limit the rate and the budgets of evictions, and the backoffs of the
+// pods whose evictions are blocked.
+type EvictionLimiter struct {
+	sync.Mutex
+	options  EvictionOptions
+	records  []evictionRecord
+	backoffs map[types.UID]evictionBackoff
+}
+
+func NewEvictionLimiter(options EvictionOptions) *EvictionLimiter {
+	return &EvictionLimiter{options: options, backoffs: make(map[types.UID]evictionBackoff)}
+}
+
+// Allow returns true if a pod of the namespace can be evicted now.
+func (l *EvictionLimiter) Allow(namespace string, now time.Time) bool {
+	l.Lock()
+	defer l.Unlock()
+
+	l.prune(now)
+
+	var inInterval, inWindow, inNamespace int
+	for _, r := range l.records {
+		if now.Sub(r.time) < l.options.RateInterval {
+			inInterval++
+		}
+		if now.Sub(r.time) < l.options.BudgetWindow {
+			inWindow++
+			if r.namespace == namespace {
+				inNamespace++
+			}
+		}
+	}
+
+	if l.options.RateLimit > 0 && inInterval >= l.options.RateLimit {
+		return false
+	}
+	if l.options.NodeBudget > 0 && inWindow >= l.options.NodeBudget {
+		return false
+	}
+	if l.options.NamespaceBudget > 0 && inNamespace >= l.options.NamespaceBudget {
+		return false
+	}
+	return true
+}
+
+// Record records a pod of the namespace is evicted.
+func (l *EvictionLimiter) Record(namespace string, now time.Time) {
+	l.Lock()
+	defer l.Unlock()
+
+	l.records = append(l.records, evictionRecord{namespace: namespace, time: now})
+}
+
+// InBackoff returns true if the eviction of the pod was blocked and it is not the time to evict it again.
+func (l *EvictionLimiter) InBackoff(uid types.UID, now time.Time) bool {
+	l.Lock()
+	defer l.Unlock()
+
+	backoff, ok := l.backoffs[uid]
+	return ok && now.Before(backoff.next)
+}
+
+// Backoff records the eviction of the pod is blocked, and returns the delay to evict it again.
+func (l *EvictionLimiter) Backoff(uid types.UID, now time.Time) time.Duration {
+	l.Lock()
+	defer l.Unlock()
+
+	var delay = evictionBackoffInitial
+	if backoff, ok := l.backoffs[uid]; ok {
+		delay = backoff.delay * 2
+		if delay > evictionBackoffMax {
+			delay = evictionBackoffMax
+		}
+	}
+	l.backoffs[uid] = evictionBackoff{next: now.Add(delay), delay: delay}
+	return delay
+}
+
+// Reset removes the backoff of the pod.
+func (l *EvictionLimiter) Reset(uid types.UID) {
+	l.Lock()
+	defer l.Unlock()
+
+	delete(l.backoffs, uid)
+}
+
+// prune removes the records out of both the rate interval and the budget window, and the backoffs of the pods which
+// are not evicted again for a long time, such as the pods deleted or not chosen any more. The caller must hold the lock.
+func (l *EvictionLimiter) prune(now time.Time) {
+	var keep = l.options.RateInterval
+	if l.options.BudgetWindow > keep {
+		keep = l.options.BudgetWindow
+	}
+
+	var i int
+	for i < len(l.records) && now.Sub(l.records[i].time) >= keep {
+		i++
+	}
+	l.records = l.records[i:]
+
+	for uid, backoff := range l.backoffs {
+		if now.Sub(backoff.next) >= evictionBackoffMax {
+			delete(l.backoffs, uid)
+		}
+	}
+}
@@ -0,0 +1,187 @@
+package executor
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	v1 "k8s.io/api/core/v1"
+	policyv1beta1 "k8s.io/api/policy/v1beta1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/kubernetes/fake"
+	corelisters "k8s.io/client-go/listers/core/v1"
+	k8stesting "k8s.io/client-go/testing"
+	"k8s.io/client-go/tools/cache"
+	"k8s.io/client-go/tools/record"
+)
+
+func TestEvictionLimiter(t *testing.T) {
+	now := time.Now()
+
+	cases := map[string]struct {
+		options   EvictionOptions
+		evicted   map[string]time.Duration
+		namespace string
+		expect    bool
+	}{
+		"unlimited": {
+			evicted:   map[string]time.Duration{"default": 0},
+			namespace: "default",
+			expect:    true,
+		},
+		"rate limited": {
+			options:   EvictionOptions{RateLimit: 1, RateInterval: time.Minute},
+			evicted:   map[string]time.Duration{"other": 30 * time.Second},
+			namespace: "default",
+			expect:    false,
+		},
+		"rate interval passed": {
+			options:   EvictionOptions{RateLimit: 1, RateInterval: time.Minute},
+			evicted:   map[string]time.Duration{"default": 2 * time.Minute},
+			namespace: "default",
+			expect:    true,
+		},
+		"node budget exhausted": {
+			options:   EvictionOptions{NodeBudget: 1, BudgetWindow: time.Hour},
+			evicted:   map[string]time.Duration{"other": 30 * time.Minute},
+			namespace: "default",
+			expect:    false,
+		},
+		"namespace budget exhausted": {
+			options:   EvictionOptions{NamespaceBudget: 1, BudgetWindow: time.Hour},
+			evicted:   map[string]time.Duration{"default": 30 * time.Minute},
+			namespace: "default",
+			expect:    false,
+		},
+		"namespace budget of other namespace": {
+			options:   EvictionOptions{NamespaceBudget: 1, BudgetWindow: time.Hour},
+			evicted:   map[string]time.Duration{"other": 30 * time.Minute},
+			namespace: "default",
+			expect:    true,
+		},
+	}
+
+	for k, v := range cases {
+		t.Run(k, func(t *testing.T) {
+			limiter := NewEvictionLimiter(v.options)
+			for namespace, ago := range v.evicted {
+				limiter.Record(namespace, now.Add(-ago))
+			}
+			if allowed := limiter.Allow(v.namespace, now); allowed != v.expect {
+				t.Errorf("Expected allowed %v, got %v", v.expect, allowed)
+			}
+		})
+	}
+}
+
+func TestEvictExecutor(t *testing.T) {
+	indexer := cache.NewIndexer(cache.MetaNamespaceKeyFunc, cache.Indexers{})
+	for _, name := range []string{"pod1", "pod2", "pod3", "blocked"} {
+		indexer.Add(&v1.Pod{ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: "default", UID: types.UID(name)}})
+	}
+
+	client := fake.NewSimpleClientset()
+	var evicted []string
+	var blockedAttempts int
+	client.PrependReactor("create", "pods", func(action k8stesting.Action) (bool, runtime.Object, error) {
+		if action.GetSubresource() != "eviction" {
+			return false, nil, nil
+		}
+		eviction := action.(k8stesting.CreateAction).GetObject().(*policyv1beta1.Eviction)
+		if eviction.Name == "blocked" {
+			blockedAttempts++
+			return true, nil, errors.NewTooManyRequests("Cannot evict pod as it would violate the pod's disruption budget.", 0)
+		}
+		evicted = append(evicted, eviction.Name)
+		return true, nil, nil
+	})
+
+	recorder := record.NewFakeRecorder(10)
+	ctx := &ExecuteContext{
+		Client:          client,
+		PodLister:       corelisters.NewPodLister(indexer),
+		Recorder:        recorder,
+		EvictionLimiter: NewEvictionLimiter(EvictionOptions{RateLimit: 2, RateInterval: time.Minute}),
+	}
+
+	var evictPods EvictPods
+	for _, name := range []string{"blocked", "pod1", "notfound", "pod2", "pod3"} {
+		evictPods = append(evictPods, EvictPod{PodKey: types.NamespacedName{Namespace: "default", Name: name}, Reason: "test"})
+	}
+	executor := EvictExecutor{EvictPods: evictPods}
+
+	// the pods failed to evict do not fail the action, so the actions after it are not skipped
+	if err := executor.Avoid(ctx); err != nil {
+		t.Errorf("Unexpected error: %v", err)
+	}
+	if blockedAttempts != 1 {
+		t.Errorf("Expected the blocked eviction not to be retried in the round, got %d attempts", blockedAttempts)
+	}
+	// pod3 is skipped by the rate limit
+	if strings.Join(evicted, ",") != "pod1,pod2" {
+		t.Errorf("Expected pod1 and pod2 evicted, got %v", evicted)
+	}
+	if len(recorder.Events) != 2 {
+		t.Errorf("Expected 2 events for the evicted pods, got %d", len(recorder.Events))
+	}
+
+	// the blocked pod is not evicted again until the backoff passes
+	executor = EvictExecutor{EvictPods: EvictPods{evictPods[0]}}
+	if err := executor.Avoid(ctx); err != nil {
+		t.Errorf("Unexpected error: %v", err)
+	}
+	if blockedAttempts != 1 {
+		t.Errorf("Expected the blocked eviction to back off, got %d attempts", blockedAttempts)
+	}
+
+	// the backoff and the rate interval pass
+	ctx.EvictionLimiter.backoffs["blocked"] = evictionBackoff{next: time.Now().Add(-time.Second), delay: evictionBackoffInitial}
+	ctx.EvictionLimiter.records = nil
+	if err := executor.Avoid(ctx); err != nil {
+		t.Errorf("Unexpected error: %v", err)
+	}
+	if blockedAttempts != 2 {
+		t.Errorf("Expected the blocked eviction retried after the backoff, got %d attempts", blockedAttempts)
+	}
+	if delay := ctx.EvictionLimiter.backoffs["blocked"].delay; delay != 2*evictionBackoffInitial {
+		t.Errorf("Expected the backoff doubled to %v, got %v", 2*evictionBackoffInitial, delay)
+	}
+}
+
+func TestEvictionBackoff(t *testing.T) {
+	now := time.Now()
+	limiter := NewEvictionLimiter(EvictionOptions{})
+
+	if limiter.InBackoff("pod1", now) {
+		t.Errorf("Expected no backoff before blocked")
+	}
+
+	var delays []time.Duration
+	for i := 0; i < 7; i++ {
+		delays = append(delays, limiter.Backoff("pod1", now))
+	}
+	expect := []time.Duration{10 * time.Second, 20 * time.Second, 40 * time.Second, 80 * time.Second, 160 * time.Second, evictionBackoffMax, evictionBackoffMax}
+	if !reflect.DeepEqual(delays, expect) {
+		t.Errorf("Expected the delays %v, got %v", expect, delays)
+	}
+
+	if !limiter.InBackoff("pod1", now.Add(evictionBackoffMax-time.Second)) || limiter.InBackoff("pod1", now.Add(evictionBackoffMax)) {
+		t.Errorf("Expected the backoff to pass after %v", evictionBackoffMax)
+	}
+
+	limiter.Reset("pod1")
+	if limiter.InBackoff("pod1", now) {
+		t.Errorf("Expected no backoff after reset")
+	}
+
+	// the backoffs not used for long are pruned
+	limiter.Backoff("pod2", now)
+	limiter.Allow("default", now.Add(evictionBackoffInitial+evictionBackoffMax))
+	if _, ok := limiter.backoffs["pod2"]; ok {
+		t.Errorf("Expected the stale backoff to be pruned")
+	}
+}
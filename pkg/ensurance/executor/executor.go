@@ -1,16 +1,21 @@
 package executor
 
 import (
+	"sync"
 	"time"
 
 	"github.com/gocrane/crane/pkg/known"
 	"github.com/gocrane/crane/pkg/metrics"
 	"google.golang.org/grpc"
 
+	v1 "k8s.io/api/core/v1"
 	coreinformers "k8s.io/client-go/informers/core/v1"
 	clientset "k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/kubernetes/scheme"
+	typedcorev1 "k8s.io/client-go/kubernetes/typed/core/v1"
 	corelisters "k8s.io/client-go/listers/core/v1"
 	"k8s.io/client-go/tools/cache"
+	"k8s.io/client-go/tools/record"
 	pb "k8s.io/cri-api/pkg/apis/runtime/v1alpha2"
 	"k8s.io/klog/v2"
 
@@ -30,11 +35,20 @@ type ActionExecutor struct {
 
 	runtimeClient pb.RuntimeServiceClient
 	runtimeConn   *grpc.ClientConn
+
+	throttleState *ThrottleState
+	recorder      record.EventRecorder
+
+	evictionLimiter *EvictionLimiter
+
+	// lastExecution is the plan and the result of the latest AvoidanceExecutor executed
+	executionLock sync.RWMutex
+	lastExecution Execution
 }
 
 // NewActionExecutor create enforcer manager
 func NewActionExecutor(client clientset.Interface, nodeName string, podInformer coreinformers.PodInformer, nodeInformer coreinformers.NodeInformer,
-	noticeCh <-chan AvoidanceExecutor, runtimeEndpoint string) *ActionExecutor {
+	noticeCh <-chan AvoidanceExecutor, runtimeEndpoint string, evictionOptions EvictionOptions) *ActionExecutor {
 
 	runtimeClient, runtimeConn, err := cruntime.GetRuntimeClient(runtimeEndpoint)
 	if err != nil {
@@ -42,6 +56,17 @@ func NewActionExecutor(client clientset.Interface, nodeName string, podInformer
 		return nil
 	}
 
+	throttleState, err := NewThrottleState(stateFileDirectory, throttleStateFileName)
+	if err != nil {
+		klog.Errorf("Could not initialize throttle state: %v, please remove the throttle state file", err)
+		return nil
+	}
+
+	eventBroadcaster := record.NewBroadcaster()
+	eventBroadcaster.StartStructuredLogging(0)
+	eventBroadcaster.StartRecordingToSink(&typedcorev1.EventSinkImpl{Interface: client.CoreV1().Events("")})
+	recorder := eventBroadcaster.NewRecorder(scheme.Scheme, v1.EventSource{Component: "crane-agent"})
+
 	return &ActionExecutor{
 		nodeName:      nodeName,
 		client:        client,
@@ -52,6 +77,10 @@ func NewActionExecutor(client clientset.Interface, nodeName string, podInformer
 		nodeSynced:    nodeInformer.Informer().HasSynced,
 		runtimeClient: runtimeClient,
 		runtimeConn:   runtimeConn,
+		throttleState: throttleState,
+		recorder:      recorder,
+
+		evictionLimiter: NewEvictionLimiter(evictionOptions),
 	}
 }
 
@@ -71,16 +100,27 @@ func (a *ActionExecutor) Run(stop <-chan struct{}) {
 		return
 	}
 
+	// apply the limits of the throttle state again after restart
+	ctx := a.newExecuteContext()
+	reconcileDiskIO(ctx, nil)
+	reconcileNetwork(ctx, nil)
+	reconcileCPUQuota(ctx, nil)
+
 	go func() {
 		for {
 			select {
 			case as := <-a.noticeCh:
 				start := time.Now()
 				metrics.UpdateLastTime(string(known.ModuleActionExecutor), metrics.StepMain, start)
+				// the plan is built before the execution, since the cpu quotas are changed by it
+				execution := Execution{Plan: NewPlan(as, a.podLister, start)}
 				if err := a.execute(as, stop); err != nil {
 					// TODO: if it failed in action, how to retry
 					klog.Errorf("Failed to execute action: %v", err)
+					execution.Error = err.Error()
 				}
+				execution.Duration = time.Since(start).String()
+				a.setLastExecution(execution)
 				metrics.UpdateDurationFromStart(string(known.ModuleActionExecutor), metrics.StepMain, start)
 
 			case <-stop:
@@ -96,15 +136,41 @@ func (a *ActionExecutor) Run(stop <-chan struct{}) {
 	return
 }
 
-func (a *ActionExecutor) execute(ae AvoidanceExecutor, _ <-chan struct{}) error {
-	var ctx = &ExecuteContext{
+func (a *ActionExecutor) setLastExecution(execution Execution) {
+	a.executionLock.Lock()
+	defer a.executionLock.Unlock()
+	a.lastExecution = execution
+}
+
+// GetLastExecution returns the plan and the result of the latest AvoidanceExecutor executed.
+func (a *ActionExecutor) GetLastExecution() Execution {
+	a.executionLock.RLock()
+	defer a.executionLock.RUnlock()
+	return a.lastExecution
+}
+
+func (a *ActionExecutor) newExecuteContext() *ExecuteContext {
+	return &ExecuteContext{
 		NodeName:      a.nodeName,
 		Client:        a.client,
 		PodLister:     a.podLister,
 		NodeLister:    a.nodeLister,
 		RuntimeClient: a.runtimeClient,
 		RuntimeConn:   a.runtimeConn,
+		ThrottleState: a.throttleState,
+		Recorder:      a.recorder,
+
+		EvictionLimiter: a.evictionLimiter,
 	}
+}
+
+func (a *ActionExecutor) execute(ae AvoidanceExecutor, _ <-chan struct{}) error {
+	var ctx = a.newExecuteContext()
+
+	// the containers and pods throttled are restored if no objective ensurance throttles them any more
+	reconcileCPUQuota(ctx, ae.ThrottleExecutor.Targets)
+	reconcileDiskIO(ctx, ae.DiskIOThrottleExecutor.Targets)
+	reconcileNetwork(ctx, ae.NetworkThrottleExecutor.Targets)
 
 	//step1 do enforcer actions
 	if err := avoid(ctx, ae); err != nil {
@@ -142,6 +208,18 @@ func avoid(ctx *ExecuteContext, ae AvoidanceExecutor) error {
 		return err
 	}
 
+	//step4 do DiskIO Throttle action
+	if err := ae.DiskIOThrottleExecutor.Avoid(ctx); err != nil {
+		metrics.ExecutorErrorCounterInc(metrics.SubComponentDiskIOThrottle, metrics.StepAvoid)
+		return err
+	}
+
+	//step5 do Network Throttle action
+	if err := ae.NetworkThrottleExecutor.Avoid(ctx); err != nil {
+		metrics.ExecutorErrorCounterInc(metrics.SubComponentNetworkThrottle, metrics.StepAvoid)
+		return err
+	}
+
 	return nil
 }
 
@@ -168,5 +246,17 @@ func restore(ctx *ExecuteContext, ae AvoidanceExecutor) error {
 		return err
 	}
 
+	//step4 do DiskIO Throttle action
+	if err := ae.DiskIOThrottleExecutor.Restore(ctx); err != nil {
+		metrics.ExecutorErrorCounterInc(metrics.SubComponentDiskIOThrottle, metrics.StepRestore)
+		return err
+	}
+
+	//step5 do Network Throttle action
+	if err := ae.NetworkThrottleExecutor.Restore(ctx); err != nil {
+		metrics.ExecutorErrorCounterInc(metrics.SubComponentNetworkThrottle, metrics.StepRestore)
+		return err
+	}
+
 	return nil
 }
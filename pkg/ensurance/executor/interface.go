@@ -4,6 +4,7 @@ import (
 	"google.golang.org/grpc"
 	clientset "k8s.io/client-go/kubernetes"
 	corelisters "k8s.io/client-go/listers/core/v1"
+	"k8s.io/client-go/tools/record"
 	pb "k8s.io/cri-api/pkg/apis/runtime/v1alpha2"
 )
 
@@ -13,9 +14,11 @@ type Executor interface {
 }
 
 type AvoidanceExecutor struct {
-	ScheduleExecutor ScheduleExecutor
-	ThrottleExecutor ThrottleExecutor
-	EvictExecutor    EvictExecutor
+	ScheduleExecutor        ScheduleExecutor
+	ThrottleExecutor        ThrottleExecutor
+	DiskIOThrottleExecutor  DiskIOThrottleExecutor
+	NetworkThrottleExecutor NetworkThrottleExecutor
+	EvictExecutor           EvictExecutor
 }
 
 type ExecuteContext struct {
@@ -25,4 +28,8 @@ type ExecuteContext struct {
 	NodeLister    corelisters.NodeLister
 	RuntimeClient pb.RuntimeServiceClient
 	RuntimeConn   *grpc.ClientConn
+	ThrottleState *ThrottleState
+	Recorder      record.EventRecorder
+
+	EvictionLimiter *EvictionLimiter
 }
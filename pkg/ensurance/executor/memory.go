@@ -0,0 +1,285 @@
+package executor
+
+import (
+	"bufio"
+	"fmt"
+	"math"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	v1 "k8s.io/api/core/v1"
+	"k8s.io/klog/v2"
+
+	"github.com/gocrane/crane/pkg/ensurance/cgroup"
+	cruntime "github.com/gocrane/crane/pkg/ensurance/runtime"
+	"github.com/gocrane/crane/pkg/utils"
+)
+
+const (
+	memoryReclaimFile    = "memory.reclaim"
+	memoryStatFile       = "memory.stat"
+	memoryForceEmptyFile = "memory.force_empty"
+)
+
+// memoryBound is the memory of a container or a pod in bytes, the current is zero if it is unlimited.
+type memoryBound struct {
+	current float64
+	usage   float64
+	request float64
+	limit   float64
+}
+
+// nextMemory returns the memory limit for the next step, it is zero if the memory should be unlimited.
+// When throttling down, the memory is stepped down toward the usage but not less than the request and the min ratio,
+// with keepUsage it is not less than the usage to avoid oom kill. When restoring, the memory is stepped up to the
+// limit of the spec, or to unlimited if it reaches max.
+func nextMemory(b memoryBound, throttle MemoryThrottleExecutor, down bool, keepUsage bool, max float64) float64 {
+	if down {
+		var base = b.current
+		if base <= 0 {
+			base = b.usage
+		}
+
+		var next = base * (1.0 - float64(throttle.StepMemoryRatio)/MaxRatio)
+
+		var floor = b.request
+		if b.limit > 0 {
+			floor = math.Max(floor, b.limit*float64(throttle.MinMemoryRatio)/MaxRatio)
+		} else {
+			floor = math.Max(floor, b.usage*float64(throttle.MinMemoryRatio)/MaxRatio)
+		}
+		if keepUsage {
+			floor = math.Max(floor, b.usage)
+		}
+		next = math.Max(next, floor)
+
+		// never throttle up when throttling down
+		if b.current > 0 {
+			next = math.Min(next, b.current)
+		}
+		return next
+	}
+
+	if b.current <= 0 {
+		return 0
+	}
+
+	var next = b.current * (1.0 + float64(throttle.StepMemoryRatio)/MaxRatio)
+	if b.limit > 0 {
+		return math.Min(next, b.limit)
+	}
+	if max > 0 && next >= max {
+		return 0
+	}
+	return next
+}
+
+func throttleDownMemory(ctx *ExecuteContext, pod *v1.Pod, throttlePod ThrottlePod) error {
+	var errs []string
+
+	if throttlePod.MemoryThrottle.StepMemoryRatio > 0 {
+		var err error
+		if throttlePod.MemoryThrottle.MemoryHigh {
+			err = throttleMemoryHigh(ctx, pod, throttlePod, true)
+		} else {
+			err = throttleMemoryLimit(ctx, pod, throttlePod, true)
+		}
+		if err != nil {
+			errs = append(errs, err.Error())
+		}
+	}
+
+	if throttlePod.MemoryThrottle.ForceGC {
+		if err := reclaimPageCache(pod); err != nil {
+			errs = append(errs, err.Error())
+		}
+	}
+
+	if len(errs) != 0 {
+		return fmt.Errorf("%s", strings.Join(errs, ";"))
+	}
+	return nil
+}
+
+func throttleUpMemory(ctx *ExecuteContext, pod *v1.Pod, throttlePod ThrottlePod) error {
+	if throttlePod.MemoryThrottle.MemoryHigh {
+		return throttleMemoryHigh(ctx, pod, throttlePod, false)
+	}
+	return throttleMemoryLimit(ctx, pod, throttlePod, false)
+}
+
+// throttleMemoryLimit steps the memory limit of the containers by the runtime.
+func throttleMemoryLimit(ctx *ExecuteContext, pod *v1.Pod, throttlePod ThrottlePod, down bool) error {
+	nodeMemory, err := getNodeMemory(ctx)
+	if err != nil {
+		return err
+	}
+
+	var errs []string
+	for _, v := range throttlePod.ContainerMemLimits {
+		// pause container to skip
+		if v.ContainerName == "" {
+			continue
+		}
+
+		container, err := utils.GetPodContainerByName(pod, v.ContainerName)
+		if err != nil {
+			errs = append(errs, err.Error())
+			continue
+		}
+
+		var bound = memoryBound{current: v.Value}
+		// cadvisor reports a huge number if the memory is unlimited
+		if bound.current >= nodeMemory {
+			bound.current = 0
+		}
+		if usage, err := GetUsageById(throttlePod.ContainerMemUsages, v.ContainerId); err == nil {
+			bound.usage = usage.Value
+		}
+		if request, ok := container.Resources.Requests[v1.ResourceMemory]; ok {
+			bound.request = float64(request.Value())
+		}
+		if limit, ok := container.Resources.Limits[v1.ResourceMemory]; ok {
+			bound.limit = float64(limit.Value())
+		}
+
+		next := nextMemory(bound, throttlePod.MemoryThrottle, down, true, nodeMemory)
+		if next <= 0 {
+			if bound.current <= 0 {
+				continue
+			}
+			// the memory limit can not be removed by the runtime, it is set to the memory of the node
+			next = nodeMemory
+		}
+
+		if utils.AlmostEqual(next, bound.current) {
+			continue
+		}
+
+		if err := cruntime.UpdateContainerResources(ctx.RuntimeClient, v.ContainerId, cruntime.UpdateOptions{MemoryLimitInBytes: int64(next)}); err != nil {
+			errs = append(errs, fmt.Sprintf("failed to update memory limit for container %s: %v", v.ContainerName, err))
+			continue
+		}
+
+		klog.V(4).Infof("ThrottleExecutor pod %s, container %s, set memory limit %.0f.", klog.KObj(pod), v.ContainerName, next)
+	}
+
+	if len(errs) != 0 {
+		return fmt.Errorf("%s", strings.Join(errs, ";"))
+	}
+	return nil
+}
+
+// throttleMemoryHigh steps the memory.high of the pod cgroup, the memory above memory.high is reclaimed by the kernel.
+func throttleMemoryHigh(ctx *ExecuteContext, pod *v1.Pod, throttlePod ThrottlePod, down bool) error {
+	dir, err := cgroup.PodDir(pod, memorySubsystem)
+	if err != nil {
+		return err
+	}
+
+	current, err := cgroup.ReadMemoryHigh(dir)
+	if err != nil {
+		return err
+	}
+
+	nodeMemory, err := getNodeMemory(ctx)
+	if err != nil {
+		return err
+	}
+
+	var bound = memoryBound{current: current, usage: throttlePod.PodMemUsage}
+	if bound.usage <= 0 {
+		for _, c := range throttlePod.ContainerMemUsages {
+			bound.usage += c.Value
+		}
+	}
+
+	var limited = true
+	for _, container := range pod.Spec.Containers {
+		if request, ok := container.Resources.Requests[v1.ResourceMemory]; ok {
+			bound.request += float64(request.Value())
+		}
+		if limit, ok := container.Resources.Limits[v1.ResourceMemory]; ok {
+			bound.limit += float64(limit.Value())
+		} else {
+			limited = false
+		}
+	}
+	if !limited {
+		bound.limit = 0
+	}
+
+	next := nextMemory(bound, throttlePod.MemoryThrottle, down, false, nodeMemory)
+	// memory.high is not needed at the limit of the spec
+	if !down && bound.limit > 0 && next >= bound.limit {
+		next = 0
+	}
+	if utils.AlmostEqual(next, current) {
+		return nil
+	}
+
+	if err := cgroup.WriteMemoryHigh(dir, next); err != nil {
+		return err
+	}
+
+	klog.V(4).Infof("ThrottleExecutor pod %s, set memory.high %.0f.", klog.KObj(pod), next)
+	return nil
+}
+
+// reclaimPageCache reclaims the page cache of the pod by memory.reclaim of cgroup v2, or memory.force_empty of cgroup v1.
+func reclaimPageCache(pod *v1.Pod) error {
+	dir, err := cgroup.PodDir(pod, memorySubsystem)
+	if err != nil {
+		return err
+	}
+
+	if _, err := os.Stat(filepath.Join(dir, memoryReclaimFile)); err == nil {
+		cache, err := readMemoryStat(filepath.Join(dir, memoryStatFile), "file")
+		if err != nil {
+			return err
+		}
+		if cache == 0 {
+			return nil
+		}
+		// it returns EAGAIN if the page cache is not fully reclaimed, which is fine
+		if err := os.WriteFile(filepath.Join(dir, memoryReclaimFile), []byte(strconv.FormatUint(cache, 10)), 0644); err != nil {
+			klog.V(4).Infof("Failed to reclaim the page cache of pod %s fully: %v", klog.KObj(pod), err)
+		}
+		return nil
+	}
+
+	if err := os.WriteFile(filepath.Join(dir, memoryForceEmptyFile), []byte("0"), 0644); err != nil {
+		return fmt.Errorf("failed to reclaim page cache: %v", err)
+	}
+	return nil
+}
+
+func getNodeMemory(ctx *ExecuteContext) (float64, error) {
+	node, err := ctx.NodeLister.Get(ctx.NodeName)
+	if err != nil {
+		return 0, err
+	}
+	return float64(node.Status.Capacity.Memory().Value()), nil
+}
+
+func readMemoryStat(file string, key string) (uint64, error) {
+	f, err := os.Open(file)
+	if err != nil {
+		return 0, err
+	}
+	defer f.Close()
+
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		fields := strings.Fields(scanner.Text())
+		if len(fields) == 2 && fields[0] == key {
+			return strconv.ParseUint(fields[1], 10, 64)
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return 0, err
+	}
+	return 0, fmt.Errorf("%s is not found in %s", key, file)
+}
@@ -0,0 +1,159 @@
+package executor
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	v1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	corelisters "k8s.io/client-go/listers/core/v1"
+	"k8s.io/client-go/tools/cache"
+
+	"github.com/gocrane/crane/pkg/ensurance/cgroup"
+	"github.com/gocrane/crane/pkg/utils"
+)
+
+func TestNextMemory(t *testing.T) {
+	throttle := MemoryThrottleExecutor{MinMemoryRatio: 50, StepMemoryRatio: 10}
+
+	cases := map[string]struct {
+		bound     memoryBound
+		down      bool
+		keepUsage bool
+		expect    float64
+	}{
+		"step down the limit": {
+			bound:  memoryBound{current: 1000, usage: 500, limit: 1000},
+			down:   true,
+			expect: 900,
+		},
+		"not less than the usage": {
+			bound:     memoryBound{current: 1000, usage: 950, limit: 1000},
+			down:      true,
+			keepUsage: true,
+			expect:    950,
+		},
+		"not less than the min ratio": {
+			bound:  memoryBound{current: 520, usage: 100, limit: 1000},
+			down:   true,
+			expect: 500,
+		},
+		"not less than the request": {
+			bound:  memoryBound{current: 800, usage: 100, request: 800, limit: 1000},
+			down:   true,
+			expect: 800,
+		},
+		"step down from the usage if unlimited": {
+			bound:  memoryBound{usage: 1000},
+			down:   true,
+			expect: 900,
+		},
+		"step up the limit": {
+			bound:  memoryBound{current: 900, usage: 500, limit: 1000},
+			expect: 990,
+		},
+		"step up to the limit of spec": {
+			bound:  memoryBound{current: 950, usage: 500, limit: 1000},
+			expect: 1000,
+		},
+		"step up to unlimited": {
+			bound:  memoryBound{current: 9500, usage: 500},
+			expect: 0,
+		},
+		"keep unlimited": {
+			bound:  memoryBound{usage: 500},
+			expect: 0,
+		},
+	}
+
+	for k, v := range cases {
+		t.Run(k, func(t *testing.T) {
+			if next := nextMemory(v.bound, throttle, v.down, v.keepUsage, 10000); !utils.AlmostEqual(next, v.expect) {
+				t.Errorf("Expected %v, got %v", v.expect, next)
+			}
+		})
+	}
+}
+
+func TestThrottleMemoryHigh(t *testing.T) {
+	root := t.TempDir()
+	defer func(origin string) { cgroup.Root = origin }(cgroup.Root)
+	cgroup.Root = root
+
+	pod := &v1.Pod{
+		ObjectMeta: metav1.ObjectMeta{Name: "pod1", Namespace: "default", UID: "uid1"},
+		Spec:       v1.PodSpec{Containers: []v1.Container{{Name: "app"}}},
+		Status:     v1.PodStatus{QOSClass: v1.PodQOSBestEffort},
+	}
+	os.WriteFile(filepath.Join(root, cgroup.ControllersFile), []byte("cpu io memory\n"), 0644)
+	dir := filepath.Join(root, "kubepods", "besteffort", "poduid1")
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(dir, cgroup.MemoryHighFile), []byte("max\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	nodeIndexer := cache.NewIndexer(cache.MetaNamespaceKeyFunc, cache.Indexers{})
+	nodeIndexer.Add(&v1.Node{
+		ObjectMeta: metav1.ObjectMeta{Name: "node1"},
+		Status:     v1.NodeStatus{Capacity: v1.ResourceList{v1.ResourceMemory: resource.MustParse("10000")}},
+	})
+	ctx := &ExecuteContext{NodeName: "node1", NodeLister: corelisters.NewNodeLister(nodeIndexer)}
+
+	throttlePod := ThrottlePod{
+		MemoryThrottle: MemoryThrottleExecutor{StepMemoryRatio: 50, MemoryHigh: true},
+		PodMemUsage:    4000,
+	}
+
+	steps := []struct {
+		down   bool
+		expect string
+	}{
+		{down: true, expect: "2000"},
+		{down: true, expect: "1000"},
+		{down: false, expect: "1500"},
+		{down: false, expect: "2250"},
+		{down: false, expect: "3375"},
+		{down: false, expect: "5062"},
+		{down: false, expect: "7593"},
+		{down: false, expect: "max"},
+	}
+	for i, step := range steps {
+		if err := throttleMemoryHigh(ctx, pod, throttlePod, step.down); err != nil {
+			t.Fatalf("Step %d: unexpected error: %v", i, err)
+		}
+		data, _ := os.ReadFile(filepath.Join(dir, cgroup.MemoryHighFile))
+		if strings.TrimSpace(string(data)) != step.expect {
+			t.Errorf("Step %d: expected memory.high %s, got %s", i, step.expect, data)
+		}
+	}
+}
+
+func TestReclaimPageCache(t *testing.T) {
+	root := t.TempDir()
+	defer func(origin string) { cgroup.Root = origin }(cgroup.Root)
+	cgroup.Root = root
+
+	pod := &v1.Pod{
+		ObjectMeta: metav1.ObjectMeta{Name: "pod1", Namespace: "default", UID: "uid1"},
+		Status:     v1.PodStatus{QOSClass: v1.PodQOSBestEffort},
+	}
+	os.WriteFile(filepath.Join(root, cgroup.ControllersFile), []byte("cpu io memory\n"), 0644)
+	dir := filepath.Join(root, "kubepods", "besteffort", "poduid1")
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		t.Fatal(err)
+	}
+	os.WriteFile(filepath.Join(dir, memoryStatFile), []byte("anon 1024\nfile 4096\n"), 0644)
+	os.WriteFile(filepath.Join(dir, memoryReclaimFile), []byte(""), 0644)
+
+	if err := reclaimPageCache(pod); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if data, _ := os.ReadFile(filepath.Join(dir, memoryReclaimFile)); string(data) != "4096" {
+		t.Errorf("Expected to reclaim 4096 bytes, got %s", data)
+	}
+}
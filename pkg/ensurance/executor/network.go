@@ -0,0 +1,254 @@
+package executor
+
+import (
+	"fmt"
+	"os/exec"
+	"strconv"
+	"strings"
+	"time"
+
+	v1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/apimachinery/pkg/util/sets"
+	"k8s.io/klog/v2"
+
+	"github.com/gocrane/crane/pkg/ensurance/cgroup"
+	"github.com/gocrane/crane/pkg/known"
+	"github.com/gocrane/crane/pkg/metrics"
+)
+
+const (
+	DefaultThrottleInterface = "eth0"
+
+	memorySubsystem = "memory"
+
+	// tbfLatency is the max time a packet waits in the tbf queue
+	tbfLatency = "50ms"
+	// tbfMinBurstBytes is the min size of the tbf bucket, it should be larger than the mtu
+	tbfMinBurstBytes = 16 * 1024
+)
+
+// runTC runs tc in the network namespace of the process, it is a variable for testing.
+var runTC = func(pid int, args ...string) error {
+	cmd := exec.Command("nsenter", append([]string{"-t", strconv.Itoa(pid), "-n", "tc"}, args...)...)
+	if out, err := cmd.CombinedOutput(); err != nil {
+		return fmt.Errorf("tc %s: %v, %s", strings.Join(args, " "), err, strings.TrimSpace(string(out)))
+	}
+	return nil
+}
+
+type NetworkThrottleExecutor struct {
+	ThrottleDownPods NetworkThrottlePods
+	ThrottleUpPods   NetworkThrottlePods
+	// Targets are the pods which may be throttled by the network throttles of the node, keyed by namespace/name. The
+	// limits of the pods out of them are removed, all the limits are kept if it is nil.
+	Targets sets.String
+}
+
+type NetworkRatio struct {
+	//the min of bandwidth ratio of the usage when the throttle begins
+	MinNetworkRatio uint64 `json:"minNetworkRatio,omitempty"`
+
+	//the step of bandwidth limit for once down-size (1-100)
+	StepNetworkRatio uint64 `json:"stepNetworkRatio,omitempty"`
+
+	//the min of egress kilobits per second
+	MinEgressKibps uint64 `json:"minEgressKibps,omitempty"`
+
+	//the network interface in the pod network namespace
+	Interface string `json:"interface,omitempty"`
+}
+
+type NetworkThrottlePod struct {
+	NetworkThrottle NetworkRatio
+	PodTypes        types.NamespacedName
+	PodNetSentKibps float64
+	PodQOSPriority  ClassAndPriority
+}
+
+type NetworkThrottlePods []NetworkThrottlePod
+
+func (t NetworkThrottlePods) Len() int      { return len(t) }
+func (t NetworkThrottlePods) Swap(i, j int) { t[i], t[j] = t[j], t[i] }
+func (t NetworkThrottlePods) Less(i, j int) bool {
+	return t[i].PodQOSPriority.Less(t[j].PodQOSPriority)
+}
+
+func (t NetworkThrottlePods) Find(podTypes types.NamespacedName) int {
+	for i, v := range t {
+		if v.PodTypes == podTypes {
+			return i
+		}
+	}
+
+	return -1
+}
+
+func (t *NetworkThrottleExecutor) Avoid(ctx *ExecuteContext) error {
+	var start = time.Now()
+	metrics.UpdateLastTimeWithSubComponent(string(known.ModuleActionExecutor), string(metrics.SubComponentNetworkThrottle), metrics.StepAvoid, start)
+	defer metrics.UpdateDurationFromStartWithSubComponent(string(known.ModuleActionExecutor), string(metrics.SubComponentNetworkThrottle), metrics.StepAvoid, start)
+
+	klog.V(6).Infof("NetworkThrottleExecutor avoid, %v", *t)
+
+	if len(t.ThrottleDownPods) == 0 {
+		metrics.UpdateExecutorStatus(metrics.SubComponentNetworkThrottle, metrics.StepAvoid, 0)
+		return nil
+	}
+
+	metrics.UpdateExecutorStatus(metrics.SubComponentNetworkThrottle, metrics.StepAvoid, 1.0)
+	metrics.ExecutorStatusCounterInc(metrics.SubComponentNetworkThrottle, metrics.StepAvoid)
+
+	return t.throttle(ctx, t.ThrottleDownPods, true)
+}
+
+func (t *NetworkThrottleExecutor) Restore(ctx *ExecuteContext) error {
+	var start = time.Now()
+	metrics.UpdateLastTimeWithSubComponent(string(known.ModuleActionExecutor), string(metrics.SubComponentNetworkThrottle), metrics.StepRestore, start)
+	defer metrics.UpdateDurationFromStartWithSubComponent(string(known.ModuleActionExecutor), string(metrics.SubComponentNetworkThrottle), metrics.StepRestore, start)
+
+	klog.V(6).Infof("NetworkThrottleExecutor restore, %v", *t)
+
+	if len(t.ThrottleUpPods) == 0 {
+		metrics.UpdateExecutorStatus(metrics.SubComponentNetworkThrottle, metrics.StepRestore, 0)
+		return nil
+	}
+
+	metrics.UpdateExecutorStatus(metrics.SubComponentNetworkThrottle, metrics.StepRestore, 1.0)
+	metrics.ExecutorStatusCounterInc(metrics.SubComponentNetworkThrottle, metrics.StepRestore)
+
+	return t.throttle(ctx, t.ThrottleUpPods, false)
+}
+
+func (t *NetworkThrottleExecutor) throttle(ctx *ExecuteContext, throttlePods NetworkThrottlePods, down bool) error {
+	if ctx.ThrottleState == nil {
+		return fmt.Errorf("throttle state is not initialized")
+	}
+
+	var errPodKeys []string
+	for _, throttlePod := range throttlePods {
+		pod, err := ctx.PodLister.Pods(throttlePod.PodTypes.Namespace).Get(throttlePod.PodTypes.Name)
+		if err != nil {
+			errPodKeys = append(errPodKeys, fmt.Sprintf("pod %s not found", throttlePod.PodTypes.String()))
+			continue
+		}
+
+		if err := throttleNetwork(ctx, pod, throttlePod, down); err != nil {
+			errPodKeys = append(errPodKeys, fmt.Sprintf("failed to throttle network for %s, error: %v", throttlePod.PodTypes.String(), err))
+		}
+	}
+
+	if len(errPodKeys) != 0 {
+		return fmt.Errorf("some pod network throttle failed,err: %s", strings.Join(errPodKeys, ";"))
+	}
+
+	return nil
+}
+
+// throttleNetwork steps the egress bandwidth of the pod by a tbf qdisc in the pod network namespace. The origin of
+// the limit is the usage when the throttle begins, it is kept in the throttle state until the limit is removed.
+// The pods in the host network are skipped.
+func throttleNetwork(ctx *ExecuteContext, pod *v1.Pod, throttlePod NetworkThrottlePod, down bool) error {
+	if pod.Spec.HostNetwork {
+		return nil
+	}
+
+	limit, ok := ctx.ThrottleState.GetNetworkLimit(string(pod.UID))
+	if !ok {
+		// the pod is not throttled
+		if !down {
+			return nil
+		}
+
+		limit = NetworkLimit{
+			Namespace:  pod.Namespace,
+			Name:       pod.Name,
+			Interface:  throttlePod.NetworkThrottle.Interface,
+			OriginKbit: uint64(throttlePod.PodNetSentKibps),
+		}
+		if limit.Interface == "" {
+			limit.Interface = DefaultThrottleInterface
+		}
+	}
+
+	var ratio = throttlePod.NetworkThrottle
+	next := nextLimit(limit.CurrentKbit, limit.OriginKbit, ratio.MinEgressKibps, ratio.MinNetworkRatio, ratio.StepNetworkRatio, down)
+	if next == limit.CurrentKbit {
+		return nil
+	}
+
+	if err := writeNetworkLimit(pod, limit.Interface, next); err != nil {
+		return err
+	}
+
+	klog.V(4).Infof("NetworkThrottleExecutor pod %s, set egress limit %dkbit.", klog.KObj(pod), next)
+
+	limit.CurrentKbit = next
+	return ctx.ThrottleState.SetNetworkLimit(string(pod.UID), limit)
+}
+
+// reconcileNetwork applies the network limits in the throttle state again, so that the limits are kept after the agent
+// or the pod sandbox restarts. The limits of the pods which are not found or not in the targets any more are removed.
+func reconcileNetwork(ctx *ExecuteContext, targets sets.String) {
+	for uid, limit := range ctx.ThrottleState.GetNetworkLimits() {
+		pod, err := ctx.PodLister.Pods(limit.Namespace).Get(limit.Name)
+		if err != nil || string(pod.UID) != uid {
+			klog.V(4).Infof("Pod %s/%s is gone, remove its network limit", limit.Namespace, limit.Name)
+			deleteNetworkLimit(ctx, uid, limit)
+			continue
+		}
+
+		if targets != nil && !targets.Has(types.NamespacedName{Namespace: pod.Namespace, Name: pod.Name}.String()) {
+			if err := writeNetworkLimit(pod, limit.Interface, 0); err != nil {
+				klog.Errorf("Failed to remove network limit of pod %s: %v", klog.KObj(pod), err)
+				continue
+			}
+			klog.V(2).Infof("Removed network limit of orphaned pod %s", klog.KObj(pod))
+			deleteNetworkLimit(ctx, uid, limit)
+			continue
+		}
+
+		if err := writeNetworkLimit(pod, limit.Interface, limit.CurrentKbit); err != nil {
+			klog.Errorf("Failed to reconcile network limit of pod %s: %v", klog.KObj(pod), err)
+		}
+	}
+}
+
+func deleteNetworkLimit(ctx *ExecuteContext, uid string, limit NetworkLimit) {
+	if err := ctx.ThrottleState.DeleteNetworkLimit(uid); err != nil {
+		klog.Errorf("Failed to remove network limit of pod %s/%s: %v", limit.Namespace, limit.Name, err)
+	}
+}
+
+// writeNetworkLimit replaces the root qdisc of the interface in the pod network namespace by a tbf qdisc,
+// zero removes the qdisc.
+func writeNetworkLimit(pod *v1.Pod, iface string, kbit uint64) error {
+	pid, err := podProcess(pod)
+	if err != nil {
+		return err
+	}
+
+	if kbit == 0 {
+		// the qdisc is gone with the network namespace if the pod sandbox is recreated
+		if err := runTC(pid, "qdisc", "del", "dev", iface, "root"); err != nil {
+			klog.V(4).Infof("Failed to remove the qdisc of pod %s: %v", klog.KObj(pod), err)
+		}
+		return nil
+	}
+
+	var burst = kbit * 1000 / 8 / 10
+	if burst < tbfMinBurstBytes {
+		burst = tbfMinBurstBytes
+	}
+	return runTC(pid, "qdisc", "replace", "dev", iface, "root", "tbf", "rate", fmt.Sprintf("%dkbit", kbit),
+		"burst", strconv.FormatUint(burst, 10), "latency", tbfLatency)
+}
+
+// podProcess returns a process in the pod cgroup to enter the pod network namespace.
+func podProcess(pod *v1.Pod) (int, error) {
+	dir, err := cgroup.PodDir(pod, memorySubsystem)
+	if err != nil {
+		return 0, err
+	}
+	return cgroup.FirstProcess(dir)
+}
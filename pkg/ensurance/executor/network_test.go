@@ -0,0 +1,84 @@
+package executor
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	v1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	corelisters "k8s.io/client-go/listers/core/v1"
+	"k8s.io/client-go/tools/cache"
+
+	"github.com/gocrane/crane/pkg/ensurance/cgroup"
+)
+
+func TestNetworkThrottleExecutor(t *testing.T) {
+	root := t.TempDir()
+	defer func(origin string) { cgroup.Root = origin }(cgroup.Root)
+	cgroup.Root = root
+
+	var commands []string
+	defer func(origin func(int, ...string) error) { runTC = origin }(runTC)
+	runTC = func(pid int, args ...string) error {
+		if pid != 1234 {
+			t.Errorf("Expected to run tc in process 1234, got %d", pid)
+		}
+		commands = append(commands, strings.Join(args, " "))
+		return nil
+	}
+
+	pod := &v1.Pod{
+		ObjectMeta: metav1.ObjectMeta{Name: "pod1", Namespace: "default", UID: "uid1"},
+		Status:     v1.PodStatus{QOSClass: v1.PodQOSBestEffort},
+	}
+	indexer := cache.NewIndexer(cache.MetaNamespaceKeyFunc, cache.Indexers{})
+	indexer.Add(pod)
+
+	containerDir := filepath.Join(root, memorySubsystem, "kubepods", "besteffort", "poduid1", "abc")
+	if err := os.MkdirAll(containerDir, 0755); err != nil {
+		t.Fatal(err)
+	}
+	os.WriteFile(filepath.Join(containerDir, cgroup.ProcsFile), []byte("1234\n"), 0644)
+
+	state, err := NewThrottleState(t.TempDir(), throttleStateFileName)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ctx := &ExecuteContext{PodLister: corelisters.NewPodLister(indexer), ThrottleState: state}
+
+	throttlePod := NetworkThrottlePod{
+		NetworkThrottle: NetworkRatio{StepNetworkRatio: 50, MinEgressKibps: 300000},
+		PodTypes:        types.NamespacedName{Namespace: "default", Name: "pod1"},
+		PodNetSentKibps: 1000000,
+	}
+	executor := NetworkThrottleExecutor{ThrottleDownPods: NetworkThrottlePods{throttlePod}, ThrottleUpPods: NetworkThrottlePods{throttlePod}}
+
+	for i := 0; i < 3; i++ {
+		if err := executor.Avoid(ctx); err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+	}
+	for i := 0; i < 3; i++ {
+		if err := executor.Restore(ctx); err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+	}
+
+	expect := []string{
+		"qdisc replace dev eth0 root tbf rate 500000kbit burst 6250000 latency 50ms",
+		"qdisc replace dev eth0 root tbf rate 300000kbit burst 3750000 latency 50ms",
+		"qdisc replace dev eth0 root tbf rate 450000kbit burst 5625000 latency 50ms",
+		"qdisc replace dev eth0 root tbf rate 675000kbit burst 8437500 latency 50ms",
+		"qdisc del dev eth0 root",
+	}
+	if strings.Join(commands, "\n") != strings.Join(expect, "\n") {
+		t.Errorf("Expected commands:\n%s\ngot:\n%s", strings.Join(expect, "\n"), strings.Join(commands, "\n"))
+	}
+
+	if _, ok := ctx.ThrottleState.GetNetworkLimit("uid1"); ok {
+		t.Errorf("Expected the network limit to be removed after restored")
+	}
+}
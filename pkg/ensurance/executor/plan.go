@@ -0,0 +1,155 @@
+package executor
+
+import (
+	"time"
+
+	corelisters "k8s.io/client-go/listers/core/v1"
+
+	"github.com/gocrane/crane/pkg/utils"
+)
+
+// Plan describes what the executor would do for an AvoidanceExecutor, it is built for the actions in preview so that
+// a policy can be checked before it is enforced.
+type Plan struct {
+	Time time.Time `json:"time"`
+
+	// Objectives are the objective ensurances which are triggered or restored in "<policy name>.<objective ensurance name>"
+	Objectives []string `json:"objectives,omitempty"`
+
+	DisableScheduling bool `json:"disableScheduling,omitempty"`
+	EnableScheduling  bool `json:"enableScheduling,omitempty"`
+
+	// CPUThrottleDown and CPUThrottleUp are the new cpu quotas of the containers
+	CPUThrottleDown []CPUQuotaPlan `json:"cpuThrottleDown,omitempty"`
+	CPUThrottleUp   []CPUQuotaPlan `json:"cpuThrottleUp,omitempty"`
+
+	// the pods in namespace/name to be throttled by the memory, disk io and network throttles
+	MemoryThrottleDown  []string `json:"memoryThrottleDown,omitempty"`
+	MemoryThrottleUp    []string `json:"memoryThrottleUp,omitempty"`
+	DiskIOThrottleDown  []string `json:"diskIOThrottleDown,omitempty"`
+	DiskIOThrottleUp    []string `json:"diskIOThrottleUp,omitempty"`
+	NetworkThrottleDown []string `json:"networkThrottleDown,omitempty"`
+	NetworkThrottleUp   []string `json:"networkThrottleUp,omitempty"`
+
+	Evict []EvictPlan `json:"evict,omitempty"`
+}
+
+// CPUQuotaPlan is the cpu quota of a container to be updated, the quotas are in cores and -1 means no limit.
+type CPUQuotaPlan struct {
+	Pod         string  `json:"pod"`
+	Container   string  `json:"container"`
+	CPUQuota    float64 `json:"cpuQuota"`
+	NewCPUQuota float64 `json:"newCPUQuota"`
+}
+
+// EvictPlan is a pod to be evicted and the reason.
+type EvictPlan struct {
+	Pod    string `json:"pod"`
+	Reason string `json:"reason,omitempty"`
+}
+
+// Execution is the plan of an AvoidanceExecutor executed and its result.
+type Execution struct {
+	Plan `json:",inline"`
+
+	Duration string `json:"duration,omitempty"`
+	// Error is the error of the execution, it is empty if succeeded
+	Error string `json:"error,omitempty"`
+}
+
+// Empty returns true if nothing would be done.
+func (p Plan) Empty() bool {
+	return !p.DisableScheduling && !p.EnableScheduling && len(p.CPUThrottleDown) == 0 && len(p.CPUThrottleUp) == 0 &&
+		len(p.MemoryThrottleDown) == 0 && len(p.MemoryThrottleUp) == 0 && len(p.DiskIOThrottleDown) == 0 &&
+		len(p.DiskIOThrottleUp) == 0 && len(p.NetworkThrottleDown) == 0 && len(p.NetworkThrottleUp) == 0 && len(p.Evict) == 0
+}
+
+// NewPlan builds the plan of the AvoidanceExecutor, the cpu quotas are computed in the same way as the throttle executor.
+func NewPlan(ae AvoidanceExecutor, podLister corelisters.PodLister, now time.Time) Plan {
+	var plan = Plan{
+		Time:              now,
+		DisableScheduling: ae.ScheduleExecutor.DisableClassAndPriority != nil,
+		EnableScheduling:  ae.ScheduleExecutor.RestoreClassAndPriority != nil,
+	}
+
+	for _, throttlePod := range ae.ThrottleExecutor.ThrottleDownPods {
+		plan.CPUThrottleDown = append(plan.CPUThrottleDown, cpuQuotaPlans(throttlePod, podLister, true)...)
+		if throttlePod.MemoryThrottle.Enabled() {
+			plan.MemoryThrottleDown = append(plan.MemoryThrottleDown, throttlePod.PodTypes.String())
+		}
+	}
+	for _, throttlePod := range ae.ThrottleExecutor.ThrottleUpPods {
+		plan.CPUThrottleUp = append(plan.CPUThrottleUp, cpuQuotaPlans(throttlePod, podLister, false)...)
+		if throttlePod.MemoryThrottle.StepMemoryRatio > 0 {
+			plan.MemoryThrottleUp = append(plan.MemoryThrottleUp, throttlePod.PodTypes.String())
+		}
+	}
+
+	for _, throttlePod := range ae.DiskIOThrottleExecutor.ThrottleDownPods {
+		plan.DiskIOThrottleDown = append(plan.DiskIOThrottleDown, throttlePod.PodTypes.String())
+	}
+	for _, throttlePod := range ae.DiskIOThrottleExecutor.ThrottleUpPods {
+		plan.DiskIOThrottleUp = append(plan.DiskIOThrottleUp, throttlePod.PodTypes.String())
+	}
+	for _, throttlePod := range ae.NetworkThrottleExecutor.ThrottleDownPods {
+		plan.NetworkThrottleDown = append(plan.NetworkThrottleDown, throttlePod.PodTypes.String())
+	}
+	for _, throttlePod := range ae.NetworkThrottleExecutor.ThrottleUpPods {
+		plan.NetworkThrottleUp = append(plan.NetworkThrottleUp, throttlePod.PodTypes.String())
+	}
+
+	for _, evictPod := range ae.EvictExecutor.EvictPods {
+		plan.Evict = append(plan.Evict, EvictPlan{Pod: evictPod.PodKey.String(), Reason: evictPod.Reason})
+	}
+
+	return plan
+}
+
+// cpuQuotaPlans returns the cpu quotas of the containers of the pod which would be changed.
+func cpuQuotaPlans(throttlePod ThrottlePod, podLister corelisters.PodLister, down bool) []CPUQuotaPlan {
+	pod, err := podLister.Pods(throttlePod.PodTypes.Namespace).Get(throttlePod.PodTypes.Name)
+	if err != nil {
+		return nil
+	}
+
+	var plans []CPUQuotaPlan
+	for _, v := range throttlePod.ContainerCPUUsages {
+		// pause container to skip
+		if v.ContainerName == "" {
+			continue
+		}
+
+		quota, err := GetUsageById(throttlePod.ContainerCPUQuotas, v.ContainerId)
+		if err != nil {
+			continue
+		}
+		period, err := GetUsageById(throttlePod.ContainerCPUPeriods, v.ContainerId)
+		if err != nil || period.Value <= 0 {
+			continue
+		}
+		container, err := utils.GetPodContainerByName(pod, v.ContainerName)
+		if err != nil {
+			continue
+		}
+
+		var quotaNew float64
+		if down {
+			quotaNew = throttleDownCPUQuota(throttlePod.CPUThrottle, container, v.Value, quota.Value, period.Value)
+		} else {
+			var ok bool
+			if quotaNew, ok = throttleUpCPUQuota(throttlePod.CPUThrottle, container, quota.Value, period.Value); !ok {
+				continue
+			}
+		}
+
+		var quotaCores = -1.0
+		if quota.Value > 0 {
+			quotaCores = quota.Value / period.Value
+		}
+		if !utils.AlmostEqual(quotaNew*period.Value, quota.Value) {
+			plans = append(plans, CPUQuotaPlan{Pod: throttlePod.PodTypes.String(), Container: v.ContainerName, CPUQuota: quotaCores, NewCPUQuota: quotaNew})
+		}
+	}
+
+	return plans
+}
@@ -0,0 +1,78 @@
+package executor
+
+import (
+	"reflect"
+	"testing"
+	"time"
+
+	v1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	corelisters "k8s.io/client-go/listers/core/v1"
+	"k8s.io/client-go/tools/cache"
+)
+
+func TestNewPlan(t *testing.T) {
+	pod := &v1.Pod{
+		ObjectMeta: metav1.ObjectMeta{Name: "pod1", Namespace: "default"},
+		Spec: v1.PodSpec{Containers: []v1.Container{
+			{Name: "limited", Resources: v1.ResourceRequirements{
+				Requests: v1.ResourceList{v1.ResourceCPU: resource.MustParse("500m")},
+				Limits:   v1.ResourceList{v1.ResourceCPU: resource.MustParse("2")},
+			}},
+			{Name: "unlimited"},
+		}},
+	}
+	podIndexer := cache.NewIndexer(cache.MetaNamespaceKeyFunc, cache.Indexers{})
+	podIndexer.Add(pod)
+	podLister := corelisters.NewPodLister(podIndexer)
+
+	var podKey = types.NamespacedName{Namespace: "default", Name: "pod1"}
+	throttlePod := ThrottlePod{
+		PodTypes:       podKey,
+		CPUThrottle:    CPURatio{MinCPURatio: 10, StepCPURatio: 50},
+		MemoryThrottle: MemoryThrottleExecutor{StepMemoryRatio: 10},
+		ContainerCPUUsages: []ContainerUsage{
+			{ContainerName: "", ContainerId: "pause", Value: 0},
+			{ContainerName: "limited", ContainerId: "c1", Value: 1.5},
+			{ContainerName: "unlimited", ContainerId: "c2", Value: 3},
+		},
+		ContainerCPUQuotas:  []ContainerUsage{{ContainerId: "c1", Value: 200000}, {ContainerId: "c2", Value: -1}},
+		ContainerCPUPeriods: []ContainerUsage{{ContainerId: "c1", Value: 100000}, {ContainerId: "c2", Value: 100000}},
+	}
+
+	var now = time.Now()
+	ae := AvoidanceExecutor{
+		ScheduleExecutor: ScheduleExecutor{DisableClassAndPriority: &ClassAndPriority{PodQOSClass: v1.PodQOSBestEffort}},
+		ThrottleExecutor: ThrottleExecutor{ThrottleDownPods: ThrottlePods{throttlePod}, ThrottleUpPods: ThrottlePods{throttlePod}},
+		EvictExecutor:    EvictExecutor{EvictPods: EvictPods{{PodKey: podKey, Reason: "evicted"}}},
+	}
+
+	plan := NewPlan(ae, podLister, now)
+
+	expect := Plan{
+		Time:              now,
+		DisableScheduling: true,
+		CPUThrottleDown: []CPUQuotaPlan{
+			// the quota is halved, but not less than the request
+			{Pod: "default/pod1", Container: "limited", CPUQuota: 2, NewCPUQuota: 1},
+			// the quota is half of the usage if the container is not limited
+			{Pod: "default/pod1", Container: "unlimited", CPUQuota: -1, NewCPUQuota: 1.5},
+		},
+		// no cpu throttle up, the quota is not more than the limit which is not changed, and the container
+		// not limited is skipped
+		MemoryThrottleDown: []string{"default/pod1"},
+		MemoryThrottleUp:   []string{"default/pod1"},
+		Evict:              []EvictPlan{{Pod: "default/pod1", Reason: "evicted"}},
+	}
+	if !reflect.DeepEqual(plan, expect) {
+		t.Errorf("Expected plan %+v, got %+v", expect, plan)
+	}
+	if plan.Empty() {
+		t.Errorf("Expected the plan not to be empty")
+	}
+	if !(Plan{Time: now}).Empty() {
+		t.Errorf("Expected the plan without actions to be empty")
+	}
+}
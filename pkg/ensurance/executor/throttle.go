@@ -7,10 +7,10 @@ import (
 
 	v1 "k8s.io/api/core/v1"
 	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/apimachinery/pkg/util/sets"
 	"k8s.io/klog/v2"
 
 	"github.com/gocrane/crane/pkg/common"
-	cruntime "github.com/gocrane/crane/pkg/ensurance/runtime"
 	"github.com/gocrane/crane/pkg/known"
 	"github.com/gocrane/crane/pkg/metrics"
 	"github.com/gocrane/crane/pkg/utils"
@@ -25,6 +25,10 @@ const (
 type ThrottleExecutor struct {
 	ThrottleDownPods ThrottlePods
 	ThrottleUpPods   ThrottlePods
+	// Targets are the pods which may be throttled by the throttle actions of the node, keyed by namespace/name. The
+	// containers throttled out of them are orphaned and their cpu quotas are restored to the origin, all the throttled
+	// containers are kept if it is nil.
+	Targets sets.String
 }
 
 type ThrottlePods []ThrottlePod
@@ -56,6 +60,19 @@ type CPURatio struct {
 type MemoryThrottleExecutor struct {
 	// to force gc the page cache of low level pods
 	ForceGC bool `json:"forceGC,omitempty"`
+
+	//the min of memory ratio for pods
+	MinMemoryRatio uint64 `json:"minMemoryRatio,omitempty"`
+
+	//the step of memory limit for once down-size (1-100), the memory is not throttled if it is zero
+	StepMemoryRatio uint64 `json:"stepMemoryRatio,omitempty"`
+
+	// to throttle the memory.high of the pod cgroup instead of the memory limit of containers
+	MemoryHigh bool `json:"memoryHigh,omitempty"`
+}
+
+func (m MemoryThrottleExecutor) Enabled() bool {
+	return m.StepMemoryRatio > 0 || m.ForceGC
 }
 
 type ThrottlePod struct {
@@ -70,6 +87,10 @@ type ThrottlePod struct {
 	ContainerCPUQuotas  []ContainerUsage
 	PodCPUPeriod        float64
 	ContainerCPUPeriods []ContainerUsage
+	PodMemUsage         float64
+	ContainerMemUsages  []ContainerUsage
+	PodMemLimit         float64
+	ContainerMemLimits  []ContainerUsage
 	PodQOSPriority      ClassAndPriority
 }
 
@@ -144,30 +165,13 @@ func (t *ThrottleExecutor) Avoid(ctx *ExecuteContext) error {
 				continue
 			}
 
-			var containerCPUQuotaNew float64
-			if utils.AlmostEqual(containerCPUQuota.Value, -1.0) || utils.AlmostEqual(containerCPUQuota.Value, 0.0) {
-				containerCPUQuotaNew = v.Value * (1.0 - float64(throttlePod.CPUThrottle.StepCPURatio)/MaxRatio)
-			} else {
-				containerCPUQuotaNew = containerCPUQuota.Value / containerCPUPeriod.Value * (1.0 - float64(throttlePod.CPUThrottle.StepCPURatio)/MaxRatio)
-			}
-
-			if requestCPU, ok := container.Resources.Requests[v1.ResourceCPU]; ok {
-				if float64(requestCPU.MilliValue())/CpuQuotaCoefficient > containerCPUQuotaNew {
-					containerCPUQuotaNew = float64(requestCPU.MilliValue()) / CpuQuotaCoefficient
-				}
-			}
-
-			if limitCPU, ok := container.Resources.Limits[v1.ResourceCPU]; ok {
-				if float64(limitCPU.MilliValue())/CpuQuotaCoefficient*float64(throttlePod.CPUThrottle.MinCPURatio)/MaxRatio > containerCPUQuotaNew {
-					containerCPUQuotaNew = float64(limitCPU.MilliValue()) * float64(throttlePod.CPUThrottle.MinCPURatio) / CpuQuotaCoefficient
-				}
-			}
+			containerCPUQuotaNew := throttleDownCPUQuota(throttlePod.CPUThrottle, container, v.Value, containerCPUQuota.Value, containerCPUPeriod.Value)
 
 			klog.V(6).Infof("Prior update container resources containerCPUQuotaNew %.2f, containerCPUQuota.Value %.2f,containerCPUPeriod %.2f",
 				containerCPUQuotaNew, containerCPUQuota.Value, containerCPUPeriod.Value)
 
 			if !utils.AlmostEqual(containerCPUQuotaNew*containerCPUPeriod.Value, containerCPUQuota.Value) {
-				err = cruntime.UpdateContainerResources(ctx.RuntimeClient, v.ContainerId, cruntime.UpdateOptions{CPUQuota: int64(containerCPUQuotaNew * containerCPUPeriod.Value)})
+				err = UpdateContainerCPUQuota(ctx.RuntimeClient, pod, v.ContainerId, int64(containerCPUQuotaNew*containerCPUPeriod.Value))
 				if err != nil {
 					errPodKeys = append(errPodKeys, fmt.Sprintf("failed to updateResource for %s/%s, error: %v", throttlePod.PodTypes.String(), v.ContainerName, err))
 					bSucceed = false
@@ -175,9 +179,17 @@ func (t *ThrottleExecutor) Avoid(ctx *ExecuteContext) error {
 				} else {
 					klog.V(4).Infof("ThrottleExecutor avoid pod %s, container %s, set cpu quota %.2f.",
 						klog.KObj(pod), v.ContainerName, containerCPUQuotaNew)
+					checkpointCPUQuota(ctx, pod, v, containerCPUQuota.Value, int64(containerCPUQuotaNew*containerCPUPeriod.Value), true)
 				}
 			}
 		}
+
+		if throttlePod.MemoryThrottle.Enabled() {
+			if err := throttleDownMemory(ctx, pod, throttlePod); err != nil {
+				errPodKeys = append(errPodKeys, fmt.Sprintf("failed to throttle memory for %s, error: %v", throttlePod.PodTypes.String(), err))
+				bSucceed = false
+			}
+		}
 	}
 
 	if !bSucceed {
@@ -194,7 +206,7 @@ func (t *ThrottleExecutor) Restore(ctx *ExecuteContext) error {
 
 	klog.V(6).Info("ThrottleExecutor restore, %v", *t)
 
-	if len(t.ThrottleDownPods) == 0 {
+	if len(t.ThrottleUpPods) == 0 {
 		metrics.UpdateExecutorStatus(metrics.SubComponentThrottle, metrics.StepRestore, 0)
 		return nil
 	}
@@ -244,26 +256,9 @@ func (t *ThrottleExecutor) Restore(ctx *ExecuteContext) error {
 				continue
 			}
 
-			var containerCPUQuotaNew float64
-			if utils.AlmostEqual(containerCPUQuota.Value, -1.0) || utils.AlmostEqual(containerCPUQuota.Value, 0.0) {
+			containerCPUQuotaNew, ok := throttleUpCPUQuota(throttlePod.CPUThrottle, container, containerCPUQuota.Value, containerCPUPeriod.Value)
+			if !ok {
 				continue
-			} else {
-				containerCPUQuotaNew = containerCPUQuota.Value / containerCPUPeriod.Value * (1.0 + float64(throttlePod.CPUThrottle.StepCPURatio)/MaxRatio)
-			}
-
-			if limitCPU, ok := container.Resources.Limits[v1.ResourceCPU]; ok {
-				if float64(limitCPU.MilliValue())/CpuQuotaCoefficient < containerCPUQuotaNew {
-					containerCPUQuotaNew = float64(limitCPU.MilliValue()) / CpuQuotaCoefficient
-				}
-			} else {
-				usage, hasExtRes := utils.GetExtCpuRes(container)
-				if hasExtRes {
-					containerCPUQuotaNew = float64(usage.MilliValue()) / CpuQuotaCoefficient
-				}
-				if !hasExtRes && containerCPUQuotaNew > MaxUpQuota*containerCPUPeriod.Value/CpuQuotaCoefficient {
-					containerCPUQuotaNew = -1
-				}
-
 			}
 
 			klog.V(6).Infof("Prior update container resources containerCPUQuotaNew %.2f,containerCPUQuota %.2f,containerCPUPeriod %.2f",
@@ -272,23 +267,32 @@ func (t *ThrottleExecutor) Restore(ctx *ExecuteContext) error {
 			if !utils.AlmostEqual(containerCPUQuotaNew*containerCPUPeriod.Value, containerCPUQuota.Value) {
 
 				if utils.AlmostEqual(containerCPUQuotaNew, -1) {
-					err = cruntime.UpdateContainerResources(ctx.RuntimeClient, v.ContainerId, cruntime.UpdateOptions{CPUQuota: int64(-1)})
+					err = UpdateContainerCPUQuota(ctx.RuntimeClient, pod, v.ContainerId, -1)
 					if err != nil {
 						errPodKeys = append(errPodKeys, fmt.Sprintf("Failed to updateResource, err %s", err.Error()), throttlePod.PodTypes.String())
 						bSucceed = false
 						continue
 					}
+					checkpointCPUQuota(ctx, pod, v, containerCPUQuota.Value, -1, false)
 				} else {
-					err = cruntime.UpdateContainerResources(ctx.RuntimeClient, v.ContainerId, cruntime.UpdateOptions{CPUQuota: int64(containerCPUQuotaNew * containerCPUPeriod.Value)})
+					err = UpdateContainerCPUQuota(ctx.RuntimeClient, pod, v.ContainerId, int64(containerCPUQuotaNew*containerCPUPeriod.Value))
 					if err != nil {
 						klog.Errorf("Failed to updateResource, err %s", err.Error())
 						errPodKeys = append(errPodKeys, fmt.Sprintf("Failed to updateResource, err %s", err.Error()), throttlePod.PodTypes.String())
 						bSucceed = false
 						continue
 					}
+					checkpointCPUQuota(ctx, pod, v, containerCPUQuota.Value, int64(containerCPUQuotaNew*containerCPUPeriod.Value), false)
 				}
 			}
 		}
+
+		if throttlePod.MemoryThrottle.StepMemoryRatio > 0 {
+			if err := throttleUpMemory(ctx, pod, throttlePod); err != nil {
+				errPodKeys = append(errPodKeys, fmt.Sprintf("failed to restore memory for %s, error: %v", throttlePod.PodTypes.String(), err))
+				bSucceed = false
+			}
+		}
 	}
 
 	if !bSucceed {
@@ -320,3 +324,53 @@ func GetPodUsage(metricName string, stateMap map[string][]common.TimeSeries, pod
 
 	return podUsage, containerUsages
 }
+
+// throttleDownCPUQuota returns the cpu quota of the container in cores after it is throttled down by a step, it is
+// not less than the request and the min ratio of the limit. The usage is in cores, the quota and period are in us.
+func throttleDownCPUQuota(ratio CPURatio, container v1.Container, usage, quota, period float64) float64 {
+	var quotaNew float64
+	if utils.AlmostEqual(quota, -1.0) || utils.AlmostEqual(quota, 0.0) {
+		quotaNew = usage * (1.0 - float64(ratio.StepCPURatio)/MaxRatio)
+	} else {
+		quotaNew = quota / period * (1.0 - float64(ratio.StepCPURatio)/MaxRatio)
+	}
+
+	if requestCPU, ok := container.Resources.Requests[v1.ResourceCPU]; ok {
+		if float64(requestCPU.MilliValue())/CpuQuotaCoefficient > quotaNew {
+			quotaNew = float64(requestCPU.MilliValue()) / CpuQuotaCoefficient
+		}
+	}
+
+	if limitCPU, ok := container.Resources.Limits[v1.ResourceCPU]; ok {
+		if minQuota := float64(limitCPU.MilliValue()) / CpuQuotaCoefficient * float64(ratio.MinCPURatio) / MaxRatio; minQuota > quotaNew {
+			quotaNew = minQuota
+		}
+	}
+
+	return quotaNew
+}
+
+// throttleUpCPUQuota returns the cpu quota of the container in cores after it is throttled up by a step, -1 means
+// the limit is removed. It returns false if the container is not limited.
+func throttleUpCPUQuota(ratio CPURatio, container v1.Container, quota, period float64) (float64, bool) {
+	if utils.AlmostEqual(quota, -1.0) || utils.AlmostEqual(quota, 0.0) {
+		return 0, false
+	}
+
+	var quotaNew = quota / period * (1.0 + float64(ratio.StepCPURatio)/MaxRatio)
+	if limitCPU, ok := container.Resources.Limits[v1.ResourceCPU]; ok {
+		if float64(limitCPU.MilliValue())/CpuQuotaCoefficient < quotaNew {
+			quotaNew = float64(limitCPU.MilliValue()) / CpuQuotaCoefficient
+		}
+	} else {
+		usage, hasExtRes := utils.GetExtCpuRes(container)
+		if hasExtRes {
+			quotaNew = float64(usage.MilliValue()) / CpuQuotaCoefficient
+		}
+		if !hasExtRes && quotaNew > MaxUpQuota*period/CpuQuotaCoefficient {
+			quotaNew = -1
+		}
+	}
+
+	return quotaNew, true
+}
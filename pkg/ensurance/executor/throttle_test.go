@@ -0,0 +1,61 @@
+package executor
+
+import (
+	"testing"
+
+	v1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+
+	"github.com/gocrane/crane/pkg/utils"
+)
+
+func TestThrottleDownCPUQuota(t *testing.T) {
+	container := func(request, limit string) v1.Container {
+		var c v1.Container
+		if request != "" {
+			c.Resources.Requests = v1.ResourceList{v1.ResourceCPU: resource.MustParse(request)}
+		}
+		if limit != "" {
+			c.Resources.Limits = v1.ResourceList{v1.ResourceCPU: resource.MustParse(limit)}
+		}
+		return c
+	}
+	ratio := CPURatio{MinCPURatio: 20, StepCPURatio: 50}
+
+	cases := map[string]struct {
+		container v1.Container
+		usage     float64
+		quota     float64
+		expect    float64
+	}{
+		"step down from the usage if unlimited": {
+			container: container("", ""),
+			usage:     2,
+			quota:     -1,
+			expect:    1,
+		},
+		"step down the quota": {
+			container: container("", "4"),
+			quota:     400000,
+			expect:    2,
+		},
+		"not less than the request": {
+			container: container("1500m", "4"),
+			quota:     200000,
+			expect:    1.5,
+		},
+		"not less than the min ratio of the limit": {
+			container: container("", "4"),
+			quota:     100000,
+			expect:    0.8,
+		},
+	}
+
+	for k, v := range cases {
+		t.Run(k, func(t *testing.T) {
+			if quota := throttleDownCPUQuota(ratio, v.container, v.usage, v.quota, 100000); !utils.AlmostEqual(quota, v.expect) {
+				t.Errorf("Expected quota %v, got %v", v.expect, quota)
+			}
+		})
+	}
+}
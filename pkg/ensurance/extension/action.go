@@ -0,0 +1,169 @@
+package extension
+
+import (
+	"encoding/json"
+	"fmt"
+
+	v1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	ensuranceapi "github.com/gocrane/api/ensurance/v1alpha1"
+
+	"github.com/gocrane/crane/pkg/known"
+)
+
+// MemoryThrottle is the extended memory throttle setting of an avoidance action which is not described by the
+// AvoidanceAction api, it is set by the known.MemoryThrottleAnnotation annotation. It is the memory counterpart
+// of the CPUThrottle of the api.
+type MemoryThrottle struct {
+	// MinMemoryRatio is the min of memory ratio for low level pods, for example: the container limit is 4Gi,
+	// ratio is 50, the minimum is 2Gi. For the containers without limit, it is the ratio of the usage.
+	// MinMemoryRatio range [0,100]
+	// +optional
+	MinMemoryRatio int32 `json:"minMemoryRatio,omitempty"`
+
+	// StepMemoryRatio is the step of memory limit for once down-size and up-size.
+	// The memory is not throttled if it is zero.
+	// StepMemoryRatio range [0,100]
+	// +optional
+	StepMemoryRatio int32 `json:"stepMemoryRatio,omitempty"`
+
+	// MemoryHigh throttles the memory.high of the pod cgroup instead of the memory limit of the containers,
+	// the pods are reclaimed by the kernel rather than oom killed. It requires cgroup v2.
+	// +optional
+	MemoryHigh bool `json:"memoryHigh,omitempty"`
+}
+
+// GetMemoryThrottle returns the extended memory throttle setting of the avoidance action.
+func GetMemoryThrottle(action *ensuranceapi.AvoidanceAction) (MemoryThrottle, error) {
+	var memoryThrottle MemoryThrottle
+
+	value, ok := action.Annotations[known.MemoryThrottleAnnotation]
+	if !ok || value == "" {
+		return memoryThrottle, nil
+	}
+
+	if err := json.Unmarshal([]byte(value), &memoryThrottle); err != nil {
+		return memoryThrottle, fmt.Errorf("failed to parse annotation %s: %v", known.MemoryThrottleAnnotation, err)
+	}
+
+	return memoryThrottle, nil
+}
+
+// DiskIOThrottle is the disk io throttle setting of an avoidance action, it is set by the
+// known.DiskIOThrottleAnnotation annotation. The bps and iops of the pods are limited by io.max of cgroup v2
+// or blkio.throttle.* of cgroup v1, starting from the usage when the throttle begins.
+type DiskIOThrottle struct {
+	// MinIORatio is the min of io ratio for low level pods, it is the ratio of the usage when the throttle begins.
+	// MinIORatio range [0,100]
+	// +optional
+	MinIORatio int32 `json:"minIORatio,omitempty"`
+
+	// StepIORatio is the step of io limit for once down-size and up-size.
+	// The disk io is not throttled if it is zero.
+	// StepIORatio range [0,100]
+	// +optional
+	StepIORatio int32 `json:"stepIORatio,omitempty"`
+
+	// MinKiBps is the min of read and write KiB per second for low level pods.
+	// +optional
+	MinKiBps int64 `json:"minKiBps,omitempty"`
+
+	// MinIOPS is the min of read and write operations per second for low level pods.
+	// +optional
+	MinIOPS int64 `json:"minIOPS,omitempty"`
+}
+
+// NetworkThrottle is the network throttle setting of an avoidance action, it is set by the
+// known.NetworkThrottleAnnotation annotation. The egress bandwidth of the pods is shaped by a tbf qdisc
+// in the pod network namespace, starting from the usage when the throttle begins.
+type NetworkThrottle struct {
+	// MinNetworkRatio is the min of bandwidth ratio for low level pods, it is the ratio of the usage when the throttle begins.
+	// MinNetworkRatio range [0,100]
+	// +optional
+	MinNetworkRatio int32 `json:"minNetworkRatio,omitempty"`
+
+	// StepNetworkRatio is the step of bandwidth limit for once down-size and up-size.
+	// The network is not throttled if it is zero.
+	// StepNetworkRatio range [0,100]
+	// +optional
+	StepNetworkRatio int32 `json:"stepNetworkRatio,omitempty"`
+
+	// MinEgressKibps is the min of egress kilobits per second for low level pods.
+	// +optional
+	MinEgressKibps int64 `json:"minEgressKibps,omitempty"`
+
+	// Interface is the network interface in the pod network namespace to shape, default: eth0
+	// +optional
+	Interface string `json:"interface,omitempty"`
+}
+
+// GetDiskIOThrottle returns the disk io throttle setting of the avoidance action, it returns nil if it is not set.
+func GetDiskIOThrottle(action *ensuranceapi.AvoidanceAction) (*DiskIOThrottle, error) {
+	value, ok := action.Annotations[known.DiskIOThrottleAnnotation]
+	if !ok || value == "" {
+		return nil, nil
+	}
+
+	var diskIOThrottle DiskIOThrottle
+	if err := json.Unmarshal([]byte(value), &diskIOThrottle); err != nil {
+		return nil, fmt.Errorf("failed to parse annotation %s: %v", known.DiskIOThrottleAnnotation, err)
+	}
+
+	return &diskIOThrottle, nil
+}
+
+// GetNetworkThrottle returns the network throttle setting of the avoidance action, it returns nil if it is not set.
+func GetNetworkThrottle(action *ensuranceapi.AvoidanceAction) (*NetworkThrottle, error) {
+	value, ok := action.Annotations[known.NetworkThrottleAnnotation]
+	if !ok || value == "" {
+		return nil, nil
+	}
+
+	var networkThrottle NetworkThrottle
+	if err := json.Unmarshal([]byte(value), &networkThrottle); err != nil {
+		return nil, fmt.Errorf("failed to parse annotation %s: %v", known.NetworkThrottleAnnotation, err)
+	}
+
+	return &networkThrottle, nil
+}
+
+// ThrottleTarget selects the pods to be throttled by an avoidance action, it is set by the known.ThrottleTargetAnnotation
+// annotation. It applies to the cpu, memory, disk io and network throttles. All the pods on the node are candidates of
+// the cpu and memory throttles if it is not set, and the pods of BestEffort and Burstable are candidates of the disk io
+// and network throttles if MaxQOSClass is not set.
+type ThrottleTarget struct {
+	// PodSelector selects the pods to be throttled by their labels.
+	// +optional
+	PodSelector *metav1.LabelSelector `json:"podSelector,omitempty"`
+
+	// MaxQOSClass is the highest QoS class of the pods to be throttled, the pods of higher classes are not throttled.
+	// For example, Burstable protects the Guaranteed pods.
+	// +optional
+	MaxQOSClass v1.PodQOSClass `json:"maxQOSClass,omitempty"`
+
+	// MaxPriority is the highest priority of the pods to be throttled in the MaxQOSClass, or in all the classes if
+	// MaxQOSClass is not set.
+	// +optional
+	MaxPriority *int32 `json:"maxPriority,omitempty"`
+
+	// ProtectedPodSelector selects the pods hurt by the contention, for example the latency sensitive services. They
+	// and the pods not lower than the lowest of them by QoS class and priority are never throttled.
+	// +optional
+	ProtectedPodSelector *metav1.LabelSelector `json:"protectedPodSelector,omitempty"`
+}
+
+// GetThrottleTarget returns the throttle target of the avoidance action, it returns nil if it is not set.
+func GetThrottleTarget(action *ensuranceapi.AvoidanceAction) (*ThrottleTarget, error) {
+	value, ok := action.Annotations[known.ThrottleTargetAnnotation]
+	if !ok || value == "" {
+		return nil, nil
+	}
+
+	var throttleTarget ThrottleTarget
+	if err := json.Unmarshal([]byte(value), &throttleTarget); err != nil {
+		return nil, fmt.Errorf("failed to parse annotation %s: %v", known.ThrottleTargetAnnotation, err)
+	}
+
+	return &throttleTarget, nil
+}
@@ -0,0 +1,94 @@
+package extension
+
+import (
+	"encoding/json"
+	"fmt"
+
+	"k8s.io/apimachinery/pkg/api/resource"
+
+	ensuranceapi "github.com/gocrane/api/ensurance/v1alpha1"
+
+	"github.com/gocrane/crane/pkg/ensurance/analyzer/aggregator"
+	"github.com/gocrane/crane/pkg/ensurance/analyzer/evaluator"
+	"github.com/gocrane/crane/pkg/known"
+)
+
+// ObjectiveEnsurance is the extended setting of an objective ensurance which is not described by the
+// NodeQOSEnsurancePolicy api, it is set by the known.ObjectiveEnsurancesAnnotation annotation.
+// For example:
+//
+//	ensurance.crane.io/objective-ensurances: '{"cpu-load": {"rego": "trigger { input.metrics.cpu_load_5_min[0].value > input.node.capacity.cpu * 1.5 }"}}'
+type ObjectiveEnsurance struct {
+	// Rego is the rego rule to evaluate the objective ensurance instead of the metric rule.
+	// The objective is triggered when the rule "trigger" is true, the package clause must be omitted.
+	// +optional
+	Rego string `json:"rego,omitempty"`
+
+	// Operator is the operator to compare the metric value with the value of the metric rule.
+	// Defaults to ">".
+	// +optional
+	Operator evaluator.Operator `json:"operator,omitempty"`
+
+	// UpperValue is the upper bound for the range operators "in" and "notin",
+	// the value of the metric rule is the lower bound.
+	// +optional
+	UpperValue *resource.Quantity `json:"upperValue,omitempty"`
+
+	// RestoreValue is the threshold to restore the objective ensurance, it makes a hysteresis band with the
+	// value of the metric rule, the metric in the band is neither counted as triggered nor as restored.
+	// For example, with operator ">", value 80 and restoreValue 70, it is triggered above 80 and restored
+	// below or equal to 70. Defaults to the value of the metric rule.
+	// +optional
+	RestoreValue *resource.Quantity `json:"restoreValue,omitempty"`
+
+	// Window is the number of the latest collections which the samples of each series are aggregated over,
+	// the series is not evaluated until the window is full. Defaults to 1, only the latest sample is evaluated.
+	// +optional
+	Window int32 `json:"window,omitempty"`
+
+	// WindowFunction is the function to aggregate the samples in the window: avg, max, min, rate or a percentile
+	// such as p95. The rate is the change per second. Defaults to avg.
+	// +optional
+	WindowFunction aggregator.Function `json:"windowFunction,omitempty"`
+
+	// SeriesFunction is the function to aggregate the series matched by the selector of the metric rule: sum, avg,
+	// max, min or a percentile, for example the sum of the cpu usage of all the BestEffort pods.
+	// If it is not set, each series is evaluated alone.
+	// +optional
+	SeriesFunction aggregator.Function `json:"seriesFunction,omitempty"`
+}
+
+// GetObjectiveEnsurances returns the extended objective ensurances of the policy keyed by the objective name.
+func GetObjectiveEnsurances(nep *ensuranceapi.NodeQOSEnsurancePolicy) (map[string]ObjectiveEnsurance, error) {
+	var objectives = make(map[string]ObjectiveEnsurance)
+
+	value, ok := nep.Annotations[known.ObjectiveEnsurancesAnnotation]
+	if !ok || value == "" {
+		return objectives, nil
+	}
+
+	if err := json.Unmarshal([]byte(value), &objectives); err != nil {
+		return objectives, fmt.Errorf("failed to parse annotation %s: %v", known.ObjectiveEnsurancesAnnotation, err)
+	}
+
+	return objectives, nil
+}
+
+// GetThresholds returns the thresholds to trigger and restore the objective ensurance.
+func GetThresholds(rule *ensuranceapi.MetricRule, ext ObjectiveEnsurance) (evaluator.Threshold, evaluator.Threshold) {
+	var triggerThreshold = evaluator.Threshold{Operator: ext.Operator, Value: rule.Value.AsApproximateFloat64()}
+	if triggerThreshold.Operator == "" {
+		triggerThreshold.Operator = evaluator.OperatorGreaterThan
+	}
+
+	if ext.UpperValue != nil {
+		triggerThreshold.UpperValue = ext.UpperValue.AsApproximateFloat64()
+	}
+
+	var restoreThreshold = triggerThreshold
+	if ext.RestoreValue != nil {
+		restoreThreshold.Value = ext.RestoreValue.AsApproximateFloat64()
+	}
+
+	return triggerThreshold, restoreThreshold
+}
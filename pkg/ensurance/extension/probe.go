@@ -0,0 +1,54 @@
+package extension
+
+import (
+	"encoding/json"
+	"fmt"
+
+	ensuranceapi "github.com/gocrane/api/ensurance/v1alpha1"
+
+	"github.com/gocrane/crane/pkg/known"
+)
+
+// MetricsSource is the source which the metrics server collector reads the metrics from.
+type MetricsSource string
+
+const (
+	// MetricsSourceKubeletSummary reads the summary api of kubelet through the node proxy of apiserver.
+	MetricsSourceKubeletSummary MetricsSource = "kubelet-summary"
+	// MetricsSourceMetricsAPI reads the resource metrics api metrics.k8s.io.
+	MetricsSourceMetricsAPI MetricsSource = "metrics-api"
+)
+
+// MetricsServerGet specifies the node quality probe by the metrics server collector, it is set by the
+// known.MetricsServerGetAnnotation annotation, for the nodes where cadvisor can not be accessed.
+type MetricsServerGet struct {
+	// Source is the source of the metrics.
+	// Defaults to kubelet-summary.
+	// +optional
+	Source MetricsSource `json:"source,omitempty"`
+}
+
+// GetMetricsServerGet returns the metrics server probe of the policy, it is nil if the annotation is not set.
+func GetMetricsServerGet(nep *ensuranceapi.NodeQOSEnsurancePolicy) (*MetricsServerGet, error) {
+	value, ok := nep.Annotations[known.MetricsServerGetAnnotation]
+	if !ok {
+		return nil, nil
+	}
+
+	var probe = &MetricsServerGet{}
+	if value != "" {
+		if err := json.Unmarshal([]byte(value), probe); err != nil {
+			return nil, fmt.Errorf("failed to parse annotation %s: %v", known.MetricsServerGetAnnotation, err)
+		}
+	}
+
+	switch probe.Source {
+	case "":
+		probe.Source = MetricsSourceKubeletSummary
+	case MetricsSourceKubeletSummary, MetricsSourceMetricsAPI:
+	default:
+		return nil, fmt.Errorf("unsupported metrics source %s", probe.Source)
+	}
+
+	return probe, nil
+}
@@ -0,0 +1,73 @@
+package extension
+
+import (
+	"encoding/json"
+	"fmt"
+
+	v1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	"github.com/gocrane/crane/pkg/known"
+)
+
+// ObjectiveStatus is the status of an objective ensurance evaluated on a node, it is kept by the
+// known.ObjectiveStatusAnnotation annotation of the node. For example:
+//
+//	ensurance.crane.io/objective-status: '{"nep1.cpu-usage": {"nodeQOSEnsurancePolicy": "nep1", "objectiveEnsurance": "cpu-usage",
+//	  "metricName": "cpu_total_usage", "value": 7500, "threshold": 6000, "triggeredCount": 3, "triggered": true, ...}}'
+type ObjectiveStatus struct {
+	// NodeQOSEnsurancePolicy is the name of the policy of the objective ensurance.
+	NodeQOSEnsurancePolicy string `json:"nodeQOSEnsurancePolicy"`
+
+	// ObjectiveEnsurance is the name of the objective ensurance.
+	ObjectiveEnsurance string `json:"objectiveEnsurance"`
+
+	// MetricName is the metric of the metric rule, it is empty for the rego rules.
+	// +optional
+	MetricName string `json:"metricName,omitempty"`
+
+	// Value is the latest value of the metric after the aggregation, it is the max value if multiple series are matched.
+	// +optional
+	Value float64 `json:"value,omitempty"`
+
+	// Threshold is the value to trigger the objective ensurance.
+	// +optional
+	Threshold float64 `json:"threshold,omitempty"`
+
+	// TriggeredCount and RestoredCount are the consecutive times the objective is triggered or restorable.
+	TriggeredCount uint64 `json:"triggeredCount"`
+	RestoredCount  uint64 `json:"restoredCount"`
+
+	// Triggered is true if the action is triggered and not restored yet.
+	Triggered bool `json:"triggered"`
+
+	// LastTriggeredTime is the time when the action was triggered last time.
+	// +optional
+	LastTriggeredTime *metav1.Time `json:"lastTriggeredTime,omitempty"`
+
+	// LastRestoredTime is the time when the action was restored last time.
+	// +optional
+	LastRestoredTime *metav1.Time `json:"lastRestoredTime,omitempty"`
+
+	// AffectedPods are the pods throttled or evicted by the action in namespace/name, the list is truncated
+	// and AffectedPodCount is the total number.
+	// +optional
+	AffectedPods     []string `json:"affectedPods,omitempty"`
+	AffectedPodCount int      `json:"affectedPodCount,omitempty"`
+}
+
+// GetObjectiveStatuses returns the status of the objective ensurances of the node keyed by "<policy name>.<objective ensurance name>".
+func GetObjectiveStatuses(node *v1.Node) (map[string]ObjectiveStatus, error) {
+	var statuses = make(map[string]ObjectiveStatus)
+
+	value, ok := node.Annotations[known.ObjectiveStatusAnnotation]
+	if !ok || value == "" {
+		return statuses, nil
+	}
+
+	if err := json.Unmarshal([]byte(value), &statuses); err != nil {
+		return statuses, fmt.Errorf("failed to parse annotation %s: %v", known.ObjectiveStatusAnnotation, err)
+	}
+
+	return statuses, nil
+}
@@ -4,3 +4,51 @@ const (
 	HPARecommendationValueAnnotation      = "analysis.crane.io/hpa-recommendation"
 	ResourceRecommendationValueAnnotation = "analysis.crane.io/resource-recommendation"
 )
+
+const (
+	// ObjectiveEnsurancesAnnotation holds the extended settings of the objective ensurances of a NodeQOSEnsurancePolicy,
+	// it is a json object keyed by the objective ensurance name.
+	ObjectiveEnsurancesAnnotation = "ensurance.crane.io/objective-ensurances"
+)
+
+const (
+	// MetricsServerGetAnnotation makes a NodeQOSEnsurancePolicy probe the node by the metrics server collector,
+	// it is a json object with the source of the metrics, such as {"source": "kubelet-summary"}.
+	MetricsServerGetAnnotation = "ensurance.crane.io/metrics-server-get"
+)
+
+const (
+	// MemoryThrottleAnnotation holds the extended memory throttle settings of an AvoidanceAction,
+	// such as {"minMemoryRatio": 50, "stepMemoryRatio": 10, "memoryHigh": true}.
+	MemoryThrottleAnnotation = "ensurance.crane.io/memory-throttle"
+)
+
+const (
+	// DiskIOThrottleAnnotation makes an AvoidanceAction throttle the disk io of the pods by the blkio/io cgroup,
+	// such as {"minIORatio": 20, "stepIORatio": 20}.
+	DiskIOThrottleAnnotation = "ensurance.crane.io/diskio-throttle"
+	// NetworkThrottleAnnotation makes an AvoidanceAction throttle the egress bandwidth of the pods by tc,
+	// such as {"minNetworkRatio": 20, "stepNetworkRatio": 20, "interface": "eth0"}.
+	NetworkThrottleAnnotation = "ensurance.crane.io/network-throttle"
+	// ThrottleTargetAnnotation selects the pods throttled by an AvoidanceAction by their labels, QoS class and priority,
+	// such as {"podSelector": {"matchLabels": {"app": "batch"}}, "maxQOSClass": "Burstable", "maxPriority": 1000}.
+	ThrottleTargetAnnotation = "ensurance.crane.io/throttle-target"
+)
+
+const (
+	// ObjectiveStatusAnnotation holds the status of the objective ensurances evaluated on a node, it is set on the node by
+	// crane-agent and it is a json object keyed by "<policy name>.<objective ensurance name>".
+	ObjectiveStatusAnnotation = "ensurance.crane.io/objective-status"
+)
+
+const (
+	// HoltWintersAnnotation holds the holt-winters config of the metrics of a TimeSeriesPrediction whose algorithm type
+	// is holtwinters, such as {"sampleInterval": "1m", "historyLength": "7d", "seasonLength": "1d"}.
+	HoltWintersAnnotation = "prediction.crane.io/holt-winters"
+)
+
+const (
+	// PredictionAccuracyAnnotation holds the accuracy of the latest predicted window evaluated of each metric of a
+	// TimeSeriesPrediction, it is set by craned and it is a json object keyed by the resource identifier of the metric.
+	PredictionAccuracyAnnotation = "prediction.crane.io/accuracy"
+)
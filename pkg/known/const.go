@@ -15,4 +15,10 @@ const (
 	DefaultDeletionGracePeriodSeconds = 30
 	MaxMinCPURatio                    = 100
 	MaxStepCPURatio                   = 100
+	MaxMinMemoryRatio                 = 100
+	MaxStepMemoryRatio                = 100
+	MaxMinIORatio                     = 100
+	MaxStepIORatio                    = 100
+	MaxMinNetworkRatio                = 100
+	MaxStepNetworkRatio               = 100
 )
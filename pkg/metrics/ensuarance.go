@@ -1,6 +1,7 @@
 package metrics
 
 import (
+	"strconv"
 	"sync"
 	"time"
 
@@ -25,7 +26,13 @@ const (
 	ExecutorStatusTotal   = "executor_status_total"
 	ExecutorErrorTotal    = "executor_error_total"
 	ExecutorEvictTotal    = "executor_evict_total"
+	ExecutorEvictionTotal = "executor_eviction_total"
 	PodResourceErrorTotal = "pod_resource_error_total"
+	PreviewPlanPods       = "preview_plan_pods"
+
+	CPUManagerAllocatedContainers = "cpu_manager_allocated_containers"
+	CPUManagerSharePoolCPUs       = "cpu_manager_share_pool_cpus"
+	CPUManagerReclaimedCPUs       = "cpu_manager_reclaimed_cpus"
 )
 
 type StepLabel string
@@ -47,10 +54,38 @@ const (
 type SubComponent string
 
 const (
-	SubComponentSchedule    SubComponent = "schedule"
-	SubComponentThrottle    SubComponent = "throttle"
-	SubComponentEvict       SubComponent = "evict"
-	SubComponentPodResource SubComponent = "pod-resource-manager"
+	SubComponentSchedule        SubComponent = "schedule"
+	SubComponentThrottle        SubComponent = "throttle"
+	SubComponentDiskIOThrottle  SubComponent = "diskio-throttle"
+	SubComponentNetworkThrottle SubComponent = "network-throttle"
+	SubComponentEvict           SubComponent = "evict"
+	SubComponentPodResource     SubComponent = "pod-resource-manager"
+)
+
+type EvictionResult string
+
+const (
+	// EvictionResultDone is the pod evicted
+	EvictionResultDone EvictionResult = "done"
+	// EvictionResultSkipped is the pod not evicted by the rate limit or the budgets, or it is not found
+	EvictionResultSkipped EvictionResult = "skipped"
+	// EvictionResultBlocked is the pod not evicted because of the PodDisruptionBudgets after retries
+	EvictionResultBlocked EvictionResult = "blocked"
+	// EvictionResultFailed is the pod failed to be evicted by other errors
+	EvictionResultFailed EvictionResult = "failed"
+)
+
+// PreviewPlanAction is the action of the plan built for the actions in preview.
+type PreviewPlanAction string
+
+const (
+	PreviewPlanDisableScheduling   PreviewPlanAction = "disableScheduling"
+	PreviewPlanCPUThrottleDown     PreviewPlanAction = "cpuThrottleDown"
+	PreviewPlanCPUThrottleUp       PreviewPlanAction = "cpuThrottleUp"
+	PreviewPlanMemoryThrottleDown  PreviewPlanAction = "memoryThrottleDown"
+	PreviewPlanDiskIOThrottleDown  PreviewPlanAction = "diskIOThrottleDown"
+	PreviewPlanNetworkThrottleDown PreviewPlanAction = "networkThrottleDown"
+	PreviewPlanEvict               PreviewPlanAction = "evict"
 )
 
 type AnalyzeType string
@@ -170,6 +205,61 @@ var (
 		},
 	)
 
+	//executorEvictionCounts records the number of pods to be evicted by executor module by the result
+	executorEvictionCounts = k8smetrics.NewCounterVec(
+		&k8smetrics.CounterOpts{
+			Namespace:      CraneNamespace,
+			Subsystem:      CraneAgentSubsystem,
+			Name:           ExecutorEvictionTotal,
+			Help:           "The number of pods to be evicted by the result, done, skipped, blocked or failed.",
+			StabilityLevel: k8smetrics.ALPHA,
+		}, []string{"result"},
+	)
+
+	//previewPlanPods records the number of pods, or containers for the cpu throttle, in the plan of the actions in preview
+	previewPlanPods = k8smetrics.NewGaugeVec(
+		&k8smetrics.GaugeOpts{
+			Namespace:      CraneNamespace,
+			Subsystem:      CraneAgentSubsystem,
+			Name:           PreviewPlanPods,
+			Help:           "The number of pods which would be affected by the actions in preview, the disableScheduling is 1 if the scheduling would be disabled.",
+			StabilityLevel: k8smetrics.ALPHA,
+		}, []string{"action"},
+	)
+
+	//cpuManagerAllocatedContainers records the number of containers with cpus allocated by the advanced cpu manager by the NUMA alignment
+	cpuManagerAllocatedContainers = k8smetrics.NewGaugeVec(
+		&k8smetrics.GaugeOpts{
+			Namespace:      CraneNamespace,
+			Subsystem:      CraneAgentSubsystem,
+			Name:           CPUManagerAllocatedContainers,
+			Help:           "The number of containers with cpus allocated by the advanced cpu manager, aligned is true if the cpus are on the fewest NUMA nodes.",
+			StabilityLevel: k8smetrics.ALPHA,
+		}, []string{"aligned"},
+	)
+
+	//cpuManagerSharePoolCPUs records the number of cpus in the share pool of each NUMA node
+	cpuManagerSharePoolCPUs = k8smetrics.NewGaugeVec(
+		&k8smetrics.GaugeOpts{
+			Namespace:      CraneNamespace,
+			Subsystem:      CraneAgentSubsystem,
+			Name:           CPUManagerSharePoolCPUs,
+			Help:           "The number of cpus in the share pool of the advanced cpu manager on each NUMA node.",
+			StabilityLevel: k8smetrics.ALPHA,
+		}, []string{"numa_node"},
+	)
+
+	//cpuManagerReclaimedCPUs records the number of cpus in the reclaimed cpuset of the offline pods
+	cpuManagerReclaimedCPUs = k8smetrics.NewGauge(
+		&k8smetrics.GaugeOpts{
+			Namespace:      CraneNamespace,
+			Subsystem:      CraneAgentSubsystem,
+			Name:           CPUManagerReclaimedCPUs,
+			Help:           "The number of cpus in the reclaimed cpuset of the offline pods of the advanced cpu manager.",
+			StabilityLevel: k8smetrics.ALPHA,
+		},
+	)
+
 	//podResourceUpdateErrorCounts records the number of errors when update pod's ext resource to quota
 	podResourceUpdateErrorCounts = k8smetrics.NewCounterVec(
 		&k8smetrics.CounterOpts{
@@ -195,6 +285,11 @@ func RegisterCraneAgent() {
 		legacyregistry.MustRegister(executorStatusCounts)
 		legacyregistry.MustRegister(executorErrorCounts)
 		legacyregistry.MustRegister(executorEvictCounts)
+		legacyregistry.MustRegister(executorEvictionCounts)
+		legacyregistry.MustRegister(previewPlanPods)
+		legacyregistry.MustRegister(cpuManagerAllocatedContainers)
+		legacyregistry.MustRegister(cpuManagerSharePoolCPUs)
+		legacyregistry.MustRegister(cpuManagerReclaimedCPUs)
 	})
 }
 
@@ -258,3 +353,23 @@ func PodResourceUpdateErrorCounterInc(subComponent SubComponent, stepName StepLa
 func ExecutorEvictCountsInc() {
 	executorEvictCounts.Inc()
 }
+
+func ExecutorEvictionCounterInc(result EvictionResult) {
+	executorEvictionCounts.With(prometheus.Labels{"result": string(result)}).Inc()
+}
+
+func UpdatePreviewPlan(action PreviewPlanAction, value float64) {
+	previewPlanPods.With(prometheus.Labels{"action": string(action)}).Set(value)
+}
+
+func UpdateCPUManagerAllocatedContainers(aligned bool, value float64) {
+	cpuManagerAllocatedContainers.With(prometheus.Labels{"aligned": strconv.FormatBool(aligned)}).Set(value)
+}
+
+func UpdateCPUManagerSharePoolCPUs(numaNode int, value float64) {
+	cpuManagerSharePoolCPUs.With(prometheus.Labels{"numa_node": strconv.Itoa(numaNode)}).Set(value)
+}
+
+func UpdateCPUManagerReclaimedCPUs(value float64) {
+	cpuManagerReclaimedCPUs.Set(value)
+}
@@ -2,6 +2,7 @@ package metrics
 
 import (
 	"context"
+	"encoding/json"
 	"fmt"
 	"sort"
 	"strconv"
@@ -14,12 +15,16 @@ import (
 	"sigs.k8s.io/controller-runtime/pkg/client"
 
 	predictionapi "github.com/gocrane/api/prediction/v1alpha1"
+
+	"github.com/gocrane/crane/pkg/known"
+	"github.com/gocrane/crane/pkg/prediction/accuracy"
 )
 
 type TspMetricCollector struct {
 	client.Client
 	resourceCpuMetric *prometheus.Desc
 	resourceMemMetric *prometheus.Desc
+	accuracyMetric    *prometheus.Desc
 }
 
 func NewTspMetricCollector(client client.Client) *TspMetricCollector {
@@ -37,6 +42,12 @@ func NewTspMetricCollector(client client.Client) *TspMetricCollector {
 			[]string{"targetKind", "targetName", "targetNamespace", "resourceIdentifier", "type", "resourceQuery", "metricQuery", "expressionQuery", "algorithm", "aggregateKey"},
 			nil,
 		),
+		accuracyMetric: prometheus.NewDesc(
+			prometheus.BuildFQName("crane", "prediction", "time_series_prediction_accuracy"),
+			"prediction accuracy of the latest evaluated window for TimeSeriesPrediction",
+			[]string{"targetKind", "targetName", "targetNamespace", "resourceIdentifier", "algorithm", "type"},
+			nil,
+		),
 	}
 }
 
@@ -46,6 +57,7 @@ func NewTspMetricCollector(client client.Client) *TspMetricCollector {
 func (c *TspMetricCollector) Describe(ch chan<- *prometheus.Desc) {
 	ch <- c.resourceCpuMetric
 	ch <- c.resourceMemMetric
+	ch <- c.accuracyMetric
 }
 
 func (c *TspMetricCollector) Collect(ch chan<- prometheus.Metric) {
@@ -76,6 +88,47 @@ func (c *TspMetricCollector) getMetrics(tsp *predictionapi.TimeSeriesPrediction)
 		outputMetrics := c.computePredictionMetric(tsp, pmMap, metricStatus)
 		ms = append(ms, outputMetrics...)
 	}
+	ms = append(ms, c.computeAccuracyMetric(tsp, pmMap)...)
+	return ms
+}
+
+// computeAccuracyMetric returns the accuracy metrics from the accuracy annotation written by the TimeSeriesPrediction controller.
+func (c *TspMetricCollector) computeAccuracyMetric(tsp *predictionapi.TimeSeriesPrediction, pmMap map[string]predictionapi.PredictionMetric) []prometheus.Metric {
+	value, ok := tsp.Annotations[known.PredictionAccuracyAnnotation]
+	if !ok {
+		return nil
+	}
+	results := map[string]accuracy.WindowResult{}
+	if err := json.Unmarshal([]byte(value), &results); err != nil {
+		klog.Error(err, "Failed to parse prediction accuracy", "tsp", klog.KObj(tsp))
+		return nil
+	}
+
+	var ms []prometheus.Metric
+	for resourceIdentifier, result := range results {
+		metricConf, ok := pmMap[resourceIdentifier]
+		if !ok {
+			continue
+		}
+		values := map[string]float64{
+			"mae":              result.MAE,
+			"prediction_error": result.PredictionError,
+		}
+		if result.MAPE != nil {
+			values["mape"] = *result.MAPE
+		}
+		for typ, v := range values {
+			labelValues := []string{
+				tsp.Spec.TargetRef.Kind,
+				tsp.Spec.TargetRef.Name,
+				tsp.Spec.TargetRef.Namespace,
+				resourceIdentifier,
+				string(metricConf.Algorithm.AlgorithmType),
+				typ,
+			}
+			ms = append(ms, prometheus.MustNewConstMetric(c.accuracyMetric, prometheus.GaugeValue, v, labelValues...))
+		}
+	}
 	return ms
 }
 
@@ -64,3 +64,39 @@ func MAE(actual, predicted []float64) (float64, error) {
 
 	return e, nil
 }
+
+// Result is the accuracy of a predicted time series against the actual one.
+type Result struct {
+	// MAPE is nil if some actual values are too close to zero
+	MAPE *float64 `json:"mape,omitempty"`
+	MAE  float64  `json:"mae"`
+	// PredictionError is MAPE, or MAE in case MAPE is nil
+	PredictionError float64 `json:"predictionError"`
+	// Samples is the number of the samples compared
+	Samples int `json:"samples"`
+}
+
+// WindowResult is the accuracy of the prediction of a time window, the timestamps are in unix seconds.
+type WindowResult struct {
+	Start int64 `json:"start"`
+	End   int64 `json:"end"`
+	Result
+}
+
+// Evaluate returns the accuracy of the predicted values against the actual values.
+func Evaluate(actual, predicted []float64) (*Result, error) {
+	if len(actual) == 0 {
+		return nil, fmt.Errorf("no actual values")
+	}
+	mae, err := MAE(actual, predicted)
+	if err != nil {
+		return nil, err
+	}
+
+	result := &Result{MAE: mae, PredictionError: mae, Samples: len(actual)}
+	if mape, err := MAPE(actual, predicted); err == nil {
+		result.MAPE = &mape
+		result.PredictionError = mape
+	}
+	return result, nil
+}
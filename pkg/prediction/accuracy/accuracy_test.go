@@ -68,3 +68,51 @@ func TestMAE(t *testing.T) {
 	assert.NoError(t, err)
 	fmt.Println(mae)
 }
+
+func TestEvaluate(t *testing.T) {
+	tests := []struct {
+		name      string
+		actual    []float64
+		predicted []float64
+		wantErr   bool
+		wantMAPE  bool
+		wantPE    float64
+	}{
+		{
+			name:      "mape",
+			actual:    []float64{1, 2, 4},
+			predicted: []float64{1.1, 2.2, 4.4},
+			wantMAPE:  true,
+			wantPE:    0.1,
+		},
+		{
+			name:      "mae in case actual values are zero",
+			actual:    []float64{0, 2, 4},
+			predicted: []float64{0.3, 2.3, 4.3},
+			wantPE:    0.3,
+		},
+		{
+			name:      "not the same length",
+			actual:    []float64{1, 2},
+			predicted: []float64{1},
+			wantErr:   true,
+		},
+		{
+			name:    "no actual values",
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result, err := Evaluate(tt.actual, tt.predicted)
+			if tt.wantErr {
+				assert.Error(t, err)
+				return
+			}
+			assert.NoError(t, err)
+			assert.Equal(t, len(tt.actual), result.Samples)
+			assert.Equal(t, tt.wantMAPE, result.MAPE != nil)
+			assert.InDelta(t, tt.wantPE, result.PredictionError, 1e-9)
+		})
+	}
+}
@@ -0,0 +1,175 @@
+package backtest
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"k8s.io/klog/v2"
+
+	predictionapi "github.com/gocrane/api/prediction/v1alpha1"
+
+	"github.com/gocrane/crane/pkg/common"
+	"github.com/gocrane/crane/pkg/metricnaming"
+	"github.com/gocrane/crane/pkg/prediction"
+	"github.com/gocrane/crane/pkg/prediction/accuracy"
+	"github.com/gocrane/crane/pkg/prediction/config"
+	"github.com/gocrane/crane/pkg/predictor"
+	"github.com/gocrane/crane/pkg/providers"
+)
+
+const (
+	caller = "backtest"
+
+	replayedDataSource providers.DataSourceType = "backtest"
+)
+
+// Case is an algorithm with its config to backtest.
+type Case struct {
+	Name      string
+	Algorithm predictionapi.AlgorithmType
+	Config    config.Config
+}
+
+// Options are the options of a backtest.
+type Options struct {
+	// Start and End are the range of the cutoff times, a round of prediction is made at each cutoff time
+	Start time.Time
+	End   time.Time
+	// Interval is the interval between the cutoff times
+	Interval time.Duration
+	// Window is the prediction window after each cutoff time to evaluate
+	Window time.Duration
+	// Step is the resolution of the actual time series to evaluate
+	Step time.Duration
+	// ModelConfig is the config of the predictors
+	ModelConfig config.AlgorithmModelConfig
+	// Timeout is the timeout of the prediction of a round
+	Timeout time.Duration
+}
+
+// Round is the result of a round of prediction.
+type Round struct {
+	// Time is the cutoff time of the round
+	Time     time.Time
+	Accuracy *accuracy.Result
+	Err      error
+}
+
+// Report is the result of the backtest of a case.
+type Report struct {
+	Case   Case
+	Rounds []Round
+	// Accuracy is evaluated over the samples of all the rounds, it is nil if no round succeeded
+	Accuracy *accuracy.Result
+}
+
+// Run replays the history through the predictor of each case, a prediction is made at each cutoff time in
+// [opts.Start, opts.End] by the history before the cutoff time, and is evaluated by the actual time series in the
+// following window. The reports are in the order of the cases.
+func Run(history providers.History, namer metricnaming.MetricNamer, cases []Case, opts Options) ([]Report, error) {
+	if opts.Interval <= 0 || opts.Window <= 0 || opts.Step <= 0 {
+		return nil, fmt.Errorf("interval, window and step must be positive")
+	}
+	if opts.End.Before(opts.Start) {
+		return nil, fmt.Errorf("end %v is before start %v", opts.End, opts.Start)
+	}
+	if opts.Timeout <= 0 {
+		opts.Timeout = time.Minute
+	}
+
+	reports := make([]Report, 0, len(cases))
+	for _, c := range cases {
+		report := Report{Case: c}
+		var actual, predicted []float64
+		for t := opts.Start.Truncate(opts.Step); !t.After(opts.End); t = t.Add(opts.Interval) {
+			roundActual, roundPredicted, err := runRound(history, namer, c, opts, t)
+			round := Round{Time: t, Err: err}
+			if err == nil {
+				round.Accuracy, round.Err = accuracy.Evaluate(roundActual, roundPredicted)
+			}
+			if round.Err == nil {
+				actual = append(actual, roundActual...)
+				predicted = append(predicted, roundPredicted...)
+			} else {
+				klog.V(4).InfoS("Backtest round failed.", "case", c.Name, "time", t, "err", round.Err)
+			}
+			report.Rounds = append(report.Rounds, round)
+		}
+		if len(actual) > 0 {
+			report.Accuracy, _ = accuracy.Evaluate(actual, predicted)
+		}
+		reports = append(reports, report)
+	}
+	return reports, nil
+}
+
+// runRound predicts the window after the cutoff time by the history before it, and returns the actual and predicted
+// values paired by the timestamps.
+func runRound(history providers.History, namer metricnaming.MetricNamer, c Case, opts Options, cutoff time.Time) ([]float64, []float64, error) {
+	replayed := newReplayedHistory(history, cutoff, time.Now(), opts.Step)
+	mgr := predictor.NewManager(
+		map[providers.DataSourceType]providers.RealTime{replayedDataSource: replayed},
+		map[providers.DataSourceType]providers.History{replayedDataSource: replayed},
+		map[predictionapi.AlgorithmType]predictor.PredictorConfig{c.Algorithm: {ModelConfig: opts.ModelConfig}},
+	)
+	p := mgr.GetPredictor(c.Algorithm)
+	if p == nil {
+		return nil, nil, fmt.Errorf("unknown algorithm %s", c.Algorithm)
+	}
+
+	stopCh := make(chan struct{})
+	defer close(stopCh)
+	go mgr.Start(stopCh)
+
+	if err := p.WithQuery(namer, caller, c.Config); err != nil {
+		return nil, nil, err
+	}
+	defer p.DeleteQuery(namer, caller)
+
+	ctx, cancel := context.WithTimeout(context.Background(), opts.Timeout)
+	defer cancel()
+	start := cutoff.Add(replayed.offset)
+	tsList, err := p.QueryPredictedTimeSeries(ctx, namer, start, start.Add(opts.Window))
+	if err != nil {
+		return nil, nil, err
+	}
+	if len(tsList) == 0 {
+		return nil, nil, fmt.Errorf("no predicted time series at %v", cutoff)
+	}
+
+	end := cutoff.Add(opts.Window)
+	actualList, err := history.QueryTimeSeries(namer, cutoff, end, opts.Step)
+	if err != nil {
+		return nil, nil, err
+	}
+	actual, predicted := pairSamples(filterTimeSeries(actualList, cutoff, end), shiftTimeSeries(tsList, -replayed.offset))
+	return actual, predicted, nil
+}
+
+// pairSamples pairs the actual and predicted samples by the labels and the timestamps.
+func pairSamples(actualList, predictedList []*common.TimeSeries) ([]float64, []float64) {
+	actualSamples := map[string]map[int64]float64{}
+	for _, ts := range actualList {
+		samples := map[int64]float64{}
+		for _, sample := range ts.Samples {
+			samples[sample.Timestamp] = sample.Value
+		}
+		actualSamples[prediction.AggregateSignalKey(ts.Labels)] = samples
+	}
+
+	var actual, predicted []float64
+	for _, ts := range predictedList {
+		samples, ok := actualSamples[prediction.AggregateSignalKey(ts.Labels)]
+		if !ok {
+			continue
+		}
+		for _, sample := range ts.Samples {
+			if value, ok := samples[sample.Timestamp]; ok {
+				actual = append(actual, value)
+				predicted = append(predicted, sample.Value)
+			}
+		}
+	}
+	return actual, predicted
+}
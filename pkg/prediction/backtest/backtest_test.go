@@ -0,0 +1,113 @@
+package backtest
+
+import (
+	"os"
+	"testing"
+	"time"
+
+	"k8s.io/apimachinery/pkg/labels"
+
+	"github.com/gocrane/api/prediction/v1alpha1"
+
+	"github.com/gocrane/crane/pkg/common"
+	"github.com/gocrane/crane/pkg/metricnaming"
+	"github.com/gocrane/crane/pkg/metricquery"
+	"github.com/gocrane/crane/pkg/prediction/config"
+	"github.com/gocrane/crane/pkg/providers/csv"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestReplayedHistory(t *testing.T) {
+	ts := &common.TimeSeries{}
+	for i := int64(0); i < 10; i++ {
+		ts.Samples = append(ts.Samples, common.Sample{Value: float64(i), Timestamp: i * 60})
+	}
+	history := &fakeHistory{ts: ts}
+
+	now := time.Unix(3600, 0)
+	r := newReplayedHistory(history, time.Unix(300, 0), now, time.Minute)
+
+	// the samples after the cutoff are not replayed
+	tsList, err := r.QueryTimeSeries(nil, now.Add(-3*time.Minute), now.Add(time.Hour), time.Minute)
+	assert.NoError(t, err)
+	assert.Len(t, tsList, 1)
+	assert.Equal(t, []common.Sample{
+		{Value: 2, Timestamp: 3420},
+		{Value: 3, Timestamp: 3480},
+		{Value: 4, Timestamp: 3540},
+		{Value: 5, Timestamp: 3600},
+	}, tsList[0].Samples)
+
+	tsList, err = r.QueryLatestTimeSeries(nil)
+	assert.NoError(t, err)
+	assert.Equal(t, []common.Sample{{Value: 5, Timestamp: 3600}}, tsList[0].Samples)
+}
+
+func TestRun(t *testing.T) {
+	f, err := os.Open("../dsp/test_data/input0.csv")
+	assert.NoError(t, err)
+	defer f.Close()
+	provider, err := csv.NewProvider(f)
+	assert.NoError(t, err)
+
+	namer := &metricnaming.GeneralMetricNamer{
+		Metric: &metricquery.Metric{
+			Type: metricquery.PromQLMetricType,
+			Prom: &metricquery.PromNamerInfo{
+				QueryExpr: "hello",
+				Selector:  labels.Nothing(),
+			},
+		}}
+	cases := []Case{
+		{
+			Name:      "dsp",
+			Algorithm: v1alpha1.AlgorithmTypeDSP,
+			Config:    config.Config{DSP: &v1alpha1.DSP{SampleInterval: "1m", HistoryLength: "6d"}},
+		},
+		{
+			Name:      "percentile",
+			Algorithm: v1alpha1.AlgorithmTypePercentile,
+			Config: config.Config{Percentile: &v1alpha1.Percentile{
+				SampleInterval: "1m",
+				HistoryLength:  "1d",
+				Percentile:     "0.5",
+				MarginFraction: "0",
+				Histogram:      v1alpha1.HistogramConfig{HalfLife: "24h", BucketSize: "1", MaxValue: "1000"},
+			}},
+		},
+	}
+	// the history is of 8 days, predict the last day in two rounds
+	start := time.Unix(1628640000, 0).Add(7 * 24 * time.Hour)
+	reports, err := Run(provider, namer, cases, Options{
+		Start:       start,
+		End:         start.Add(12 * time.Hour),
+		Interval:    12 * time.Hour,
+		Window:      12 * time.Hour,
+		Step:        time.Minute,
+		ModelConfig: config.AlgorithmModelConfig{UpdateInterval: time.Hour},
+		Timeout:     time.Minute,
+	})
+	assert.NoError(t, err)
+	assert.Len(t, reports, 2)
+	for _, report := range reports {
+		assert.Len(t, report.Rounds, 2)
+		for _, round := range report.Rounds {
+			assert.NoError(t, round.Err, report.Case.Name)
+			assert.NotNil(t, round.Accuracy, report.Case.Name)
+		}
+		assert.NotNil(t, report.Accuracy, report.Case.Name)
+	}
+	// the daily periodic time series is predicted better by dsp than by a percentile of the last day
+	assert.Less(t, reports[0].Accuracy.MAE, reports[1].Accuracy.MAE)
+
+	_, err = Run(provider, namer, cases, Options{Start: start, End: start.Add(-time.Hour), Interval: time.Hour, Window: time.Hour, Step: time.Minute})
+	assert.Error(t, err)
+}
+
+type fakeHistory struct {
+	ts *common.TimeSeries
+}
+
+func (h *fakeHistory) QueryTimeSeries(_ metricnaming.MetricNamer, _ time.Time, _ time.Time, _ time.Duration) ([]*common.TimeSeries, error) {
+	return []*common.TimeSeries{h.ts}, nil
+}
@@ -0,0 +1,91 @@
+package backtest
+
+import (
+	"time"
+
+	"github.com/gocrane/crane/pkg/common"
+	"github.com/gocrane/crane/pkg/metricnaming"
+	"github.com/gocrane/crane/pkg/providers"
+)
+
+var _ providers.Interface = &replayedHistory{}
+
+// replayedHistory replays the history before the cutoff as if it is happening now, the timestamps of the samples are
+// shifted by the offset, and the samples after the cutoff are dropped so the predictors can not see the future.
+type replayedHistory struct {
+	history providers.History
+	offset  time.Duration
+	cutoff  time.Time
+	step    time.Duration
+}
+
+func newReplayedHistory(history providers.History, cutoff time.Time, now time.Time, step time.Duration) *replayedHistory {
+	return &replayedHistory{
+		history: history,
+		offset:  now.Truncate(step).Sub(cutoff),
+		cutoff:  cutoff,
+		step:    step,
+	}
+}
+
+// QueryTimeSeries returns the time series in [startTime, endTime] of the replayed time, at most to the cutoff.
+func (r *replayedHistory) QueryTimeSeries(namer metricnaming.MetricNamer, startTime time.Time, endTime time.Time, step time.Duration) ([]*common.TimeSeries, error) {
+	start, end := startTime.Add(-r.offset), endTime.Add(-r.offset)
+	if end.After(r.cutoff) {
+		end = r.cutoff
+	}
+	if end.Before(start) {
+		return nil, nil
+	}
+
+	tsList, err := r.history.QueryTimeSeries(namer, start, end, step)
+	if err != nil {
+		return nil, err
+	}
+	return shiftTimeSeries(filterTimeSeries(tsList, start, end), r.offset), nil
+}
+
+// QueryLatestTimeSeries returns the latest samples before the cutoff.
+func (r *replayedHistory) QueryLatestTimeSeries(namer metricnaming.MetricNamer) ([]*common.TimeSeries, error) {
+	tsList, err := r.history.QueryTimeSeries(namer, r.cutoff.Add(-5*r.step), r.cutoff, r.step)
+	if err != nil {
+		return nil, err
+	}
+
+	var latest []*common.TimeSeries
+	for _, ts := range filterTimeSeries(tsList, r.cutoff.Add(-5*r.step), r.cutoff) {
+		latest = append(latest, &common.TimeSeries{Labels: ts.Labels, Samples: ts.Samples[len(ts.Samples)-1:]})
+	}
+	return shiftTimeSeries(latest, r.offset), nil
+}
+
+// filterTimeSeries returns the samples in [start, end] of the time series, some providers return the samples out of
+// the range queried.
+func filterTimeSeries(tsList []*common.TimeSeries, start, end time.Time) []*common.TimeSeries {
+	var result []*common.TimeSeries
+	for _, ts := range tsList {
+		var samples []common.Sample
+		for _, sample := range ts.Samples {
+			if sample.Timestamp >= start.Unix() && sample.Timestamp <= end.Unix() {
+				samples = append(samples, sample)
+			}
+		}
+		if len(samples) > 0 {
+			result = append(result, &common.TimeSeries{Labels: ts.Labels, Samples: samples})
+		}
+	}
+	return result
+}
+
+func shiftTimeSeries(tsList []*common.TimeSeries, offset time.Duration) []*common.TimeSeries {
+	seconds := int64(offset / time.Second)
+	result := make([]*common.TimeSeries, 0, len(tsList))
+	for _, ts := range tsList {
+		samples := make([]common.Sample, 0, len(ts.Samples))
+		for _, sample := range ts.Samples {
+			samples = append(samples, common.Sample{Value: sample.Value, Timestamp: sample.Timestamp + seconds})
+		}
+		result = append(result, &common.TimeSeries{Labels: ts.Labels, Samples: samples})
+	}
+	return result
+}
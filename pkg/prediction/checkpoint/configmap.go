@@ -0,0 +1,114 @@
+package checkpoint
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+
+	v1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/klog/v2"
+)
+
+const (
+	// checkpointLabel labels the ConfigMaps of the checkpoints
+	checkpointLabel = "prediction.crane.io/checkpoint"
+	// checkpointDataKey is the key of the checkpoint in the data of the ConfigMap
+	checkpointDataKey = "checkpoint"
+	// configMapMaxDataSize is the max size of the data of a checkpoint, a ConfigMap is limited to 1MiB and the rest is
+	// left for the metadata and the other fields of the checkpoint
+	configMapMaxDataSize = 1000 << 10
+)
+
+type configMapStore struct {
+	client    kubernetes.Interface
+	namespace string
+}
+
+// NewConfigMapStore returns a store which saves each checkpoint to a ConfigMap of the namespace.
+func NewConfigMapStore(client kubernetes.Interface, namespace string) Store {
+	return &configMapStore{client: client, namespace: namespace}
+}
+
+func (s *configMapStore) Save(ctx context.Context, checkpoint *Checkpoint) error {
+	if len(checkpoint.Data) > configMapMaxDataSize {
+		return fmt.Errorf("the checkpoint of %d bytes exceeds the max size %d of a ConfigMap", len(checkpoint.Data), configMapMaxDataSize)
+	}
+
+	data, err := json.Marshal(checkpoint)
+	if err != nil {
+		return err
+	}
+
+	name := checkpointName(checkpoint.Predictor, checkpoint.QueryExpr)
+	configMap, err := s.client.CoreV1().ConfigMaps(s.namespace).Get(ctx, name, metav1.GetOptions{})
+	if errors.IsNotFound(err) {
+		configMap = &v1.ConfigMap{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:      name,
+				Namespace: s.namespace,
+				Labels:    map[string]string{checkpointLabel: "true"},
+			},
+			Data: map[string]string{checkpointDataKey: string(data)},
+		}
+		_, err = s.client.CoreV1().ConfigMaps(s.namespace).Create(ctx, configMap, metav1.CreateOptions{})
+		return err
+	} else if err != nil {
+		return err
+	}
+
+	configMap = configMap.DeepCopy()
+	configMap.Data = map[string]string{checkpointDataKey: string(data)}
+	_, err = s.client.CoreV1().ConfigMaps(s.namespace).Update(ctx, configMap, metav1.UpdateOptions{})
+	return err
+}
+
+func (s *configMapStore) MaxDataSize() int {
+	return configMapMaxDataSize
+}
+
+func (s *configMapStore) Load(ctx context.Context, predictor string, queryExpr string) (*Checkpoint, error) {
+	configMap, err := s.client.CoreV1().ConfigMaps(s.namespace).Get(ctx, checkpointName(predictor, queryExpr), metav1.GetOptions{})
+	if errors.IsNotFound(err) {
+		return nil, nil
+	} else if err != nil {
+		return nil, err
+	}
+	return decodeCheckpointConfigMap(configMap)
+}
+
+func (s *configMapStore) List(ctx context.Context) ([]*Checkpoint, error) {
+	configMaps, err := s.client.CoreV1().ConfigMaps(s.namespace).List(ctx, metav1.ListOptions{LabelSelector: checkpointLabel})
+	if err != nil {
+		return nil, err
+	}
+
+	var checkpoints []*Checkpoint
+	for i := range configMaps.Items {
+		checkpoint, err := decodeCheckpointConfigMap(&configMaps.Items[i])
+		if err != nil {
+			klog.Warningf("Skip the invalid checkpoint ConfigMap %s: %v", klog.KObj(&configMaps.Items[i]), err)
+			continue
+		}
+		checkpoints = append(checkpoints, checkpoint)
+	}
+	return checkpoints, nil
+}
+
+func (s *configMapStore) Delete(ctx context.Context, predictor string, queryExpr string) error {
+	err := s.client.CoreV1().ConfigMaps(s.namespace).Delete(ctx, checkpointName(predictor, queryExpr), metav1.DeleteOptions{})
+	if err != nil && !errors.IsNotFound(err) {
+		return err
+	}
+	return nil
+}
+
+func decodeCheckpointConfigMap(configMap *v1.ConfigMap) (*Checkpoint, error) {
+	var checkpoint Checkpoint
+	if err := json.Unmarshal([]byte(configMap.Data[checkpointDataKey]), &checkpoint); err != nil {
+		return nil, err
+	}
+	return &checkpoint, nil
+}
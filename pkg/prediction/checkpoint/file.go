@@ -0,0 +1,108 @@
+package checkpoint
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"k8s.io/klog/v2"
+)
+
+const checkpointFileExt = ".json"
+
+type fileStore struct {
+	dir string
+}
+
+// NewFileStore returns a store which saves each checkpoint to a json file of the directory.
+func NewFileStore(dir string) (Store, error) {
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return nil, fmt.Errorf("failed to create checkpoint directory %s: %v", dir, err)
+	}
+	return &fileStore{dir: dir}, nil
+}
+
+func (s *fileStore) Save(_ context.Context, checkpoint *Checkpoint) error {
+	data, err := json.Marshal(checkpoint)
+	if err != nil {
+		return err
+	}
+
+	// write to a temp file and rename it, so that the checkpoint is never read half written
+	path := s.path(checkpoint.Predictor, checkpoint.QueryExpr)
+	tmp, err := ioutil.TempFile(s.dir, filepath.Base(path)+".tmp")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+
+	if _, err = tmp.Write(data); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err = tmp.Close(); err != nil {
+		return err
+	}
+	return os.Rename(tmp.Name(), path)
+}
+
+func (s *fileStore) MaxDataSize() int {
+	return 0
+}
+
+func (s *fileStore) Load(_ context.Context, predictor string, queryExpr string) (*Checkpoint, error) {
+	checkpoint, err := readCheckpointFile(s.path(predictor, queryExpr))
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	return checkpoint, err
+}
+
+func (s *fileStore) List(_ context.Context) ([]*Checkpoint, error) {
+	files, err := ioutil.ReadDir(s.dir)
+	if err != nil {
+		return nil, err
+	}
+
+	var checkpoints []*Checkpoint
+	for _, file := range files {
+		if file.IsDir() || !strings.HasSuffix(file.Name(), checkpointFileExt) {
+			continue
+		}
+		checkpoint, err := readCheckpointFile(filepath.Join(s.dir, file.Name()))
+		if err != nil {
+			klog.Warningf("Skip the invalid checkpoint file %s: %v", file.Name(), err)
+			continue
+		}
+		checkpoints = append(checkpoints, checkpoint)
+	}
+	return checkpoints, nil
+}
+
+func (s *fileStore) Delete(_ context.Context, predictor string, queryExpr string) error {
+	if err := os.Remove(s.path(predictor, queryExpr)); err != nil && !os.IsNotExist(err) {
+		return err
+	}
+	return nil
+}
+
+func (s *fileStore) path(predictor string, queryExpr string) string {
+	return filepath.Join(s.dir, checkpointName(predictor, queryExpr)+checkpointFileExt)
+}
+
+func readCheckpointFile(path string) (*Checkpoint, error) {
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	var checkpoint Checkpoint
+	if err := json.Unmarshal(data, &checkpoint); err != nil {
+		return nil, err
+	}
+	return &checkpoint, nil
+}
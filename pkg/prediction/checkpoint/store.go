@@ -0,0 +1,80 @@
+package checkpoint
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"time"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/klog/v2"
+)
+
+const (
+	// StoreTypeFile saves the checkpoints to the files of a local directory
+	StoreTypeFile = "file"
+	// StoreTypeConfigMap saves the checkpoints to the ConfigMaps of a namespace
+	StoreTypeConfigMap = "configmap"
+)
+
+// Checkpoint is the model of a query expression saved by a predictor, so that the model can be restored without
+// fetching the history again.
+type Checkpoint struct {
+	// Predictor is the name of the predictor which saved the checkpoint
+	Predictor string `json:"predictor"`
+	// QueryExpr is the unique key of the metric namer of the model
+	QueryExpr string `json:"queryExpr"`
+	// Data is the model encoded by the predictor
+	Data json.RawMessage `json:"data"`
+	// UpdateTime is the time the checkpoint is saved, the stale checkpoints are expired by it
+	UpdateTime metav1.Time `json:"updateTime"`
+}
+
+// Store saves and loads the checkpoints of the predictors.
+type Store interface {
+	// Save creates or updates the checkpoint
+	Save(ctx context.Context, checkpoint *Checkpoint) error
+	// Load returns the checkpoint of the query expression saved by the predictor, nil is returned if not found
+	Load(ctx context.Context, predictor string, queryExpr string) (*Checkpoint, error)
+	// List returns all the checkpoints
+	List(ctx context.Context) ([]*Checkpoint, error)
+	// Delete deletes the checkpoint, it is not an error if the checkpoint is not found
+	Delete(ctx context.Context, predictor string, queryExpr string) error
+	// MaxDataSize returns the max size in bytes of the data of a checkpoint, zero means unlimited
+	MaxDataSize() int
+}
+
+// IsExpired returns true if the checkpoint is older than the expiration, the checkpoints never expire if the
+// expiration is zero.
+func IsExpired(checkpoint *Checkpoint, expiration time.Duration, now time.Time) bool {
+	return expiration > 0 && now.Sub(checkpoint.UpdateTime.Time) > expiration
+}
+
+// DeleteExpired deletes the checkpoints of the predictor which are older than the expiration.
+func DeleteExpired(ctx context.Context, store Store, predictor string, expiration time.Duration, now time.Time) {
+	checkpoints, err := store.List(ctx)
+	if err != nil {
+		klog.ErrorS(err, "Failed to list the checkpoints.", "predictor", predictor)
+		return
+	}
+	for _, c := range checkpoints {
+		if c.Predictor != predictor || !IsExpired(c, expiration, now) {
+			continue
+		}
+		if err := store.Delete(ctx, c.Predictor, c.QueryExpr); err != nil {
+			klog.ErrorS(err, "Failed to delete the stale checkpoint.", "predictor", predictor, "queryExpr", c.QueryExpr)
+			continue
+		}
+		klog.V(4).InfoS("Stale checkpoint deleted.", "predictor", predictor, "queryExpr", c.QueryExpr, "updateTime", c.UpdateTime)
+	}
+}
+
+// checkpointName returns the name of the checkpoint of the query expression saved by the predictor. The query
+// expressions are hashed because they are too long and have characters not allowed in the names of files and objects.
+func checkpointName(predictor string, queryExpr string) string {
+	sum := sha256.Sum256([]byte(predictor + "/" + queryExpr))
+	return fmt.Sprintf("%s-%s", strings.ToLower(predictor), hex.EncodeToString(sum[:])[:32])
+}
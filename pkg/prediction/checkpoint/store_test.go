@@ -0,0 +1,70 @@
+package checkpoint
+
+import (
+	"context"
+	"io/ioutil"
+	"os"
+	"strings"
+	"testing"
+	"time"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes/fake"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestStore(t *testing.T) {
+	dir, err := ioutil.TempDir("", "checkpoint")
+	assert.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	fileStore, err := NewFileStore(dir)
+	assert.NoError(t, err)
+
+	stores := map[string]Store{
+		"file":      fileStore,
+		"configmap": NewConfigMapStore(fake.NewSimpleClientset(), "crane-system"),
+	}
+	for name, store := range stores {
+		t.Run(name, func(t *testing.T) {
+			ctx := context.TODO()
+
+			c, err := store.Load(ctx, "Percentile", "cpu{pod=a}")
+			assert.NoError(t, err)
+			assert.Nil(t, c, "checkpoint not found")
+
+			hello := &Checkpoint{Predictor: "Percentile", QueryExpr: "cpu{pod=a}", Data: []byte(`{"status":"Ready"}`), UpdateTime: metav1.NewTime(time.Unix(1000, 0))}
+			hey := &Checkpoint{Predictor: "DSP", QueryExpr: "cpu{pod=a}", Data: []byte(`{}`), UpdateTime: metav1.NewTime(time.Unix(1000, 0))}
+			assert.NoError(t, store.Save(ctx, hello))
+			assert.NoError(t, store.Save(ctx, hey))
+
+			// update the checkpoint
+			hello.Data = []byte(`{"status":"Initializing"}`)
+			hello.UpdateTime = metav1.NewTime(time.Unix(2000, 0))
+			assert.NoError(t, store.Save(ctx, hello))
+
+			c, err = store.Load(ctx, "Percentile", "cpu{pod=a}")
+			assert.NoError(t, err)
+			assert.Equal(t, `{"status":"Initializing"}`, string(c.Data))
+			assert.True(t, hello.UpdateTime.Equal(&c.UpdateTime))
+
+			checkpoints, err := store.List(ctx)
+			assert.NoError(t, err)
+			assert.Len(t, checkpoints, 2)
+
+			assert.NoError(t, store.Delete(ctx, "Percentile", "cpu{pod=a}"))
+			assert.NoError(t, store.Delete(ctx, "Percentile", "cpu{pod=a}"), "delete a deleted checkpoint")
+			checkpoints, err = store.List(ctx)
+			assert.NoError(t, err)
+			assert.Len(t, checkpoints, 1)
+			assert.Equal(t, "DSP", checkpoints[0].Predictor)
+
+			// the checkpoint larger than the max size is not saved
+			if max := store.MaxDataSize(); max > 0 {
+				large := &Checkpoint{Predictor: "DSP", QueryExpr: "cpu{pod=b}", Data: []byte(`"` + strings.Repeat("a", max) + `"`)}
+				assert.Error(t, store.Save(ctx, large))
+			}
+		})
+	}
+}
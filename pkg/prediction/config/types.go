@@ -6,8 +6,15 @@ import (
 	"github.com/gocrane/api/prediction/v1alpha1"
 )
 
+// AlgorithmTypeHoltWinters is the algorithm type of the holt-winters predictor, it is not defined in the api yet.
+const AlgorithmTypeHoltWinters v1alpha1.AlgorithmType = "holtwinters"
+
 type AlgorithmModelConfig struct {
 	UpdateInterval time.Duration
+	// CheckpointInterval is the interval to save the models to the checkpoint store
+	CheckpointInterval time.Duration
+	// CheckpointExpiration is the max age of the checkpoints, the stale checkpoints are not restored and deleted
+	CheckpointExpiration time.Duration
 }
 
 type ModelInitMode string
@@ -23,7 +30,25 @@ const (
 )
 
 type Config struct {
-	InitMode   *ModelInitMode
-	DSP        *v1alpha1.DSP
-	Percentile *v1alpha1.Percentile
+	InitMode    *ModelInitMode
+	DSP         *v1alpha1.DSP
+	Percentile  *v1alpha1.Percentile
+	HoltWinters *HoltWinters
+}
+
+// HoltWinters is the config of the holt-winters predictor. The smoothing factors are fitted by the history if they
+// are not specified.
+type HoltWinters struct {
+	// SampleInterval is the resolution of the history, such as 1m
+	SampleInterval string `json:"sampleInterval,omitempty"`
+	// HistoryLength is the length of the history to fit the model, it covers two seasons at least, such as 7d
+	HistoryLength string `json:"historyLength,omitempty"`
+	// SeasonLength is the length of a season, such as 1d
+	SeasonLength string `json:"seasonLength,omitempty"`
+	// Alpha is the smoothing factor of the level, in (0, 1]
+	Alpha string `json:"alpha,omitempty"`
+	// Beta is the smoothing factor of the trend, in [0, 1]
+	Beta string `json:"beta,omitempty"`
+	// Gamma is the smoothing factor of the seasonal component, in [0, 1]
+	Gamma string `json:"gamma,omitempty"`
 }
@@ -11,6 +11,10 @@ type aggregateSignal struct {
 	startTime           time.Time
 	endTime             time.Time
 	lastUpdateTime      time.Time
+	// estimator is the estimator chosen for the signal
+	estimator string
+	// cycleDuration is the cycle of the signal, such as an hour, a day or a week
+	cycleDuration time.Duration
 }
 
 func newAggregateSignal() *aggregateSignal {
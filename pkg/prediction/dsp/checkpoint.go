@@ -0,0 +1,182 @@
+package dsp
+
+import (
+	"context"
+	"encoding/json"
+	"time"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/klog/v2"
+
+	"github.com/gocrane/crane/pkg/common"
+	"github.com/gocrane/crane/pkg/metricnaming"
+	"github.com/gocrane/crane/pkg/prediction"
+	"github.com/gocrane/crane/pkg/prediction/checkpoint"
+)
+
+// modelCheckpoint is the model of a query expression saved to the checkpoint store. The history is saved as well if the
+// size of the store is unlimited, so that only the samples after the history are fetched to update the model, otherwise
+// only the predictions are saved and the full history is fetched.
+type modelCheckpoint struct {
+	HistoryResolution time.Duration                `json:"historyResolution"`
+	Signals           map[string]*signalCheckpoint `json:"signals"`
+}
+
+// signalCheckpoint is a time series saved to the checkpoint store, the prediction is empty if it is not periodic.
+type signalCheckpoint struct {
+	Labels           []common.Label  `json:"labels,omitempty"`
+	Estimator        string          `json:"estimator,omitempty"`
+	CycleDuration    time.Duration   `json:"cycleDuration,omitempty"`
+	PredictedSamples []common.Sample `json:"predictedSamples,omitempty"`
+	// HistoryStart is the timestamp of the first history sample, the samples are evenly spaced by the history resolution
+	HistoryStart  int64     `json:"historyStart,omitempty"`
+	HistoryValues []float64 `json:"historyValues,omitempty"`
+}
+
+// loadCheckpoint returns the checkpoint of the query expression, nil is returned if the checkpoint store is not
+// configured, or the checkpoint is not found, stale or saved with a different history resolution.
+func (p *periodicSignalPrediction) loadCheckpoint(queryExpr string, config *internalConfig) *modelCheckpoint {
+	if p.store == nil {
+		return nil
+	}
+
+	c, err := p.store.Load(context.TODO(), p.Name(), queryExpr)
+	if err != nil {
+		klog.ErrorS(err, "Failed to load the checkpoint.", "queryExpr", queryExpr)
+		return nil
+	}
+	if c == nil || checkpoint.IsExpired(c, p.modelConfig.CheckpointExpiration, time.Now()) {
+		return nil
+	}
+
+	var model modelCheckpoint
+	if err := json.Unmarshal(c.Data, &model); err != nil {
+		klog.ErrorS(err, "Failed to decode the checkpoint.", "queryExpr", queryExpr)
+		return nil
+	}
+	if model.HistoryResolution != config.historyResolution {
+		klog.V(4).InfoS("The history resolution of the checkpoint is changed, it is not used.", "queryExpr", queryExpr,
+			"checkpoint", model.HistoryResolution, "config", config.historyResolution)
+		return nil
+	}
+	return &model
+}
+
+// saveCheckpoint saves the signals of the query expression to the checkpoint store, the preprocessed history is saved
+// only if the size of the store is unlimited. The checkpoint is not saved if it exceeds the max size of the store.
+func (p *periodicSignalPrediction) saveCheckpoint(queryExpr string, historyTimeSeriesList []*common.TimeSeries, config *internalConfig) {
+	if p.store == nil {
+		return
+	}
+
+	// the history of a day at the resolution of a minute is much larger than the predictions, it is too large for the
+	// stores such as ConfigMaps
+	maxSize := p.store.MaxDataSize()
+	signals, _ := p.a.GetSignals(queryExpr)
+	model := modelCheckpoint{HistoryResolution: config.historyResolution, Signals: map[string]*signalCheckpoint{}}
+	for _, ts := range historyTimeSeriesList {
+		if len(ts.Samples) == 0 {
+			continue
+		}
+		key := prediction.AggregateSignalKey(ts.Labels)
+		sc := &signalCheckpoint{Labels: ts.Labels}
+		if maxSize == 0 {
+			sc.HistoryStart = ts.Samples[0].Timestamp
+			sc.HistoryValues = make([]float64, len(ts.Samples))
+			for i := range ts.Samples {
+				sc.HistoryValues[i] = ts.Samples[i].Value
+			}
+		}
+		if signal, ok := signals[key]; ok && signal.predictedTimeSeries != nil {
+			sc.Estimator = signal.estimator
+			sc.CycleDuration = signal.cycleDuration
+			sc.PredictedSamples = signal.predictedTimeSeries.Samples
+		}
+		model.Signals[key] = sc
+	}
+
+	data, err := json.Marshal(model)
+	if err != nil {
+		klog.ErrorS(err, "Failed to encode the checkpoint.", "queryExpr", queryExpr)
+		return
+	}
+	if maxSize > 0 && len(data) > maxSize {
+		klog.ErrorS(nil, "The checkpoint exceeds the max size of the store, it is not saved.", "queryExpr", queryExpr, "size", len(data), "maxSize", maxSize)
+		return
+	}
+	if err := p.store.Save(context.TODO(), &checkpoint.Checkpoint{
+		Predictor:  p.Name(),
+		QueryExpr:  queryExpr,
+		Data:       data,
+		UpdateTime: metav1.Now(),
+	}); err != nil {
+		klog.ErrorS(err, "Failed to save the checkpoint.", "queryExpr", queryExpr)
+		return
+	}
+	klog.V(4).InfoS("Checkpoint saved.", "queryExpr", queryExpr, "timeSeriesLength", len(model.Signals))
+}
+
+// restoreFromCheckpoint restores the predicted time series of the query expression from the checkpoint, so the
+// predictions are served before the model is updated.
+func (p *periodicSignalPrediction) restoreFromCheckpoint(namer metricnaming.MetricNamer) {
+	queryExpr := namer.BuildUniqueKey()
+	model := p.loadCheckpoint(queryExpr, p.a.GetConfig(queryExpr))
+	if model == nil {
+		return
+	}
+
+	signals := map[string]*aggregateSignal{}
+	for key, sc := range model.Signals {
+		if len(sc.PredictedSamples) == 0 {
+			continue
+		}
+		signal := newAggregateSignal()
+		signal.setPredictedTimeSeries(&common.TimeSeries{Labels: sc.Labels, Samples: sc.PredictedSamples})
+		signal.estimator = sc.Estimator
+		signal.cycleDuration = sc.CycleDuration
+		signals[key] = signal
+	}
+	if len(signals) > 0 {
+		p.a.SetSignals(queryExpr, signals)
+		klog.V(4).InfoS("Predicted time series restored from checkpoint.", "queryExpr", queryExpr, "timeSeriesLength", len(signals))
+	}
+}
+
+// historyEnd returns the time of the last history sample of the checkpoint.
+func (m *modelCheckpoint) historyEnd() time.Time {
+	var end int64
+	for _, sc := range m.Signals {
+		if n := len(sc.HistoryValues); n > 0 {
+			if last := sc.HistoryStart + int64(n-1)*int64(m.HistoryResolution.Seconds()); last > end {
+				end = last
+			}
+		}
+	}
+	return time.Unix(end, 0)
+}
+
+// mergeHistory prepends the history samples of the checkpoint since the start to the time series fetched. The history
+// of the time series which are not fetched any more is dropped.
+func (m *modelCheckpoint) mergeHistory(tsList []*common.TimeSeries, start time.Time) []*common.TimeSeries {
+	intervalSeconds := int64(m.HistoryResolution.Seconds())
+	for _, ts := range tsList {
+		sc, ok := m.Signals[prediction.AggregateSignalKey(ts.Labels)]
+		if !ok {
+			continue
+		}
+
+		var samples []common.Sample
+		for i, value := range sc.HistoryValues {
+			timestamp := sc.HistoryStart + int64(i)*intervalSeconds
+			if timestamp < start.Unix() {
+				continue
+			}
+			if len(ts.Samples) > 0 && timestamp >= ts.Samples[0].Timestamp {
+				break
+			}
+			samples = append(samples, common.Sample{Value: value, Timestamp: timestamp})
+		}
+		ts.Samples = append(samples, ts.Samples...)
+	}
+	return tsList
+}
@@ -0,0 +1,185 @@
+package dsp
+
+import (
+	"context"
+	"encoding/json"
+	"io/ioutil"
+	"math"
+	"os"
+	"testing"
+	"time"
+
+	"k8s.io/apimachinery/pkg/labels"
+
+	"github.com/gocrane/api/prediction/v1alpha1"
+
+	"github.com/gocrane/crane/pkg/common"
+	"github.com/gocrane/crane/pkg/metricnaming"
+	"github.com/gocrane/crane/pkg/metricquery"
+	"github.com/gocrane/crane/pkg/prediction"
+	"github.com/gocrane/crane/pkg/prediction/checkpoint"
+	"github.com/gocrane/crane/pkg/prediction/config"
+	"github.com/stretchr/testify/assert"
+)
+
+// sineHistory is a history provider of an hourly sine wave, it records the start time of the queries.
+type sineHistory struct {
+	starts []time.Time
+}
+
+func (h *sineHistory) QueryTimeSeries(_ metricnaming.MetricNamer, start time.Time, end time.Time, step time.Duration) ([]*common.TimeSeries, error) {
+	h.starts = append(h.starts, start)
+	ts := &common.TimeSeries{}
+	for t := start; !t.After(end); t = t.Add(step) {
+		value := 10 + 5*math.Sin(2*math.Pi*float64(t.Unix())/3600)
+		ts.Samples = append(ts.Samples, common.Sample{Value: value, Timestamp: t.Unix()})
+	}
+	return []*common.TimeSeries{ts}, nil
+}
+
+func TestCheckpoint(t *testing.T) {
+	dir, err := ioutil.TempDir("", "checkpoint")
+	assert.NoError(t, err)
+	defer os.RemoveAll(dir)
+	store, err := checkpoint.NewFileStore(dir)
+	assert.NoError(t, err)
+
+	namer := &metricnaming.GeneralMetricNamer{
+		Metric: &metricquery.Metric{
+			Type: metricquery.PromQLMetricType,
+			Prom: &metricquery.PromNamerInfo{
+				QueryExpr: "hello",
+				Selector:  labels.Nothing(),
+			},
+		}}
+	qc := prediction.QueryExprWithCaller{
+		MetricNamer: namer,
+		Caller:      "link",
+		Config:      config.Config{DSP: &v1alpha1.DSP{SampleInterval: "1m", HistoryLength: "48h"}},
+	}
+	queryExpr := namer.BuildUniqueKey()
+	mc := config.AlgorithmModelConfig{CheckpointExpiration: time.Hour}
+
+	// the full history is fetched without checkpoint
+	history := &sineHistory{}
+	p := NewPrediction(nil, history, mc, store).(*periodicSignalPrediction)
+	p.a.Add(qc)
+	assert.NoError(t, p.updateAggregateSignalsWithQuery(namer))
+	end := time.Now().Truncate(time.Minute)
+	assert.Len(t, history.starts, 1)
+	assert.True(t, history.starts[0].Before(end.Add(-48*time.Hour)))
+
+	signals, status := p.a.GetSignals(queryExpr)
+	assert.Equal(t, prediction.StatusReady, status)
+	assert.Len(t, signals, 1)
+
+	// the predictions are served from the checkpoint after restarted
+	restored := NewPrediction(nil, history, mc, store).(*periodicSignalPrediction)
+	restored.a.Add(qc)
+	restored.restoreFromCheckpoint(namer)
+	restoredSignals, status := restored.a.GetSignals(queryExpr)
+	assert.Equal(t, prediction.StatusReady, status)
+	for key, signal := range signals {
+		assert.Equal(t, signal.estimator, restoredSignals[key].estimator)
+		assert.Equal(t, signal.cycleDuration, restoredSignals[key].cycleDuration)
+		assert.Equal(t, signal.predictedTimeSeries.Samples, restoredSignals[key].predictedTimeSeries.Samples)
+	}
+
+	// drop the last 30 minutes of the history, only the samples after the history are fetched
+	c, err := store.Load(context.TODO(), restored.Name(), queryExpr)
+	assert.NoError(t, err)
+	var model modelCheckpoint
+	assert.NoError(t, json.Unmarshal(c.Data, &model))
+	for _, sc := range model.Signals {
+		sc.HistoryValues = sc.HistoryValues[:len(sc.HistoryValues)-30]
+	}
+	historyEnd := model.historyEnd()
+	c.Data, err = json.Marshal(model)
+	assert.NoError(t, err)
+	assert.NoError(t, store.Save(context.TODO(), c))
+
+	assert.NoError(t, restored.updateAggregateSignalsWithQuery(namer))
+	assert.Len(t, history.starts, 2)
+	assert.Equal(t, historyEnd.Add(time.Minute), history.starts[1])
+
+	// the history merged is continuous
+	model = *restored.loadCheckpoint(queryExpr, restored.a.GetConfig(queryExpr))
+	for _, sc := range model.Signals {
+		assert.Equal(t, 0, len(sc.HistoryValues)%60, "history of whole hours")
+		assert.NotEmpty(t, sc.PredictedSamples)
+		assert.True(t, model.historyEnd().After(historyEnd))
+		for i, value := range sc.HistoryValues {
+			timestamp := sc.HistoryStart + int64(i)*60
+			assert.InDelta(t, 10+5*math.Sin(2*math.Pi*float64(timestamp)/3600), value, 1e-9)
+		}
+	}
+}
+
+// limitedStore is a file store with the max size of the checkpoints.
+type limitedStore struct {
+	checkpoint.Store
+	maxDataSize int
+}
+
+func (s *limitedStore) MaxDataSize() int {
+	return s.maxDataSize
+}
+
+func TestCheckpointWithLimitedStore(t *testing.T) {
+	dir, err := ioutil.TempDir("", "checkpoint")
+	assert.NoError(t, err)
+	defer os.RemoveAll(dir)
+	fileStore, err := checkpoint.NewFileStore(dir)
+	assert.NoError(t, err)
+
+	namer := &metricnaming.GeneralMetricNamer{
+		Metric: &metricquery.Metric{
+			Type: metricquery.PromQLMetricType,
+			Prom: &metricquery.PromNamerInfo{
+				QueryExpr: "hello",
+				Selector:  labels.Nothing(),
+			},
+		}}
+	qc := prediction.QueryExprWithCaller{
+		MetricNamer: namer,
+		Caller:      "link",
+		Config:      config.Config{DSP: &v1alpha1.DSP{SampleInterval: "1m", HistoryLength: "48h"}},
+	}
+	queryExpr := namer.BuildUniqueKey()
+	mc := config.AlgorithmModelConfig{CheckpointExpiration: time.Hour}
+
+	// only the predictions are saved to the limited store
+	store := &limitedStore{Store: fileStore, maxDataSize: 1 << 20}
+	history := &sineHistory{}
+	p := NewPrediction(nil, history, mc, store).(*periodicSignalPrediction)
+	p.a.Add(qc)
+	assert.NoError(t, p.updateAggregateSignalsWithQuery(namer))
+
+	c, err := store.Load(context.TODO(), p.Name(), queryExpr)
+	assert.NoError(t, err)
+	assert.NotNil(t, c)
+	assert.Less(t, len(c.Data), store.maxDataSize)
+	model := p.loadCheckpoint(queryExpr, p.a.GetConfig(queryExpr))
+	for _, sc := range model.Signals {
+		assert.Empty(t, sc.HistoryValues)
+		assert.NotEmpty(t, sc.PredictedSamples)
+	}
+
+	// the predictions are restored, and the full history is fetched to update the model
+	restored := NewPrediction(nil, history, mc, store).(*periodicSignalPrediction)
+	restored.a.Add(qc)
+	restored.restoreFromCheckpoint(namer)
+	_, status := restored.a.GetSignals(queryExpr)
+	assert.Equal(t, prediction.StatusReady, status)
+	assert.NoError(t, restored.updateAggregateSignalsWithQuery(namer))
+	assert.Len(t, history.starts, 2)
+	assert.True(t, history.starts[1].Before(time.Now().Add(-48*time.Hour)))
+
+	// the checkpoint exceeding the max size is not saved
+	assert.NoError(t, store.Delete(context.TODO(), p.Name(), queryExpr))
+	store.maxDataSize = 1024
+	assert.NoError(t, p.updateAggregateSignalsWithQuery(namer))
+	c, err = store.Load(context.TODO(), p.Name(), queryExpr)
+	assert.NoError(t, err)
+	assert.Nil(t, c)
+}
@@ -24,6 +24,10 @@ var defaultEstimators = []Estimator{
 	&fftEstimator{minNumOfSpectrumItems: 50, lowAmplitudeThreshold: 0.05, marginFraction: 0.10},
 	&fftEstimator{minNumOfSpectrumItems: 50, lowAmplitudeThreshold: 0.05, marginFraction: 0.15},
 	&fftEstimator{minNumOfSpectrumItems: 50, lowAmplitudeThreshold: 0.05, marginFraction: 0.20},
+	&trendEstimator{seasonal: &fftEstimator{minNumOfSpectrumItems: 3, lowAmplitudeThreshold: 1.0, marginFraction: 0.01}},
+	&trendEstimator{seasonal: &fftEstimator{minNumOfSpectrumItems: 3, lowAmplitudeThreshold: 1.0, marginFraction: 0.10}},
+	&trendEstimator{seasonal: &fftEstimator{minNumOfSpectrumItems: 50, lowAmplitudeThreshold: 0.05, marginFraction: 0.01}},
+	&trendEstimator{seasonal: &fftEstimator{minNumOfSpectrumItems: 50, lowAmplitudeThreshold: 0.05, marginFraction: 0.10}},
 }
 
 type internalConfig struct {
@@ -2,6 +2,7 @@ package dsp
 
 import (
 	"fmt"
+	"math"
 	"math/cmplx"
 	"sort"
 	"time"
@@ -38,6 +39,12 @@ func NewFFTEstimator(minNumOfSpectrumItems, maxNumOfSpectrumItems int, highFrequ
 	}
 }
 
+// NewTrendEstimator returns an estimator which estimates the detrended signal by the seasonal estimator, and adds the
+// growth of the trend back to the estimation.
+func NewTrendEstimator(seasonal Estimator) Estimator {
+	return &trendEstimator{seasonal: seasonal}
+}
+
 type maxValueEstimator struct {
 	marginFraction float64
 }
@@ -50,6 +57,12 @@ type fftEstimator struct {
 	marginFraction         float64
 }
 
+// trendEstimator is a trend-plus-seasonal estimator for the growing signals, which the other estimators under-predict
+// since they assume the signal repeats each cycle at the same level.
+type trendEstimator struct {
+	seasonal Estimator
+}
+
 func (m *maxValueEstimator) GetEstimation(signal *Signal, cycleDuration time.Duration) *Signal {
 	nSamplesPerCycle := int(cycleDuration.Seconds() * signal.SampleRate)
 	estimation := make([]float64, 0, nSamplesPerCycle)
@@ -185,3 +198,36 @@ func (f *fftEstimator) String() string {
 	return fmt.Sprintf("FFT Estimator {minNumOfSpectrumItems: %d, maxNumOfSpectrumItems: %d, highFrequencyThreshold: %f, lowAmplitudeThreshold: %f, marginFraction: %f}",
 		minNumOfSpectrumItems, maxNumOfSpectrumItems, highFrequencyThreshold, lowAmplitudeThreshold, marginFraction)
 }
+
+func (e *trendEstimator) GetEstimation(signal *Signal, cycleDuration time.Duration) *Signal {
+	nSamplesPerCycle := int(cycleDuration.Seconds() * signal.SampleRate)
+	// The trend of a single cycle is confounded with the seasonality.
+	if nSamplesPerCycle == 0 || len(signal.Samples) < 2*nSamplesPerCycle {
+		return nil
+	}
+
+	detrended, slope := signal.Detrend()
+	estimated := e.seasonal.GetEstimation(detrended, cycleDuration)
+	if estimated == nil {
+		return nil
+	}
+	// The decline is not extrapolated, it is covered by leveling the signal to the end of the trend, and extrapolating
+	// it may under-predict when the decline stops.
+	if slope < 0 {
+		slope = 0
+	}
+
+	samples := make([]float64, len(estimated.Samples))
+	for i, a := range estimated.Samples {
+		samples[i] = math.Max(a+slope*float64(i+1), defaultFFTMinValue)
+	}
+
+	return &Signal{
+		SampleRate: signal.SampleRate,
+		Samples:    samples,
+	}
+}
+
+func (e *trendEstimator) String() string {
+	return fmt.Sprintf("Trend Estimator {seasonal: %s}", e.seasonal)
+}
@@ -2,6 +2,7 @@ package dsp
 
 import (
 	"fmt"
+	"math"
 	"net/http"
 	"testing"
 
@@ -71,3 +72,57 @@ func TestFftEstimator_GetEstimation(t *testing.T) {
 	fmt.Println("Open your browser and access 'http://localhost:7001'")
 	//http.ListenAndServe(":7001", nil)
 }
+
+func TestTrendEstimator_GetEstimation(t *testing.T) {
+	// a daily cycle growing by 10% of its mean a day
+	value := func(i int) float64 {
+		return 100 + 10*float64(i)/1440 + 20*math.Sin(2*math.Pi*float64(i)/1440)
+	}
+	newSignal := func(from, to int) *Signal {
+		s := &Signal{SampleRate: 1.0 / 60}
+		for i := from; i < to; i++ {
+			s.Samples = append(s.Samples, value(i))
+		}
+		return s
+	}
+	history, actual := newSignal(0, 1440*7), newSignal(1440*7, 1440*8)
+
+	tests := []struct {
+		name      string
+		estimator Estimator
+		minError  float64
+		maxError  float64
+	}{
+		{
+			name:      "the fft estimator under-predicts",
+			estimator: &fftEstimator{minNumOfSpectrumItems: 3, lowAmplitudeThreshold: 1.0, marginFraction: 0.01},
+			minError:  5,
+			maxError:  math.MaxFloat64,
+		},
+		{
+			name:      "the trend estimator follows the growth",
+			estimator: NewTrendEstimator(&fftEstimator{minNumOfSpectrumItems: 3, lowAmplitudeThreshold: 1.0, marginFraction: 0.01}),
+			maxError:  2,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			estimated := tt.estimator.GetEstimation(history, Day)
+			assert.Len(t, estimated.Samples, 1440)
+			var underPredicted float64
+			for i := range actual.Samples {
+				underPredicted = math.Max(underPredicted, actual.Samples[i]-estimated.Samples[i])
+			}
+			assert.GreaterOrEqual(t, underPredicted, tt.minError)
+			assert.Less(t, underPredicted, tt.maxError)
+		})
+	}
+
+	// the trend is not estimated by a single cycle
+	assert.Nil(t, NewTrendEstimator(&fftEstimator{}).GetEstimation(newSignal(0, 1440), Day))
+
+	// the trend estimator is chosen on the held-out cycles
+	best := bestEstimator("growing", defaultEstimators, history, 7, Day)
+	_, ok := best.(*trendEstimator)
+	assert.True(t, ok, best.String())
+}
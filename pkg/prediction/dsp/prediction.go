@@ -8,12 +8,14 @@ import (
 	"sync"
 	"time"
 
+	"k8s.io/apimachinery/pkg/util/wait"
 	"k8s.io/klog/v2"
 
 	"github.com/gocrane/crane/pkg/common"
 	"github.com/gocrane/crane/pkg/metricnaming"
 	"github.com/gocrane/crane/pkg/prediction"
 	"github.com/gocrane/crane/pkg/prediction/accuracy"
+	"github.com/gocrane/crane/pkg/prediction/checkpoint"
 	"github.com/gocrane/crane/pkg/prediction/config"
 	"github.com/gocrane/crane/pkg/providers"
 )
@@ -26,6 +28,8 @@ var (
 
 const (
 	defaultFuture = time.Hour
+	// maxHeldOutCycles is the max number of the last cycles held out to choose the best estimator
+	maxHeldOutCycles = 3
 )
 
 type periodicSignalPrediction struct {
@@ -35,13 +39,15 @@ type periodicSignalPrediction struct {
 	// record the query routine already started
 	queryRoutines sync.Map
 	modelConfig   config.AlgorithmModelConfig
+	// store saves the models to checkpoints, the models are not saved if it is nil
+	store checkpoint.Store
 }
 
 func (p *periodicSignalPrediction) QueryPredictionStatus(ctx context.Context, metricNamer metricnaming.MetricNamer) (prediction.Status, error) {
 	panic("implement me")
 }
 
-func NewPrediction(realtimeProvider providers.RealTime, historyProvider providers.History, mc config.AlgorithmModelConfig) prediction.Interface {
+func NewPrediction(realtimeProvider providers.RealTime, historyProvider providers.History, mc config.AlgorithmModelConfig, store checkpoint.Store) prediction.Interface {
 	withCh, delCh := make(chan prediction.QueryExprWithCaller), make(chan prediction.QueryExprWithCaller)
 	return &periodicSignalPrediction{
 		GenericPrediction: prediction.NewGenericPrediction(realtimeProvider, historyProvider, withCh, delCh),
@@ -49,6 +55,7 @@ func NewPrediction(realtimeProvider providers.RealTime, historyProvider provider
 		stopChMap:         sync.Map{},
 		queryRoutines:     sync.Map{},
 		modelConfig:       mc,
+		store:             store,
 	}
 }
 
@@ -135,9 +142,10 @@ func preProcessTimeSeries(ts *common.TimeSeries, config *internalConfig, unit ti
 	return nil
 }
 
-// isPeriodicTimeSeries returns  time series with specified periodicity
+// isPeriodicTimeSeries returns  time series with specified periodicity, the time series is detrended first so that the
+// periodicity of a growing time series is not hidden by the low frequencies of the trend
 func isPeriodicTimeSeries(ts *common.TimeSeries, sampleInterval time.Duration, cycleDuration time.Duration) bool {
-	signal := SamplesToSignal(ts.Samples, sampleInterval)
+	signal, _ := SamplesToSignal(ts.Samples, sampleInterval).Detrend()
 	return signal.IsPeriodic(cycleDuration)
 }
 
@@ -183,6 +191,7 @@ func (p *periodicSignalPrediction) Run(stopCh <-chan struct{}) {
 				v, _ := p.stopChMap.LoadOrStore(queryExpr, make(chan struct{}))
 				predStopCh := v.(chan struct{})
 
+				p.restoreFromCheckpoint(namer)
 				for {
 					if err := p.updateAggregateSignalsWithQuery(namer); err != nil {
 						klog.ErrorS(err, "Failed to updateAggregateSignalsWithQuery.")
@@ -219,6 +228,12 @@ func (p *periodicSignalPrediction) Run(stopCh <-chan struct{}) {
 		}
 	}()
 
+	if p.store != nil && p.modelConfig.CheckpointInterval > 0 {
+		go wait.Until(func() {
+			checkpoint.DeleteExpired(context.TODO(), p.store, p.Name(), p.modelConfig.CheckpointExpiration, time.Now())
+		}, p.modelConfig.CheckpointInterval, stopCh)
+	}
+
 	klog.Infof("predictor %v started", p.Name())
 
 	<-stopCh
@@ -226,15 +241,33 @@ func (p *periodicSignalPrediction) Run(stopCh <-chan struct{}) {
 	klog.Infof("predictor %v stopped", p.Name())
 }
 
+// updateAggregateSignalsWithQuery updates the model of the query by the history. If there is a checkpoint, only the
+// samples after the history of the checkpoint are fetched and merged with it.
 func (p *periodicSignalPrediction) updateAggregateSignalsWithQuery(namer metricnaming.MetricNamer) error {
+	queryExpr := namer.BuildUniqueKey()
+	cfg := p.a.GetConfig(queryExpr)
+
+	end := time.Now().Truncate(cfg.historyResolution)
+	windowStart := end.Add(-cfg.historyDuration - time.Hour)
+	start := windowStart
+	model := p.loadCheckpoint(queryExpr, cfg)
+	if model != nil {
+		if historyEnd := model.historyEnd(); !historyEnd.Before(start) {
+			start = historyEnd.Add(cfg.historyResolution)
+		}
+		if start.After(end) {
+			klog.V(4).InfoS("No new samples after the checkpoint.", "queryExpr", queryExpr)
+			return nil
+		}
+	}
+
 	// Query history data for prediction
 	maxAttempts := 10
 	attempts := 0
 	var tsList []*common.TimeSeries
 	var err error
-	queryExpr := namer.BuildUniqueKey()
 	for attempts < maxAttempts {
-		tsList, err = p.queryHistoryTimeSeries(namer)
+		tsList, err = p.queryHistoryTimeSeries(namer, start, end)
 		if err != nil {
 			attempts++
 			t := time.Second * time.Duration(math.Pow(2., float64(attempts)))
@@ -249,16 +282,23 @@ func (p *periodicSignalPrediction) updateAggregateSignalsWithQuery(namer metricn
 		return err
 	}
 
-	klog.V(6).InfoS("Update aggregate signals.", "queryExpr", queryExpr, "timeSeriesLength", len(tsList))
+	if model != nil {
+		tsList = model.mergeHistory(tsList, windowStart)
+	}
+	tsList, err = preProcessTimeSeriesList(tsList, cfg)
+	if err != nil {
+		return err
+	}
 
-	cfg := p.a.GetConfig(queryExpr)
+	klog.V(6).InfoS("Update aggregate signals.", "queryExpr", queryExpr, "timeSeriesLength", len(tsList), "incremental", model != nil)
 
 	p.updateAggregateSignals(queryExpr, tsList, cfg)
+	p.saveCheckpoint(queryExpr, tsList, cfg)
 
 	return nil
 }
 
-func (p *periodicSignalPrediction) queryHistoryTimeSeries(namer metricnaming.MetricNamer) ([]*common.TimeSeries, error) {
+func (p *periodicSignalPrediction) queryHistoryTimeSeries(namer metricnaming.MetricNamer, start, end time.Time) ([]*common.TimeSeries, error) {
 	if p.GetHistoryProvider() == nil {
 		return nil, fmt.Errorf("history provider not provisioned")
 	}
@@ -266,9 +306,6 @@ func (p *periodicSignalPrediction) queryHistoryTimeSeries(namer metricnaming.Met
 	queryExpr := namer.BuildUniqueKey()
 	config := p.a.GetConfig(queryExpr)
 
-	end := time.Now().Truncate(config.historyResolution)
-	start := end.Add(-config.historyDuration - time.Hour)
-
 	tsList, err := p.GetHistoryProvider().QueryTimeSeries(namer, start, end, config.historyResolution)
 	if err != nil {
 		klog.ErrorS(err, "Failed to query history time series.")
@@ -277,11 +314,11 @@ func (p *periodicSignalPrediction) queryHistoryTimeSeries(namer metricnaming.Met
 
 	klog.V(6).InfoS("dsp queryHistoryTimeSeries", "timeSeriesList", tsList, "config", *config)
 
-	return preProcessTimeSeriesList(tsList, config)
+	return tsList, nil
 }
 
 func (p *periodicSignalPrediction) updateAggregateSignals(queryExpr string, historyTimeSeriesList []*common.TimeSeries, config *internalConfig) {
-	var predictedTimeSeriesList []*common.TimeSeries
+	signals := map[string]*aggregateSignal{}
 
 	for _, ts := range historyTimeSeriesList {
 		if klog.V(6).Enabled() {
@@ -340,54 +377,71 @@ func (p *periodicSignalPrediction) updateAggregateSignals(queryExpr string, hist
 				}
 			}
 
-			predictedTimeSeriesList = append(predictedTimeSeriesList, &common.TimeSeries{
+			signal := newAggregateSignal()
+			signal.setPredictedTimeSeries(&common.TimeSeries{
 				Labels:  ts.Labels,
 				Samples: samples,
 			})
+			signal.estimator = chosenEstimator.String()
+			signal.cycleDuration = cycleDuration
+			signals[prediction.AggregateSignalKey(ts.Labels)] = signal
 		}
 	}
 
-	signals := map[string]*aggregateSignal{}
-	for i := range predictedTimeSeriesList {
-		key := prediction.AggregateSignalKey(predictedTimeSeriesList[i].Labels)
-		signal := newAggregateSignal()
-		signal.setPredictedTimeSeries(predictedTimeSeriesList[i])
-		signals[key] = signal
-	}
 	p.a.SetSignals(queryExpr, signals)
 }
 
+// bestEstimator returns the estimator with the min mean prediction error on the held-out cycles, each held-out cycle is
+// estimated by the cycles before it.
 func bestEstimator(id string, estimators []Estimator, signal *Signal, totalCycles int, cycleDuration time.Duration) Estimator {
 	samplesPerCycle := len(signal.Samples) / totalCycles
-
-	history := &Signal{
-		SampleRate: signal.SampleRate,
-		Samples:    signal.Samples[:(totalCycles-1)*samplesPerCycle],
-	}
-
-	actual := &Signal{
-		SampleRate: signal.SampleRate,
-		Samples:    signal.Samples[(totalCycles-1)*samplesPerCycle:],
+	heldOutCycles := maxHeldOutCycles
+	if heldOutCycles > totalCycles-1 {
+		heldOutCycles = totalCycles - 1
 	}
 
 	minPE := math.MaxFloat64
 	var bestEstimator Estimator
 	for i := range estimators {
-		estimated := estimators[i].GetEstimation(history, cycleDuration)
-		if estimated != nil {
-			pe, err := accuracy.PredictionError(actual.Samples, estimated.Samples)
-			klog.V(6).InfoS("Testing estimators ...", "key", id, "estimator", estimators[i].String(), "pe", pe, "error", err)
-			if err == nil && pe < minPE {
-				minPE = pe
-				bestEstimator = estimators[i]
-			}
+		pe, err := heldOutPredictionError(estimators[i], signal, samplesPerCycle, totalCycles-heldOutCycles, totalCycles, cycleDuration)
+		klog.V(6).InfoS("Testing estimators ...", "key", id, "estimator", estimators[i].String(), "pe", pe, "error", err)
+		if err == nil && pe < minPE {
+			minPE = pe
+			bestEstimator = estimators[i]
 		}
 	}
 
-	klog.V(4).InfoS("Got the best estimator.", "key", id, "estimator", bestEstimator.String(), "minPE", minPE, "totalCycles", totalCycles)
+	if bestEstimator == nil {
+		klog.V(4).InfoS("No estimator is available.", "key", id, "totalCycles", totalCycles)
+		return nil
+	}
+	klog.V(4).InfoS("Got the best estimator.", "key", id, "estimator", bestEstimator.String(), "minPE", minPE, "totalCycles", totalCycles, "heldOutCycles", heldOutCycles)
 	return bestEstimator
 }
 
+// heldOutPredictionError returns the mean prediction error of the estimator on the cycles in [from, to).
+func heldOutPredictionError(estimator Estimator, signal *Signal, samplesPerCycle, from, to int, cycleDuration time.Duration) (float64, error) {
+	var sum float64
+	for c := from; c < to; c++ {
+		history := &Signal{
+			SampleRate: signal.SampleRate,
+			Samples:    signal.Samples[:c*samplesPerCycle],
+		}
+		actual := signal.Samples[c*samplesPerCycle : (c+1)*samplesPerCycle]
+
+		estimated := estimator.GetEstimation(history, cycleDuration)
+		if estimated == nil {
+			return 0, fmt.Errorf("no estimation of cycle %d", c)
+		}
+		pe, err := accuracy.PredictionError(actual, estimated.Samples)
+		if err != nil {
+			return 0, err
+		}
+		sum += pe
+	}
+	return sum / float64(to-from), nil
+}
+
 func (p *periodicSignalPrediction) QueryPredictedTimeSeries(ctx context.Context, namer metricnaming.MetricNamer, startTime time.Time, endTime time.Time) ([]*common.TimeSeries, error) {
 	return p.getPredictedTimeSeriesList(ctx, namer, startTime, endTime), nil
 }
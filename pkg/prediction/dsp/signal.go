@@ -127,6 +127,39 @@ func (s *Signal) Denormalize(min, max float64) (*Signal, error) {
 	}, nil
 }
 
+// Detrend removes the linear trend fitted by least squares from the signal and returns a new signal instance with the
+// slope of the trend per sample. The samples are leveled to the trend at the end of the signal, so the detrended signal
+// stays at the current level.
+func (s *Signal) Detrend() (*Signal, float64 /*slope*/) {
+	n := len(s.Samples)
+	if n < 2 {
+		return &Signal{
+			SampleRate: s.SampleRate,
+			Samples:    s.Samples,
+		}, 0
+	}
+
+	var sumX, sumY, sumXY, sumXX float64
+	for i, y := range s.Samples {
+		x := float64(i)
+		sumX += x
+		sumY += y
+		sumXY += x * y
+		sumXX += x * x
+	}
+	slope := (float64(n)*sumXY - sumX*sumY) / (float64(n)*sumXX - sumX*sumX)
+
+	detrended := make([]float64, n)
+	for i, y := range s.Samples {
+		detrended[i] = y - slope*float64(i-n+1)
+	}
+
+	return &Signal{
+		SampleRate: s.SampleRate,
+		Samples:    detrended,
+	}, slope
+}
+
 // Filter filters out frequency components whose amplitudes are less than the threshold and returns a new signal
 func (s *Signal) Filter(threshold float64) *Signal {
 	X := fft.FFTReal(s.Samples)
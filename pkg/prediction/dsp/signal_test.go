@@ -81,6 +81,20 @@ func TestSignal_Denormalize(t *testing.T) {
 	assert.InEpsilonSlice(t, signal.Samples, denormalized.Samples, amplitude*0.01)
 }
 
+func TestSignal_Detrend(t *testing.T) {
+	s := &Signal{SampleRate: signal.SampleRate, Samples: make([]float64, len(signal.Samples))}
+	for i := range signal.Samples {
+		s.Samples[i] = signal.Samples[i] + 0.5*float64(i)
+	}
+	detrended, slope := s.Detrend()
+	assert.InDelta(t, 0.5, slope, 1e-3)
+	// the detrended signal is leveled to the end of the trend
+	n := len(s.Samples)
+	for i := range detrended.Samples {
+		assert.InDelta(t, signal.Samples[i]+0.5*float64(n-1), detrended.Samples[i], 1.0)
+	}
+}
+
 func TestSignal_IsPeriodic(t *testing.T) {
 	signals := make([]*Signal, nInputs)
 	for i := 0; i < nInputs; i++ {
@@ -0,0 +1,33 @@
+package holtwinters
+
+import (
+	"time"
+
+	"github.com/gocrane/crane/pkg/common"
+)
+
+type aggregateSignal struct {
+	predictedTimeSeries *common.TimeSeries
+	startTime           time.Time
+	endTime             time.Time
+	lastUpdateTime      time.Time
+	// alpha, beta and gamma are the smoothing factors of the model fitted, and phi is the damping factor of the trend
+	alpha float64
+	beta  float64
+	gamma float64
+	phi   float64
+}
+
+func newAggregateSignal() *aggregateSignal {
+	return &aggregateSignal{}
+}
+
+func (a *aggregateSignal) setPredictedTimeSeries(ts *common.TimeSeries) {
+	n := len(ts.Samples)
+	if n > 0 {
+		a.startTime = time.Unix(ts.Samples[0].Timestamp, 0)
+		a.endTime = time.Unix(ts.Samples[n-1].Timestamp, 0)
+		a.predictedTimeSeries = ts
+		a.lastUpdateTime = time.Now()
+	}
+}
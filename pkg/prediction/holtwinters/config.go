@@ -0,0 +1,104 @@
+package holtwinters
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/gocrane/crane/pkg/prediction/config"
+	"github.com/gocrane/crane/pkg/utils"
+)
+
+var defaultInternalConfig = internalConfig{
+	historyResolution: time.Minute,
+	historyDuration:   time.Hour * 24 * 7,
+	seasonDuration:    time.Hour * 24,
+}
+
+type internalConfig struct {
+	historyResolution time.Duration
+	historyDuration   time.Duration
+	seasonDuration    time.Duration
+	// alpha, beta and gamma are the smoothing factors, they are fitted by the history if nil
+	alpha *float64
+	beta  *float64
+	gamma *float64
+}
+
+func (i internalConfig) String() string {
+	return fmt.Sprintf("HoltWinters internal Config: {historyResolution: %s, historyDuration: %s, seasonDuration: %s, alpha: %s, beta: %s, gamma: %s}",
+		i.historyResolution, i.historyDuration, i.seasonDuration, factorString(i.alpha), factorString(i.beta), factorString(i.gamma))
+}
+
+// seasonLength returns the number of samples in a season.
+func (i internalConfig) seasonLength() int {
+	return int(i.seasonDuration / i.historyResolution)
+}
+
+func factorString(f *float64) string {
+	if f == nil {
+		return "fitted"
+	}
+	return fmt.Sprintf("%v", *f)
+}
+
+func makeInternalConfig(h *config.HoltWinters) (*internalConfig, error) {
+	cfg := defaultInternalConfig
+
+	var err error
+	if h.SampleInterval != "" {
+		if cfg.historyResolution, err = utils.ParseDuration(h.SampleInterval); err != nil {
+			return nil, err
+		}
+	}
+	if cfg.historyResolution <= 0 || cfg.historyResolution > time.Hour {
+		return nil, fmt.Errorf("historyResolution %s is out of range (0, 1h]", cfg.historyResolution)
+	}
+
+	if h.SeasonLength != "" {
+		if cfg.seasonDuration, err = utils.ParseDuration(h.SeasonLength); err != nil {
+			return nil, err
+		}
+	}
+	if cfg.seasonDuration%cfg.historyResolution != 0 || cfg.seasonLength() < 2 {
+		return nil, fmt.Errorf("seasonLength %s is not a multiple of historyResolution %s", cfg.seasonDuration, cfg.historyResolution)
+	}
+
+	if h.HistoryLength != "" {
+		if cfg.historyDuration, err = utils.ParseDuration(h.HistoryLength); err != nil {
+			return nil, err
+		}
+	}
+	if cfg.historyDuration < 2*cfg.seasonDuration {
+		return nil, fmt.Errorf("historyDuration %s is shorter than two seasons", cfg.historyDuration)
+	}
+
+	if cfg.alpha, err = parseFactor(h.Alpha, "alpha"); err != nil {
+		return nil, err
+	}
+	if cfg.beta, err = parseFactor(h.Beta, "beta"); err != nil {
+		return nil, err
+	}
+	if cfg.gamma, err = parseFactor(h.Gamma, "gamma"); err != nil {
+		return nil, err
+	}
+	if cfg.alpha != nil && *cfg.alpha == 0 {
+		return nil, fmt.Errorf("alpha is out of range (0, 1]")
+	}
+
+	return &cfg, nil
+}
+
+// parseFactor parses a smoothing factor in [0, 1], nil is returned if it is empty.
+func parseFactor(str string, name string) (*float64, error) {
+	if str == "" {
+		return nil, nil
+	}
+	f, err := utils.ParseFloat(str, 0)
+	if err != nil {
+		return nil, err
+	}
+	if f < 0 || f > 1 {
+		return nil, fmt.Errorf("%s %v is out of range [0, 1]", name, f)
+	}
+	return &f, nil
+}
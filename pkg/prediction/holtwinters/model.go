@@ -0,0 +1,158 @@
+package holtwinters
+
+import (
+	"fmt"
+	"math"
+)
+
+// candidateFactors are the smoothing factors searched to fit the model if they are not specified.
+var candidateFactors = []float64{0.01, 0.05, 0.1, 0.2, 0.3, 0.5, 0.7, 0.9}
+
+// dampingFactors are the factors searched to damp the trend, the trend of the samples at a high resolution is damped
+// heavily to keep the long-term forecasts from being driven by the short-term changes.
+var dampingFactors = []float64{0.9, 0.99, 0.999, 1}
+
+// model is an additive holt-winters model, which smooths the level, the trend and the seasonal component of a time
+// series by the factors alpha, beta and gamma.
+type model struct {
+	alpha float64
+	beta  float64
+	gamma float64
+	// phi damps the trend of the forecasts, the trend is not damped if it is 1
+	phi   float64
+	level float64
+	trend float64
+	// seasonals are the seasonal components, seasonals[i] is of the samples whose index modulo the season length is i
+	seasonals []float64
+	// n is the number of samples smoothed
+	n int
+	// sse is the sum of squared errors of the one-step forecasts
+	sse float64
+}
+
+// newModel initializes a model by the first two seasons of the values. The level is the mean of the first season, the
+// trend is the difference between the means of the first two seasons, and the seasonal components are the detrended
+// values of the first season.
+func newModel(values []float64, seasonLength int, alpha, beta, gamma, phi float64) *model {
+	m := float64(seasonLength)
+	first, second := mean(values[:seasonLength]), mean(values[seasonLength:2*seasonLength])
+	trend := (second - first) / m
+	seasonals := make([]float64, seasonLength)
+	for i := 0; i < seasonLength; i++ {
+		seasonals[i] = values[i] - (first + trend*(float64(i)-(m-1)/2))
+	}
+
+	return &model{
+		alpha:     alpha,
+		beta:      beta,
+		gamma:     gamma,
+		phi:       phi,
+		level:     first + trend*(m-1)/2,
+		trend:     trend,
+		seasonals: seasonals,
+		n:         seasonLength,
+	}
+}
+
+// update smooths the model by the next sample.
+func (m *model) update(value float64) {
+	i := m.n % len(m.seasonals)
+	seasonal := m.seasonals[i]
+
+	err := value - (m.level + m.phi*m.trend + seasonal)
+	m.sse += err * err
+
+	level := m.alpha*(value-seasonal) + (1-m.alpha)*(m.level+m.phi*m.trend)
+	m.trend = m.beta*(level-m.level) + (1-m.beta)*m.phi*m.trend
+	m.seasonals[i] = m.gamma*(value-level) + (1-m.gamma)*seasonal
+	m.level = level
+	m.n++
+}
+
+// forecast returns the next h values after the samples smoothed.
+func (m *model) forecast(h int) []float64 {
+	values := make([]float64, h)
+	var damped, damping float64 = 0, 1
+	for k := 1; k <= h; k++ {
+		damping *= m.phi
+		damped += damping
+		values[k-1] = m.level + damped*m.trend + m.seasonals[(m.n+k-1)%len(m.seasonals)]
+	}
+	return values
+}
+
+// fit returns the model smoothed by the values. The factors which are not specified are searched among the candidates,
+// the ones forecasting the last season best by the former seasons are chosen. If there are less than three seasons, the
+// ones minimizing the sum of squared errors of the one-step forecasts are chosen.
+func fit(values []float64, seasonLength int, alpha, beta, gamma *float64) (*model, error) {
+	if seasonLength < 2 || len(values) < 2*seasonLength {
+		return nil, fmt.Errorf("holt-winters needs two seasons of %d samples at least, but got %d samples", seasonLength, len(values))
+	}
+
+	holdout := len(values) >= 3*seasonLength
+	history, actual := values, []float64(nil)
+	if holdout {
+		history, actual = values[:len(values)-seasonLength], values[len(values)-seasonLength:]
+	}
+
+	var best *model
+	minError := math.MaxFloat64
+	for _, a := range factorCandidates(alpha) {
+		for _, b := range factorCandidates(beta) {
+			for _, g := range factorCandidates(gamma) {
+				for _, phi := range dampingFactors {
+					m := smooth(history, seasonLength, a, b, g, phi)
+					e := m.sse
+					if holdout {
+						e = sse(actual, m.forecast(seasonLength))
+					}
+					if math.IsNaN(e) || math.IsInf(e, 0) {
+						continue
+					}
+					if best == nil || e < minError {
+						best, minError = m, e
+					}
+				}
+			}
+		}
+	}
+	if best == nil {
+		return nil, fmt.Errorf("failed to fit the holt-winters model")
+	}
+	if holdout {
+		return smooth(values, seasonLength, best.alpha, best.beta, best.gamma, best.phi), nil
+	}
+	return best, nil
+}
+
+// smooth returns the model initialized and smoothed by the values.
+func smooth(values []float64, seasonLength int, alpha, beta, gamma, phi float64) *model {
+	m := newModel(values, seasonLength, alpha, beta, gamma, phi)
+	for _, value := range values[seasonLength:] {
+		m.update(value)
+	}
+	return m
+}
+
+func sse(actual, predicted []float64) float64 {
+	var e float64
+	for i := range actual {
+		e += (actual[i] - predicted[i]) * (actual[i] - predicted[i])
+	}
+	return e
+}
+
+func factorCandidates(f *float64) []float64 {
+	if f != nil {
+		return []float64{*f}
+	}
+	return candidateFactors
+}
+
+func mean(values []float64) float64 {
+	var sum float64
+	for _, v := range values {
+		sum += v
+	}
+	return sum / float64(len(values))
+}
@@ -0,0 +1,53 @@
+package holtwinters
+
+import (
+	"math"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestFit(t *testing.T) {
+	seasonLength := 24
+	// a series growing steadily with a seasonal component
+	value := func(i int) float64 {
+		return 100 + 0.5*float64(i) + 10*math.Sin(2*math.Pi*float64(i)/float64(seasonLength))
+	}
+
+	tests := []struct {
+		name    string
+		n       int
+		alpha   *float64
+		wantErr bool
+	}{
+		{name: "fitted", n: 7 * seasonLength},
+		{name: "specified alpha", n: 7 * seasonLength, alpha: float64Ptr(0.5)},
+		{name: "less than two seasons", n: 2*seasonLength - 1, wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			values := make([]float64, tt.n)
+			for i := range values {
+				values[i] = value(i)
+			}
+			m, err := fit(values, seasonLength, tt.alpha, nil, nil)
+			if tt.wantErr {
+				assert.Error(t, err)
+				return
+			}
+			assert.NoError(t, err)
+			if tt.alpha != nil {
+				assert.Equal(t, *tt.alpha, m.alpha)
+			}
+
+			// the trend and the season are both forecasted
+			for i, predicted := range m.forecast(2 * seasonLength) {
+				assert.InDelta(t, value(tt.n+i), predicted, 1.0)
+			}
+		})
+	}
+}
+
+func float64Ptr(f float64) *float64 {
+	return &f
+}
@@ -0,0 +1,383 @@
+package holtwinters
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"sync"
+	"time"
+
+	"k8s.io/klog/v2"
+
+	"github.com/gocrane/crane/pkg/common"
+	"github.com/gocrane/crane/pkg/metricnaming"
+	"github.com/gocrane/crane/pkg/prediction"
+	"github.com/gocrane/crane/pkg/prediction/config"
+	"github.com/gocrane/crane/pkg/providers"
+)
+
+const (
+	defaultFuture = time.Hour
+	// minForecastDuration is the min length of the predicted time series, so the predictions do not run out between
+	// the model updates when the season is short
+	minForecastDuration = time.Hour * 24
+	// maxGapDuration is the max gap filled in the history, the samples before a larger gap are dropped
+	maxGapDuration = time.Hour
+)
+
+type holtWintersPrediction struct {
+	prediction.GenericPrediction
+	a         aggregateSignals
+	stopChMap sync.Map
+	// record the query routine already started
+	queryRoutines sync.Map
+	modelConfig   config.AlgorithmModelConfig
+}
+
+func NewPrediction(realtimeProvider providers.RealTime, historyProvider providers.History, mc config.AlgorithmModelConfig) prediction.Interface {
+	withCh, delCh := make(chan prediction.QueryExprWithCaller), make(chan prediction.QueryExprWithCaller)
+	return &holtWintersPrediction{
+		GenericPrediction: prediction.NewGenericPrediction(realtimeProvider, historyProvider, withCh, delCh),
+		a:                 newAggregateSignals(),
+		stopChMap:         sync.Map{},
+		queryRoutines:     sync.Map{},
+		modelConfig:       mc,
+	}
+}
+
+func (p *holtWintersPrediction) QueryPredictionStatus(ctx context.Context, metricNamer metricnaming.MetricNamer) (prediction.Status, error) {
+	_, status := p.a.GetSignals(metricNamer.BuildUniqueKey())
+	return status, nil
+}
+
+func (p *holtWintersPrediction) Run(stopCh <-chan struct{}) {
+	if p.GetHistoryProvider() == nil {
+		klog.ErrorS(fmt.Errorf("history provider not provisioned"), "Failed to run holtWintersPrediction.")
+		return
+	}
+
+	go func() {
+		for {
+			// Waiting for a WithQuery request
+			qc := <-p.WithCh
+			// update if the query config updated, idempotent
+			p.a.Add(qc)
+			QueryExpr := qc.MetricNamer.BuildUniqueKey()
+
+			if _, ok := p.queryRoutines.Load(QueryExpr); ok {
+				continue
+			}
+			if _, ok := p.stopChMap.Load(QueryExpr); ok {
+				continue
+			}
+			klog.V(6).InfoS("Register a query expression for prediction.", "queryExpr", QueryExpr, "caller", qc.Caller)
+
+			go func(namer metricnaming.MetricNamer) {
+				queryExpr := namer.BuildUniqueKey()
+				p.queryRoutines.Store(queryExpr, struct{}{})
+				ticker := time.NewTicker(p.modelConfig.UpdateInterval)
+				defer ticker.Stop()
+
+				v, _ := p.stopChMap.LoadOrStore(queryExpr, make(chan struct{}))
+				predStopCh := v.(chan struct{})
+
+				for {
+					if err := p.updateAggregateSignalsWithQuery(namer); err != nil {
+						klog.ErrorS(err, "Failed to updateAggregateSignalsWithQuery.")
+					}
+
+					select {
+					case <-predStopCh:
+						p.queryRoutines.Delete(queryExpr)
+						klog.V(4).InfoS("Prediction routine stopped.", "queryExpr", queryExpr)
+						return
+					case <-ticker.C:
+						continue
+					}
+				}
+			}(qc.MetricNamer)
+		}
+	}()
+
+	go func() {
+		for {
+			qc := <-p.DelCh
+			QueryExpr := qc.MetricNamer.BuildUniqueKey()
+			klog.V(4).InfoS("Unregister a query expression from prediction.", "queryExpr", QueryExpr, "caller", qc.Caller)
+
+			go func(qc prediction.QueryExprWithCaller) {
+				if p.a.Delete(qc) {
+					val, loaded := p.stopChMap.LoadAndDelete(QueryExpr)
+					if loaded {
+						predStopCh := val.(chan struct{})
+						predStopCh <- struct{}{}
+					}
+				}
+			}(qc)
+		}
+	}()
+
+	klog.Infof("predictor %v started", p.Name())
+
+	<-stopCh
+
+	klog.Infof("predictor %v stopped", p.Name())
+}
+
+// updateAggregateSignalsWithQuery fits the models of the query by the history and updates the predicted time series.
+func (p *holtWintersPrediction) updateAggregateSignalsWithQuery(namer metricnaming.MetricNamer) error {
+	queryExpr := namer.BuildUniqueKey()
+	cfg := p.a.GetConfig(queryExpr)
+
+	// Query history data for prediction
+	maxAttempts := 10
+	attempts := 0
+	var tsList []*common.TimeSeries
+	var err error
+	for attempts < maxAttempts {
+		tsList, err = p.queryHistoryTimeSeries(namer, cfg)
+		if err != nil {
+			attempts++
+			t := time.Second * time.Duration(math.Pow(2., float64(attempts)))
+			klog.ErrorS(err, "Failed to get time series.", "queryExpr", queryExpr, "attempts", attempts)
+			time.Sleep(t)
+		} else {
+			break
+		}
+	}
+	if attempts == maxAttempts {
+		klog.Errorf("After attempting %d times, still cannot get history time series for query expression '%s'.", maxAttempts, queryExpr)
+		return err
+	}
+
+	klog.V(6).InfoS("Update aggregate signals.", "queryExpr", queryExpr, "timeSeriesLength", len(tsList))
+
+	signals := map[string]*aggregateSignal{}
+	for _, ts := range tsList {
+		signal, err := predict(ts, cfg, forecastDuration(cfg))
+		if err != nil {
+			klog.V(4).InfoS("Failed to predict the time series.", "queryExpr", queryExpr, "labels", ts.Labels, "err", err)
+			continue
+		}
+		klog.V(4).InfoS("Holt-winters model fitted.", "queryExpr", queryExpr, "labels", ts.Labels,
+			"alpha", signal.alpha, "beta", signal.beta, "gamma", signal.gamma, "phi", signal.phi)
+		signals[prediction.AggregateSignalKey(ts.Labels)] = signal
+	}
+	p.a.SetSignals(queryExpr, signals)
+
+	return nil
+}
+
+func (p *holtWintersPrediction) queryHistoryTimeSeries(namer metricnaming.MetricNamer, cfg *internalConfig) ([]*common.TimeSeries, error) {
+	if p.GetHistoryProvider() == nil {
+		return nil, fmt.Errorf("history provider not provisioned")
+	}
+
+	end := time.Now().Truncate(cfg.historyResolution)
+	start := end.Add(-cfg.historyDuration)
+
+	tsList, err := p.GetHistoryProvider().QueryTimeSeries(namer, start, end, cfg.historyResolution)
+	if err != nil {
+		klog.ErrorS(err, "Failed to query history time series.")
+		return nil, err
+	}
+
+	klog.V(6).InfoS("holt-winters queryHistoryTimeSeries", "timeSeriesList", tsList, "config", *cfg)
+
+	return tsList, nil
+}
+
+// forecastDuration returns the length of the predicted time series, it is a season and a day at least.
+func forecastDuration(cfg *internalConfig) time.Duration {
+	if cfg.seasonDuration < minForecastDuration {
+		return minForecastDuration
+	}
+	return cfg.seasonDuration
+}
+
+// predict fits a model by the history time series and returns the signal of the values predicted in the future.
+func predict(ts *common.TimeSeries, cfg *internalConfig, future time.Duration) (*aggregateSignal, error) {
+	samples, err := preProcessSamples(ts.Samples, cfg.historyResolution)
+	if err != nil {
+		return nil, err
+	}
+
+	values := make([]float64, len(samples))
+	for i := range samples {
+		values[i] = samples[i].Value
+	}
+	m, err := fit(values, cfg.seasonLength(), cfg.alpha, cfg.beta, cfg.gamma)
+	if err != nil {
+		return nil, err
+	}
+
+	intervalSeconds := int64(cfg.historyResolution.Seconds())
+	nextTimestamp := samples[len(samples)-1].Timestamp + intervalSeconds
+	forecast := m.forecast(int(future / cfg.historyResolution))
+	predictedSamples := make([]common.Sample, len(forecast))
+	for i, value := range forecast {
+		// the metrics such as cpu and memory usage are never negative
+		predictedSamples[i] = common.Sample{Value: math.Max(value, 0), Timestamp: nextTimestamp}
+		nextTimestamp += intervalSeconds
+	}
+
+	signal := newAggregateSignal()
+	signal.setPredictedTimeSeries(&common.TimeSeries{Labels: ts.Labels, Samples: predictedSamples})
+	signal.alpha, signal.beta, signal.gamma, signal.phi = m.alpha, m.beta, m.gamma, m.phi
+	return signal, nil
+}
+
+// preProcessSamples returns the samples evenly spaced by the interval. The missing samples are filled by linear
+// interpolation, and the samples before a gap larger than maxGapDuration are dropped.
+func preProcessSamples(samples []common.Sample, interval time.Duration) ([]common.Sample, error) {
+	if len(samples) == 0 {
+		return nil, fmt.Errorf("empty time series")
+	}
+
+	intervalSeconds := int64(interval.Seconds())
+	for i := len(samples) - 1; i > 0; i-- {
+		if samples[i].Timestamp-samples[i-1].Timestamp > int64(maxGapDuration.Seconds()) {
+			samples = samples[i:]
+			break
+		}
+	}
+
+	newSamples := []common.Sample{samples[0]}
+	for i := 1; i < len(samples); i++ {
+		diff := samples[i].Timestamp - samples[i-1].Timestamp
+		// The samples should be in chronological order and spaced by integral multiple of interval.
+		if diff <= 0 || diff%intervalSeconds != 0 {
+			return nil, fmt.Errorf("invalid time series")
+		}
+		times := diff / intervalSeconds
+		unitDiff := (samples[i].Value - samples[i-1].Value) / float64(times)
+		// Fill the missing samples if any
+		for j := int64(1); j < times; j++ {
+			newSamples = append(newSamples, common.Sample{
+				Value:     samples[i-1].Value + unitDiff*float64(j),
+				Timestamp: samples[i-1].Timestamp + intervalSeconds*j,
+			})
+		}
+		newSamples = append(newSamples, samples[i])
+	}
+
+	return newSamples, nil
+}
+
+func (p *holtWintersPrediction) QueryPredictedTimeSeries(ctx context.Context, namer metricnaming.MetricNamer, startTime time.Time, endTime time.Time) ([]*common.TimeSeries, error) {
+	return p.getPredictedTimeSeriesList(ctx, namer, startTime, endTime), nil
+}
+
+func (p *holtWintersPrediction) QueryRealtimePredictedValues(ctx context.Context, namer metricnaming.MetricNamer) ([]*common.TimeSeries, error) {
+	queryExpr := namer.BuildUniqueKey()
+	cfg := p.a.GetConfig(queryExpr)
+
+	now := time.Now()
+	start := now.Truncate(cfg.historyResolution)
+	end := start.Add(defaultFuture)
+
+	return maxValues(p.getPredictedTimeSeriesList(ctx, namer, start, end), now), nil
+}
+
+// QueryRealtimePredictedValuesOnce fits the models by the history and returns the max values predicted in the next
+// hour, it is a stateless process and the models are not kept.
+func (p *holtWintersPrediction) QueryRealtimePredictedValuesOnce(ctx context.Context, namer metricnaming.MetricNamer, config config.Config) ([]*common.TimeSeries, error) {
+	cfg := &defaultInternalConfig
+	if config.HoltWinters != nil {
+		var err error
+		if cfg, err = makeInternalConfig(config.HoltWinters); err != nil {
+			return nil, err
+		}
+	}
+
+	tsList, err := p.queryHistoryTimeSeries(namer, cfg)
+	if err != nil {
+		return nil, err
+	}
+
+	var predictedTimeSeriesList []*common.TimeSeries
+	for _, ts := range tsList {
+		signal, err := predict(ts, cfg, defaultFuture)
+		if err != nil {
+			klog.V(4).InfoS("Failed to predict the time series.", "queryExpr", namer.BuildUniqueKey(), "labels", ts.Labels, "err", err)
+			continue
+		}
+		predictedTimeSeriesList = append(predictedTimeSeriesList, signal.predictedTimeSeries)
+	}
+	if len(predictedTimeSeriesList) == 0 {
+		return nil, fmt.Errorf("no time series predicted for %s", namer.BuildUniqueKey())
+	}
+
+	return maxValues(predictedTimeSeriesList, time.Now()), nil
+}
+
+// maxValues returns the max value of each time series at the time.
+func maxValues(tsList []*common.TimeSeries, now time.Time) []*common.TimeSeries {
+	var maxValueTimeSeriesList []*common.TimeSeries
+	for _, ts := range tsList {
+		if len(ts.Samples) < 1 {
+			continue
+		}
+		maxValue := ts.Samples[0].Value
+		for i := 1; i < len(ts.Samples); i++ {
+			if maxValue < ts.Samples[i].Value {
+				maxValue = ts.Samples[i].Value
+			}
+		}
+		maxValueTimeSeriesList = append(maxValueTimeSeriesList, &common.TimeSeries{
+			Labels:  ts.Labels,
+			Samples: []common.Sample{{Value: maxValue, Timestamp: now.Unix()}},
+		})
+	}
+	return maxValueTimeSeriesList
+}
+
+func (p *holtWintersPrediction) getPredictedTimeSeriesList(ctx context.Context, namer metricnaming.MetricNamer, start, end time.Time) []*common.TimeSeries {
+	var predictedTimeSeriesList []*common.TimeSeries
+	ticker := time.NewTicker(100 * time.Millisecond)
+	defer ticker.Stop()
+
+	queryExpr := namer.BuildUniqueKey()
+	for {
+		signals, status := p.a.GetSignals(queryExpr)
+		if status == prediction.StatusDeleted {
+			klog.V(4).InfoS("Aggregated has been deleted.", "queryExpr", queryExpr)
+			return predictedTimeSeriesList
+		}
+		if signals != nil && status == prediction.StatusReady {
+			for key, signal := range signals {
+				var samples []common.Sample
+				for _, sample := range signal.predictedTimeSeries.Samples {
+					t := time.Unix(sample.Timestamp, 0)
+					// Check if t is in [startTime, endTime]
+					if !t.Before(start) && !t.After(end) {
+						samples = append(samples, sample)
+					} else if t.After(end) {
+						break
+					}
+				}
+
+				if len(samples) > 0 {
+					predictedTimeSeriesList = append(predictedTimeSeriesList, &common.TimeSeries{
+						Labels:  signal.predictedTimeSeries.Labels,
+						Samples: samples,
+					})
+				}
+
+				klog.V(6).InfoS("Got holt-winters predicted samples.", "queryExpr", queryExpr, "labels", key, "len", len(samples))
+			}
+			return predictedTimeSeriesList
+		}
+		select {
+		case <-ctx.Done():
+			klog.Infoln("Time out.")
+			return predictedTimeSeriesList
+		case <-ticker.C:
+			continue
+		}
+	}
+}
+
+func (p *holtWintersPrediction) Name() string {
+	return "HoltWinters"
+}
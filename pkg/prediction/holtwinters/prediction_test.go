@@ -0,0 +1,108 @@
+package holtwinters
+
+import (
+	"context"
+	"os"
+	"testing"
+	"time"
+
+	"k8s.io/apimachinery/pkg/labels"
+
+	"github.com/gocrane/crane/pkg/common"
+	"github.com/gocrane/crane/pkg/metricnaming"
+	"github.com/gocrane/crane/pkg/metricquery"
+	"github.com/gocrane/crane/pkg/prediction"
+	"github.com/gocrane/crane/pkg/prediction/accuracy"
+	"github.com/gocrane/crane/pkg/prediction/config"
+	"github.com/gocrane/crane/pkg/providers/csv"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestPreProcessSamples(t *testing.T) {
+	samples := []common.Sample{
+		{Value: 1.0, Timestamp: 0},
+		{Value: 0.9, Timestamp: 7200},
+		{Value: 1.0, Timestamp: 7260},
+		{Value: 1.3, Timestamp: 7440},
+		{Value: 1.4, Timestamp: 7500},
+	}
+	processed, err := preProcessSamples(samples, time.Minute)
+	assert.NoError(t, err)
+	// the samples before the gap of two hours are dropped, and the missing samples are filled
+	assert.Len(t, processed, 6)
+	for i := 1; i < len(processed); i++ {
+		assert.InDelta(t, 0.1, processed[i].Value-processed[i-1].Value, 1e-9)
+		assert.Equal(t, int64(60), processed[i].Timestamp-processed[i-1].Timestamp)
+	}
+
+	_, err = preProcessSamples([]common.Sample{{Timestamp: 0}, {Timestamp: 90}}, time.Minute)
+	assert.Error(t, err)
+}
+
+func TestPredictWithCSV(t *testing.T) {
+	f, err := os.Open("../dsp/test_data/input0.csv")
+	assert.NoError(t, err)
+	defer f.Close()
+	provider, err := csv.NewProvider(f)
+	assert.NoError(t, err)
+
+	namer := &metricnaming.GeneralMetricNamer{}
+	tsList, err := provider.QueryTimeSeries(namer, time.Time{}, time.Time{}, time.Minute)
+	assert.NoError(t, err)
+	samples := tsList[0].Samples
+
+	// fit the model by the first 7 days, and predict the last day
+	history := &common.TimeSeries{Samples: samples[:len(samples)-1440]}
+	actual := make([]float64, 1440)
+	for i, sample := range samples[len(samples)-1440:] {
+		actual[i] = sample.Value
+	}
+
+	signal, err := predict(history, &defaultInternalConfig, forecastDuration(&defaultInternalConfig))
+	assert.NoError(t, err)
+	assert.Len(t, signal.predictedTimeSeries.Samples, 1440)
+	predicted := make([]float64, 1440)
+	for i, sample := range signal.predictedTimeSeries.Samples {
+		assert.Equal(t, samples[len(samples)-1440+i].Timestamp, sample.Timestamp)
+		predicted[i] = sample.Value
+	}
+	mae, err := accuracy.MAE(actual, predicted)
+	assert.NoError(t, err)
+	assert.Less(t, mae/mean(actual), 0.2)
+}
+
+func TestQueryPredictedTimeSeries(t *testing.T) {
+	f, err := os.Open("../dsp/test_data/input0.csv")
+	assert.NoError(t, err)
+	defer f.Close()
+	provider, err := csv.NewProvider(f)
+	assert.NoError(t, err)
+
+	namer := &metricnaming.GeneralMetricNamer{
+		Metric: &metricquery.Metric{
+			Type: metricquery.PromQLMetricType,
+			Prom: &metricquery.PromNamerInfo{
+				QueryExpr: "hello",
+				Selector:  labels.Nothing(),
+			},
+		}}
+	p := NewPrediction(nil, provider, config.AlgorithmModelConfig{UpdateInterval: time.Hour}).(*holtWintersPrediction)
+	p.a.Add(prediction.QueryExprWithCaller{MetricNamer: namer, Caller: "link", Config: config.Config{HoltWinters: &config.HoltWinters{HistoryLength: "8d"}}})
+
+	status, err := p.QueryPredictionStatus(context.TODO(), namer)
+	assert.NoError(t, err)
+	assert.Equal(t, prediction.StatusNotStarted, status)
+
+	assert.NoError(t, p.updateAggregateSignalsWithQuery(namer))
+	status, err = p.QueryPredictionStatus(context.TODO(), namer)
+	assert.NoError(t, err)
+	assert.Equal(t, prediction.StatusReady, status)
+
+	// the day after the history is predicted
+	samples, _ := provider.QueryLatestTimeSeries(namer)
+	last := time.Unix(samples[0].Samples[0].Timestamp, 0)
+	tsList, err := p.QueryPredictedTimeSeries(context.TODO(), namer, last.Add(time.Hour), last.Add(2*time.Hour))
+	assert.NoError(t, err)
+	assert.Len(t, tsList, 1)
+	assert.Len(t, tsList[0].Samples, 61)
+}
@@ -205,3 +205,15 @@ func (a *aggregateSignals) GetSignals(queryExpr string) (map[string]*aggregateSi
 	}
 	return m, a.statusMap[queryExpr]
 }
+
+// GetQueryExprs returns the query expressions registered.
+func (a *aggregateSignals) GetQueryExprs() []string {
+	a.mutex.RLock()
+	defer a.mutex.RUnlock()
+
+	queryExprs := make([]string, 0, len(a.signalMap))
+	for queryExpr := range a.signalMap {
+		queryExprs = append(queryExprs, queryExpr)
+	}
+	return queryExprs
+}
@@ -0,0 +1,144 @@
+package percentile
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	vpatypes "k8s.io/autoscaler/vertical-pod-autoscaler/pkg/apis/autoscaling.k8s.io/v1"
+	"k8s.io/klog/v2"
+
+	"github.com/gocrane/crane/pkg/common"
+	"github.com/gocrane/crane/pkg/metricnaming"
+	"github.com/gocrane/crane/pkg/prediction"
+	"github.com/gocrane/crane/pkg/prediction/checkpoint"
+)
+
+// modelCheckpoint is the model of a query expression saved to the checkpoint store.
+type modelCheckpoint struct {
+	Status  prediction.Status            `json:"status"`
+	Signals map[string]*signalCheckpoint `json:"signals"`
+}
+
+// signalCheckpoint is an aggregate signal saved to the checkpoint store.
+type signalCheckpoint struct {
+	Labels            []common.Label                `json:"labels,omitempty"`
+	Histogram         *vpatypes.HistogramCheckpoint `json:"histogram"`
+	FirstSampleTime   time.Time                     `json:"firstSampleTime"`
+	LastSampleTime    time.Time                     `json:"lastSampleTime"`
+	TotalSamplesCount int                           `json:"totalSamplesCount"`
+	CreationTime      time.Time                     `json:"creationTime"`
+}
+
+func (a *aggregateSignal) saveToCheckpoint() (*signalCheckpoint, error) {
+	histogram, err := a.histogram.SaveToChekpoint()
+	if err != nil {
+		return nil, err
+	}
+	return &signalCheckpoint{
+		Labels:            a.labels,
+		Histogram:         histogram,
+		FirstSampleTime:   a.firstSampleTime,
+		LastSampleTime:    a.lastSampleTime,
+		TotalSamplesCount: a.totalSamplesCount,
+		CreationTime:      a.creationTime,
+	}, nil
+}
+
+func (a *aggregateSignal) loadFromCheckpoint(c *signalCheckpoint) error {
+	if err := a.histogram.LoadFromCheckpoint(c.Histogram); err != nil {
+		return err
+	}
+	a.labels = c.Labels
+	a.firstSampleTime = c.FirstSampleTime
+	a.lastSampleTime = c.LastSampleTime
+	a.totalSamplesCount = c.TotalSamplesCount
+	a.creationTime = c.CreationTime
+	return nil
+}
+
+// saveCheckpoints saves the models which are ready or initializing to the checkpoint store, and deletes the stale
+// checkpoints of the predictor.
+func (p *percentilePrediction) saveCheckpoints() {
+	ctx := context.TODO()
+	now := time.Now()
+
+	var saved int
+	for _, queryExpr := range p.a.GetQueryExprs() {
+		signals, status := p.a.GetSignals(queryExpr)
+		if len(signals) == 0 || (status != prediction.StatusReady && status != prediction.StatusInitializing) {
+			continue
+		}
+
+		model := modelCheckpoint{Status: status, Signals: make(map[string]*signalCheckpoint, len(signals))}
+		for key, signal := range signals {
+			c, err := signal.saveToCheckpoint()
+			if err != nil {
+				klog.ErrorS(err, "Failed to save the signal to checkpoint.", "queryExpr", queryExpr, "key", key)
+				continue
+			}
+			model.Signals[key] = c
+		}
+		data, err := json.Marshal(model)
+		if err != nil {
+			klog.ErrorS(err, "Failed to encode the checkpoint.", "queryExpr", queryExpr)
+			continue
+		}
+
+		if err := p.store.Save(ctx, &checkpoint.Checkpoint{
+			Predictor:  p.Name(),
+			QueryExpr:  queryExpr,
+			Data:       data,
+			UpdateTime: metav1.NewTime(now),
+		}); err != nil {
+			klog.ErrorS(err, "Failed to save the checkpoint.", "queryExpr", queryExpr)
+			continue
+		}
+		saved++
+	}
+	klog.V(4).InfoS("Checkpoints saved.", "predictor", p.Name(), "count", saved)
+
+	checkpoint.DeleteExpired(ctx, p.store, p.Name(), p.modelConfig.CheckpointExpiration, now)
+}
+
+// restoreFromCheckpoint restores the model of the metric namer from the checkpoint store with the status saved, false is
+// returned if there is no checkpoint or the checkpoint is stale.
+func (p *percentilePrediction) restoreFromCheckpoint(namer metricnaming.MetricNamer) (bool, error) {
+	queryExpr := namer.BuildUniqueKey()
+	c, err := p.store.Load(context.TODO(), p.Name(), queryExpr)
+	if err != nil {
+		return false, err
+	}
+	if c == nil || checkpoint.IsExpired(c, p.modelConfig.CheckpointExpiration, time.Now()) {
+		return false, nil
+	}
+
+	var model modelCheckpoint
+	if err := json.Unmarshal(c.Data, &model); err != nil {
+		return false, fmt.Errorf("failed to decode the checkpoint: %v", err)
+	}
+
+	cfg := p.a.GetConfig(queryExpr)
+	signals := make(map[string]*aggregateSignal, len(model.Signals))
+	for key, sc := range model.Signals {
+		signal := newAggregateSignal(cfg)
+		if err := signal.loadFromCheckpoint(sc); err != nil {
+			return false, fmt.Errorf("failed to load the signal %s from checkpoint: %v", key, err)
+		}
+		signals[key] = signal
+	}
+	// the checkpoint is not restored if it was saved when the config was not aggregated
+	if _, exists := signals["__all__"]; cfg.aggregated && !exists {
+		return false, nil
+	}
+
+	status := prediction.StatusInitializing
+	if model.Status == prediction.StatusReady {
+		status = prediction.StatusReady
+	}
+	p.a.SetSignalsWithStatus(queryExpr, signals, status)
+	klog.V(4).InfoS("Model restored from checkpoint.", "queryExpr", queryExpr, "status", status, "updateTime", c.UpdateTime)
+	return true, nil
+}
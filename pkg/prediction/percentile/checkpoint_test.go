@@ -0,0 +1,107 @@
+package percentile
+
+import (
+	"context"
+	"io/ioutil"
+	"os"
+	"testing"
+	"time"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+
+	"github.com/gocrane/crane/pkg/common"
+	"github.com/gocrane/crane/pkg/metricnaming"
+	"github.com/gocrane/crane/pkg/metricquery"
+	"github.com/gocrane/crane/pkg/prediction"
+	"github.com/gocrane/crane/pkg/prediction/checkpoint"
+	"github.com/gocrane/crane/pkg/prediction/config"
+	"github.com/gocrane/crane/pkg/providers"
+	"github.com/stretchr/testify/assert"
+)
+
+func newCheckpointPrediction(store checkpoint.Store, history providers.History) *percentilePrediction {
+	mc := config.AlgorithmModelConfig{CheckpointInterval: time.Minute, CheckpointExpiration: time.Hour}
+	return NewPrediction(nil, history, mc, store).(*percentilePrediction)
+}
+
+type fakeHistory struct {
+	ts *common.TimeSeries
+}
+
+func (h *fakeHistory) QueryTimeSeries(_ metricnaming.MetricNamer, _ time.Time, _ time.Time, _ time.Duration) ([]*common.TimeSeries, error) {
+	return []*common.TimeSeries{h.ts}, nil
+}
+
+func TestCheckpoint(t *testing.T) {
+	dir, err := ioutil.TempDir("", "checkpoint")
+	assert.NoError(t, err)
+	defer os.RemoveAll(dir)
+	store, err := checkpoint.NewFileStore(dir)
+	assert.NoError(t, err)
+
+	namer := &metricnaming.GeneralMetricNamer{
+		Metric: &metricquery.Metric{
+			Type: metricquery.PromQLMetricType,
+			Prom: &metricquery.PromNamerInfo{
+				QueryExpr: "hello",
+				Selector:  labels.Nothing(),
+			},
+		}}
+	qc := prediction.QueryExprWithCaller{MetricNamer: namer, Caller: "link"}
+	queryExpr := namer.BuildUniqueKey()
+
+	// train a model and save it
+	p := newCheckpointPrediction(store, nil)
+	p.a.Add(qc)
+	signal := newAggregateSignal(p.a.GetConfig(queryExpr))
+	now := time.Now()
+	for i := 0; i < 100; i++ {
+		signal.addSample(now.Add(time.Duration(i)*time.Minute), float64(i%10))
+	}
+	p.a.SetSignal(queryExpr, "__all__", signal)
+	p.saveCheckpoints()
+
+	// restore the model by a new predictor
+	restored := newCheckpointPrediction(store, nil)
+	restored.a.Add(qc)
+	assert.NoError(t, restored.initByCheckPoint(namer))
+	signals, status := restored.a.GetSignals(queryExpr)
+	assert.Equal(t, prediction.StatusReady, status)
+	assert.Equal(t, 100, signals["__all__"].totalSamplesCount)
+
+	estimator := NewPercentileEstimator(0.9)
+	assert.InDelta(t, estimator.GetEstimation(signal.histogram), estimator.GetEstimation(signals["__all__"].histogram), 0.1)
+
+	// the stale checkpoint is not restored, the model is initialized from the history
+	c, err := store.Load(context.TODO(), restored.Name(), queryExpr)
+	assert.NoError(t, err)
+	c.UpdateTime = metav1.NewTime(now.Add(-2 * time.Hour))
+	assert.NoError(t, store.Save(context.TODO(), c))
+
+	history := &fakeHistory{ts: &common.TimeSeries{}}
+	for i := 0; i < 30; i++ {
+		history.ts.Samples = append(history.ts.Samples, common.Sample{Value: float64(i), Timestamp: now.Add(time.Duration(i) * time.Minute).Unix()})
+	}
+	stale := newCheckpointPrediction(store, history)
+	stale.a.Add(qc)
+	assert.NoError(t, stale.initByCheckPoint(namer))
+	signals, status = stale.a.GetSignals(queryExpr)
+	assert.Equal(t, prediction.StatusReady, status)
+	assert.Equal(t, 30, signals["__all__"].totalSamplesCount)
+
+	// the model falls back to lazy training without the history provider
+	lazy := newCheckpointPrediction(store, nil)
+	lazy.a.Add(qc)
+	assert.NoError(t, lazy.initByCheckPoint(namer))
+	signals, status = lazy.a.GetSignals(queryExpr)
+	assert.Equal(t, prediction.StatusInitializing, status)
+	assert.Equal(t, 0, signals["__all__"].totalSamplesCount)
+
+	// the stale checkpoint is deleted when the checkpoints are saved
+	stale.a.Delete(qc)
+	stale.saveCheckpoints()
+	c, err = store.Load(context.TODO(), stale.Name(), queryExpr)
+	assert.NoError(t, err)
+	assert.Nil(t, c)
+}
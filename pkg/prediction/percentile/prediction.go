@@ -6,11 +6,13 @@ import (
 	"sync"
 	"time"
 
+	"k8s.io/apimachinery/pkg/util/wait"
 	"k8s.io/klog/v2"
 
 	"github.com/gocrane/crane/pkg/common"
 	"github.com/gocrane/crane/pkg/metricnaming"
 	"github.com/gocrane/crane/pkg/prediction"
+	"github.com/gocrane/crane/pkg/prediction/checkpoint"
 	"github.com/gocrane/crane/pkg/prediction/config"
 	"github.com/gocrane/crane/pkg/providers"
 )
@@ -23,6 +25,9 @@ type percentilePrediction struct {
 	// record the query routine already started
 	queryRoutines sync.Map
 	stopChMap     sync.Map
+	modelConfig   config.AlgorithmModelConfig
+	// store saves the models to checkpoints, the models are not saved if it is nil
+	store checkpoint.Store
 }
 
 func (p *percentilePrediction) QueryPredictionStatus(ctx context.Context, metricNamer metricnaming.MetricNamer) (prediction.Status, error) {
@@ -262,13 +267,15 @@ func (p *percentilePrediction) process(namer metricnaming.MetricNamer, config co
 	}
 }
 
-func NewPrediction(realtimeProvider providers.RealTime, historyProvider providers.History) prediction.Interface {
+func NewPrediction(realtimeProvider providers.RealTime, historyProvider providers.History, mc config.AlgorithmModelConfig, store checkpoint.Store) prediction.Interface {
 	withCh, delCh := make(chan prediction.QueryExprWithCaller), make(chan prediction.QueryExprWithCaller)
 	return &percentilePrediction{
 		GenericPrediction: prediction.NewGenericPrediction(realtimeProvider, historyProvider, withCh, delCh),
 		a:                 newAggregateSignals(),
 		queryRoutines:     sync.Map{},
 		stopChMap:         sync.Map{},
+		modelConfig:       mc,
+		store:             store,
 	}
 }
 
@@ -364,6 +371,10 @@ func (p *percentilePrediction) Run(stopCh <-chan struct{}) {
 		}
 	}()
 
+	if p.store != nil && p.modelConfig.CheckpointInterval > 0 {
+		go wait.Until(p.saveCheckpoints, p.modelConfig.CheckpointInterval, stopCh)
+	}
+
 	klog.Infof("predictor %v started", p.Name())
 
 	<-stopCh
@@ -404,10 +415,28 @@ func (p *percentilePrediction) initByRealTimeProvider(namer metricnaming.MetricN
 	}
 }
 
-// todo:
-// nolint:unused
+// initByCheckPoint restores the histogram model from the checkpoint directly, so the model is ready immediately if it was
+// ready when saved. The model is initialized from the history if there is no checkpoint, or the checkpoint is stale or
+// fails to be restored.
 func (p *percentilePrediction) initByCheckPoint(namer metricnaming.MetricNamer) error {
-	return fmt.Errorf("Do not support checkpoint now")
+	queryExpr := namer.BuildUniqueKey()
+	if p.store != nil {
+		restored, err := p.restoreFromCheckpoint(namer)
+		if err != nil {
+			klog.ErrorS(err, "Failed to restore from checkpoint, fall back to the history.", "queryExpr", queryExpr)
+		}
+		if restored {
+			return nil
+		}
+	}
+
+	// the model can only be trained lazily without the history provider
+	if p.GetHistoryProvider() == nil {
+		klog.V(4).InfoS("History provider not configured, fall back to lazy training.", "queryExpr", queryExpr)
+		p.initByRealTimeProvider(namer)
+		return nil
+	}
+	return p.initFromHistory(namer)
 }
 
 func (p *percentilePrediction) initFromHistory(namer metricnaming.MetricNamer) error {
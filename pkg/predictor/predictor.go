@@ -9,8 +9,10 @@ import (
 	predictionapi "github.com/gocrane/api/prediction/v1alpha1"
 
 	"github.com/gocrane/crane/pkg/prediction"
+	"github.com/gocrane/crane/pkg/prediction/checkpoint"
 	predconf "github.com/gocrane/crane/pkg/prediction/config"
 	"github.com/gocrane/crane/pkg/prediction/dsp"
+	"github.com/gocrane/crane/pkg/prediction/holtwinters"
 	"github.com/gocrane/crane/pkg/prediction/percentile"
 	"github.com/gocrane/crane/pkg/providers"
 )
@@ -25,17 +27,25 @@ type AlgorithmDataProviders struct {
 type PredictorConfig struct {
 	DataProviders AlgorithmDataProviders
 	ModelConfig   predconf.AlgorithmModelConfig
+	// CheckpointStore saves the models of the predictor, it is nil if checkpoint is disabled
+	CheckpointStore checkpoint.Store
 }
 
 // DefaultPredictorsConfig will use all datasources you for real time and history provider. data proxy will select the first available.
 // Now, for RealTimeProvider if you specified metricserver in command args, it is [metricserver,prom] in order, if not, it is [prom]. for HistoryProvider is [prom]
-func DefaultPredictorsConfig(modelConfig predconf.AlgorithmModelConfig) map[predictionapi.AlgorithmType]PredictorConfig {
+func DefaultPredictorsConfig(modelConfig predconf.AlgorithmModelConfig, checkpointStore checkpoint.Store) map[predictionapi.AlgorithmType]PredictorConfig {
 	configs := map[predictionapi.AlgorithmType]PredictorConfig{
 		predictionapi.AlgorithmTypeDSP: {
-			ModelConfig: modelConfig,
+			ModelConfig:     modelConfig,
+			CheckpointStore: checkpointStore,
 		},
 		predictionapi.AlgorithmTypePercentile: {
-			DataProviders: AlgorithmDataProviders{},
+			DataProviders:   AlgorithmDataProviders{},
+			ModelConfig:     modelConfig,
+			CheckpointStore: checkpointStore,
+		},
+		predconf.AlgorithmTypeHoltWinters: {
+			ModelConfig: modelConfig,
 		},
 	}
 	return configs
@@ -104,15 +114,20 @@ func NewManager(realtimeProviders map[providers.DataSourceType]providers.RealTim
 
 		switch algo {
 		case predictionapi.AlgorithmTypePercentile:
-			pctPredictor := percentile.NewPrediction(algorithmRealTimeProxy, algorithmHistoryProxy)
+			pctPredictor := percentile.NewPrediction(algorithmRealTimeProxy, algorithmHistoryProxy, predictorConf.ModelConfig, predictorConf.CheckpointStore)
 			m.predictors[algo] = pctPredictor
 			m.historyDataProxys[algo] = algorithmHistoryProxy
 			m.realTimeDataProxys[algo] = algorithmRealTimeProxy
 		case predictionapi.AlgorithmTypeDSP:
-			dspPredictor := dsp.NewPrediction(algorithmRealTimeProxy, algorithmHistoryProxy, predictorConf.ModelConfig)
+			dspPredictor := dsp.NewPrediction(algorithmRealTimeProxy, algorithmHistoryProxy, predictorConf.ModelConfig, predictorConf.CheckpointStore)
 			m.predictors[algo] = dspPredictor
 			m.historyDataProxys[algo] = algorithmHistoryProxy
 			m.realTimeDataProxys[algo] = algorithmRealTimeProxy
+		case predconf.AlgorithmTypeHoltWinters:
+			hwPredictor := holtwinters.NewPrediction(algorithmRealTimeProxy, algorithmHistoryProxy, predictorConf.ModelConfig)
+			m.predictors[algo] = hwPredictor
+			m.historyDataProxys[algo] = algorithmHistoryProxy
+			m.realTimeDataProxys[algo] = algorithmRealTimeProxy
 		default:
 			klog.Errorf("Unknown predictor %v", algo)
 			continue
@@ -6,6 +6,7 @@ import (
 	"fmt"
 	"math"
 	"strconv"
+	"sync"
 	"time"
 
 	v1 "k8s.io/api/core/v1"
@@ -50,6 +51,16 @@ type ReserveResource struct {
 	MemPercent *float64
 }
 
+// ExtResource is an extended resource computed by the node resource manager.
+type ExtResource struct {
+	// Value is the next recommendation of the resource
+	Value resource.Quantity `json:"value"`
+	// From is where the max usage which can not be reclaimed is from, tsp or local
+	From string `json:"from"`
+	// Updated is false if the change to the capacity of the node is less than MinDeltaRatio, so it is not updated
+	Updated bool `json:"updated"`
+}
+
 type NodeResourceManager struct {
 	nodeName string
 	client   clientset.Interface
@@ -71,6 +82,11 @@ type NodeResourceManager struct {
 	reserveResource ReserveResource
 
 	tspName string
+
+	// extResources are the extended resources of the latest computation
+	extResourceLock sync.RWMutex
+	extResources    map[v1.ResourceName]ExtResource
+	extResourceTime time.Time
 }
 
 func NewNodeResourceManager(client clientset.Interface, nodeName string, op map[string]string, tspName string, nodeInformer coreinformers.NodeInformer,
@@ -202,6 +218,8 @@ func (o *NodeResourceManager) BuildNodeStatus(node *v1.Node) map[v1.ResourceName
 	}
 
 	extResourceFrom := map[v1.ResourceName]string{}
+	extResources := map[v1.ResourceName]ExtResource{}
+	defer o.setExtResources(extResources)
 
 	for resourceName, value := range tspCanNotBeReclaimedResource {
 		resourceFrom := "tsp"
@@ -229,6 +247,10 @@ func (o *NodeResourceManager) BuildNodeStatus(node *v1.Node) map[v1.ResourceName
 			nextRecommendation = 0
 		}
 		extResourceName := fmt.Sprintf(utils.ExtResourcePrefixFormat, string(resourceName))
+		extResources[v1.ResourceName(extResourceName)] = ExtResource{
+			Value: *resource.NewQuantity(int64(nextRecommendation), resource.DecimalSI),
+			From:  resourceFrom,
+		}
 		resValue, exists := node.Status.Capacity[v1.ResourceName(extResourceName)]
 		if exists && resValue.Value() != 0 &&
 			math.Abs(float64(resValue.Value())-
@@ -241,11 +263,27 @@ func (o *NodeResourceManager) BuildNodeStatus(node *v1.Node) map[v1.ResourceName
 			*resource.NewQuantity(int64(nextRecommendation), resource.DecimalSI)
 
 		extResourceFrom[resourceName] = resourceFrom
+		extResource := extResources[v1.ResourceName(extResourceName)]
+		extResource.Updated = true
+		extResources[v1.ResourceName(extResourceName)] = extResource
 	}
 
 	return extResourceFrom
 }
 
+func (o *NodeResourceManager) setExtResources(extResources map[v1.ResourceName]ExtResource) {
+	o.extResourceLock.Lock()
+	defer o.extResourceLock.Unlock()
+	o.extResources, o.extResourceTime = extResources, time.Now()
+}
+
+// GetExtResources returns the extended resources of the latest computation and the time when they are computed.
+func (o *NodeResourceManager) GetExtResources() (map[v1.ResourceName]ExtResource, time.Time) {
+	o.extResourceLock.RLock()
+	defer o.extResourceLock.RUnlock()
+	return o.extResources, o.extResourceTime
+}
+
 func (o *NodeResourceManager) GetCanNotBeReclaimedResourceFromTsp(node *v1.Node) map[v1.ResourceName]float64 {
 	canNotBeReclaimedResource := map[v1.ResourceName]float64{
 		v1.ResourceCPU:    0,
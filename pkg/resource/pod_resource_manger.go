@@ -176,8 +176,8 @@ func (o *PodResourceManager) updatePodExtResToCgroup(pod *v1.Pod) {
 					continue
 				}
 
-				// Update cpu quota by CRI
-				err = cruntime.UpdateContainerResources(o.runtimeClient, containerId, cruntime.UpdateOptions{CPUQuota: int64(float64(val.MilliValue()) / executor.CpuQuotaCoefficient * containerPeriod)})
+				// Update cpu quota by CRI, or cpu.max on cgroup v2 if the runtime fails to update it
+				err = executor.UpdateContainerCPUQuota(o.runtimeClient, pod, containerId, int64(float64(val.MilliValue())/executor.CpuQuotaCoefficient*containerPeriod))
 				if err != nil {
 					metrics.PodResourceUpdateErrorCounterInc(metrics.SubComponentPodResource, metrics.StepUpdateQuota)
 					klog.Errorf("Failed to update pod %s container %s Resource, err %s", pod.Name, containerId, err.Error())
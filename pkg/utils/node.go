@@ -1,12 +1,14 @@
 package utils
 
 import (
+	"encoding/json"
 	"fmt"
 
 	"golang.org/x/net/context"
 	v1 "k8s.io/api/core/v1"
 	"k8s.io/apimachinery/pkg/api/errors"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
 	clientset "k8s.io/client-go/kubernetes"
 	corelisters "k8s.io/client-go/listers/core/v1"
 	"k8s.io/klog/v2"
@@ -155,3 +157,23 @@ func removeNodeTaints(node *v1.Node, taint v1.Taint) (*v1.Node, bool) {
 
 	return updatedNode, false
 }
+
+// PatchNodeAnnotation sets the annotation of the node by a merge patch, the annotation is removed if the value is empty.
+func PatchNodeAnnotation(client clientset.Interface, nodeName string, key string, value string) error {
+	var annotationValue interface{}
+	if value != "" {
+		annotationValue = value
+	}
+
+	patch, err := json.Marshal(map[string]interface{}{
+		"metadata": map[string]interface{}{
+			"annotations": map[string]interface{}{key: annotationValue},
+		},
+	})
+	if err != nil {
+		return err
+	}
+
+	_, err = client.CoreV1().Nodes().Patch(context.Background(), nodeName, types.MergePatchType, patch, metav1.PatchOptions{})
+	return err
+}
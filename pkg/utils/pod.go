@@ -2,6 +2,7 @@ package utils
 
 import (
 	"context"
+	"encoding/json"
 	"fmt"
 	"strings"
 	"time"
@@ -10,6 +11,7 @@ import (
 	policyv1beta1 "k8s.io/api/policy/v1beta1"
 	"k8s.io/apimachinery/pkg/api/resource"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
 	clientset "k8s.io/client-go/kubernetes"
 	"k8s.io/klog/v2"
 	kubelettypes "k8s.io/kubernetes/pkg/kubelet/types"
@@ -196,3 +198,23 @@ func GetContainerIdFromPod(pod *v1.Pod, containerName string) string {
 	}
 	return ""
 }
+
+// PatchPodAnnotation sets the annotation of the pod by a merge patch, the annotation is removed if the value is empty.
+func PatchPodAnnotation(client clientset.Interface, namespace, name string, key string, value string) error {
+	var annotationValue interface{}
+	if value != "" {
+		annotationValue = value
+	}
+
+	patch, err := json.Marshal(map[string]interface{}{
+		"metadata": map[string]interface{}{
+			"annotations": map[string]interface{}{key: annotationValue},
+		},
+	})
+	if err != nil {
+		return err
+	}
+
+	_, err = client.CoreV1().Pods(namespace).Patch(context.Background(), name, types.MergePatchType, patch, metav1.PatchOptions{})
+	return err
+}
@@ -8,8 +8,11 @@ import (
 )
 
 const (
-	CgroupKubePods  = "/kubepods"
 	CgroupPodPrefix = "pod"
+	// CgroupSystemdSlice and CgroupSystemdScope are the suffixes of the cgroups created by the systemd cgroup driver,
+	// the slices are the qos and pod cgroups and the scopes are the container cgroups, such as cri-containerd-<id>.scope
+	CgroupSystemdSlice = ".slice"
+	CgroupSystemdScope = ".scope"
 )
 
 func GetNodeRef(nodeName string) *v1.ObjectReference {
@@ -25,13 +28,18 @@ func GetContainerIdFromKey(key string) string {
 	subPaths := strings.Split(key, "/")
 
 	if len(subPaths) > 0 {
-		// if the latest sub path is pod-xxx-xxx, we regard as it od path
+		// if the latest sub path is pod-xxx-xxx or a systemd slice, we regard as it od path
 		// if not we used the latest sub path as the containerId
-		if strings.HasPrefix(subPaths[len(subPaths)-1], CgroupPodPrefix) {
+		lastPath := subPaths[len(subPaths)-1]
+		if strings.HasPrefix(lastPath, CgroupPodPrefix) || strings.HasSuffix(lastPath, CgroupSystemdSlice) {
 			return ""
-		} else {
-			return subPaths[len(subPaths)-1]
 		}
+		// the systemd scope is <runtime>-<id>.scope
+		if strings.HasSuffix(lastPath, CgroupSystemdScope) {
+			lastPath = strings.TrimSuffix(lastPath, CgroupSystemdScope)
+			return lastPath[strings.LastIndex(lastPath, "-")+1:]
+		}
+		return lastPath
 	}
 
 	return ""
@@ -9,15 +9,17 @@ import (
 	"k8s.io/apimachinery/pkg/util/validation/field"
 
 	ensuranceapi "github.com/gocrane/api/ensurance/v1alpha1"
+	"github.com/gocrane/crane/pkg/ensurance/extension"
 	"github.com/gocrane/crane/pkg/known"
 )
 
 func TestValidateNodeQualityProbe(t *testing.T) {
 	cases := map[string]struct {
-		nodeProbe   ensuranceapi.NodeQualityProbe
-		expectErr   bool
-		errorType   field.ErrorType
-		errorDetail string
+		nodeProbe        ensuranceapi.NodeQualityProbe
+		metricsServerGet bool
+		expectErr        bool
+		errorType        field.ErrorType
+		errorDetail      string
 	}{
 		"invalid NodeQualityProbe, httpGet and nodeLocalGet not set": {
 			nodeProbe:   ensuranceapi.NodeQualityProbe{},
@@ -44,11 +46,26 @@ func TestValidateNodeQualityProbe(t *testing.T) {
 			errorDetail: "The httpGet and nodeLocalGet can not set at the same time",
 			expectErr:   true,
 		},
+		"valid NodeQualityProbe, metrics server set": {
+			nodeProbe:        ensuranceapi.NodeQualityProbe{},
+			metricsServerGet: true,
+			expectErr:        false,
+		},
+		"invalid NodeQualityProbe, metrics server and nodeLocalGet can not set at the same time": {
+			nodeProbe: ensuranceapi.NodeQualityProbe{
+				NodeLocalGet: &ensuranceapi.NodeLocalGet{
+					LocalCacheTTLSeconds: 60},
+			},
+			metricsServerGet: true,
+			errorType:        field.ErrorTypeInvalid,
+			errorDetail:      "can not be set with the metrics server probe",
+			expectErr:        true,
+		},
 	}
 
 	for k, v := range cases {
 		t.Run(k, func(t *testing.T) {
-			errs := validateNodeQualityProbe(v.nodeProbe, field.NewPath("nodeQualityProbe"))
+			errs := validateNodeQualityProbe(v.nodeProbe, v.metricsServerGet, field.NewPath("nodeQualityProbe"))
 			t.Logf("%s: len %d", k, len(errs))
 			if v.expectErr && len(errs) > 0 {
 				if errs[0].Type != v.errorType || !strings.Contains(errs[0].Detail, v.errorDetail) {
@@ -109,6 +126,22 @@ func TestValidateMetricRule(t *testing.T) {
 			httpGetEnable: false,
 			expectErr:     false,
 		},
+		"probe metric name is built-in": {
+			rule: ensuranceapi.MetricRule{
+				Name:  "cpu_total_usage",
+				Value: resource.MustParse("6000")},
+			httpGetEnable: true,
+			errorType:     field.ErrorTypeInvalid,
+			errorDetail:   "built-in metric",
+			expectErr:     true,
+		},
+		"probe metric name is valid": {
+			rule: ensuranceapi.MetricRule{
+				Name:  "request_latency_ms",
+				Value: resource.MustParse("200")},
+			httpGetEnable: true,
+			expectErr:     false,
+		},
 	}
 
 	for k, v := range cases {
@@ -132,6 +165,7 @@ func TestValidateMetricRule(t *testing.T) {
 func TestValidateObjectiveEnsurances(t *testing.T) {
 	cases := map[string]struct {
 		objects       []ensuranceapi.ObjectiveEnsurance
+		extObjects    map[string]extension.ObjectiveEnsurance
 		httpGetEnable bool
 		expectErr     bool
 		errorType     field.ErrorType
@@ -194,6 +228,13 @@ func TestValidateObjectiveEnsurances(t *testing.T) {
 			errorDetail:   "",
 			expectErr:     true,
 		},
+		"object metric rule is not required with rego rule": {
+			objects: []ensuranceapi.ObjectiveEnsurance{{Name: "aaa", AvoidanceActionName: "eviction",
+				AvoidanceThreshold: known.DefaultAvoidedThreshold, RestoreThreshold: known.DefaultRestoredThreshold}},
+			extObjects:    map[string]extension.ObjectiveEnsurance{"aaa": {Rego: "trigger { input.metrics.cpu_total_usage[0].value > 6000 }"}},
+			httpGetEnable: false,
+			expectErr:     false,
+		},
 		"object valid": {
 			objects: []ensuranceapi.ObjectiveEnsurance{{Name: "aaa",
 				AvoidanceActionName: "eviction",
@@ -209,7 +250,7 @@ func TestValidateObjectiveEnsurances(t *testing.T) {
 
 	for k, v := range cases {
 		t.Run(k, func(t *testing.T) {
-			errs := validateObjectiveEnsurances(v.objects, field.NewPath("objectiveEnsurances"), v.httpGetEnable)
+			errs := validateObjectiveEnsurances(v.objects, field.NewPath("objectiveEnsurances"), v.httpGetEnable, v.extObjects)
 			t.Logf("%s: len %d", k, len(errs))
 			if v.expectErr && len(errs) > 0 {
 				if errs[0].Type != v.errorType || !strings.Contains(errs[0].Detail, v.errorDetail) {
@@ -224,3 +265,267 @@ func TestValidateObjectiveEnsurances(t *testing.T) {
 		})
 	}
 }
+
+func TestValidateObjectiveEnsurancesAnnotation(t *testing.T) {
+	cases := map[string]struct {
+		annotation  string
+		metricRule  *ensuranceapi.MetricRule
+		expectErr   bool
+		errorType   field.ErrorType
+		errorDetail string
+	}{
+		"annotation is not set": {
+			annotation: "",
+			expectErr:  false,
+		},
+		"annotation is not json": {
+			annotation:  "aaa",
+			errorType:   field.ErrorTypeInvalid,
+			errorDetail: "failed to parse annotation",
+			expectErr:   true,
+		},
+		"objective is not found": {
+			annotation: `{"bbb": {"rego": "trigger { true }"}}`,
+			errorType:  field.ErrorTypeNotFound,
+			expectErr:  true,
+		},
+		"rego rule is invalid": {
+			annotation:  `{"aaa": {"rego": "trigger { input.metrics.cpu_total_usage[0].value > }"}}`,
+			errorType:   field.ErrorTypeInvalid,
+			errorDetail: "failed to compile rego rule",
+			expectErr:   true,
+		},
+		"rego rule is valid": {
+			annotation: `{"aaa": {"rego": "trigger { input.metrics.cpu_total_usage[0].value > 6000 }"}}`,
+			expectErr:  false,
+		},
+		"operator is not supported": {
+			annotation: `{"aaa": {"operator": "!="}}`,
+			metricRule: &ensuranceapi.MetricRule{Name: "cpu_total_usage", Value: resource.MustParse("6000")},
+			errorType:  field.ErrorTypeNotSupported,
+			expectErr:  true,
+		},
+		"upper value is required for range operator": {
+			annotation: `{"aaa": {"operator": "in"}}`,
+			metricRule: &ensuranceapi.MetricRule{Name: "cpu_total_usage", Value: resource.MustParse("6000")},
+			errorType:  field.ErrorTypeRequired,
+			expectErr:  true,
+		},
+		"upper value is less than value": {
+			annotation: `{"aaa": {"operator": "notin", "upperValue": "5000"}}`,
+			metricRule: &ensuranceapi.MetricRule{Name: "cpu_total_usage", Value: resource.MustParse("6000")},
+			errorType:  field.ErrorTypeInvalid,
+			expectErr:  true,
+		},
+		"restore value is out of the hysteresis band": {
+			annotation:  `{"aaa": {"operator": "<", "restoreValue": "5000"}}`,
+			metricRule:  &ensuranceapi.MetricRule{Name: "cpu_total_usage", Value: resource.MustParse("6000")},
+			errorType:   field.ErrorTypeInvalid,
+			errorDetail: "must be greater than or equal to the value of the metric rule",
+			expectErr:   true,
+		},
+		"window is too large": {
+			annotation:  `{"aaa": {"window": 361}}`,
+			metricRule:  &ensuranceapi.MetricRule{Name: "cpu_total_usage", Value: resource.MustParse("6000")},
+			errorType:   field.ErrorTypeInvalid,
+			errorDetail: "must be between 0 and 360",
+			expectErr:   true,
+		},
+		"window function without window": {
+			annotation:  `{"aaa": {"windowFunction": "max"}}`,
+			metricRule:  &ensuranceapi.MetricRule{Name: "cpu_total_usage", Value: resource.MustParse("6000")},
+			errorType:   field.ErrorTypeInvalid,
+			errorDetail: "only used with a window greater than 1",
+			expectErr:   true,
+		},
+		"window function is not supported": {
+			annotation: `{"aaa": {"window": 6, "windowFunction": "sum"}}`,
+			metricRule: &ensuranceapi.MetricRule{Name: "cpu_total_usage", Value: resource.MustParse("6000")},
+			errorType:  field.ErrorTypeInvalid,
+			expectErr:  true,
+		},
+		"series function is not supported": {
+			annotation: `{"aaa": {"seriesFunction": "rate"}}`,
+			metricRule: &ensuranceapi.MetricRule{Name: "cpu_total_usage", Value: resource.MustParse("6000")},
+			errorType:  field.ErrorTypeInvalid,
+			expectErr:  true,
+		},
+		"aggregation is valid": {
+			annotation: `{"aaa": {"window": 6, "windowFunction": "p95", "seriesFunction": "sum"}}`,
+			metricRule: &ensuranceapi.MetricRule{Name: "cpu_total_usage", Value: resource.MustParse("6000")},
+			expectErr:  false,
+		},
+		"restore value is valid": {
+			annotation: `{"aaa": {"operator": ">=", "restoreValue": "5000"}}`,
+			metricRule: &ensuranceapi.MetricRule{Name: "cpu_total_usage", Value: resource.MustParse("6000")},
+			expectErr:  false,
+		},
+	}
+
+	for k, v := range cases {
+		t.Run(k, func(t *testing.T) {
+			nep := &ensuranceapi.NodeQOSEnsurancePolicy{}
+			nep.Annotations = map[string]string{known.ObjectiveEnsurancesAnnotation: v.annotation}
+			nep.Spec.ObjectiveEnsurances = []ensuranceapi.ObjectiveEnsurance{{Name: "aaa", MetricRule: v.metricRule}}
+			_, errs := validateObjectiveEnsurancesAnnotation(nep, field.NewPath("metadata").Child("annotations").Key(known.ObjectiveEnsurancesAnnotation))
+			if v.expectErr && len(errs) > 0 {
+				if errs[0].Type != v.errorType || !strings.Contains(errs[0].Detail, v.errorDetail) {
+					t.Errorf("[%s] Expected error type %q with detail %q, got %v", k, v.errorType, v.errorDetail, errs)
+				}
+			} else if v.expectErr && len(errs) == 0 {
+				t.Errorf("Unexpected success")
+			}
+			if !v.expectErr && len(errs) != 0 {
+				t.Errorf("Unexpected error(s): %v", errs)
+			}
+		})
+	}
+}
+
+func TestValidateMemoryThrottleAnnotation(t *testing.T) {
+	cases := map[string]struct {
+		annotation string
+		throttle   *ensuranceapi.ThrottleAction
+		expectErr  bool
+	}{
+		"annotation is not set": {
+			expectErr: false,
+		},
+		"annotation is not json": {
+			annotation: "aaa",
+			throttle:   &ensuranceapi.ThrottleAction{},
+			expectErr:  true,
+		},
+		"step ratio is out of range": {
+			annotation: `{"stepMemoryRatio": 101}`,
+			throttle:   &ensuranceapi.ThrottleAction{},
+			expectErr:  true,
+		},
+		"throttle is not set": {
+			annotation: `{"stepMemoryRatio": 10}`,
+			expectErr:  true,
+		},
+		"valid memory throttle": {
+			annotation: `{"minMemoryRatio": 50, "stepMemoryRatio": 10, "memoryHigh": true}`,
+			throttle:   &ensuranceapi.ThrottleAction{},
+			expectErr:  false,
+		},
+	}
+
+	for k, v := range cases {
+		t.Run(k, func(t *testing.T) {
+			action := &ensuranceapi.AvoidanceAction{Spec: ensuranceapi.AvoidanceActionSpec{Throttle: v.throttle}}
+			if v.annotation != "" {
+				action.Annotations = map[string]string{known.MemoryThrottleAnnotation: v.annotation}
+			}
+			errs := validateMemoryThrottleAnnotation(action, field.NewPath("metadata").Child("annotations").Key(known.MemoryThrottleAnnotation))
+			if v.expectErr != (len(errs) != 0) {
+				t.Errorf("Expected error %v, got %v", v.expectErr, errs)
+			}
+		})
+	}
+}
+
+func TestValidateIOThrottleAnnotations(t *testing.T) {
+	cases := map[string]struct {
+		key       string
+		value     string
+		expectErr bool
+	}{
+		"disk io throttle is not json": {
+			key:       known.DiskIOThrottleAnnotation,
+			value:     "aaa",
+			expectErr: true,
+		},
+		"disk io step ratio is out of range": {
+			key:       known.DiskIOThrottleAnnotation,
+			value:     `{"stepIORatio": 101}`,
+			expectErr: true,
+		},
+		"disk io min iops is negative": {
+			key:       known.DiskIOThrottleAnnotation,
+			value:     `{"stepIORatio": 10, "minIOPS": -1}`,
+			expectErr: true,
+		},
+		"valid disk io throttle": {
+			key:       known.DiskIOThrottleAnnotation,
+			value:     `{"minIORatio": 20, "stepIORatio": 20, "minKiBps": 1024, "minIOPS": 100}`,
+			expectErr: false,
+		},
+		"network min ratio is out of range": {
+			key:       known.NetworkThrottleAnnotation,
+			value:     `{"minNetworkRatio": 101, "stepNetworkRatio": 10}`,
+			expectErr: true,
+		},
+		"network interface is invalid": {
+			key:       known.NetworkThrottleAnnotation,
+			value:     `{"stepNetworkRatio": 10, "interface": "eth0/1"}`,
+			expectErr: true,
+		},
+		"valid network throttle": {
+			key:       known.NetworkThrottleAnnotation,
+			value:     `{"minNetworkRatio": 20, "stepNetworkRatio": 20, "minEgressKibps": 1000, "interface": "eth0"}`,
+			expectErr: false,
+		},
+	}
+
+	for k, v := range cases {
+		t.Run(k, func(t *testing.T) {
+			action := &ensuranceapi.AvoidanceAction{}
+			action.Annotations = map[string]string{v.key: v.value}
+			errs := validateDiskIOThrottleAnnotation(action, field.NewPath("metadata").Child("annotations").Key(known.DiskIOThrottleAnnotation))
+			errs = append(errs, validateNetworkThrottleAnnotation(action, field.NewPath("metadata").Child("annotations").Key(known.NetworkThrottleAnnotation))...)
+			if v.expectErr != (len(errs) != 0) {
+				t.Errorf("Expected error %v, got %v", v.expectErr, errs)
+			}
+		})
+	}
+}
+
+func TestValidateThrottleTargetAnnotation(t *testing.T) {
+	cases := map[string]struct {
+		value     string
+		throttle  bool
+		expectErr bool
+	}{
+		"throttle target is not json": {
+			value:     "aaa",
+			throttle:  true,
+			expectErr: true,
+		},
+		"pod selector is invalid": {
+			value:     `{"podSelector": {"matchExpressions": [{"key": "app", "operator": "In"}]}}`,
+			throttle:  true,
+			expectErr: true,
+		},
+		"qos class is not supported": {
+			value:     `{"maxQOSClass": "Unknown"}`,
+			throttle:  true,
+			expectErr: true,
+		},
+		"throttle is not set": {
+			value:     `{"maxQOSClass": "Burstable"}`,
+			throttle:  false,
+			expectErr: true,
+		},
+		"valid throttle target": {
+			value:     `{"podSelector": {"matchLabels": {"app": "batch"}}, "maxQOSClass": "Burstable", "maxPriority": 1000}`,
+			throttle:  true,
+			expectErr: false,
+		},
+	}
+
+	for k, v := range cases {
+		t.Run(k, func(t *testing.T) {
+			action := &ensuranceapi.AvoidanceAction{}
+			action.Annotations = map[string]string{known.ThrottleTargetAnnotation: v.value}
+			if v.throttle {
+				action.Spec.Throttle = &ensuranceapi.ThrottleAction{}
+			}
+			errs := validateThrottleTargetAnnotation(action, field.NewPath("metadata").Child("annotations").Key(known.ThrottleTargetAnnotation))
+			if v.expectErr != (len(errs) != 0) {
+				t.Errorf("Expected error %v, got %v", v.expectErr, errs)
+			}
+		})
+	}
+}
@@ -2,6 +2,7 @@ package ensurance
 
 import (
 	"fmt"
+	"strings"
 
 	"golang.org/x/net/context"
 	corev1 "k8s.io/api/core/v1"
@@ -16,7 +17,10 @@ import (
 	"k8s.io/kubernetes/pkg/apis/core"
 
 	ensuranceapi "github.com/gocrane/api/ensurance/v1alpha1"
+	"github.com/gocrane/crane/pkg/ensurance/analyzer/aggregator"
+	"github.com/gocrane/crane/pkg/ensurance/analyzer/evaluator"
 	"github.com/gocrane/crane/pkg/ensurance/collector"
+	"github.com/gocrane/crane/pkg/ensurance/extension"
 	"github.com/gocrane/crane/pkg/known"
 )
 
@@ -40,13 +44,21 @@ func (p *NepValidationAdmission) ValidateCreate(ctx context.Context, req runtime
 		allErrs = append(allErrs, metavalidation.ValidateLabelSelector(nep.Spec.Selector, field.NewPath("spec").Child("selector"))...)
 	}
 
-	allErrs = append(allErrs, validateNodeQualityProbe(nep.Spec.NodeQualityProbe, field.NewPath("nodeQualityProbe"))...)
+	metricsServerGet, err := extension.GetMetricsServerGet(nep)
+	if err != nil {
+		allErrs = append(allErrs, field.Invalid(field.NewPath("metadata").Child("annotations").Key(known.MetricsServerGetAnnotation), nep.Annotations[known.MetricsServerGetAnnotation], err.Error()))
+	}
+
+	allErrs = append(allErrs, validateNodeQualityProbe(nep.Spec.NodeQualityProbe, metricsServerGet != nil, field.NewPath("nodeQualityProbe"))...)
 
 	var httpGetEnable bool
 	if nep.Spec.NodeQualityProbe.HTTPGet != nil {
 		httpGetEnable = true
 	}
-	allErrs = append(allErrs, validateObjectiveEnsurances(nep.Spec.ObjectiveEnsurances, field.NewPath("objectiveEnsurances"), httpGetEnable)...)
+
+	extObjects, errs := validateObjectiveEnsurancesAnnotation(nep, field.NewPath("metadata").Child("annotations").Key(known.ObjectiveEnsurancesAnnotation))
+	allErrs = append(allErrs, errs...)
+	allErrs = append(allErrs, validateObjectiveEnsurances(nep.Spec.ObjectiveEnsurances, field.NewPath("objectiveEnsurances"), httpGetEnable, extObjects)...)
 
 	if len(allErrs) != 0 {
 		return allErrs.ToAggregate()
@@ -55,9 +67,16 @@ func (p *NepValidationAdmission) ValidateCreate(ctx context.Context, req runtime
 	return nil
 }
 
-func validateNodeQualityProbe(nodeProbe ensuranceapi.NodeQualityProbe, fldPath *field.Path) field.ErrorList {
+func validateNodeQualityProbe(nodeProbe ensuranceapi.NodeQualityProbe, metricsServerGet bool, fldPath *field.Path) field.ErrorList {
 	var allErrs field.ErrorList
 
+	if metricsServerGet {
+		if (nodeProbe.NodeLocalGet != nil) || (nodeProbe.HTTPGet != nil) {
+			allErrs = append(allErrs, field.Invalid(fldPath, "", "The httpGet and nodeLocalGet can not be set with the metrics server probe"))
+		}
+		return allErrs
+	}
+
 	if (nodeProbe.NodeLocalGet == nil) && (nodeProbe.HTTPGet == nil) {
 		allErrs = append(allErrs, field.Invalid(fldPath.Child("httpGet"), "", "HttpGet and nodeLocalGet cannot be empty at the same time."))
 		return allErrs
@@ -83,6 +102,10 @@ func validateHTTPGetAction(http *corev1.HTTPGetAction, fldPath *field.Path) fiel
 	}
 
 	allErrs = append(allErrs, validatePortNumOrName(http.Port, fldPath.Child("port"))...)
+	// the node quality probe is requested by crane-agent which can not resolve the named ports
+	if http.Port.Type != intstr.Int {
+		allErrs = append(allErrs, field.Invalid(fldPath.Child("port"), http.Port.String(), "must be a port number"))
+	}
 
 	var supportedHTTPSchemes = sets.NewString(string(core.URISchemeHTTP), string(core.URISchemeHTTPS))
 	if !supportedHTTPSchemes.Has(string(http.Scheme)) {
@@ -98,7 +121,109 @@ func validateHTTPGetAction(http *corev1.HTTPGetAction, fldPath *field.Path) fiel
 	return allErrs
 }
 
-func validateObjectiveEnsurances(objects []ensuranceapi.ObjectiveEnsurance, fldPath *field.Path, httpGetEnable bool) field.ErrorList {
+func validateObjectiveEnsurancesAnnotation(nep *ensuranceapi.NodeQOSEnsurancePolicy, fldPath *field.Path) (map[string]extension.ObjectiveEnsurance, field.ErrorList) {
+	allErrs := field.ErrorList{}
+
+	extObjects, err := extension.GetObjectiveEnsurances(nep)
+	if err != nil {
+		return extObjects, append(allErrs, field.Invalid(fldPath, nep.Annotations[known.ObjectiveEnsurancesAnnotation], err.Error()))
+	}
+
+	var objects = make(map[string]ensuranceapi.ObjectiveEnsurance)
+	for _, obj := range nep.Spec.ObjectiveEnsurances {
+		objects[obj.Name] = obj
+	}
+
+	for name, extObj := range extObjects {
+		obj, ok := objects[name]
+		if !ok {
+			allErrs = append(allErrs, field.NotFound(fldPath.Key(name), name))
+			continue
+		}
+
+		if extObj.Rego != "" {
+			if _, err := evaluator.CompileRegoRule(extObj.Rego); err != nil {
+				allErrs = append(allErrs, field.Invalid(fldPath.Key(name).Child("rego"), extObj.Rego, err.Error()))
+			}
+		}
+
+		allErrs = append(allErrs, validateMetricThresholds(obj.MetricRule, extObj, fldPath.Key(name))...)
+		allErrs = append(allErrs, validateAggregation(extObj, fldPath.Key(name))...)
+	}
+
+	return extObjects, allErrs
+}
+
+func validateMetricThresholds(rule *ensuranceapi.MetricRule, extObj extension.ObjectiveEnsurance, fldPath *field.Path) field.ErrorList {
+	allErrs := field.ErrorList{}
+
+	var supportedOperators = sets.NewString()
+	for _, op := range evaluator.Operators {
+		supportedOperators.Insert(string(op))
+	}
+
+	var operator = extObj.Operator
+	if operator == "" {
+		operator = evaluator.OperatorGreaterThan
+	}
+
+	if !supportedOperators.Has(string(operator)) {
+		return append(allErrs, field.NotSupported(fldPath.Child("operator"), operator, supportedOperators.List()))
+	}
+
+	if evaluator.IsRangeOperator(operator) {
+		if extObj.UpperValue == nil {
+			allErrs = append(allErrs, field.Required(fldPath.Child("upperValue"), "upperValue is required for range operators"))
+		} else if rule != nil && extObj.UpperValue.Cmp(rule.Value) < 0 {
+			allErrs = append(allErrs, field.Invalid(fldPath.Child("upperValue"), extObj.UpperValue.String(), "must be greater than or equal to the value of the metric rule"))
+		}
+	} else if extObj.UpperValue != nil {
+		allErrs = append(allErrs, field.Invalid(fldPath.Child("upperValue"), extObj.UpperValue.String(), "upperValue is only used by range operators"))
+	}
+
+	if extObj.RestoreValue != nil && rule != nil {
+		switch operator {
+		case evaluator.OperatorGreaterThan, evaluator.OperatorGreaterThanOrEqual:
+			if extObj.RestoreValue.Cmp(rule.Value) > 0 {
+				allErrs = append(allErrs, field.Invalid(fldPath.Child("restoreValue"), extObj.RestoreValue.String(), "must be less than or equal to the value of the metric rule"))
+			}
+		case evaluator.OperatorLessThan, evaluator.OperatorLessThanOrEqual:
+			if extObj.RestoreValue.Cmp(rule.Value) < 0 {
+				allErrs = append(allErrs, field.Invalid(fldPath.Child("restoreValue"), extObj.RestoreValue.String(), "must be greater than or equal to the value of the metric rule"))
+			}
+		default:
+			allErrs = append(allErrs, field.Invalid(fldPath.Child("restoreValue"), extObj.RestoreValue.String(), fmt.Sprintf("restoreValue is not supported by operator %s", operator)))
+		}
+	}
+
+	return allErrs
+}
+
+func validateAggregation(extObj extension.ObjectiveEnsurance, fldPath *field.Path) field.ErrorList {
+	allErrs := field.ErrorList{}
+
+	if extObj.Window < 0 || extObj.Window > aggregator.MaxWindowSize {
+		allErrs = append(allErrs, field.Invalid(fldPath.Child("window"), extObj.Window, fmt.Sprintf("must be between 0 and %d", aggregator.MaxWindowSize)))
+	}
+
+	if extObj.WindowFunction != "" {
+		if extObj.Window <= 1 {
+			allErrs = append(allErrs, field.Invalid(fldPath.Child("windowFunction"), extObj.WindowFunction, "windowFunction is only used with a window greater than 1"))
+		} else if err := aggregator.ValidateWindowFunction(extObj.WindowFunction); err != nil {
+			allErrs = append(allErrs, field.Invalid(fldPath.Child("windowFunction"), extObj.WindowFunction, err.Error()))
+		}
+	}
+
+	if extObj.SeriesFunction != "" {
+		if err := aggregator.ValidateSeriesFunction(extObj.SeriesFunction); err != nil {
+			allErrs = append(allErrs, field.Invalid(fldPath.Child("seriesFunction"), extObj.SeriesFunction, err.Error()))
+		}
+	}
+
+	return allErrs
+}
+
+func validateObjectiveEnsurances(objects []ensuranceapi.ObjectiveEnsurance, fldPath *field.Path, httpGetEnable bool, extObjects map[string]extension.ObjectiveEnsurance) field.ErrorList {
 	allErrs := field.ErrorList{}
 
 	if len(objects) == 0 {
@@ -146,8 +271,11 @@ func validateObjectiveEnsurances(objects []ensuranceapi.ObjectiveEnsurance, fldP
 			allErrs = append(allErrs, genericvalidation.ValidateNonnegativeField(int64(obj.RestoreThreshold), idxPath.Child("restoreThreshold"))...)
 		}
 
+		// the metric rule is not required if the objective is evaluated by rego rule
 		if obj.MetricRule == nil {
-			allErrs = append(allErrs, field.Required(idxPath.Child("metricRule"), ""))
+			if extObjects[obj.Name].Rego == "" {
+				allErrs = append(allErrs, field.Required(idxPath.Child("metricRule"), ""))
+			}
 		} else {
 			allErrs = append(allErrs, validateMetricRule(obj.MetricRule, idxPath.Child("metricRule"), httpGetEnable)...)
 		}
@@ -166,6 +294,9 @@ func validateMetricRule(rule *ensuranceapi.MetricRule, fldPath *field.Path, http
 			if !collector.CheckMetricNameExist(rule.Name) {
 				allErrs = append(allErrs, field.NotSupported(fldPath.Child("name"), rule.Name, []string{}))
 			}
+		} else if collector.CheckMetricNameExist(rule.Name) {
+			// the probe metrics of the built-in names are dropped by crane-agent
+			allErrs = append(allErrs, field.Invalid(fldPath.Child("name"), rule.Name, "the metric of the httpGet probe can not use the name of a built-in metric"))
 		}
 	}
 
@@ -216,6 +347,10 @@ func (p *ActionValidationAdmission) ValidateCreate(ctx context.Context, req runt
 
 	allErrs := genericvalidation.ValidateObjectMeta(&action.ObjectMeta, false, genericvalidation.NameIsDNSLabel, field.NewPath("metadata"))
 	allErrs = append(allErrs, validateAvoidanceActionSpec(action.Spec, field.NewPath("spec"))...)
+	allErrs = append(allErrs, validateMemoryThrottleAnnotation(action, field.NewPath("metadata").Child("annotations").Key(known.MemoryThrottleAnnotation))...)
+	allErrs = append(allErrs, validateDiskIOThrottleAnnotation(action, field.NewPath("metadata").Child("annotations").Key(known.DiskIOThrottleAnnotation))...)
+	allErrs = append(allErrs, validateNetworkThrottleAnnotation(action, field.NewPath("metadata").Child("annotations").Key(known.NetworkThrottleAnnotation))...)
+	allErrs = append(allErrs, validateThrottleTargetAnnotation(action, field.NewPath("metadata").Child("annotations").Key(known.ThrottleTargetAnnotation))...)
 
 	if len(allErrs) != 0 {
 		return allErrs.ToAggregate()
@@ -262,6 +397,112 @@ func validateThrottleAction(throttle *ensuranceapi.ThrottleAction, fldPath *fiel
 	return allErrs
 }
 
+func validateMemoryThrottleAnnotation(action *ensuranceapi.AvoidanceAction, fldPath *field.Path) field.ErrorList {
+	var allErrs field.ErrorList
+
+	memoryThrottle, err := extension.GetMemoryThrottle(action)
+	if err != nil {
+		return append(allErrs, field.Invalid(fldPath, action.Annotations[known.MemoryThrottleAnnotation], err.Error()))
+	}
+
+	if memoryThrottle.MinMemoryRatio < 0 || memoryThrottle.MinMemoryRatio > known.MaxMinMemoryRatio {
+		allErrs = append(allErrs, field.Invalid(fldPath.Child("minMemoryRatio"), memoryThrottle.MinMemoryRatio, fmt.Sprintf("must be between 0 and %d", known.MaxMinMemoryRatio)))
+	}
+
+	if memoryThrottle.StepMemoryRatio < 0 || memoryThrottle.StepMemoryRatio > known.MaxStepMemoryRatio {
+		allErrs = append(allErrs, field.Invalid(fldPath.Child("stepMemoryRatio"), memoryThrottle.StepMemoryRatio, fmt.Sprintf("must be between 0 and %d", known.MaxStepMemoryRatio)))
+	}
+
+	if action.Spec.Throttle == nil && action.Annotations[known.MemoryThrottleAnnotation] != "" {
+		allErrs = append(allErrs, field.Invalid(fldPath, action.Annotations[known.MemoryThrottleAnnotation], "the memory throttle requires spec.throttle"))
+	}
+
+	return allErrs
+}
+
+func validateDiskIOThrottleAnnotation(action *ensuranceapi.AvoidanceAction, fldPath *field.Path) field.ErrorList {
+	var allErrs field.ErrorList
+
+	diskIOThrottle, err := extension.GetDiskIOThrottle(action)
+	if err != nil {
+		return append(allErrs, field.Invalid(fldPath, action.Annotations[known.DiskIOThrottleAnnotation], err.Error()))
+	}
+	if diskIOThrottle == nil {
+		return allErrs
+	}
+
+	if diskIOThrottle.MinIORatio < 0 || diskIOThrottle.MinIORatio > known.MaxMinIORatio {
+		allErrs = append(allErrs, field.Invalid(fldPath.Child("minIORatio"), diskIOThrottle.MinIORatio, fmt.Sprintf("must be between 0 and %d", known.MaxMinIORatio)))
+	}
+
+	if diskIOThrottle.StepIORatio < 0 || diskIOThrottle.StepIORatio > known.MaxStepIORatio {
+		allErrs = append(allErrs, field.Invalid(fldPath.Child("stepIORatio"), diskIOThrottle.StepIORatio, fmt.Sprintf("must be between 0 and %d", known.MaxStepIORatio)))
+	}
+
+	allErrs = append(allErrs, genericvalidation.ValidateNonnegativeField(diskIOThrottle.MinKiBps, fldPath.Child("minKiBps"))...)
+	allErrs = append(allErrs, genericvalidation.ValidateNonnegativeField(diskIOThrottle.MinIOPS, fldPath.Child("minIOPS"))...)
+
+	return allErrs
+}
+
+func validateNetworkThrottleAnnotation(action *ensuranceapi.AvoidanceAction, fldPath *field.Path) field.ErrorList {
+	var allErrs field.ErrorList
+
+	networkThrottle, err := extension.GetNetworkThrottle(action)
+	if err != nil {
+		return append(allErrs, field.Invalid(fldPath, action.Annotations[known.NetworkThrottleAnnotation], err.Error()))
+	}
+	if networkThrottle == nil {
+		return allErrs
+	}
+
+	if networkThrottle.MinNetworkRatio < 0 || networkThrottle.MinNetworkRatio > known.MaxMinNetworkRatio {
+		allErrs = append(allErrs, field.Invalid(fldPath.Child("minNetworkRatio"), networkThrottle.MinNetworkRatio, fmt.Sprintf("must be between 0 and %d", known.MaxMinNetworkRatio)))
+	}
+
+	if networkThrottle.StepNetworkRatio < 0 || networkThrottle.StepNetworkRatio > known.MaxStepNetworkRatio {
+		allErrs = append(allErrs, field.Invalid(fldPath.Child("stepNetworkRatio"), networkThrottle.StepNetworkRatio, fmt.Sprintf("must be between 0 and %d", known.MaxStepNetworkRatio)))
+	}
+
+	allErrs = append(allErrs, genericvalidation.ValidateNonnegativeField(networkThrottle.MinEgressKibps, fldPath.Child("minEgressKibps"))...)
+
+	// the max length of the interface name is IFNAMSIZ-1
+	if len(networkThrottle.Interface) > 15 || strings.ContainsAny(networkThrottle.Interface, " /") {
+		allErrs = append(allErrs, field.Invalid(fldPath.Child("interface"), networkThrottle.Interface, "must be a valid network interface name"))
+	}
+
+	return allErrs
+}
+
+func validateThrottleTargetAnnotation(action *ensuranceapi.AvoidanceAction, fldPath *field.Path) field.ErrorList {
+	var allErrs field.ErrorList
+
+	throttleTarget, err := extension.GetThrottleTarget(action)
+	if err != nil {
+		return append(allErrs, field.Invalid(fldPath, action.Annotations[known.ThrottleTargetAnnotation], err.Error()))
+	}
+	if throttleTarget == nil {
+		return allErrs
+	}
+
+	if throttleTarget.PodSelector != nil {
+		allErrs = append(allErrs, metavalidation.ValidateLabelSelector(throttleTarget.PodSelector, fldPath.Child("podSelector"))...)
+	}
+
+	switch throttleTarget.MaxQOSClass {
+	case "", corev1.PodQOSGuaranteed, corev1.PodQOSBurstable, corev1.PodQOSBestEffort:
+	default:
+		allErrs = append(allErrs, field.NotSupported(fldPath.Child("maxQOSClass"), throttleTarget.MaxQOSClass,
+			[]string{string(corev1.PodQOSGuaranteed), string(corev1.PodQOSBurstable), string(corev1.PodQOSBestEffort)}))
+	}
+
+	if action.Spec.Throttle == nil {
+		allErrs = append(allErrs, field.Invalid(fldPath, action.Annotations[known.ThrottleTargetAnnotation], "the throttle target requires spec.throttle"))
+	}
+
+	return allErrs
+}
+
 func validateEvictionAction(eviction *ensuranceapi.EvictionAction, fldPath *field.Path) field.ErrorList {
 
 	var allErrs field.ErrorList